@@ -6,20 +6,26 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/exaring/otelpgx"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace"
 	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/audience"
 	"splajompy.com/api/v2/internal/auth"
 	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/comment"
+	"splajompy.com/api/v2/internal/counter"
 	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/reaction"
 	"splajompy.com/api/v2/internal/stats"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 
@@ -27,6 +33,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/resend/resend-go/v3"
 
+	"splajompy.com/api/v2/internal/export"
 	"splajompy.com/api/v2/internal/handler"
 	"splajompy.com/api/v2/internal/middleware"
 )
@@ -69,6 +76,7 @@ func main() {
 	defer conn.Close()
 
 	q := queries.New(conn)
+	txManager := transaction.NewManager(conn)
 
 	resendApiKey := os.Getenv("RESEND_API_KEY")
 	resendClient := resend.NewClient(resendApiKey)
@@ -85,10 +93,11 @@ func main() {
 	bucketRepository := bucket.NewS3BucketRepository(s3Client, cloudfrontSigner)
 
 	postRepository := post.NewDBPostRepository(q)
-	userRepository := user.NewUserRepository(q)
+	userRepository := user.NewUserRepository(q, bucketRepository)
 	notificationsRepository := notification.NewNotificationStore(q)
 	commentRepository := comment.NewStore(q)
 	likeRepository := like.NewStore(q)
+	reactionRepository := reaction.NewStore(q)
 	statsRepository := stats.NewStore(q)
 
 	privateKeyString := os.Getenv("APN_PRIVATE_KEY")
@@ -98,23 +107,48 @@ func main() {
 
 	notificationService := notification.NewService(notificationsRepository, postRepository, commentRepository, userRepository, bucketRepository, *apnClient)
 
-	postService := post.NewService(postRepository, userRepository, likeRepository, *notificationService, bucketRepository, resendClient)
+	postService := post.NewService(postRepository, userRepository, likeRepository, reactionRepository, *notificationService, bucketRepository, resendClient, txManager)
 	postHandler := post.NewHandler(postService)
-	commentService := comment.NewService(commentRepository, postRepository, *notificationService, userRepository, likeRepository, bucketRepository)
+	commentService := comment.NewService(commentRepository, postRepository, *notificationService, userRepository, likeRepository, reactionRepository, bucketRepository, txManager)
 	commentHandler := comment.NewHandler(commentService)
-	userService := user.NewUserService(userRepository, *notificationService, resendClient)
+	userService := user.NewUserService(userRepository, *notificationService, bucketRepository, resendClient, txManager)
 	userHandler := user.NewHandler(userService)
 	notificationHandler := notification.NewHandler(notificationService)
-	authService := auth.NewService(userRepository, postRepository, bucketRepository, resendClient)
+	appleVerifier := auth.NewAppleTokenVerifier(auth.NewJWKSKeySource(auth.AppleKeysURL, nil), apns.ProductionBundleId, apns.DevelopmentBundleId)
+	authService := auth.NewService(userRepository, postRepository, bucketRepository, resendClient, appleVerifier, txManager)
 	authHandler := auth.NewHandler(authService)
+	exportService := export.NewService(export.NewStore(q), bucketRepository, resendClient)
+	exportHandler := export.NewHandler(exportService)
 	statsService := stats.NewService(statsRepository)
 	statsHandler := stats.NewHandler(statsService)
+	audienceService := audience.NewService(audience.NewStore(q, userRepository))
+	audienceHandler := audience.NewHandler(audienceService)
+	reactionService := reaction.NewService(reactionRepository, likeRepository, postRepository, commentRepository, *notificationService, reaction.EmojisFromEnv())
+	reactionHandler := reaction.NewHandler(reactionService)
 
-	h := handler.NewHandler(postHandler, commentHandler, userHandler, notificationHandler, authHandler, statsHandler)
+	if err := media.CheckFFmpeg(); err != nil {
+		log.Fatalf("failed to find ffmpeg: %v", err)
+	}
+	mediaStore := media.NewStore(q)
+	mediaWorker := media.NewWorker(mediaStore, bucketRepository, media.FFmpegPosterExtractor{})
+	stagingCleaner := media.NewStagingCleaner(mediaStore, bucketRepository)
+	counterRepairer := counter.NewRepairer(counter.NewStore(q), txManager)
+
+	go utilities.RunPeriodically(ctx, "purge deactivated accounts", 6*time.Hour, authService.PurgeDeactivatedAccounts)
+	go utilities.RunPeriodically(ctx, "process media", 15*time.Second, mediaWorker.ProcessPending)
+	go utilities.RunPeriodically(ctx, "clean staged uploads", time.Hour, stagingCleaner.CleanStaging)
+	go utilities.RunPeriodically(ctx, "notify ended polls", time.Minute, postService.NotifyEndedPolls)
+	go utilities.RunPeriodically(ctx, "delete expired muted words", time.Hour, userService.DeleteExpiredMutedWords)
+	go utilities.RunPeriodically(ctx, "delete expired mutes", time.Hour, userService.DeleteExpiredMutes)
+	go utilities.RunPeriodically(ctx, "repair counters", 6*time.Hour, counterRepairer.Repair)
+	go utilities.RunPeriodically(ctx, "process data exports", time.Minute, exportService.ProcessPending)
+	go utilities.RunPeriodically(ctx, "delete expired exports", time.Hour, exportService.DeleteExpiredExports)
+
+	h := handler.NewHandler(postHandler, commentHandler, userHandler, notificationHandler, authHandler, statsHandler, exportHandler, audienceHandler, reactionHandler)
 
 	mux := http.NewServeMux()
 
-	authMiddleware := middleware.AuthMiddleware(q)
+	authMiddleware := middleware.AuthMiddleware(q, userRepository)
 	h.RegisterRoutes(mux.HandleFunc, authMiddleware)
 
 	routedMux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,179 @@
+package audience
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/utilities"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWriter, *http.Request))) {
+	withAuth("GET /audience-lists", h.GetLists)
+	withAuth("POST /audience-lists", h.CreateList)
+	withAuth("POST /audience-lists/{id}", h.RenameList)
+	withAuth("DELETE /audience-lists/{id}", h.DeleteList)
+
+	withAuth("GET /audience-lists/{id}/members", h.GetMembers)
+	withAuth("POST /audience-lists/{id}/members/{user_id}", h.AddMember)
+	withAuth("DELETE /audience-lists/{id}/members/{user_id}", h.RemoveMember)
+}
+
+type ListNameRequest struct {
+	Name string `json:"name"`
+}
+
+// handleServiceError writes the response for an error returned by the service.
+func handleServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrListNotFound), errors.Is(err, ErrUserNotFound):
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidListName):
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+	default:
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
+
+// GetLists GET /audience-lists
+//
+// Returns the current user's audience lists.
+func (h *Handler) GetLists(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	lists, err := h.svc.GetLists(r.Context(), *currentUser)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleSuccess(w, lists)
+}
+
+// CreateList POST /audience-lists
+//
+// Creates a new, empty audience list.
+func (h *Handler) CreateList(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	var request ListNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	list, err := h.svc.CreateList(r.Context(), *currentUser, request.Name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleSuccess(w, list)
+}
+
+// RenameList POST /audience-lists/{id}
+func (h *Handler) RenameList(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	listId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	var request ListNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.svc.RenameList(r.Context(), *currentUser, listId, request.Name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+// DeleteList DELETE /audience-lists/{id}
+func (h *Handler) DeleteList(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	listId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	err = h.svc.DeleteList(r.Context(), *currentUser, listId)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+// GetMembers GET /audience-lists/{id}/members
+func (h *Handler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	listId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	members, err := h.svc.GetMembers(r.Context(), *currentUser, listId)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleSuccess(w, members)
+}
+
+// AddMember POST /audience-lists/{id}/members/{user_id}
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	h.updateMember(w, r, h.svc.AddMember)
+}
+
+// RemoveMember DELETE /audience-lists/{id}/members/{user_id}
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	h.updateMember(w, r, h.svc.RemoveMember)
+}
+
+func (h *Handler) updateMember(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, currentUser models.PublicUser, listId int, userId int) error) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	listId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	userId, err := utilities.GetIntPathParam(r, "user_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing user ID parameter")
+		return
+	}
+
+	err = update(r.Context(), *currentUser, listId, userId)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
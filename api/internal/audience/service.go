@@ -0,0 +1,130 @@
+package audience
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"splajompy.com/api/v2/internal/models"
+)
+
+// maxListNameLength is the longest name an audience list can have
+const maxListNameLength = 50
+
+var (
+	ErrListNotFound    = errors.New("this audience list does not exist")
+	ErrInvalidListName = errors.New("audience list names must be between 1 and 50 characters")
+	ErrUserNotFound    = errors.New("this user does not exist")
+)
+
+type Service struct {
+	store Store
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// CreateList creates a new audience list for the current user.
+func (s *Service) CreateList(ctx context.Context, currentUser models.PublicUser, name string) (*models.AudienceList, error) {
+	name, err := validateListName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.store.CreateList(ctx, currentUser.UserID, name)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetLists returns the current user's audience lists.
+func (s *Service) GetLists(ctx context.Context, currentUser models.PublicUser) ([]models.AudienceList, error) {
+	return s.store.GetListsForUser(ctx, currentUser.UserID)
+}
+
+// RenameList renames one of the current user's audience lists.
+func (s *Service) RenameList(ctx context.Context, currentUser models.PublicUser, listId int, name string) error {
+	name, err := validateListName(name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureOwner(ctx, currentUser, listId); err != nil {
+		return err
+	}
+
+	return s.store.RenameList(ctx, listId, name)
+}
+
+// DeleteList deletes one of the current user's audience lists. Posts shared with the list
+// stay visible only to their author.
+func (s *Service) DeleteList(ctx context.Context, currentUser models.PublicUser, listId int) error {
+	if err := s.ensureOwner(ctx, currentUser, listId); err != nil {
+		return err
+	}
+
+	return s.store.DeleteList(ctx, listId)
+}
+
+// GetMembers returns the users in one of the current user's audience lists.
+func (s *Service) GetMembers(ctx context.Context, currentUser models.PublicUser, listId int) ([]models.PublicUser, error) {
+	if err := s.ensureOwner(ctx, currentUser, listId); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetMembers(ctx, listId)
+}
+
+// AddMember adds a user to one of the current user's audience lists. Members only see posts
+// shared with the list after they were added.
+func (s *Service) AddMember(ctx context.Context, currentUser models.PublicUser, listId int, userId int) error {
+	if err := s.ensureOwner(ctx, currentUser, listId); err != nil {
+		return err
+	}
+
+	exists, err := s.store.UserExists(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	return s.store.AddMember(ctx, listId, userId)
+}
+
+// RemoveMember removes a user from one of the current user's audience lists.
+func (s *Service) RemoveMember(ctx context.Context, currentUser models.PublicUser, listId int, userId int) error {
+	if err := s.ensureOwner(ctx, currentUser, listId); err != nil {
+		return err
+	}
+
+	return s.store.RemoveMember(ctx, listId, userId)
+}
+
+// ensureOwner returns ErrListNotFound unless the list exists and belongs to the current user,
+// so other users' lists are indistinguishable from missing ones.
+func (s *Service) ensureOwner(ctx context.Context, currentUser models.PublicUser, listId int) error {
+	ownerId, err := s.store.GetListOwner(ctx, listId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrListNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if ownerId != currentUser.UserID {
+		return ErrListNotFound
+	}
+	return nil
+}
+
+func validateListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxListNameLength {
+		return "", ErrInvalidListName
+	}
+	return name, nil
+}
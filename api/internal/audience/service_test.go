@@ -0,0 +1,102 @@
+package audience_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/audience"
+	"splajompy.com/api/v2/internal/testutil"
+)
+
+type audienceServiceTestEnv struct {
+	svc *audience.Service
+	db  *testutil.TestDB
+}
+
+func setupAudienceTest(t *testing.T) audienceServiceTestEnv {
+	t.Helper()
+	testDB := testutil.StartPostgres(t)
+
+	return audienceServiceTestEnv{
+		svc: audience.NewService(audience.NewStore(testDB.Queries, testDB.UserRepository)),
+		db:  testDB,
+	}
+}
+
+func TestCreateAndRenameList(t *testing.T) {
+	env := setupAudienceTest(t)
+	ctx := t.Context()
+
+	user := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+
+	_, err := env.svc.CreateList(ctx, user, "   ")
+	assert.ErrorIs(t, err, audience.ErrInvalidListName)
+
+	list, err := env.svc.CreateList(ctx, user, " friends ")
+	require.NoError(t, err)
+	assert.Equal(t, "friends", list.Name)
+
+	require.NoError(t, env.svc.RenameList(ctx, user, list.ListID, "family"))
+
+	lists, err := env.svc.GetLists(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+	assert.Equal(t, "family", lists[0].Name)
+	assert.Equal(t, 0, lists[0].MemberCount)
+}
+
+func TestListMembers(t *testing.T) {
+	env := setupAudienceTest(t)
+	ctx := t.Context()
+
+	owner := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+	member0 := testutil.CreateTestUser(t, env.db.UserRepository, "user1")
+	member1 := testutil.CreateTestUser(t, env.db.UserRepository, "user2")
+
+	list, err := env.svc.CreateList(ctx, owner, "friends")
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddMember(ctx, owner, list.ListID, member0.UserID))
+	require.NoError(t, env.svc.AddMember(ctx, owner, list.ListID, member1.UserID))
+	// adding someone twice is a no-op
+	require.NoError(t, env.svc.AddMember(ctx, owner, list.ListID, member1.UserID))
+
+	err = env.svc.AddMember(ctx, owner, list.ListID, 999999)
+	assert.ErrorIs(t, err, audience.ErrUserNotFound)
+
+	members, err := env.svc.GetMembers(ctx, owner, list.ListID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	require.NoError(t, env.svc.RemoveMember(ctx, owner, list.ListID, member0.UserID))
+
+	lists, err := env.svc.GetLists(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+	assert.Equal(t, 1, lists[0].MemberCount)
+}
+
+func TestListsAreOnlyVisibleToTheirOwner(t *testing.T) {
+	env := setupAudienceTest(t)
+	ctx := t.Context()
+
+	owner := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+	other := testutil.CreateTestUser(t, env.db.UserRepository, "user1")
+
+	list, err := env.svc.CreateList(ctx, owner, "friends")
+	require.NoError(t, err)
+
+	_, err = env.svc.GetMembers(ctx, other, list.ListID)
+	assert.ErrorIs(t, err, audience.ErrListNotFound)
+	assert.ErrorIs(t, env.svc.AddMember(ctx, other, list.ListID, other.UserID), audience.ErrListNotFound)
+	assert.ErrorIs(t, env.svc.RenameList(ctx, other, list.ListID, "mine"), audience.ErrListNotFound)
+	assert.ErrorIs(t, env.svc.DeleteList(ctx, other, list.ListID), audience.ErrListNotFound)
+
+	lists, err := env.svc.GetLists(ctx, other)
+	require.NoError(t, err)
+	assert.Empty(t, lists)
+
+	require.NoError(t, env.svc.DeleteList(ctx, owner, list.ListID))
+	assert.ErrorIs(t, env.svc.DeleteList(ctx, owner, list.ListID), audience.ErrListNotFound)
+}
@@ -0,0 +1,124 @@
+package audience
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/user"
+)
+
+type Store struct {
+	querier        queries.Querier
+	userRepository user.Store
+}
+
+func NewStore(querier queries.Querier, userRepository user.Store) Store {
+	return Store{querier: querier, userRepository: userRepository}
+}
+
+// CreateList creates a new, empty audience list owned by a user
+func (s Store) CreateList(ctx context.Context, userId int, name string) (models.AudienceList, error) {
+	list, err := s.querier.CreateAudienceList(ctx, queries.CreateAudienceListParams{
+		UserID: userId,
+		Name:   name,
+	})
+	if err != nil {
+		return models.AudienceList{}, err
+	}
+
+	return models.AudienceList{
+		ListID:    list.ListID,
+		Name:      list.Name,
+		CreatedAt: list.CreatedAt.UTC(),
+	}, nil
+}
+
+// GetListOwner retrieves the id of the user who owns an audience list
+func (s Store) GetListOwner(ctx context.Context, listId int) (int, error) {
+	list, err := s.querier.GetAudienceListById(ctx, listId)
+	if err != nil {
+		return 0, err
+	}
+	return list.UserID, nil
+}
+
+// GetListsForUser retrieves every audience list owned by a user, oldest first
+func (s Store) GetListsForUser(ctx context.Context, userId int) ([]models.AudienceList, error) {
+	rows, err := s.querier.ListAudienceListsForUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]models.AudienceList, len(rows))
+	for i, row := range rows {
+		lists[i] = models.AudienceList{
+			ListID:      row.ListID,
+			Name:        row.Name,
+			MemberCount: int(row.MemberCount),
+			CreatedAt:   row.CreatedAt.UTC(),
+		}
+	}
+	return lists, nil
+}
+
+// RenameList changes the name of an audience list
+func (s Store) RenameList(ctx context.Context, listId int, name string) error {
+	return s.querier.RenameAudienceList(ctx, queries.RenameAudienceListParams{
+		ListID: listId,
+		Name:   name,
+	})
+}
+
+// DeleteList removes an audience list and its members
+func (s Store) DeleteList(ctx context.Context, listId int) error {
+	return s.querier.DeleteAudienceList(ctx, listId)
+}
+
+// AddMember adds a user to an audience list
+func (s Store) AddMember(ctx context.Context, listId int, userId int) error {
+	return s.querier.AddAudienceListMember(ctx, queries.AddAudienceListMemberParams{
+		ListID: listId,
+		UserID: userId,
+	})
+}
+
+// RemoveMember removes a user from an audience list
+func (s Store) RemoveMember(ctx context.Context, listId int, userId int) error {
+	return s.querier.RemoveAudienceListMember(ctx, queries.RemoveAudienceListMemberParams{
+		ListID: listId,
+		UserID: userId,
+	})
+}
+
+// GetMembers retrieves the users in an audience list, most recently added first
+func (s Store) GetMembers(ctx context.Context, listId int) ([]models.PublicUser, error) {
+	userIds, err := s.querier.GetAudienceListMemberIds(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepository.GetUsersByIds(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]models.PublicUser, 0, len(userIds))
+	for _, userId := range userIds {
+		if member, ok := users[userId]; ok {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// UserExists checks whether a user with the given id exists
+func (s Store) UserExists(ctx context.Context, userId int) (bool, error) {
+	_, err := s.querier.GetUserById(ctx, userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
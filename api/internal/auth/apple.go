@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AppleIssuer  = "https://appleid.apple.com"
+	AppleKeysURL = "https://appleid.apple.com/auth/keys"
+
+	applePrivateRelayDomain = "@privaterelay.appleid.com"
+
+	jwksCacheTTL           = 24 * time.Hour
+	jwksMinRefreshInterval = time.Minute
+)
+
+var (
+	ErrInvalidAppleToken  = errors.New("invalid apple identity token")
+	ErrAppleKeyNotFound   = errors.New("apple signing key not found")
+	ErrAppleEmailRequired = errors.New("apple identity token does not include a verified email")
+	ErrAppleNotConfigured = errors.New("sign in with apple is not configured")
+	ErrAppleAccountLinked = errors.New("this account is already linked to a different apple id")
+)
+
+// AppleKeySource provides the public keys Apple uses to sign identity tokens.
+type AppleKeySource interface {
+	PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// JWKSKeySource fetches RSA signing keys from a JWKS endpoint. Keys are cached for a day,
+// and an unknown key ID triggers a refetch so that Apple's key rotation is picked up.
+type JWKSKeySource struct {
+	url        string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKSKeySource(url string, httpClient *http.Client) *JWKSKeySource {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKSKeySource{
+		url:        url,
+		httpClient: httpClient,
+	}
+}
+
+func (s *JWKSKeySource) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > jwksCacheTTL
+	if ok && !stale {
+		return key, nil
+	}
+
+	// don't let tokens with made-up key IDs hammer the JWKS endpoint
+	if !ok && !stale && time.Since(s.fetchedAt) < jwksMinRefreshInterval {
+		return nil, ErrAppleKeyNotFound
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	key, ok = s.keys[kid]
+	if !ok {
+		return nil, ErrAppleKeyNotFound
+	}
+
+	return key, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (s *JWKSKeySource) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks: unexpected status %d", res.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+
+	return nil
+}
+
+// AppleIdentity is the verified subset of an Apple identity token.
+type AppleIdentity struct {
+	Subject        string
+	Email          string
+	EmailVerified  bool
+	IsPrivateEmail bool
+}
+
+// appleBool handles Apple sending boolean claims as either JSON booleans or strings.
+type appleBool bool
+
+func (b *appleBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `true`, `"true"`:
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+type appleClaims struct {
+	Email          string    `json:"email"`
+	EmailVerified  appleBool `json:"email_verified"`
+	IsPrivateEmail appleBool `json:"is_private_email"`
+	jwt.RegisteredClaims
+}
+
+// AppleTokenVerifier validates identity tokens issued by Sign in with Apple.
+type AppleTokenVerifier struct {
+	keySource AppleKeySource
+	audiences []string
+}
+
+// NewAppleTokenVerifier creates a verifier that accepts tokens issued to any of the given
+// audiences (the app's bundle IDs).
+func NewAppleTokenVerifier(keySource AppleKeySource, audiences ...string) *AppleTokenVerifier {
+	return &AppleTokenVerifier{
+		keySource: keySource,
+		audiences: audiences,
+	}
+}
+
+// Verify checks the token's signature, issuer, audience and expiry, and returns the identity it carries.
+func (v *AppleTokenVerifier) Verify(ctx context.Context, identityToken string) (*AppleIdentity, error) {
+	var claims appleClaims
+	_, err := jwt.ParseWithClaims(identityToken, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrAppleKeyNotFound
+		}
+		return v.keySource.PublicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(AppleIssuer),
+		jwt.WithAudience(v.audiences...),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAppleToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, ErrInvalidAppleToken
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	return &AppleIdentity{
+		Subject:        claims.Subject,
+		Email:          email,
+		EmailVerified:  bool(claims.EmailVerified),
+		IsPrivateEmail: bool(claims.IsPrivateEmail) || strings.HasSuffix(email, applePrivateRelayDomain),
+	}, nil
+}
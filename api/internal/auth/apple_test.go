@@ -0,0 +1,223 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/auth"
+)
+
+const testAppleAudience = "splajompy.com.Splajompy"
+
+// fakeAppleJWKS serves a JWKS containing a single RSA key, standing in for Apple's key endpoint.
+type fakeAppleJWKS struct {
+	key      *rsa.PrivateKey
+	kid      string
+	server   *httptest.Server
+	requests atomic.Int32
+}
+
+func newFakeAppleJWKS(t *testing.T) *fakeAppleJWKS {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	f := &fakeAppleJWKS{key: key, kid: "test-key"}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": f.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeAppleJWKS) verifier() *auth.AppleTokenVerifier {
+	return auth.NewAppleTokenVerifier(auth.NewJWKSKeySource(f.server.URL, f.server.Client()), testAppleAudience)
+}
+
+func (f *fakeAppleJWKS) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	return signAppleToken(t, f.key, f.kid, claims)
+}
+
+func signAppleToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func appleClaims(subject string, email string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            auth.AppleIssuer,
+		"aud":            testAppleAudience,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": "true",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestAppleTokenVerifier_Verify(t *testing.T) {
+	jwks := newFakeAppleJWKS(t)
+	verifier := jwks.verifier()
+
+	identity, err := verifier.Verify(t.Context(), jwks.sign(t, appleClaims("000123.abc", "Wesley@Example.com")))
+	require.NoError(t, err)
+	assert.Equal(t, "000123.abc", identity.Subject)
+	assert.Equal(t, "wesley@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.False(t, identity.IsPrivateEmail)
+}
+
+func TestAppleTokenVerifier_PrivateRelayEmail(t *testing.T) {
+	jwks := newFakeAppleJWKS(t)
+	verifier := jwks.verifier()
+
+	claims := appleClaims("000123.abc", "x7k2p9@privaterelay.appleid.com")
+	claims["email_verified"] = true
+	claims["is_private_email"] = "true"
+
+	identity, err := verifier.Verify(t.Context(), jwks.sign(t, claims))
+	require.NoError(t, err)
+	assert.True(t, identity.EmailVerified)
+	assert.True(t, identity.IsPrivateEmail)
+
+	// the relay domain alone is enough to mark the email as private
+	delete(claims, "is_private_email")
+	identity, err = verifier.Verify(t.Context(), jwks.sign(t, claims))
+	require.NoError(t, err)
+	assert.True(t, identity.IsPrivateEmail)
+}
+
+func TestAppleTokenVerifier_RejectsInvalidTokens(t *testing.T) {
+	jwks := newFakeAppleJWKS(t)
+	verifier := jwks.verifier()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := appleClaims("sub", "a@example.com")
+				claims["aud"] = "com.example.other"
+				return jwks.sign(t, claims)
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := appleClaims("sub", "a@example.com")
+				claims["iss"] = "https://example.com"
+				return jwks.sign(t, claims)
+			},
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := appleClaims("sub", "a@example.com")
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+				return jwks.sign(t, claims)
+			},
+		},
+		{
+			name: "missing expiry",
+			token: func() string {
+				claims := appleClaims("sub", "a@example.com")
+				delete(claims, "exp")
+				return jwks.sign(t, claims)
+			},
+		},
+		{
+			name: "missing subject",
+			token: func() string {
+				claims := appleClaims("", "a@example.com")
+				return jwks.sign(t, claims)
+			},
+		},
+		{
+			name: "unknown key id",
+			token: func() string {
+				return signAppleToken(t, jwks.key, "unknown", appleClaims("sub", "a@example.com"))
+			},
+		},
+		{
+			name: "signed with a different key",
+			token: func() string {
+				return signAppleToken(t, otherKey, jwks.kid, appleClaims("sub", "a@example.com"))
+			},
+		},
+		{
+			name: "symmetric signing method",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, appleClaims("sub", "a@example.com"))
+				token.Header["kid"] = jwks.kid
+				signed, err := token.SignedString([]byte("secret"))
+				require.NoError(t, err)
+				return signed
+			},
+		},
+		{
+			name: "malformed",
+			token: func() string {
+				return "not-a-token"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(t.Context(), tt.token())
+			assert.ErrorIs(t, err, auth.ErrInvalidAppleToken)
+		})
+	}
+}
+
+func TestJWKSKeySource_CachesKeys(t *testing.T) {
+	jwks := newFakeAppleJWKS(t)
+	source := auth.NewJWKSKeySource(jwks.server.URL, jwks.server.Client())
+
+	for range 3 {
+		key, err := source.PublicKey(t.Context(), jwks.kid)
+		require.NoError(t, err)
+		assert.Equal(t, jwks.key.PublicKey.N, key.N)
+	}
+
+	_, err := source.PublicKey(t.Context(), "unknown")
+	assert.ErrorIs(t, err, auth.ErrAppleKeyNotFound)
+
+	assert.Equal(t, int32(1), jwks.requests.Load())
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"splajompy.com/api/v2/internal/utilities"
@@ -18,6 +19,7 @@ func NewHandler(svc *Service) *Handler {
 func (h *Handler) RegisterRoutes(public, withAuth func(string, func(http.ResponseWriter, *http.Request))) {
 	public("POST /register", h.Register)
 	public("POST /login", h.Login)
+	public("POST /login/apple", h.LoginWithApple)
 	public("POST /otc/generate", h.GenerateOTC)
 	public("POST /otc/verify", h.VerifyOTC)
 	withAuth("POST /account/delete", h.DeleteAccount)
@@ -64,6 +66,49 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	utilities.HandleSuccess(w, response)
 }
 
+type AppleLoginRequest struct {
+	IdentityToken string `json:"identityToken"`
+	Name          string `json:"name"`
+}
+
+// LoginWithApple authenticates a user with a Sign in with Apple identity token, creating an
+// account on first use.
+//
+// Request body should contain:
+//   - identityToken: the JWT returned by Apple to the client
+//   - name: optional full name, which Apple only shares with the client on first sign in
+//
+// Returns 200 with auth token on success, 401 for an invalid token.
+func (h *Handler) LoginWithApple(w http.ResponseWriter, r *http.Request) {
+	var request AppleLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if request.IdentityToken == "" {
+		utilities.HandleError(w, http.StatusBadRequest, "Validation error")
+		return
+	}
+
+	response, err := h.svc.SignInWithApple(r.Context(), request.IdentityToken, request.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAppleToken):
+			utilities.HandleError(w, http.StatusUnauthorized, "Unable to verify Apple sign in")
+		case errors.Is(err, ErrAppleEmailRequired):
+			utilities.HandleError(w, http.StatusBadRequest, "An email address is required to sign in with Apple")
+		case errors.Is(err, ErrAppleAccountLinked):
+			utilities.HandleError(w, http.StatusConflict, "This account is already linked to a different Apple ID")
+		default:
+			utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		}
+		return
+	}
+
+	utilities.HandleSuccess(w, response)
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
@@ -146,7 +191,8 @@ type DeleteAccountRequest struct {
 	Password string `json:"password"`
 }
 
-// DeleteAccount deletes the current users account, given the correct password.
+// DeleteAccount deactivates the current users account, given the correct password. The account
+// is permanently deleted once the grace period passes, unless the user signs back in before then.
 //
 // Request body should contain:
 //   - password: user's password
@@ -167,7 +213,7 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.svc.DeleteAccount(r.Context(), *user)
+	err = h.svc.DeactivateAccount(r.Context(), *user)
 	if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
@@ -10,7 +10,7 @@ import (
 )
 
 func TestAuthService_ValidateRegistrationData(t *testing.T) {
-	authService := auth.NewService(user.Store{}, post.Store{}, nil, nil)
+	authService := auth.NewService(user.Store{}, post.Store{}, nil, nil, nil, nil)
 
 	tests := []struct {
 		name     string
@@ -6,16 +6,21 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
+	"os"
 	"strings"
 	"time"
 
 	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/resend/resend-go/v3"
 	"golang.org/x/crypto/bcrypt"
 	"splajompy.com/api/v2/internal/models"
@@ -27,17 +32,25 @@ type Service struct {
 	postRepository   post.Store
 	bucketRepository bucket.Repository
 	resendClient     *resend.Client
+	appleVerifier    *AppleTokenVerifier
+	txManager        *transaction.Manager
 }
 
-func NewService(userRepository user.Store, postRepository post.Store, bucketRepository bucket.Repository, resendClient *resend.Client) *Service {
+func NewService(userRepository user.Store, postRepository post.Store, bucketRepository bucket.Repository, resendClient *resend.Client, appleVerifier *AppleTokenVerifier, txManager *transaction.Manager) *Service {
 	return &Service{
 		userRepository:   userRepository,
 		postRepository:   postRepository,
 		bucketRepository: bucketRepository,
 		resendClient:     resendClient,
+		appleVerifier:    appleVerifier,
+		txManager:        txManager,
 	}
 }
 
+// DeactivationGracePeriod is how long a deactivated account can be restored by signing back in
+// before it is permanently deleted.
+const DeactivationGracePeriod = 30 * 24 * time.Hour
+
 var (
 	ErrUserNotFound          = errors.New("user not found")
 	ErrInvalidPassword       = errors.New("incorrect password")
@@ -90,13 +103,13 @@ func (s *Service) Register(ctx context.Context, email string, username string, p
 		return nil, err
 	}
 
-	sessionId, err := s.createSessionToken(ctx, user.UserID)
+	token, err := s.createSessionToken(ctx, user.UserID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		Token: sessionId,
+		Token: token,
 		User:  user,
 	}, nil
 }
@@ -175,6 +188,10 @@ func (s *Service) LoginWithCredentials(ctx context.Context, credentials *Credent
 		return nil, ErrUserNotFound
 	}
 
+	if err := s.restoreAccount(ctx, user.UserID); err != nil {
+		return nil, err
+	}
+
 	token, err := s.createSessionToken(ctx, user.UserID)
 	if err != nil {
 		return nil, ErrGeneral
@@ -205,6 +222,10 @@ func (s *Service) VerifyOTCCode(ctx context.Context, identifier string, code str
 		return nil, errors.New("code expired")
 	}
 
+	if err := s.restoreAccount(ctx, user.UserID); err != nil {
+		return nil, err
+	}
+
 	token, err := s.createSessionToken(ctx, user.UserID)
 	if err != nil {
 		return nil, ErrGeneral
@@ -218,6 +239,195 @@ func (s *Service) VerifyOTCCode(ctx context.Context, identifier string, code str
 	}, nil
 }
 
+// SignInWithApple signs a user in with an Apple identity token. Tokens for an Apple ID that is
+// already linked sign in to that account; otherwise the account with the same verified email is
+// linked, or a new account is created with a generated username.
+func (s *Service) SignInWithApple(ctx context.Context, identityToken string, name string) (*AuthResponse, error) {
+	if s.appleVerifier == nil {
+		return nil, ErrAppleNotConfigured
+	}
+
+	identity, err := s.appleVerifier.Verify(ctx, identityToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getOrCreateAppleUser(ctx, identity, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.restoreAccount(ctx, user.UserID); err != nil {
+		return nil, err
+	}
+
+	token, err := s.createSessionToken(ctx, user.UserID)
+	if err != nil {
+		return nil, ErrGeneral
+	}
+
+	return &AuthResponse{
+		Token: token,
+		User:  user,
+	}, nil
+}
+
+func (s *Service) getOrCreateAppleUser(ctx context.Context, identity *AppleIdentity, name string) (models.FullUser, error) {
+	linked, err := s.userRepository.GetAppleIdentity(ctx, identity.Subject)
+	if err == nil {
+		return s.userRepository.GetFullUserById(ctx, linked.UserID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.FullUser{}, err
+	}
+
+	// Apple only includes the email on the token when it has been verified, but be explicit
+	// about it since linking by an unverified address would hand over someone else's account.
+	if identity.Email == "" || !identity.EmailVerified {
+		return models.FullUser{}, ErrAppleEmailRequired
+	}
+
+	// a new account is only kept if the Apple ID can be linked to it
+	var user models.FullUser
+	err = s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		userRepository := s.userRepository.WithTx(uow)
+
+		user, err = userRepository.GetUserByEmail(ctx, identity.Email)
+		if errors.Is(err, pgx.ErrNoRows) {
+			user, err = s.createAppleUser(ctx, userRepository, identity, name)
+		}
+		if err != nil {
+			return err
+		}
+
+		linked, err := userRepository.LinkAppleIdentity(ctx, user.UserID, identity.Subject, identity.Email, identity.IsPrivateEmail)
+		if err != nil {
+			return err
+		}
+		if !linked {
+			return ErrAppleAccountLinked
+		}
+		return nil
+	})
+	if errors.Is(err, ErrAppleAccountLinked) {
+		// a concurrent sign in with the same token may have linked the Apple ID first
+		linked, lookupErr := s.userRepository.GetAppleIdentity(ctx, identity.Subject)
+		if lookupErr == nil {
+			return s.userRepository.GetFullUserById(ctx, linked.UserID)
+		}
+		if !errors.Is(lookupErr, pgx.ErrNoRows) {
+			return models.FullUser{}, lookupErr
+		}
+	}
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) createAppleUser(ctx context.Context, userRepository user.Store, identity *AppleIdentity, name string) (models.FullUser, error) {
+	username, err := s.generateUsername(ctx, appleUsernameBase(name, identity))
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	// the account has no password, so store a hash of random bytes nobody knows
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return models.FullUser{}, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(base64.StdEncoding.EncodeToString(b)), 10)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	referralCode, err := s.generateReferralCode(ctx)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	user, err := userRepository.CreateUser(ctx, username, identity.Email, string(hashedPassword), *referralCode)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	if name != "" {
+		if err := userRepository.UpdateUserName(ctx, user.UserID, name); err != nil {
+			return models.FullUser{}, err
+		}
+		user.Name = name
+	}
+
+	return user, nil
+}
+
+// appleUsernameBase picks the starting point for a generated username: the user's name if Apple
+// shared it, otherwise the local part of their email. Private relay addresses are random
+// strings, so they're never used.
+func appleUsernameBase(name string, identity *AppleIdentity) string {
+	if base := sanitizeUsername(name); base != "" {
+		return base
+	}
+
+	if !identity.IsPrivateEmail {
+		localPart, _, _ := strings.Cut(identity.Email, "@")
+		if base := sanitizeUsername(localPart); base != "" {
+			return base
+		}
+	}
+
+	return "user"
+}
+
+// sanitizeUsername lowercases s and strips anything that isn't allowed in a username.
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+
+	username := strings.Trim(b.String(), "._")
+	if len(username) > usernameBaseMaxLength {
+		username = strings.TrimRight(username[:usernameBaseMaxLength], "._")
+	}
+	if len(username) < 2 {
+		return ""
+	}
+
+	return username
+}
+
+// usernameBaseMaxLength leaves room for a numeric suffix within the 25 character limit.
+const usernameBaseMaxLength = 20
+
+// generateUsername returns an unused username derived from base, appending a random numeric
+// suffix when base is already taken.
+func (s *Service) generateUsername(ctx context.Context, base string) (string, error) {
+	username := base
+	for range 10 {
+		if utilities.UsernameRegex.MatchString(username) {
+			inUse, err := s.userRepository.GetIsUsernameInUse(ctx, username)
+			if err != nil {
+				return "", err
+			}
+			if !inUse {
+				return username, nil
+			}
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s%d", base, n.Int64())
+	}
+
+	return "", ErrUsernameTaken
+}
+
 func (s *Service) ProcessOTC(ctx context.Context, identifier string) error {
 	identifier = strings.ToLower(identifier)
 
@@ -263,23 +473,21 @@ func (s *Service) GenerateOTCCode() (string, error) {
 	return code, nil
 }
 
+// createSessionToken creates a session for the user and returns its token. Only a hash of the
+// token is stored, so it can't be recovered from the database.
 func (s *Service) createSessionToken(ctx context.Context, userId int) (string, error) {
-	b := make([]byte, 64)
-
-	_, err := rand.Read(b)
+	token, sessionId, err := utilities.NewSessionToken()
 	if err != nil {
 		return "", err
 	}
 
-	sessionId := base64.StdEncoding.EncodeToString(b)
-
 	err = s.userRepository.CreateSession(ctx, sessionId, userId,
 		time.Now().Add(time.Hour*24*90))
 	if err != nil {
 		return "", err
 	}
 
-	return sessionId, nil
+	return token, nil
 }
 
 // generateReferralCode returns a unique referral code, generated by taking the prefix of a UUID
@@ -318,32 +526,108 @@ func (s *Service) sendSignInEmail(email, username string) {
 	_, _ = s.resendClient.Emails.Send(params)
 }
 
-func (s *Service) DeleteAccount(ctx context.Context, currentUser models.PublicUser) error {
-	images, err := s.postRepository.GetAllImagesForUser(ctx, currentUser.UserID)
+// DeactivateAccount hides a user's profile, posts and comments and signs them out everywhere.
+// Signing back in within DeactivationGracePeriod restores the account; after that it is
+// permanently deleted by PurgeDeactivatedAccounts.
+func (s *Service) DeactivateAccount(ctx context.Context, currentUser models.PublicUser) error {
+	// a deactivated account must not keep any sessions that would let it be used
+	return s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		return s.userRepository.WithTx(uow).DeactivateAccount(ctx, currentUser.UserID)
+	})
+}
+
+// restoreAccount reactivates a deactivated account when its owner signs back in during the
+// grace period. Accounts past the grace period are treated as though they were already deleted.
+func (s *Service) restoreAccount(ctx context.Context, userId int) error {
+	deactivatedAt, err := s.userRepository.GetDeactivatedAt(ctx, userId)
 	if err != nil {
-		return fmt.Errorf("failed to get user images: %w", err)
+		return err
+	}
+	if deactivatedAt == nil {
+		return nil
 	}
 
-	var s3Keys []string
-	for _, image := range images {
-		if image.ImageBlobUrl != "" {
-			s3Keys = append(s3Keys, image.ImageBlobUrl)
+	if time.Since(*deactivatedAt) > DeactivationGracePeriod {
+		return ErrUserNotFound
+	}
+
+	return s.userRepository.ReactivateAccount(ctx, userId)
+}
+
+// PurgeDeactivatedAccounts permanently deletes every account that has been deactivated for longer
+// than DeactivationGracePeriod. An account whose images can't be removed is kept, so it can be
+// picked up again on the next run.
+func (s *Service) PurgeDeactivatedAccounts(ctx context.Context) error {
+	userIds, err := s.userRepository.GetUserIdsDeactivatedBefore(ctx, time.Now().Add(-DeactivationGracePeriod))
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range userIds {
+		if err := s.purgeAccount(ctx, userId); err != nil {
+			slog.ErrorContext(ctx, "failed to purge deactivated account", "user_id", userId, "error", err)
 		}
 	}
 
-	// Delete the user account (this will CASCADE delete all related data)
-	err = s.userRepository.DeleteAccount(ctx, currentUser.UserID)
+	return nil
+}
+
+func (s *Service) purgeAccount(ctx context.Context, userId int) error {
+	s3Keys, err := s.getUserBlobKeys(ctx, userId)
 	if err != nil {
-		return fmt.Errorf("failed to delete user account: %w", err)
+		return err
 	}
 
-	// Delete images from S3 (best effort - don't fail if this fails)
+	// blobs go first, since their keys are lost once the account is deleted
 	if len(s3Keys) > 0 {
-		err = s.bucketRepository.DeleteObjects(ctx, s3Keys)
+		err = utilities.Retry(ctx, 3, time.Second, func() error {
+			return s.bucketRepository.DeleteObjects(ctx, s3Keys)
+		})
 		if err != nil {
-			fmt.Printf("Warning: Failed to delete %d images from S3 for user %d: %v\n", len(s3Keys), currentUser.UserID, err)
+			return fmt.Errorf("failed to delete %d images: %w", len(s3Keys), err)
 		}
 	}
 
+	// this will CASCADE delete all related data
+	if err := s.userRepository.DeleteAccount(ctx, userId); err != nil {
+		return fmt.Errorf("failed to delete user account: %w", err)
+	}
+
 	return nil
 }
+
+// getUserBlobKeys returns the keys of every blob a user owns: their post images and resized
+// variants, avatar and banner, and their data exports.
+func (s *Service) getUserBlobKeys(ctx context.Context, userId int) ([]string, error) {
+	images, err := s.postRepository.GetAllImagesForUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user images: %w", err)
+	}
+
+	avatarKey, bannerKey, err := s.userRepository.GetProfileImageKeys(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile images: %w", err)
+	}
+
+	var s3Keys []string
+	for _, image := range images {
+		if image.BlobKey != "" {
+			s3Keys = append(s3Keys, media.BlobKeys(image)...)
+		}
+	}
+	for _, key := range []string{avatarKey, bannerKey} {
+		if key != "" {
+			s3Keys = append(s3Keys, key)
+		}
+	}
+
+	exports, err := s.bucketRepository.ListObjects(ctx, bucket.ExportPrefix(os.Getenv("ENVIRONMENT"), userId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+	for _, export := range exports {
+		s3Keys = append(s3Keys, export.Key)
+	}
+
+	return s3Keys, nil
+}
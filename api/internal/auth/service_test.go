@@ -2,17 +2,30 @@ package auth_test
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/auth"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/testutil"
 	"splajompy.com/api/v2/internal/user"
+	"splajompy.com/api/v2/internal/utilities"
 )
 
 type authServiceTestEnv struct {
 	svc            *auth.Service
 	userRepository user.Store
+	queries        *queries.Queries
+	pool           *pgxpool.Pool
+	apple          *fakeAppleJWKS
+	bucket         bucket.Repository
 }
 
 func setupAuthServiceTest(t *testing.T) authServiceTestEnv {
@@ -21,22 +34,188 @@ func setupAuthServiceTest(t *testing.T) authServiceTestEnv {
 
 	_ = os.Setenv("ENVIRONMENT", "test")
 
-	svc := auth.NewService(db.UserRepository, db.PostRepository, db.BucketRepository, nil)
+	apple := newFakeAppleJWKS(t)
+	svc := auth.NewService(db.UserRepository, db.PostRepository, db.BucketRepository, nil, apple.verifier(), db.TxManager)
 
 	return authServiceTestEnv{
 		svc:            svc,
 		userRepository: db.UserRepository,
+		queries:        db.Queries,
+		pool:           db.Pool,
+		apple:          apple,
+		bucket:         db.BucketRepository,
 	}
 }
 
-func TestDeleteAccount(t *testing.T) {
+func TestRegister_StoresHashedSession(t *testing.T) {
 	env := setupAuthServiceTest(t)
 
-	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	res, err := env.svc.Register(t.Context(), "user0@splajompy.com", "user0", "password123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Token, utilities.SessionTokenPrefix))
 
-	err := env.svc.DeleteAccount(t.Context(), user0)
+	_, err = env.queries.GetSessionById(t.Context(), res.Token)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	session, err := env.queries.GetSessionById(t.Context(), utilities.HashSessionToken(res.Token))
+	require.NoError(t, err)
+	assert.Equal(t, res.User.UserID, session.UserID)
+}
+
+func TestSignInWithApple_CreatesUser(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	token := env.apple.sign(t, appleClaims("apple-sub-1", "wesley@example.com"))
+
+	res, err := env.svc.SignInWithApple(t.Context(), token, "Wesley Weisenberger")
+	require.NoError(t, err)
+	assert.NotEmpty(t, res.Token)
+	assert.Equal(t, "wesley@example.com", res.User.Email)
+	assert.Equal(t, "wesleyweisenberger", res.User.Username)
+	assert.Equal(t, "Wesley Weisenberger", res.User.Name)
+
+	// signing in again with the same Apple ID returns the same account
+	res2, err := env.svc.SignInWithApple(t.Context(), token, "")
+	require.NoError(t, err)
+	assert.Equal(t, res.User.UserID, res2.User.UserID)
+}
+
+func TestSignInWithApple_LinksExistingAccountByEmail(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	existing := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	token := env.apple.sign(t, appleClaims("apple-sub-1", "User0@Splajompy.com"))
+
+	res, err := env.svc.SignInWithApple(t.Context(), token, "")
+	require.NoError(t, err)
+	assert.Equal(t, existing.UserID, res.User.UserID)
+	assert.Equal(t, "user0", res.User.Username)
+}
+
+func TestSignInWithApple_RejectsAccountLinkedToAnotherAppleID(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	existing := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	_, err := env.svc.SignInWithApple(t.Context(), env.apple.sign(t, appleClaims("apple-sub-1", "user0@splajompy.com")), "")
+	require.NoError(t, err)
+
+	_, err = env.svc.SignInWithApple(t.Context(), env.apple.sign(t, appleClaims("apple-sub-2", "user0@splajompy.com")), "")
+	assert.ErrorIs(t, err, auth.ErrAppleAccountLinked)
+
+	linked, err := env.userRepository.GetAppleIdentity(t.Context(), "apple-sub-1")
+	require.NoError(t, err)
+	assert.Equal(t, existing.UserID, linked.UserID)
+}
+
+func TestSignInWithApple_DoesNotLinkUnverifiedEmail(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	claims := appleClaims("apple-sub-1", "user0@splajompy.com")
+	claims["email_verified"] = "false"
+
+	_, err := env.svc.SignInWithApple(t.Context(), env.apple.sign(t, claims), "")
+	assert.ErrorIs(t, err, auth.ErrAppleEmailRequired)
+}
+
+func TestSignInWithApple_PrivateRelayEmail(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	token := env.apple.sign(t, appleClaims("apple-sub-1", "x7k2p9qz@privaterelay.appleid.com"))
+
+	res, err := env.svc.SignInWithApple(t.Context(), token, "")
+	require.NoError(t, err)
+	assert.Equal(t, "x7k2p9qz@privaterelay.appleid.com", res.User.Email)
+	assert.NotContains(t, res.User.Username, "x7k2p9qz")
+	assert.Regexp(t, utilities.UsernameRegex, res.User.Username)
+}
+
+func TestSignInWithApple_GeneratesUniqueUsername(t *testing.T) {
+	env := setupAuthServiceTest(t)
+
+	testutil.CreateTestUser(t, env.userRepository, "wesley")
+
+	token := env.apple.sign(t, appleClaims("apple-sub-1", "wesley@example.com"))
+
+	res, err := env.svc.SignInWithApple(t.Context(), token, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, "wesley", res.User.Username)
+	assert.Regexp(t, "^wesley[0-9]+$", res.User.Username)
+	assert.LessOrEqual(t, len(res.User.Username), 25)
+}
+
+func TestDeactivateAccount_RestoredBySigningIn(t *testing.T) {
+	env := setupAuthServiceTest(t)
+	ctx := t.Context()
+
+	res, err := env.svc.Register(ctx, "user0@splajompy.com", "user0", "password123")
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.DeactivateAccount(ctx, models.PublicUser{UserID: res.User.UserID}))
+
+	// deactivating signs the user out everywhere
+	_, err = env.queries.GetSessionById(ctx, utilities.HashSessionToken(res.Token))
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = env.svc.LoginWithCredentials(ctx, &auth.Credentials{Identifier: "user0", Password: "password123"})
+	require.NoError(t, err)
+
+	deactivatedAt, err := env.userRepository.GetDeactivatedAt(ctx, res.User.UserID)
+	require.NoError(t, err)
+	assert.Nil(t, deactivatedAt)
+}
+
+func TestDeactivateAccount_NotRestoredAfterGracePeriod(t *testing.T) {
+	env := setupAuthServiceTest(t)
+	ctx := t.Context()
+
+	res, err := env.svc.Register(ctx, "user0@splajompy.com", "user0", "password123")
+	require.NoError(t, err)
+
+	backdateDeactivation(t, env, res.User.UserID, auth.DeactivationGracePeriod+time.Hour)
+
+	_, err = env.svc.LoginWithCredentials(ctx, &auth.Credentials{Identifier: "user0", Password: "password123"})
+	assert.ErrorIs(t, err, auth.ErrUserNotFound)
+}
+
+func TestPurgeDeactivatedAccounts(t *testing.T) {
+	env := setupAuthServiceTest(t)
+	ctx := t.Context()
+
+	expired := testutil.CreateTestUser(t, env.userRepository, "user0")
+	recent := testutil.CreateTestUser(t, env.userRepository, "user1")
+	active := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	backdateDeactivation(t, env, expired.UserID, auth.DeactivationGracePeriod+time.Hour)
+	require.NoError(t, env.svc.DeactivateAccount(ctx, recent))
+
+	for _, u := range []models.PublicUser{expired, recent} {
+		key := bucket.ExportPrefix(os.Getenv("ENVIRONMENT"), u.UserID) + "export.zip"
+		require.NoError(t, env.bucket.PutObject(ctx, key, "application/zip", strings.NewReader("archive")))
+	}
+
+	require.NoError(t, env.svc.PurgeDeactivatedAccounts(ctx))
+
+	exports, err := env.bucket.ListObjects(ctx, bucket.ExportPrefix(os.Getenv("ENVIRONMENT"), expired.UserID))
+	require.NoError(t, err)
+	assert.Empty(t, exports, "a purged account's data exports are deleted")
+	exports, err = env.bucket.ListObjects(ctx, bucket.ExportPrefix(os.Getenv("ENVIRONMENT"), recent.UserID))
+	require.NoError(t, err)
+	assert.Len(t, exports, 1)
+
+	_, err = env.userRepository.GetUserById(ctx, expired.UserID)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = env.userRepository.GetUserById(ctx, recent.UserID)
+	assert.NoError(t, err)
+	_, err = env.userRepository.GetUserById(ctx, active.UserID)
 	assert.NoError(t, err)
+}
 
-	_, err = env.userRepository.GetUserById(t.Context(), user0.UserID)
-	assert.Error(t, err)
+func backdateDeactivation(t *testing.T, env authServiceTestEnv, userId int, age time.Duration) {
+	t.Helper()
+	_, err := env.pool.Exec(t.Context(), "UPDATE users SET deactivated_at = $1 WHERE user_id = $2", time.Now().Add(-age), userId)
+	require.NoError(t, err)
 }
@@ -1,22 +1,111 @@
 package bucket
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"splajompy.com/api/v2/internal/models"
 )
 
-type FakeBucketRepository struct{}
+var ErrFakeObjectNotFound = errors.New("object not found")
 
-func (f *FakeBucketRepository) CopyObject(_ context.Context, _, _ string) error   { return nil }
-func (f *FakeBucketRepository) DeleteObject(_ context.Context, _ string) error    { return nil }
-func (f *FakeBucketRepository) DeleteObjects(_ context.Context, _ []string) error { return nil }
+// FakeBucketRepository is an in-memory Repository for tests. Objects written with PutObject can be
+// read back with GetObject, listed with ListObjects and copied with CopyObject; every other operation
+// succeeds without doing anything.
+type FakeBucketRepository struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified map[string]time.Time
+}
+
+func (f *FakeBucketRepository) CopyObject(_ context.Context, sourceKey, destinationKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if data, ok := f.objects[sourceKey]; ok {
+		f.objects[destinationKey] = data
+		f.modified[destinationKey] = time.Now()
+	}
+	return nil
+}
+func (f *FakeBucketRepository) DeleteObject(ctx context.Context, key string) error {
+	return f.DeleteObjects(ctx, []string{key})
+}
+func (f *FakeBucketRepository) DeleteObjects(_ context.Context, keys []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range keys {
+		delete(f.objects, key)
+		delete(f.modified, key)
+	}
+	return nil
+}
 func (f *FakeBucketRepository) GetPresignedPutObject(_ context.Context, _ int, _, _ string) (string, string, error) {
 	return "", "", nil
 }
 func (f *FakeBucketRepository) GetPresignedGetObject(_ context.Context, key string) (string, error) {
 	return key, nil
 }
+func (f *FakeBucketRepository) GetPresignedGetObjectWithExpiry(_ context.Context, key string, _ time.Duration) (string, error) {
+	return key, nil
+}
+func (f *FakeBucketRepository) GetObject(_ context.Context, key string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, ErrFakeObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+func (f *FakeBucketRepository) ListObjects(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var objects []ObjectInfo
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, LastModified: f.modified[key]})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// SetLastModified backdates an object, e.g. to simulate an upload that was abandoned long ago.
+func (f *FakeBucketRepository) SetLastModified(key string, modified time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.objects[key]; ok {
+		f.modified[key] = modified
+	}
+}
+func (f *FakeBucketRepository) PutObject(_ context.Context, key string, _ string, body io.ReadSeeker) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.objects == nil {
+		f.objects = make(map[string][]byte)
+		f.modified = make(map[string]time.Time)
+	}
+	f.objects[key] = data
+	f.modified[key] = time.Now()
+	return nil
+}
 func (f *FakeBucketRepository) PublishStagedImages(_ context.Context, _ int, _ string, _ int, imageKeymap map[int]models.ImageData) (map[int]string, error) {
 	keys := make(map[int]string, len(imageKeymap))
 	for i, data := range imageKeymap {
@@ -3,8 +3,10 @@ package bucket
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -22,9 +24,19 @@ type Repository interface {
 	DeleteObjects(ctx context.Context, keys []string) error
 	GetPresignedPutObject(ctx context.Context, userID int, extension, folder string) (string, string, error)
 	GetPresignedGetObject(ctx context.Context, key string) (string, error)
+	GetPresignedGetObjectWithExpiry(ctx context.Context, key string, expiry time.Duration) (string, error)
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	PutObject(ctx context.Context, key string, contentType string, body io.ReadSeeker) error
 	PublishStagedImages(ctx context.Context, userId int, blobType string, identifier int, imageKeymap map[int]models.ImageData) (map[int]string, error)
 }
 
+// ObjectInfo describes a stored object without its contents.
+type ObjectInfo struct {
+	Key          string
+	LastModified time.Time
+}
+
 type S3BucketRepository struct {
 	s3Client         *s3.Client
 	cloudfrontSigner *sign.URLSigner
@@ -53,6 +65,53 @@ func (r *S3BucketRepository) CopyObject(ctx context.Context, sourceKey, destinat
 	return err
 }
 
+func (r *S3BucketRepository) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := r.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+// ListObjects returns every object whose key starts with prefix.
+func (r *S3BucketRepository) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	paginator := s3.NewListObjectsV2Paginator(r.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.bucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	var objects []ObjectInfo
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+func (r *S3BucketRepository) PutObject(ctx context.Context, key string, contentType string, body io.ReadSeeker) error {
+	_, err := r.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.bucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+
+	return err
+}
+
 func (r *S3BucketRepository) DeleteObject(ctx context.Context, key string) error {
 	_, err := r.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(r.bucketName),
@@ -102,7 +161,7 @@ func (r *S3BucketRepository) DeleteObjects(ctx context.Context, keys []string) e
 func (r *S3BucketRepository) GetPresignedPutObject(ctx context.Context, userID int, extension string, folder string) (string, string, error) {
 	presignClient := s3.NewPresignClient(r.s3Client)
 
-	blobPath := fmt.Sprintf("%s/posts/staging/%d/%s/%s.%s", r.environment, userID, folder, uuid.New(), extension)
+	blobPath := fmt.Sprintf("%s%s/%s.%s", StagingPrefix(r.environment, userID), folder, uuid.New(), extension)
 
 	req, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(r.bucketName),
@@ -121,9 +180,16 @@ func (r *S3BucketRepository) GetPresignedPutObject(ctx context.Context, userID i
 }
 
 func (r *S3BucketRepository) GetPresignedGetObject(ctx context.Context, key string) (string, error) {
+	return r.GetPresignedGetObjectWithExpiry(ctx, key, time.Hour)
+}
+
+// GetPresignedGetObjectWithExpiry returns a signed CDN URL for key that stays valid for the given duration.
+// URLs are signed with the CloudFront key pair rather than the server's AWS credentials, so they outlive
+// any credential rotation and long expiries, like those on emailed download links, are honoured.
+func (r *S3BucketRepository) GetPresignedGetObjectWithExpiry(ctx context.Context, key string, expiry time.Duration) (string, error) {
 	path := "https://" + r.cdnBaseURL + "/" + key
 
-	url, err := r.cloudfrontSigner.Sign(path, time.Now().UTC().Add(time.Hour))
+	url, err := r.cloudfrontSigner.Sign(path, time.Now().UTC().Add(expiry))
 	if err != nil {
 		slog.ErrorContext(ctx, "unable to sign cloudfront url", "error", err)
 		return "", err
@@ -132,6 +198,39 @@ func (r *S3BucketRepository) GetPresignedGetObject(ctx context.Context, key stri
 	return url, nil
 }
 
+// StagingRoot returns the key prefix under which every user's uploads are staged.
+func StagingRoot(environment string) string {
+	return environment + "/posts/staging/"
+}
+
+// StagingPrefix returns the key prefix under which a user's uploads are staged until they're published.
+func StagingPrefix(environment string, userId int) string {
+	return fmt.Sprintf("%s%d/", StagingRoot(environment), userId)
+}
+
+// ExportPrefix returns the key prefix under which a user's data exports are stored.
+func ExportPrefix(environment string, userId int) string {
+	return fmt.Sprintf("%s/exports/%d/", environment, userId)
+}
+
+// IsStagedKeyForUser reports whether key points at an upload in the given user's staging area.
+func IsStagedKeyForUser(userId int, key string) bool {
+	return strings.HasPrefix(key, StagingPrefix(os.Getenv("ENVIRONMENT"), userId)) && !strings.Contains(key, "..")
+}
+
+// MediaTypeForKey returns the type of media a staged upload holds, based on its extension.
+// Anything that isn't a GIF or a video is treated as a still image.
+func MediaTypeForKey(key string) models.MediaType {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".gif":
+		return models.MediaTypeGIF
+	case ".mp4", ".m4v", ".mov":
+		return models.MediaTypeVideo
+	default:
+		return models.MediaTypeImage
+	}
+}
+
 // GetDestinationKey returns a permenant blob URI given the current URI of a staged blob.
 // An example blob URI might be production/{userId}/comment/{comment_id}/{fileName}.jpg
 func GetDestinationKey(userId int, blobType string, identifier int, stagedBlobUrl string) string {
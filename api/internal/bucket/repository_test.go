@@ -2,11 +2,14 @@ package bucket_test
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/models"
 )
 
 func TestGetDestinationKey(t *testing.T) {
@@ -19,3 +22,34 @@ func TestGetDestinationKey(t *testing.T) {
 
 	assert.Equal(t, "production/10/posts/10/469f794b-65d2-436c-a18e-58409b47a683.jpg", key)
 }
+
+func TestIsStagedKeyForUser(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+
+	assert.True(t, bucket.IsStagedKeyForUser(10, "production/posts/staging/10/avatar/a.jpg"))
+	assert.False(t, bucket.IsStagedKeyForUser(10, "production/posts/staging/11/avatar/a.jpg"))
+	assert.False(t, bucket.IsStagedKeyForUser(10, "production/posts/staging/100/avatar/a.jpg"))
+	assert.False(t, bucket.IsStagedKeyForUser(10, "production/posts/staging/10/../../11/a.jpg"))
+	assert.False(t, bucket.IsStagedKeyForUser(10, "production/10/posts/1/a.jpg"))
+}
+
+func TestMediaTypeForKey(t *testing.T) {
+	assert.Equal(t, models.MediaTypeImage, bucket.MediaTypeForKey("production/posts/staging/10/a.jpg"))
+	assert.Equal(t, models.MediaTypeGIF, bucket.MediaTypeForKey("production/posts/staging/10/a.GIF"))
+	assert.Equal(t, models.MediaTypeVideo, bucket.MediaTypeForKey("production/posts/staging/10/a.mov"))
+	assert.Equal(t, models.MediaTypeVideo, bucket.MediaTypeForKey("production/posts/staging/10/a.mp4"))
+}
+
+func TestFakeBucketRepository_ListObjects(t *testing.T) {
+	fake := &bucket.FakeBucketRepository{}
+	for _, key := range []string{"test/posts/staging/1/b.jpg", "test/posts/staging/1/a.jpg", "test/1/post/1/a.jpg"} {
+		require.NoError(t, fake.PutObject(t.Context(), key, "image/jpeg", strings.NewReader("data")))
+	}
+
+	objects, err := fake.ListObjects(t.Context(), bucket.StagingRoot("test"))
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "test/posts/staging/1/a.jpg", objects[0].Key)
+	assert.Equal(t, "test/posts/staging/1/b.jpg", objects[1].Key)
+	assert.WithinDuration(t, time.Now(), objects[0].LastModified, time.Minute)
+}
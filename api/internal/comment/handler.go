@@ -2,9 +2,12 @@ package comment
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/post"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
@@ -21,6 +24,7 @@ func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWrit
 	withAuth("POST /post/{post_id}/comment/{comment_id}/liked", h.AddCommentLike)
 	withAuth("DELETE /post/{post_id}/comment/{comment_id}/liked", h.RemoveCommentLike)
 	withAuth("DELETE /comment/{comment_id}", h.DeleteComment)
+	withAuth("PUT /comment/{comment_id}/images/{image_id}/alt-text", h.UpdateImageAltText)
 	withAuth("GET /post/{id}/comments", h.GetCommentsByPost)
 }
 
@@ -33,6 +37,10 @@ func (h *Handler) GetCommentsByPost(w http.ResponseWriter, r *http.Request) {
 
 	currentUser := utilities.GetAuthenticatedUser(r)
 	comments, err := h.svc.GetCommentsByPostId(r.Context(), *currentUser, id)
+	if errors.Is(err, post.ErrPostNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
@@ -62,6 +70,14 @@ func (h *Handler) AddCommentToPostById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	comment, err := h.svc.AddCommentToPost(r.Context(), *currentUser, postId, requestBody.Text, requestBody.ImageKeyMap)
+	if errors.Is(err, post.ErrPostNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if media.IsRejected(err) {
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
@@ -119,6 +135,44 @@ func (h *Handler) RemoveCommentLike(w http.ResponseWriter, r *http.Request) {
 	utilities.HandleEmptySuccess(w)
 }
 
+// UpdateImageAltText changes the description of one of the current user's comment images.
+func (h *Handler) UpdateImageAltText(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	commentId, err := utilities.GetIntPathParam(r, "comment_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing parameter")
+		return
+	}
+	imageId, err := utilities.GetIntPathParam(r, "image_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing parameter")
+		return
+	}
+
+	var requestBody struct {
+		AltText string `json:"altText"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Bad request format")
+		return
+	}
+
+	err = h.svc.UpdateImageAltText(r.Context(), *currentUser, commentId, imageId, requestBody.AltText)
+	switch {
+	case errors.Is(err, ErrCommentNotFound), errors.Is(err, post.ErrImageNotFound):
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrNotCommentAuthor):
+		utilities.HandleError(w, http.StatusForbidden, err.Error())
+	case media.IsRejected(err):
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+	default:
+		utilities.HandleEmptySuccess(w)
+	}
+}
+
 // DeleteComment DELETE /comment/{comment_id}
 func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	currentUser := utilities.GetAuthenticatedUser(r)
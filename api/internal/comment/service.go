@@ -5,22 +5,38 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
+var ErrNotCommentAuthor = errors.New("only the author can edit this comment")
+var ErrCommentNotFound = errors.New("this comment does not exist")
+
 type Service struct {
 	commentRepository   *Store
 	postRepository      post.Store
 	notificationService notification.Service
 	userRepository      user.Store
 	likeRepository      like.Store
+	reactionRepository  reactionReader
 	bucketRepository    bucket.Repository
+	imageProcessor      *media.Processor
+	txManager           *transaction.Manager
+}
+
+// reactionReader summarizes reactions. It's satisfied by reaction.Store, which can't be imported
+// here since the reaction package depends on this one.
+type reactionReader interface {
+	GetCommentReactionSummaries(ctx context.Context, currentUserId int, commentIds []int) (map[int][]models.ReactionSummary, error)
 }
 
 func NewService(
@@ -29,7 +45,9 @@ func NewService(
 	notificationService notification.Service,
 	userRepository user.Store,
 	likeRepository like.Store,
+	reactionRepository reactionReader,
 	bucketRepository bucket.Repository,
+	txManager *transaction.Manager,
 ) *Service {
 	return &Service{
 		commentRepository:   commentRepo,
@@ -37,7 +55,10 @@ func NewService(
 		notificationService: notificationService,
 		userRepository:      userRepository,
 		likeRepository:      likeRepository,
+		reactionRepository:  reactionRepository,
 		bucketRepository:    bucketRepository,
+		imageProcessor:      media.NewProcessor(bucketRepository),
+		txManager:           txManager,
 	}
 }
 
@@ -45,70 +66,99 @@ func NewService(
 func (s *Service) AddCommentToPost(ctx context.Context, currentUser models.PublicUser, postId int, content string, imageKeyMap map[int]models.ImageData) (*models.DetailedComment, error) {
 	post, err := s.postRepository.GetPostById(ctx, postId, currentUser.UserID)
 	if err != nil {
-		return nil, errors.New("unable to find post")
+		return nil, err
 	}
 
 	commentFacets, err := utilities.GenerateFacets(ctx, s.userRepository, content)
 	if err != nil {
 		return nil, errors.New("unable to generate facets")
 	}
-	comment, err := s.commentRepository.AddCommentToPost(ctx, currentUser.UserID, postId, content, commentFacets)
+
+	if len(imageKeyMap) > 0 {
+		requiresAltText, err := s.userRepository.GetUserRequiresAltText(ctx, currentUser.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if requiresAltText {
+			if err := media.RequireAltText(imageKeyMap); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	images, err := s.imageProcessor.Prepare(ctx, currentUser.UserID, imageKeyMap)
 	if err != nil {
-		return nil, errors.New("unable to create new comment")
+		return nil, err
 	}
 
-	// TODO: unpublish images and rest of comment on failure w/ images
-	imageBlobUrls, err := s.bucketRepository.PublishStagedImages(ctx, currentUser.UserID, "comment", comment.CommentID, imageKeyMap)
+	// images are published before the transaction, under a reserved comment ID, so uploading
+	// doesn't hold a connection, or the post's counter row, for its duration
+	commentId, err := s.commentRepository.ReserveCommentId(ctx)
+	if err != nil {
+		return nil, errors.New("unable to create new comment")
+	}
+	publishedImages, err := s.imageProcessor.Publish(ctx, currentUser.UserID, "comment", commentId, images)
 	if err != nil {
 		return nil, err
 	}
 
+	// the comment, its images and its notifications are created together or not at all
+	var comment queries.Comment
 	commentImages := []models.DetailedImage{}
-	for i, blobUrl := range imageBlobUrls {
-		image, err := s.commentRepository.InsertImage(ctx, comment.CommentID, imageKeyMap[i].Height, imageKeyMap[i].Width, blobUrl, 0)
-		if err != nil {
-			return nil, err
-		}
+	err = s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		uow.OnRollback(func(ctx context.Context) error {
+			return s.bucketRepository.DeleteObjects(ctx, media.PublishedKeys(publishedImages))
+		})
 
-		presignedUrl, err := s.bucketRepository.GetPresignedGetObject(ctx, blobUrl)
+		commentRepository := s.commentRepository.WithTx(uow)
+		notificationService := s.notificationService.WithTx(uow)
+
+		comment, err = commentRepository.AddReservedCommentToPost(ctx, commentId, currentUser.UserID, postId, content, commentFacets)
 		if err != nil {
-			return nil, err
+			return errors.New("unable to create new comment")
 		}
 
-		commentImages = append(commentImages, models.DetailedImage{
-			ImageID:      image.ImageID,
-			PostId:       postId,
-			Height:       image.Height,
-			Width:        image.Width,
-			ImageBlobUrl: presignedUrl,
-			DisplayOrder: 0,
-		})
-	}
+		for _, published := range publishedImages {
+			image, err := commentRepository.InsertImage(ctx, comment.CommentID, published, 0)
+			if err != nil {
+				return err
+			}
 
-	commentId := comment.CommentID
+			detailedImage, err := media.MapImage(ctx, s.bucketRepository, *image, postId, 0)
+			if err != nil {
+				return err
+			}
+			commentImages = append(commentImages, detailedImage)
+		}
 
-	if currentUser.UserID != post.UserID {
-		text := fmt.Sprintf("@%s commented", currentUser.Username)
-		_, err = s.notificationService.AddNotification(ctx, post.UserID, &postId, &commentId, nil, text, models.NotificationTypeComment, &comment.Text)
-		if err != nil {
-			return nil, err
+		if currentUser.UserID != post.UserID {
+			text := fmt.Sprintf("@%s commented", currentUser.Username)
+			_, err = notificationService.AddNotification(ctx, post.UserID, &currentUser.UserID, &postId, &commentId, nil, text, models.NotificationTypeComment, &comment.Text)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	// also send notifications to mentioned users
-	usersToNotify := map[int]bool{}
-	for _, facet := range commentFacets {
-		if facet.UserId != post.UserID && facet.UserId != currentUser.UserID {
-			usersToNotify[facet.UserId] = true
+		// also send notifications to mentioned users
+		usersToNotify := map[int]bool{}
+		for _, facet := range commentFacets {
+			if facet.UserId != post.UserID && facet.UserId != currentUser.UserID {
+				usersToNotify[facet.UserId] = true
+			}
 		}
-	}
 
-	for userId := range usersToNotify {
-		text := fmt.Sprintf("@%s mentioned you", currentUser.Username)
-		_, err = s.notificationService.AddNotification(ctx, userId, &postId, &commentId, nil, text, models.NotificationTypeMention, &comment.Text)
-		if err != nil {
-			return nil, errors.New("unable to create a new comment notification")
+		for userId := range usersToNotify {
+			text := fmt.Sprintf("@%s mentioned you", currentUser.Username)
+			_, err = notificationService.AddNotification(ctx, userId, &currentUser.UserID, &postId, &commentId, nil, text, models.NotificationTypeMention, &comment.Text)
+			if err != nil {
+				return errors.New("unable to create a new comment notification")
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	detailedComment := models.DetailedComment{
@@ -121,6 +171,7 @@ func (s *Service) AddCommentToPost(ctx context.Context, currentUser models.Publi
 		User:      currentUser,
 		IsLiked:   false,
 		Images:    commentImages,
+		Reactions: []models.ReactionSummary{},
 	}
 
 	return &detailedComment, nil
@@ -128,12 +179,25 @@ func (s *Service) AddCommentToPost(ctx context.Context, currentUser models.Publi
 
 // GetCommentsByPostId retrieves all comments for a specific post with like status
 func (s *Service) GetCommentsByPostId(ctx context.Context, currentUser models.PublicUser, postID int) ([]models.DetailedComment, error) {
+	// comments are only visible to users who can see the post itself
+	if _, err := s.postRepository.GetPostById(ctx, postID, currentUser.UserID); err != nil {
+		return nil, err
+	}
 
 	dbComments, err := s.commentRepository.GetCommentsByPostId(ctx, postID, currentUser.UserID)
 	if err != nil {
 		return nil, errors.New("unable to find comments")
 	}
 
+	commentIds := make([]int, len(dbComments))
+	for i, dbComment := range dbComments {
+		commentIds[i] = dbComment.CommentID
+	}
+	reactionsByComment, err := s.reactionRepository.GetCommentReactionSummaries(ctx, currentUser.UserID, commentIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve comment reactions")
+	}
+
 	comments := make([]models.DetailedComment, 0, len(dbComments))
 	for _, dbComment := range dbComments {
 
@@ -158,23 +222,19 @@ func (s *Service) GetCommentsByPostId(ctx context.Context, currentUser models.Pu
 		}
 		images := []models.DetailedImage{}
 		for _, image := range dbImages {
-			blobUrl, err := s.bucketRepository.GetPresignedGetObject(ctx, image.ImageBlobUrl)
+			currentImage, err := media.MapImage(ctx, s.bucketRepository, image, postID, 0)
 			if err != nil {
 				return nil, err
 			}
 
-			currentImage := models.DetailedImage{
-				ImageID:      image.ImageID,
-				PostId:       postID,
-				Height:       image.Height,
-				Width:        image.Width,
-				ImageBlobUrl: blobUrl,
-				DisplayOrder: 0,
-			}
-
 			images = append(images, currentImage)
 		}
 
+		reactions := reactionsByComment[dbComment.CommentID]
+		if reactions == nil {
+			reactions = []models.ReactionSummary{}
+		}
+
 		if len(dbImages) > 0 {
 			if !utilities.IsAppUpdatedToVersion(ctx, "v1.8.0") {
 				prefix := ""
@@ -195,6 +255,8 @@ func (s *Service) GetCommentsByPostId(ctx context.Context, currentUser models.Pu
 			CreatedAt: dbComment.CreatedAt.Time,
 			User:      user,
 			IsLiked:   isLiked,
+			LikeCount: dbComment.LikeCount,
+			Reactions: reactions,
 		}
 
 		comments = append(comments, detailedComment)
@@ -236,6 +298,35 @@ func (s *Service) RemoveLikeFromCommentById(ctx context.Context, user models.Pub
 	return s.notificationService.RemoveLikeNotification(ctx, user.UserID, postId, &commentId)
 }
 
+// UpdateImageAltText changes the description of one of the current user's comment images. An
+// empty description removes it.
+func (s *Service) UpdateImageAltText(ctx context.Context, currentUser models.PublicUser, commentId int, imageId int, altText string) error {
+	comment, err := s.commentRepository.GetCommentById(ctx, commentId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCommentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if comment.UserID != currentUser.UserID {
+		return ErrNotCommentAuthor
+	}
+
+	altText, err = media.NormalizeAltText(altText)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.commentRepository.UpdateImageAltText(ctx, commentId, imageId, altText)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return post.ErrImageNotFound
+	}
+	return nil
+}
+
 // DeleteComment deletes a comment by ID if the current user owns it
 func (s *Service) DeleteComment(ctx context.Context, currentUser models.PublicUser, commentId int) error {
 	comment, err := s.commentRepository.GetCommentById(ctx, commentId)
@@ -257,9 +348,9 @@ func (s *Service) DeleteComment(ctx context.Context, currentUser models.PublicUs
 	}
 
 	if len(images) > 0 {
-		keys := make([]string, len(images))
-		for i, img := range images {
-			keys[i] = img.ImageBlobUrl
+		var keys []string
+		for _, img := range images {
+			keys = append(keys, media.BlobKeys(img)...)
 		}
 		if err := s.bucketRepository.DeleteObjects(ctx, keys); err != nil {
 			return errors.New("unable to delete comment images from storage")
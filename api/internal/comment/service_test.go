@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/comment"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
@@ -15,10 +16,11 @@ import (
 )
 
 type commentServiceTestEnv struct {
-	svc            *comment.Service
-	userSvc        *user.Service
-	postRepository post.Store
-	userRepository user.Store
+	svc              *comment.Service
+	userSvc          *user.Service
+	postRepository   post.Store
+	userRepository   user.Store
+	bucketRepository bucket.Repository
 }
 
 func setupCommentTest(t *testing.T) commentServiceTestEnv {
@@ -26,14 +28,15 @@ func setupCommentTest(t *testing.T) commentServiceTestEnv {
 	db := testutil.StartPostgres(t)
 
 	notificationService := notification.NewService(db.NotificationStore, db.PostRepository, &db.CommentRepository, db.UserRepository, db.BucketRepository, apns.Client{})
-	svc := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.BucketRepository)
-	userSvc := user.NewUserService(db.UserRepository, *notificationService, nil)
+	svc := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.ReactionStore, db.BucketRepository, db.TxManager)
+	userSvc := user.NewUserService(db.UserRepository, *notificationService, db.BucketRepository, nil, db.TxManager)
 
 	return commentServiceTestEnv{
-		svc:            svc,
-		userSvc:        userSvc,
-		postRepository: db.PostRepository,
-		userRepository: db.UserRepository,
+		svc:              svc,
+		userSvc:          userSvc,
+		postRepository:   db.PostRepository,
+		userRepository:   db.UserRepository,
+		bucketRepository: db.BucketRepository,
 	}
 }
 
@@ -42,7 +45,7 @@ func TestAddCommentToPost(t *testing.T) {
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "post0", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	commentContent := "test comment"
@@ -63,7 +66,7 @@ func TestDeleteComment_UnauthorizedUser(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.svc.AddCommentToPost(t.Context(), user0, post.PostID, "test comment", nil)
@@ -84,7 +87,7 @@ func TestGetComments_DoesNotReturnBlockedUserComments(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.svc.AddCommentToPost(t.Context(), user1, post.PostID, "test comment", nil)
@@ -103,13 +106,40 @@ func TestGetComments_DoesNotReturnBlockedUserComments(t *testing.T) {
 	assert.Empty(t, comments)
 }
 
+func TestGetComments_DoesNotReturnCommentsWithMutedWords(t *testing.T) {
+	env := setupCommentTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	_, err = env.svc.AddCommentToPost(t.Context(), user1, post.PostID, "Spoiler! it was him", nil)
+	require.NoError(t, err)
+	visible, err := env.svc.AddCommentToPost(t.Context(), user1, post.PostID, "no spoilers please", nil)
+	require.NoError(t, err)
+
+	_, err = env.userSvc.MuteWord(t.Context(), user0, "spoiler", []models.MutedWordScope{models.MutedWordScopeComments}, nil)
+	require.NoError(t, err)
+
+	comments, err := env.svc.GetCommentsByPostId(t.Context(), user0, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, visible.CommentID, comments[0].CommentID)
+
+	comments, err = env.svc.GetCommentsByPostId(t.Context(), user1, post.PostID)
+	require.NoError(t, err)
+	assert.Len(t, comments, 2)
+}
+
 func TestGetComments_DoesNotReturnMutedUserComments(t *testing.T) {
 	env := setupCommentTest(t)
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.svc.AddCommentToPost(t.Context(), user1, post.PostID, "test comment", nil)
@@ -120,7 +150,7 @@ func TestGetComments_DoesNotReturnMutedUserComments(t *testing.T) {
 	assert.Len(t, comments, 1)
 	assert.Equal(t, comment.CommentID, comments[0].CommentID)
 
-	err = env.userSvc.MuteUser(t.Context(), user0, user1.UserID)
+	err = env.userSvc.MuteUser(t.Context(), user0, user1.UserID, "", nil)
 	assert.NoError(t, err)
 
 	comments, err = env.svc.GetCommentsByPostId(t.Context(), user0, post.PostID)
@@ -133,11 +163,11 @@ func TestGetComments_WithImage(t *testing.T) {
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	images := map[int]models.ImageData{
-		0: {S3Key: "images/photo1.jpg", Width: 1920, Height: 1080},
+		0: testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 800, 600),
 	}
 
 	_, err = env.svc.AddCommentToPost(t.Context(), user0, post.PostID, "test comment", images)
@@ -148,7 +178,9 @@ func TestGetComments_WithImage(t *testing.T) {
 	assert.Len(t, comments, 1)
 
 	assert.NotNil(t, comments[0].Images)
-	assert.Len(t, comments[0].Images, 1)
+	require.Len(t, comments[0].Images, 1)
+	assert.Equal(t, 800, comments[0].Images[0].Width)
+	assert.Len(t, comments[0].Images[0].Variants, 2)
 }
 
 func TestGetComments_ReturnsMutedUserCommentsOnMutedUserPost(t *testing.T) {
@@ -157,7 +189,7 @@ func TestGetComments_ReturnsMutedUserCommentsOnMutedUserPost(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	_, err = env.svc.AddCommentToPost(t.Context(), user0, post.PostID, "test comment", nil)
@@ -167,7 +199,7 @@ func TestGetComments_ReturnsMutedUserCommentsOnMutedUserPost(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, comments, 1)
 
-	err = env.userRepository.MuteUser(t.Context(), user1.UserID, user0.UserID)
+	err = env.userRepository.MuteUser(t.Context(), user1.UserID, user0.UserID, models.MuteModePosts, nil)
 	require.NoError(t, err)
 
 	comments, err = env.svc.GetCommentsByPostId(t.Context(), user1, post.PostID)
@@ -181,7 +213,7 @@ func TestGetComments_DoesNotReturnBlockingUserComments(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	_, err = env.svc.AddCommentToPost(t.Context(), user1, post.PostID, "test comment", nil)
@@ -205,13 +237,13 @@ func TestGetComments_DoesNotReturnOtherPostComments(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post_0, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post_0, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	_, err = env.svc.AddCommentToPost(t.Context(), user1, post_0.PostID, "test comment", nil)
 	require.NoError(t, err)
 
-	post_1, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post_1, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	_, err = env.svc.AddCommentToPost(t.Context(), user1, post_1.PostID, "test comment", nil)
@@ -225,3 +257,34 @@ func TestGetComments_DoesNotReturnOtherPostComments(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, comments, 1)
 }
+
+func TestGetComments_HiddenWhenPostIsNotVisible(t *testing.T) {
+	env := setupCommentTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityFollowers), nil)
+	require.NoError(t, err)
+
+	_, err = env.svc.AddCommentToPost(t.Context(), user0, post.PostID, "test comment", nil)
+	require.NoError(t, err)
+
+	_, err = env.svc.GetCommentsByPostId(t.Context(), user1, post.PostID)
+	assert.Error(t, err)
+
+	require.NoError(t, env.userRepository.FollowUser(t.Context(), user1.UserID, user0.UserID))
+
+	comments, err := env.svc.GetCommentsByPostId(t.Context(), user1, post.PostID)
+	require.NoError(t, err)
+	assert.Len(t, comments, 1)
+}
+
+func TestUpdateImageAltText_MissingComment(t *testing.T) {
+	env := setupCommentTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	err := env.svc.UpdateImageAltText(t.Context(), user0, 12345, 1, "a cat")
+	assert.ErrorIs(t, err, comment.ErrCommentNotFound)
+}
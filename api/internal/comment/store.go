@@ -5,19 +5,36 @@ import (
 
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/media"
+	"splajompy.com/api/v2/internal/transaction"
 )
 
 type Store struct {
 	querier queries.Querier
 }
 
+// ReserveCommentId allocates the ID of a comment that hasn't been created yet, for AddReservedCommentToPost
+func (r Store) ReserveCommentId(ctx context.Context) (int, error) {
+	return r.querier.ReserveCommentId(ctx)
+}
+
 // AddCommentToPost adds a new comment to a post
 func (r Store) AddCommentToPost(ctx context.Context, userId int, postId int, content string, facets db.Facets) (queries.Comment, error) {
+	return r.addCommentToPost(ctx, nil, userId, postId, content, facets)
+}
+
+// AddReservedCommentToPost adds a new comment to a post with an ID from ReserveCommentId
+func (r Store) AddReservedCommentToPost(ctx context.Context, commentId int, userId int, postId int, content string, facets db.Facets) (queries.Comment, error) {
+	return r.addCommentToPost(ctx, &commentId, userId, postId, content, facets)
+}
+
+func (r Store) addCommentToPost(ctx context.Context, commentId *int, userId int, postId int, content string, facets db.Facets) (queries.Comment, error) {
 	return r.querier.AddCommentToPost(ctx, queries.AddCommentToPostParams{
-		PostID: postId,
-		UserID: userId,
-		Text:   content,
-		Facets: facets,
+		PostID:    postId,
+		UserID:    userId,
+		Text:      content,
+		Facets:    facets,
+		CommentID: commentId,
 	})
 }
 
@@ -25,6 +42,20 @@ func (r Store) GetCommentById(ctx context.Context, commentId int) (queries.Comme
 	return r.querier.GetCommentById(ctx, commentId)
 }
 
+// GetCommentsByIds retrieves the comments out of commentIds whose authors are still active, keyed by ID
+func (r Store) GetCommentsByIds(ctx context.Context, commentIds []int) (map[int]queries.Comment, error) {
+	rows, err := r.querier.GetCommentsByIds(ctx, commentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(map[int]queries.Comment, len(rows))
+	for _, comment := range rows {
+		comments[comment.CommentID] = comment
+	}
+	return comments, nil
+}
+
 // GetCommentsByPostId retrieves all comments for a specific post, excluding comments from users blocked by userId
 func (r Store) GetCommentsByPostId(ctx context.Context, postId int, userId int) ([]queries.GetCommentsByPostIdRow, error) {
 	return r.querier.GetCommentsByPostId(ctx, queries.GetCommentsByPostIdParams{
@@ -43,19 +74,26 @@ func (r Store) GetUserById(ctx context.Context, userId int) (queries.User, error
 	return r.querier.GetUserById(ctx, userId)
 }
 
-func (r Store) InsertImage(ctx context.Context, commentId int, height int, width int, url string, displayOrder int) (*queries.Image, error) {
-	image, err := r.querier.InsertImage(ctx, queries.InsertImageParams{
-		Height:       height,
-		Width:        width,
-		ImageBlobUrl: url,
+// UpdateImageAltText sets the description of one of a comment's images, reporting whether the
+// image belongs to the comment.
+func (r Store) UpdateImageAltText(ctx context.Context, commentId int, imageId int, altText string) (bool, error) {
+	updated, err := r.querier.UpdateCommentMediaAltText(ctx, queries.UpdateCommentMediaAltTextParams{
+		CommentID: commentId,
+		MediaID:   imageId,
+		AltText:   media.AltTextParam(altText),
 	})
+	return updated > 0, err
+}
+
+func (r Store) InsertImage(ctx context.Context, commentId int, published media.PublishedMedia, displayOrder int) (*queries.Media, error) {
+	image, err := r.querier.InsertMedia(ctx, published.InsertParams())
 	if err != nil {
 		return nil, err
 	}
 
-	err = r.querier.AttachImageToComment(ctx, queries.AttachImageToCommentParams{
+	err = r.querier.AttachMediaToComment(ctx, queries.AttachMediaToCommentParams{
 		CommentID: commentId,
-		ImageID:   image.ImageID,
+		MediaID:   image.MediaID,
 	})
 	if err != nil {
 		return nil, err
@@ -64,8 +102,22 @@ func (r Store) InsertImage(ctx context.Context, commentId int, height int, width
 	return &image, nil
 }
 
-func (r *Store) GetImagesByCommentId(ctx context.Context, commentId int) ([]queries.Image, error) {
-	return r.querier.GetImagesByCommentId(ctx, commentId)
+func (r *Store) GetImagesByCommentId(ctx context.Context, commentId int) ([]queries.Media, error) {
+	return r.querier.GetMediaByCommentId(ctx, commentId)
+}
+
+// GetImagesByCommentIds retrieves the images attached to each of commentIds, keyed by comment ID
+func (r *Store) GetImagesByCommentIds(ctx context.Context, commentIds []int) (map[int][]queries.Media, error) {
+	rows, err := r.querier.GetMediaByCommentIds(ctx, commentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[int][]queries.Media)
+	for _, row := range rows {
+		images[row.CommentID] = append(images[row.CommentID], row.Media)
+	}
+	return images, nil
 }
 
 // NewStore creates a new comment repository
@@ -74,3 +126,8 @@ func NewStore(querier queries.Querier) *Store {
 		querier: querier,
 	}
 }
+
+// WithTx returns a copy of the repository that runs its queries in the unit of work's transaction
+func (r Store) WithTx(uow *transaction.UnitOfWork) *Store {
+	return NewStore(uow.Querier())
+}
@@ -0,0 +1,71 @@
+package counter
+
+import (
+	"context"
+	"log/slog"
+
+	"splajompy.com/api/v2/internal/transaction"
+)
+
+// repairBatchSize is how many counters are locked and recounted in each transaction
+const repairBatchSize = 1000
+
+// Repairer fixes engagement counters that have drifted from the rows they count. Counters are
+// updated in the same statement as the likes, comments, votes and follows they count, so this is
+// a safety net for anything that changes those rows without going through the queries that do.
+type Repairer struct {
+	store     Store
+	txManager *transaction.Manager
+}
+
+func NewRepairer(store Store, txManager *transaction.Manager) *Repairer {
+	return &Repairer{store: store, txManager: txManager}
+}
+
+// Repair recomputes every counter and corrects the ones that are wrong.
+func (r *Repairer) Repair(ctx context.Context) error {
+	posts, err := r.repairBatches(ctx, Store.RepairPostCounters)
+	if err != nil {
+		return err
+	}
+
+	comments, err := r.repairBatches(ctx, Store.RepairCommentCounters)
+	if err != nil {
+		return err
+	}
+
+	users, err := r.repairBatches(ctx, Store.RepairUserCounters)
+	if err != nil {
+		return err
+	}
+
+	if posts+comments+users > 0 {
+		slog.WarnContext(ctx, "repaired drifted counters", "posts", posts, "comments", comments, "users", users)
+	}
+	return nil
+}
+
+// repairBatches runs repairBatch over one batch of counters at a time, each in its own transaction
+// so that the counter rows it locks are held only briefly, and returns how many it repaired.
+// Counts are taken after the lock, so a like or follow that commits meanwhile isn't overwritten.
+func (r *Repairer) repairBatches(ctx context.Context, repairBatch func(Store, context.Context, int, int) (int, int64, error)) (int64, error) {
+	var total int64
+	afterId := 0
+	for {
+		var lastId int
+		var repaired int64
+		err := r.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+			var err error
+			lastId, repaired, err = repairBatch(r.store.WithTx(uow), ctx, afterId, repairBatchSize)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		if lastId == 0 {
+			return total, nil
+		}
+		total += repaired
+		afterId = lastId
+	}
+}
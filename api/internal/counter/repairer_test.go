@@ -0,0 +1,81 @@
+package counter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/counter"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/testutil"
+)
+
+func TestRepair_FixesDriftedCounters(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	repairer := counter.NewRepairer(counter.NewStore(db.Queries), db.TxManager)
+
+	author := testutil.CreateTestUser(t, db.UserRepository, "author")
+	liker := testutil.CreateTestUser(t, db.UserRepository, "liker")
+	post, err := db.PostRepository.InsertPost(t.Context(), author.UserID, "post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	comment, err := db.CommentRepository.AddCommentToPost(t.Context(), liker.UserID, post.PostID, "comment", nil)
+	require.NoError(t, err)
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), liker.UserID, post.PostID, nil))
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), author.UserID, post.PostID, &comment.CommentID))
+
+	_, err = db.Pool.Exec(t.Context(), `UPDATE post_counter SET like_count = 5, comment_count = 0`)
+	require.NoError(t, err)
+	_, err = db.Pool.Exec(t.Context(), `DELETE FROM comment_counter`)
+	require.NoError(t, err)
+	_, err = db.Pool.Exec(t.Context(), `UPDATE user_counter SET post_count = 3`)
+	require.NoError(t, err)
+
+	require.NoError(t, repairer.Repair(t.Context()))
+
+	counters, err := db.PostRepository.GetPostCounters(t.Context(), []int{post.PostID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, counters[post.PostID].LikeCount)
+	assert.Equal(t, 1, counters[post.PostID].CommentCount)
+
+	comments, err := db.CommentRepository.GetCommentsByPostId(t.Context(), post.PostID, author.UserID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, 1, comments[0].LikeCount)
+
+	authorCounter, err := db.Queries.GetUserCounter(t.Context(), author.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, authorCounter.PostCount)
+}
+
+func TestDeleteAccount_TakesInteractionsOffCounters(t *testing.T) {
+	db := testutil.StartPostgres(t)
+
+	author := testutil.CreateTestUser(t, db.UserRepository, "author")
+	other := testutil.CreateTestUser(t, db.UserRepository, "other")
+	deleted := testutil.CreateTestUser(t, db.UserRepository, "deleted")
+	post, err := db.PostRepository.InsertPost(t.Context(), author.UserID, "post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	comment, err := db.CommentRepository.AddCommentToPost(t.Context(), other.UserID, post.PostID, "comment", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), deleted.UserID, post.PostID, nil))
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), deleted.UserID, post.PostID, &comment.CommentID))
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), other.UserID, post.PostID, nil))
+	require.NoError(t, db.PostRepository.InsertVote(t.Context(), post.PostID, deleted.UserID, 0))
+	deletedComment, err := db.CommentRepository.AddCommentToPost(t.Context(), deleted.UserID, post.PostID, "comment", nil)
+	require.NoError(t, err)
+	require.NoError(t, db.LikeRepository.AddLike(t.Context(), other.UserID, post.PostID, &deletedComment.CommentID))
+
+	require.NoError(t, db.UserRepository.DeleteAccount(t.Context(), deleted.UserID))
+
+	counters, err := db.PostRepository.GetPostCounters(t.Context(), []int{post.PostID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, counters[post.PostID].LikeCount)
+	assert.Equal(t, 1, counters[post.PostID].CommentCount)
+	assert.Equal(t, 0, counters[post.PostID].PollVoteCount)
+
+	comments, err := db.CommentRepository.GetCommentsByPostId(t.Context(), post.PostID, author.UserID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, 0, comments[0].LikeCount)
+}
@@ -0,0 +1,93 @@
+package counter
+
+import (
+	"context"
+
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/transaction"
+)
+
+type Store struct {
+	querier queries.Querier
+}
+
+func NewStore(querier queries.Querier) Store {
+	return Store{querier: querier}
+}
+
+// WithTx returns a copy of the store that runs its queries in the unit of work's transaction
+func (s Store) WithTx(uow *transaction.UnitOfWork) Store {
+	return Store{querier: uow.Querier()}
+}
+
+// RepairPostCounters locks the counters of up to batchSize posts after afterId, then recomputes
+// their like, comment and poll vote counts. It returns the last post in the batch, or 0 once there
+// are none left, and how many counters had drifted.
+func (s Store) RepairPostCounters(ctx context.Context, afterId int, batchSize int) (int, int64, error) {
+	err := s.querier.CreateMissingPostCounters(ctx, queries.CreateMissingPostCountersParams{
+		AfterPostID: afterId,
+		BatchSize:   batchSize,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	postIds, err := s.querier.LockPostCounters(ctx, queries.LockPostCountersParams{
+		AfterPostID: afterId,
+		BatchSize:   batchSize,
+	})
+	if err != nil || len(postIds) == 0 {
+		return 0, 0, err
+	}
+
+	repaired, err := s.querier.RecountPostCounters(ctx, postIds)
+	return postIds[len(postIds)-1], repaired, err
+}
+
+// RepairCommentCounters locks the counters of up to batchSize comments after afterId, then
+// recomputes their like counts. It returns the last comment in the batch, or 0 once there are none
+// left, and how many counters had drifted.
+func (s Store) RepairCommentCounters(ctx context.Context, afterId int, batchSize int) (int, int64, error) {
+	err := s.querier.CreateMissingCommentCounters(ctx, queries.CreateMissingCommentCountersParams{
+		AfterCommentID: afterId,
+		BatchSize:      batchSize,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	commentIds, err := s.querier.LockCommentCounters(ctx, queries.LockCommentCountersParams{
+		AfterCommentID: afterId,
+		BatchSize:      batchSize,
+	})
+	if err != nil || len(commentIds) == 0 {
+		return 0, 0, err
+	}
+
+	repaired, err := s.querier.RecountCommentCounters(ctx, commentIds)
+	return commentIds[len(commentIds)-1], repaired, err
+}
+
+// RepairUserCounters locks the counters of up to batchSize users after afterId, then recomputes
+// their follower, following and post counts. It returns the last user in the batch, or 0 once
+// there are none left, and how many counters had drifted.
+func (s Store) RepairUserCounters(ctx context.Context, afterId int, batchSize int) (int, int64, error) {
+	err := s.querier.CreateMissingUserCounters(ctx, queries.CreateMissingUserCountersParams{
+		AfterUserID: afterId,
+		BatchSize:   batchSize,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	userIds, err := s.querier.LockUserCounters(ctx, queries.LockUserCountersParams{
+		AfterUserID: afterId,
+		BatchSize:   batchSize,
+	})
+	if err != nil || len(userIds) == 0 {
+		return 0, 0, err
+	}
+
+	repaired, err := s.querier.RecountUserCounters(ctx, userIds)
+	return userIds[len(userIds)-1], repaired, err
+}
@@ -20,8 +20,33 @@ type Attributes struct {
 type Poll struct {
 	Title   string   `json:"title"`
 	Options []string `json:"options"`
+	// ClosesAt is when voting ends. Polls without one stay open.
+	ClosesAt *time.Time `json:"closesAt,omitempty"`
+	// MaxSelections is how many options a user can vote for. Zero means one.
+	MaxSelections int `json:"maxSelections,omitempty"`
+	// HideResultsUntilVoted hides vote counts from users until they vote or the poll closes.
+	HideResultsUntilVoted bool `json:"hideResultsUntilVoted,omitempty"`
 }
 
+// SelectionLimit returns how many options a user can vote for.
+func (p Poll) SelectionLimit() int {
+	return max(1, p.MaxSelections)
+}
+
+// IsClosed reports whether voting on the poll has ended.
+func (p Poll) IsClosed(now time.Time) bool {
+	return p.ClosesAt != nil && !now.Before(*p.ClosesAt)
+}
+
+// ImageVariant is a resized copy of an uploaded image.
+type ImageVariant struct {
+	Key    string `json:"key"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type ImageVariants []ImageVariant
+
 type UserDisplayProperties struct {
 	FontChoiceId     *int       `json:"fontChoiceId"`
 	LatestAppVersion *string    `json:"latestAppVersion"`
@@ -13,16 +13,34 @@ import (
 )
 
 const addCommentToPost = `-- name: AddCommentToPost :one
-INSERT INTO comments (post_id, user_id, text, facets)
-VALUES ($1, $2, $3, $4)
+WITH counter AS (
+    INSERT INTO post_counter (post_id, comment_count)
+    SELECT posts.post_id, 1
+    FROM posts
+    WHERE posts.post_id = $1
+    AND NOT EXISTS ( -- comments hidden by a block with the post's author aren't counted
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = $2)
+            OR (block.user_id = $2 AND block.target_user_id = posts.user_id)
+    )
+    ON CONFLICT (post_id) DO UPDATE
+    SET comment_count = post_counter.comment_count + 1
+)
+INSERT INTO comments (post_id, user_id, text, facets, comment_id)
+VALUES (
+    $1, $2, $3, $4,
+    COALESCE($5::int, nextval(pg_get_serial_sequence('comments', 'comment_id')))
+)
 RETURNING comment_id, post_id, user_id, text, facets, created_at
 `
 
 type AddCommentToPostParams struct {
-	PostID int       `json:"postId"`
-	UserID int       `json:"userId"`
-	Text   string    `json:"text"`
-	Facets db.Facets `json:"facets"`
+	PostID    int       `json:"postId"`
+	UserID    int       `json:"userId"`
+	Text      string    `json:"text"`
+	Facets    db.Facets `json:"facets"`
+	CommentID *int      `json:"commentId"`
 }
 
 func (q *Queries) AddCommentToPost(ctx context.Context, arg AddCommentToPostParams) (Comment, error) {
@@ -31,6 +49,7 @@ func (q *Queries) AddCommentToPost(ctx context.Context, arg AddCommentToPostPara
 		arg.UserID,
 		arg.Text,
 		arg.Facets,
+		arg.CommentID,
 	)
 	var i Comment
 	err := row.Scan(
@@ -44,24 +63,40 @@ func (q *Queries) AddCommentToPost(ctx context.Context, arg AddCommentToPostPara
 	return i, err
 }
 
-const attachImageToComment = `-- name: AttachImageToComment :exec
-INSERT INTO comment_images (comment_id, image_id)
+const attachMediaToComment = `-- name: AttachMediaToComment :exec
+INSERT INTO comment_media (comment_id, media_id)
 VALUES ($1, $2)
 `
 
-type AttachImageToCommentParams struct {
+type AttachMediaToCommentParams struct {
 	CommentID int `json:"commentId"`
-	ImageID   int `json:"imageId"`
+	MediaID   int `json:"mediaId"`
 }
 
-func (q *Queries) AttachImageToComment(ctx context.Context, arg AttachImageToCommentParams) error {
-	_, err := q.db.Exec(ctx, attachImageToComment, arg.CommentID, arg.ImageID)
+func (q *Queries) AttachMediaToComment(ctx context.Context, arg AttachMediaToCommentParams) error {
+	_, err := q.db.Exec(ctx, attachMediaToComment, arg.CommentID, arg.MediaID)
 	return err
 }
 
 const deleteComment = `-- name: DeleteComment :exec
-DELETE FROM comments
-WHERE comment_id = $1
+WITH deleted AS (
+    DELETE FROM comments
+    WHERE comment_id = $1
+    RETURNING post_id, user_id
+)
+UPDATE post_counter
+SET comment_count = post_counter.comment_count - 1
+FROM deleted
+JOIN posts ON posts.post_id = deleted.post_id
+JOIN users ON users.user_id = deleted.user_id
+WHERE post_counter.post_id = deleted.post_id
+AND users.deactivated_at IS NULL
+AND NOT EXISTS ( -- hidden comments were never counted
+    SELECT 1
+    FROM block
+    WHERE (block.user_id = posts.user_id AND block.target_user_id = deleted.user_id)
+        OR (block.user_id = deleted.user_id AND block.target_user_id = posts.user_id)
+)
 `
 
 func (q *Queries) DeleteComment(ctx context.Context, commentID int) error {
@@ -73,6 +108,9 @@ const getCommentById = `-- name: GetCommentById :one
 SELECT comment_id, post_id, user_id, text, facets, created_at
 FROM comments
 WHERE comment_id = $1
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = comments.user_id AND users.deactivated_at IS NULL
+)
 LIMIT 1
 `
 
@@ -90,7 +128,50 @@ func (q *Queries) GetCommentById(ctx context.Context, commentID int) (Comment, e
 	return i, err
 }
 
+const getCommentsByIds = `-- name: GetCommentsByIds :many
+SELECT comment_id, post_id, user_id, text, facets, created_at
+FROM comments
+WHERE comment_id = ANY($1::int[])
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = comments.user_id AND users.deactivated_at IS NULL
+)
+`
+
+func (q *Queries) GetCommentsByIds(ctx context.Context, commentIds []int) ([]Comment, error) {
+	rows, err := q.db.Query(ctx, getCommentsByIds, commentIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.CommentID,
+			&i.PostID,
+			&i.UserID,
+			&i.Text,
+			&i.Facets,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCommentsByPostId = `-- name: GetCommentsByPostId :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $2
+        AND mute_comments
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT
   comments.comment_id,
   comments.post_id,
@@ -99,11 +180,14 @@ SELECT
   comments.facets,
   comments.created_at,
   users.username,
-  users.name
+  users.name,
+  COALESCE(comment_counter.like_count, 0)::int AS like_count
 FROM comments
 JOIN users ON comments.user_id = users.user_id
 JOIN posts ON comments.post_id = posts.post_id
+LEFT JOIN comment_counter ON comment_counter.comment_id = comments.comment_id
 WHERE comments.post_id = $1
+AND users.deactivated_at IS NULL
 AND NOT EXISTS (
     SELECT 1
     FROM block
@@ -113,11 +197,25 @@ AND NOT EXISTS (
     FROM block
     WHERE block.user_id = comments.user_id AND target_user_id = $2
 )
+AND NOT EXISTS ( -- no comments from users on either side of a block with the post's author
+    SELECT 1
+    FROM block
+    WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+        OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1
     FROM mute
     WHERE mute.user_id = $2 AND target_user_id = comments.user_id
         AND posts.user_id != comments.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+)
+AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE comments.user_id != $2
+        AND comments.text ~* muted_pattern.pattern
 )
 ORDER BY comments.created_at DESC
 `
@@ -136,6 +234,7 @@ type GetCommentsByPostIdRow struct {
 	CreatedAt pgtype.Timestamp `json:"createdAt"`
 	Username  string           `json:"username"`
 	Name      pgtype.Text      `json:"name"`
+	LikeCount int              `json:"likeCount"`
 }
 
 func (q *Queries) GetCommentsByPostId(ctx context.Context, arg GetCommentsByPostIdParams) ([]GetCommentsByPostIdRow, error) {
@@ -156,6 +255,7 @@ func (q *Queries) GetCommentsByPostId(ctx context.Context, arg GetCommentsByPost
 			&i.CreatedAt,
 			&i.Username,
 			&i.Name,
+			&i.LikeCount,
 		); err != nil {
 			return nil, err
 		}
@@ -167,27 +267,84 @@ func (q *Queries) GetCommentsByPostId(ctx context.Context, arg GetCommentsByPost
 	return items, nil
 }
 
-const getImagesByCommentId = `-- name: GetImagesByCommentId :many
-SELECT images.image_id, images.height, images.width, images.image_blob_url
-FROM images
-JOIN comment_images ON images.image_id = comment_images.image_id
-WHERE comment_images.comment_id = $1
+const getMediaByCommentId = `-- name: GetMediaByCommentId :many
+SELECT media.media_id, media.height, media.width, media.blob_key, media.blurhash, media.variants, media.media_type, media.processing_status, media.processing_attempts, media.processing_started_at, media.duration_ms, media.poster_key, media.alt_text
+FROM media
+JOIN comment_media ON media.media_id = comment_media.media_id
+WHERE comment_media.comment_id = $1
 `
 
-func (q *Queries) GetImagesByCommentId(ctx context.Context, commentID int) ([]Image, error) {
-	rows, err := q.db.Query(ctx, getImagesByCommentId, commentID)
+func (q *Queries) GetMediaByCommentId(ctx context.Context, commentID int) ([]Media, error) {
+	rows, err := q.db.Query(ctx, getMediaByCommentId, commentID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Image
+	var items []Media
 	for rows.Next() {
-		var i Image
+		var i Media
 		if err := rows.Scan(
-			&i.ImageID,
+			&i.MediaID,
 			&i.Height,
 			&i.Width,
-			&i.ImageBlobUrl,
+			&i.BlobKey,
+			&i.Blurhash,
+			&i.Variants,
+			&i.MediaType,
+			&i.ProcessingStatus,
+			&i.ProcessingAttempts,
+			&i.ProcessingStartedAt,
+			&i.DurationMs,
+			&i.PosterKey,
+			&i.AltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaByCommentIds = `-- name: GetMediaByCommentIds :many
+SELECT comment_media.comment_id, media.media_id, media.height, media.width, media.blob_key, media.blurhash, media.variants, media.media_type, media.processing_status, media.processing_attempts, media.processing_started_at, media.duration_ms, media.poster_key, media.alt_text
+FROM media
+JOIN comment_media ON media.media_id = comment_media.media_id
+WHERE comment_media.comment_id = ANY($1::int[])
+ORDER BY comment_media.comment_id, media.media_id
+`
+
+type GetMediaByCommentIdsRow struct {
+	CommentID int   `json:"commentId"`
+	Media     Media `json:"media"`
+}
+
+func (q *Queries) GetMediaByCommentIds(ctx context.Context, commentIds []int) ([]GetMediaByCommentIdsRow, error) {
+	rows, err := q.db.Query(ctx, getMediaByCommentIds, commentIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMediaByCommentIdsRow
+	for rows.Next() {
+		var i GetMediaByCommentIdsRow
+		if err := rows.Scan(
+			&i.CommentID,
+			&i.Media.MediaID,
+			&i.Media.Height,
+			&i.Media.Width,
+			&i.Media.BlobKey,
+			&i.Media.Blurhash,
+			&i.Media.Variants,
+			&i.Media.MediaType,
+			&i.Media.ProcessingStatus,
+			&i.Media.ProcessingAttempts,
+			&i.Media.ProcessingStartedAt,
+			&i.Media.DurationMs,
+			&i.Media.PosterKey,
+			&i.Media.AltText,
 		); err != nil {
 			return nil, err
 		}
@@ -198,3 +355,37 @@ func (q *Queries) GetImagesByCommentId(ctx context.Context, commentID int) ([]Im
 	}
 	return items, nil
 }
+
+const reserveCommentId = `-- name: ReserveCommentId :one
+SELECT nextval(pg_get_serial_sequence('comments', 'comment_id'))::int AS comment_id
+`
+
+func (q *Queries) ReserveCommentId(ctx context.Context) (int, error) {
+	row := q.db.QueryRow(ctx, reserveCommentId)
+	var comment_id int
+	err := row.Scan(&comment_id)
+	return comment_id, err
+}
+
+const updateCommentMediaAltText = `-- name: UpdateCommentMediaAltText :execrows
+UPDATE media
+SET alt_text = $3
+FROM comment_media
+WHERE media.media_id = comment_media.media_id
+  AND comment_media.comment_id = $1
+  AND media.media_id = $2
+`
+
+type UpdateCommentMediaAltTextParams struct {
+	CommentID int         `json:"commentId"`
+	MediaID   int         `json:"mediaId"`
+	AltText   pgtype.Text `json:"altText"`
+}
+
+func (q *Queries) UpdateCommentMediaAltText(ctx context.Context, arg UpdateCommentMediaAltTextParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateCommentMediaAltText, arg.CommentID, arg.MediaID, arg.AltText)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -12,6 +12,13 @@ import (
 )
 
 const getAllPostIdsCursor = `-- name: GetAllPostIdsCursor :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1::int
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT post_id
 FROM posts
 WHERE NOT EXISTS (
@@ -26,15 +33,46 @@ WHERE NOT EXISTS (
     SELECT 1
     FROM mute
     WHERE mute.user_id = $1::int AND target_user_id = posts.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+) AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE posts.user_id != $1::int
+        AND posts.text ~* muted_pattern.pattern
 ) AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $1::int
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $1::int
             AND user_relationship.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $1::int
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $1::int AND follows.following_id = posts.user_id
+    ))
+) AND EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+) AND (
+    posts.user_id = $1::int
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $1::int AND follows.following_id = posts.user_id
     )
 ) AND ($2::timestamp IS NULL OR posts.created_at < $2::timestamp)
 ORDER BY posts.created_at DESC
@@ -68,9 +106,17 @@ func (q *Queries) GetAllPostIdsCursor(ctx context.Context, arg GetAllPostIdsCurs
 }
 
 const getPostById = `-- name: GetPostById :one
-SELECT post_id, user_id, text, created_at, facets, attributes, visibilitytype
+SELECT post_id, user_id, text, created_at, facets, attributes, visibilitytype, audience_list_id
 FROM posts
 WHERE post_id = $1
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+)
+AND (
+    posts.user_id = $2
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = $2
 )
@@ -80,13 +126,25 @@ AND NOT EXISTS (
 AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $2
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $2
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $2
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id
+    ))
 )
 `
 
@@ -106,11 +164,19 @@ func (q *Queries) GetPostById(ctx context.Context, arg GetPostByIdParams) (Post,
 		&i.Facets,
 		&i.Attributes,
 		&i.Visibilitytype,
+		&i.AudienceListID,
 	)
 	return i, err
 }
 
 const getPostIdsByFollowingCursor = `-- name: GetPostIdsByFollowingCursor :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT post_id
 FROM posts
 WHERE (posts.user_id = $1 OR EXISTS (
@@ -129,16 +195,39 @@ WHERE (posts.user_id = $1 OR EXISTS (
     SELECT 1
     FROM mute
     WHERE user_id = $1 AND target_user_id = posts.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+) AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE posts.user_id != $1
+        AND posts.text ~* muted_pattern.pattern
 ) AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $1
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $1
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $1
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+    ))
+) AND EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
 ) AND ($3::timestamp IS NULL OR posts.created_at < $3::timestamp)
 ORDER BY posts.created_at DESC
 LIMIT $2
@@ -177,6 +266,12 @@ JOIN users ON users.user_id = $1::int
 WHERE posts.user_id = $1::int
 AND ($2::timestamp IS NULL OR posts.created_at < $2::timestamp)
 AND (users.pinned_post_id IS NULL OR posts.post_id != users.pinned_post_id)
+AND users.deactivated_at IS NULL
+AND (
+    NOT users.is_private
+    OR posts.user_id = $3
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $3 AND follows.following_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = $3
 )
@@ -186,13 +281,25 @@ AND NOT EXISTS (
 AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $3
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $3
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $3
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $3 AND follows.following_id = posts.user_id
+    ))
 )
 ORDER BY posts.created_at DESC
 LIMIT $4::int
@@ -231,7 +338,14 @@ func (q *Queries) GetPostIdsByUserIdCursor(ctx context.Context, arg GetPostIdsBy
 }
 
 const getPostIdsForMutualFeedCursor = `-- name: GetPostIdsForMutualFeedCursor :many
-WITH user_relationships AS (
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+),
+user_relationships AS (
   SELECT posts.post_id, posts.user_id,
     CASE
       WHEN posts.user_id = $1 THEN 'own'
@@ -246,17 +360,46 @@ WITH user_relationships AS (
                  WHERE f1.follower_id = $1 AND f2.following_id = posts.user_id))
     AND NOT EXISTS (SELECT 1 FROM block WHERE user_id = $1 AND target_user_id = posts.user_id)
     AND NOT EXISTS (SELECT 1 FROM block WHERE user_id = posts.user_id AND target_user_id = $1)
-    AND NOT EXISTS (SELECT 1 FROM mute WHERE user_id = $1 AND target_user_id = posts.user_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM mute
+        WHERE user_id = $1 AND target_user_id = posts.user_id
+            AND mute.hide_posts
+            AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+    )
+    AND NOT EXISTS (
+        SELECT 1
+        FROM muted_pattern
+        WHERE posts.user_id != $1
+            AND posts.text ~* muted_pattern.pattern
+    )
+    AND EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL)
+    AND (
+        posts.user_id = $1
+        OR f.follower_id IS NOT NULL
+        OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    )
     AND (
         posts.visibilityType = 0 -- public
         OR posts.user_id = $1
-        OR EXISTS (
+        OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
             SELECT 1
             FROM user_relationship
             WHERE user_id = posts.user_id
                 AND target_user_id = $1
                 AND user_relationship.created_at < posts.created_at
-        )
+        ))
+        OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+            SELECT 1
+            FROM audience_list_member
+            WHERE audience_list_member.list_id = posts.audience_list_id
+                AND audience_list_member.user_id = $1
+                AND audience_list_member.created_at < posts.created_at
+        ))
+        OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+            SELECT 1
+            FROM follows
+            WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+        ))
     )
     AND ($3::timestamp IS NULL OR posts.created_at < $3::timestamp)
 )
@@ -309,3 +452,80 @@ func (q *Queries) GetPostIdsForMutualFeedCursor(ctx context.Context, arg GetPost
 	}
 	return items, nil
 }
+
+const getPostsByIds = `-- name: GetPostsByIds :many
+SELECT post_id, user_id, text, created_at, facets, attributes, visibilitytype, audience_list_id
+FROM posts
+WHERE post_id = ANY($1::int[])
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+)
+AND (
+    posts.user_id = $2
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = $2
+)
+AND NOT EXISTS (
+    SELECT 1 FROM block WHERE block.user_id = $2 AND block.target_user_id = posts.user_id
+)
+AND (
+    posts.visibilityType = 0 -- public
+    OR posts.user_id = $2
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
+        SELECT 1
+        FROM user_relationship
+        WHERE user_id = posts.user_id
+            AND target_user_id = $2
+            AND user_relationship.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $2
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id
+    ))
+)
+`
+
+type GetPostsByIdsParams struct {
+	PostIds      []int `json:"postIds"`
+	TargetUserID int   `json:"targetUserId"`
+}
+
+func (q *Queries) GetPostsByIds(ctx context.Context, arg GetPostsByIdsParams) ([]Post, error) {
+	rows, err := q.db.Query(ctx, getPostsByIds, arg.PostIds, arg.TargetUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.PostID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.Facets,
+			&i.Attributes,
+			&i.Visibilitytype,
+			&i.AudienceListID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
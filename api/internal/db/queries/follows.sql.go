@@ -12,9 +12,91 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acceptAllFollowRequests = `-- name: AcceptAllFollowRequests :exec
+WITH accepted AS (
+    DELETE FROM follow_request
+    WHERE follow_request.following_id = $1
+    RETURNING follower_id, following_id
+), inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    SELECT accepted.follower_id, accepted.following_id
+    FROM accepted
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count
+`
+
+func (q *Queries) AcceptAllFollowRequests(ctx context.Context, followingID int) error {
+	_, err := q.db.Exec(ctx, acceptAllFollowRequests, followingID)
+	return err
+}
+
+const acceptFollowRequest = `-- name: AcceptFollowRequest :execrows
+WITH accepted AS (
+    DELETE FROM follow_request
+    WHERE follow_request.follower_id = $1 AND follow_request.following_id = $2
+    RETURNING follower_id, following_id
+), inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    SELECT accepted.follower_id, accepted.following_id
+    FROM accepted
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count
+`
+
+type AcceptFollowRequestParams struct {
+	FollowerID  int `json:"followerId"`
+	FollowingID int `json:"followingId"`
+}
+
+func (q *Queries) AcceptFollowRequest(ctx context.Context, arg AcceptFollowRequestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, acceptFollowRequest, arg.FollowerID, arg.FollowingID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteFollow = `-- name: DeleteFollow :exec
-DELETE FROM follows
-WHERE following_id = $1 AND follower_id = $2
+WITH deleted AS (
+    DELETE FROM follows
+    WHERE following_id = $1 AND follower_id = $2
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, -1 AS follower_delta, 0 AS following_delta FROM deleted
+    UNION ALL
+    SELECT follower_id, 0, -1 FROM deleted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count
 `
 
 type DeleteFollowParams struct {
@@ -27,6 +109,21 @@ func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) erro
 	return err
 }
 
+const deleteFollowRequest = `-- name: DeleteFollowRequest :exec
+DELETE FROM follow_request
+WHERE follower_id = $1 AND following_id = $2
+`
+
+type DeleteFollowRequestParams struct {
+	FollowerID  int `json:"followerId"`
+	FollowingID int `json:"followingId"`
+}
+
+func (q *Queries) DeleteFollowRequest(ctx context.Context, arg DeleteFollowRequestParams) error {
+	_, err := q.db.Exec(ctx, deleteFollowRequest, arg.FollowerID, arg.FollowingID)
+	return err
+}
+
 const getFollowersByUserId = `-- name: GetFollowersByUserId :many
 SELECT u.user_id, u.email, u.username, u.created_at, u.name
 FROM users u
@@ -76,6 +173,47 @@ func (q *Queries) GetFollowersByUserId(ctx context.Context, arg GetFollowersByUs
 	return items, nil
 }
 
+const getFollowerUserIds = `-- name: GetFollowerUserIds :many
+SELECT users.user_id, follows.created_at
+FROM users
+INNER JOIN follows ON users.user_id = follows.follower_id
+WHERE follows.following_id = $1::int
+    AND ($2::timestamptz IS NULL OR follows.created_at < $2)
+ORDER BY follows.created_at DESC
+LIMIT $3::int
+`
+
+type GetFollowerUserIdsParams struct {
+	UserID int        `json:"userId"`
+	Before *time.Time `json:"before"`
+	Limit  int        `json:"limit"`
+}
+
+type GetFollowerUserIdsRow struct {
+	UserID    int              `json:"userId"`
+	CreatedAt pgtype.Timestamp `json:"createdAt"`
+}
+
+func (q *Queries) GetFollowerUserIds(ctx context.Context, arg GetFollowerUserIdsParams) ([]GetFollowerUserIdsRow, error) {
+	rows, err := q.db.Query(ctx, getFollowerUserIds, arg.UserID, arg.Before, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowerUserIdsRow
+	for rows.Next() {
+		var i GetFollowerUserIdsRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFollowingByUserId = `-- name: GetFollowingByUserId :many
 SELECT u.user_id, u.email, u.username, u.created_at, u.name
 FROM users u
@@ -166,6 +304,66 @@ func (q *Queries) GetFollowingUserIds(ctx context.Context, arg GetFollowingUserI
 	return items, nil
 }
 
+const getFollowRequestUserIds = `-- name: GetFollowRequestUserIds :many
+SELECT follower_id, created_at
+FROM follow_request
+WHERE following_id = $1::int
+    AND ($2::timestamptz IS NULL OR created_at < $2)
+ORDER BY created_at DESC
+LIMIT $3::int
+`
+
+type GetFollowRequestUserIdsParams struct {
+	UserID int        `json:"userId"`
+	Before *time.Time `json:"before"`
+	Limit  int        `json:"limit"`
+}
+
+type GetFollowRequestUserIdsRow struct {
+	FollowerID int       `json:"followerId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (q *Queries) GetFollowRequestUserIds(ctx context.Context, arg GetFollowRequestUserIdsParams) ([]GetFollowRequestUserIdsRow, error) {
+	rows, err := q.db.Query(ctx, getFollowRequestUserIds, arg.UserID, arg.Before, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowRequestUserIdsRow
+	for rows.Next() {
+		var i GetFollowRequestUserIdsRow
+		if err := rows.Scan(&i.FollowerID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHasRequestedToFollow = `-- name: GetHasRequestedToFollow :one
+SELECT EXISTS (
+  SELECT 1
+  FROM follow_request
+  WHERE follower_id = $1 AND following_id = $2
+)
+`
+
+type GetHasRequestedToFollowParams struct {
+	FollowerID  int `json:"followerId"`
+	FollowingID int `json:"followingId"`
+}
+
+func (q *Queries) GetHasRequestedToFollow(ctx context.Context, arg GetHasRequestedToFollowParams) (bool, error) {
+	row := q.db.QueryRow(ctx, getHasRequestedToFollow, arg.FollowerID, arg.FollowingID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const getIsUserFollowingUser = `-- name: GetIsUserFollowingUser :one
 SELECT EXISTS (
   SELECT 1
@@ -322,9 +520,23 @@ func (q *Queries) GetMutualsByUserIdV2(ctx context.Context, arg GetMutualsByUser
 }
 
 const insertFollow = `-- name: InsertFollow :exec
-INSERT INTO follows (follower_id, following_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING
+WITH inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    VALUES ($1, $2)
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count
 `
 
 type InsertFollowParams struct {
@@ -336,3 +548,22 @@ func (q *Queries) InsertFollow(ctx context.Context, arg InsertFollowParams) erro
 	_, err := q.db.Exec(ctx, insertFollow, arg.FollowerID, arg.FollowingID)
 	return err
 }
+
+const insertFollowRequest = `-- name: InsertFollowRequest :execrows
+INSERT INTO follow_request (follower_id, following_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type InsertFollowRequestParams struct {
+	FollowerID  int `json:"followerId"`
+	FollowingID int `json:"followingId"`
+}
+
+func (q *Queries) InsertFollowRequest(ctx context.Context, arg InsertFollowRequestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertFollowRequest, arg.FollowerID, arg.FollowingID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -7,11 +7,26 @@ package queries
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const addLike = `-- name: AddLike :exec
-INSERT INTO likes (post_id, comment_id, user_id)
-VALUES ($1, $2, $3)
+WITH inserted AS (
+    INSERT INTO likes (post_id, comment_id, user_id)
+    VALUES ($1, $2, $3)
+    RETURNING post_id, comment_id
+), post_counts AS (
+    INSERT INTO post_counter (post_id, like_count)
+    SELECT post_id, 1 FROM inserted WHERE comment_id IS NULL
+    ON CONFLICT (post_id) DO UPDATE
+    SET like_count = post_counter.like_count + 1
+)
+INSERT INTO comment_counter (comment_id, like_count)
+SELECT comment_id, 1 FROM inserted WHERE comment_id IS NOT NULL
+ON CONFLICT (comment_id) DO UPDATE
+SET like_count = comment_counter.like_count + 1
 `
 
 type AddLikeParams struct {
@@ -59,26 +74,96 @@ func (q *Queries) GetIsLikedByUser(ctx context.Context, arg GetIsLikedByUserPara
 	return exists, err
 }
 
-const getIsPostLikedByUser = `-- name: GetIsPostLikedByUser :one
-SELECT EXISTS (
-  SELECT 1
-  FROM likes
-  WHERE user_id = $1
-    AND post_id = $2
+const getLikedPostIds = `-- name: GetLikedPostIds :many
+SELECT post_id
+FROM likes
+WHERE user_id = $1
+    AND post_id = ANY($2::int[])
     AND comment_id IS NULL
-)
 `
 
-type GetIsPostLikedByUserParams struct {
-	UserID int `json:"userId"`
-	PostID int `json:"postId"`
+type GetLikedPostIdsParams struct {
+	UserID  int   `json:"userId"`
+	PostIds []int `json:"postIds"`
 }
 
-func (q *Queries) GetIsPostLikedByUser(ctx context.Context, arg GetIsPostLikedByUserParams) (bool, error) {
-	row := q.db.QueryRow(ctx, getIsPostLikedByUser, arg.UserID, arg.PostID)
-	var exists bool
-	err := row.Scan(&exists)
-	return exists, err
+func (q *Queries) GetLikedPostIds(ctx context.Context, arg GetLikedPostIdsParams) ([]int, error) {
+	rows, err := q.db.Query(ctx, getLikedPostIds, arg.UserID, arg.PostIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int
+	for rows.Next() {
+		var post_id int
+		if err := rows.Scan(&post_id); err != nil {
+			return nil, err
+		}
+		items = append(items, post_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLikerUserIds = `-- name: GetLikerUserIds :many
+SELECT likes.user_id, likes.created_at
+FROM likes
+WHERE likes.post_id = $1::int
+    AND likes.comment_id IS NOT DISTINCT FROM $2::int
+    AND ($3::timestamptz IS NULL OR likes.created_at < $3)
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = $4::int
+            AND block.target_user_id = likes.user_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = likes.user_id
+            AND block.target_user_id = $4::int
+    )
+ORDER BY likes.created_at DESC
+LIMIT $5::int
+`
+
+type GetLikerUserIdsParams struct {
+	PostID        int        `json:"postId"`
+	CommentID     *int       `json:"commentId"`
+	Before        *time.Time `json:"before"`
+	CurrentUserID int        `json:"currentUserId"`
+	Limit         int        `json:"limit"`
+}
+
+type GetLikerUserIdsRow struct {
+	UserID    int                `json:"userId"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+func (q *Queries) GetLikerUserIds(ctx context.Context, arg GetLikerUserIdsParams) ([]GetLikerUserIdsRow, error) {
+	rows, err := q.db.Query(ctx, getLikerUserIds,
+		arg.PostID,
+		arg.CommentID,
+		arg.Before,
+		arg.CurrentUserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLikerUserIdsRow
+	for rows.Next() {
+		var i GetLikerUserIdsRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getPostLikes = `-- name: GetPostLikes :many
@@ -129,11 +214,74 @@ func (q *Queries) GetPostLikes(ctx context.Context, arg GetPostLikesParams) ([]G
 	return items, nil
 }
 
+const getPostLikesByPostIds = `-- name: GetPostLikesByPostIds :many
+SELECT post_id, username, user_id
+FROM (
+    SELECT likes.post_id, users.username, users.user_id,
+        ROW_NUMBER() OVER (PARTITION BY likes.post_id) AS position
+    FROM likes
+    JOIN users ON likes.user_id = users.user_id
+    WHERE likes.post_id = ANY($1::int[]) AND likes.comment_id IS NULL
+    AND likes.user_id != $2
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = $2
+            AND likes.user_id = block.target_user_id
+    ) AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = likes.user_id
+            AND block.target_user_id = $2
+    )
+) AS ranked
+WHERE position <= 3
+`
+
+type GetPostLikesByPostIdsParams struct {
+	PostIds []int `json:"postIds"`
+	UserID  int   `json:"userId"`
+}
+
+type GetPostLikesByPostIdsRow struct {
+	PostID   int    `json:"postId"`
+	Username string `json:"username"`
+	UserID   int    `json:"userId"`
+}
+
+func (q *Queries) GetPostLikesByPostIds(ctx context.Context, arg GetPostLikesByPostIdsParams) ([]GetPostLikesByPostIdsRow, error) {
+	rows, err := q.db.Query(ctx, getPostLikesByPostIds, arg.PostIds, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostLikesByPostIdsRow
+	for rows.Next() {
+		var i GetPostLikesByPostIdsRow
+		if err := rows.Scan(&i.PostID, &i.Username, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeLike = `-- name: RemoveLike :exec
-DELETE FROM likes
-WHERE post_id = $1
-AND user_id = $2
-AND ($3 = TRUE OR comment_id = $4)
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE post_id = $1
+    AND user_id = $2
+    AND ($3 = TRUE OR comment_id = $4)
+    RETURNING post_id, comment_id
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id AND deleted.comment_id IS NULL)
+    WHERE post_id IN (SELECT post_id FROM deleted WHERE comment_id IS NULL)
+)
+UPDATE comment_counter
+SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.comment_id = comment_counter.comment_id)
+WHERE comment_id IN (SELECT comment_id FROM deleted)
 `
 
 type RemoveLikeParams struct {
@@ -11,6 +11,28 @@ import (
 	db "splajompy.com/api/v2/internal/db"
 )
 
+type AppleIdentity struct {
+	ID             int         `json:"id"`
+	UserID         int         `json:"userId"`
+	Subject        string      `json:"subject"`
+	Email          pgtype.Text `json:"email"`
+	IsPrivateEmail bool        `json:"isPrivateEmail"`
+	CreatedAt      *time.Time  `json:"createdAt"`
+}
+
+type AudienceList struct {
+	ListID    int       `json:"listId"`
+	UserID    int       `json:"userId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AudienceListMember struct {
+	ListID    int              `json:"listId"`
+	UserID    int              `json:"userId"`
+	CreatedAt pgtype.Timestamp `json:"createdAt"`
+}
+
 type Bio struct {
 	ID     int    `json:"id"`
 	UserID int    `json:"userId"`
@@ -33,9 +55,25 @@ type Comment struct {
 	CreatedAt pgtype.Timestamp `json:"createdAt"`
 }
 
-type CommentImage struct {
+type CommentCounter struct {
 	CommentID int `json:"commentId"`
-	ImageID   int `json:"imageId"`
+	LikeCount int `json:"likeCount"`
+}
+
+type CommentMedia struct {
+	CommentID int `json:"commentId"`
+	MediaID   int `json:"mediaId"`
+}
+
+type DataExport struct {
+	ID          int         `json:"id"`
+	UserID      int         `json:"userId"`
+	Status      string      `json:"status"`
+	ObjectKey   pgtype.Text `json:"objectKey"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	CompletedAt *time.Time  `json:"completedAt"`
+	Attempts    int         `json:"attempts"`
+	StartedAt   *time.Time  `json:"startedAt"`
 }
 
 type DeviceToken struct {
@@ -54,11 +92,10 @@ type Follow struct {
 	CreatedAt   pgtype.Timestamp `json:"createdAt"`
 }
 
-type Image struct {
-	ImageID      int    `json:"imageId"`
-	Height       int    `json:"height"`
-	Width        int    `json:"width"`
-	ImageBlobUrl string `json:"imageBlobUrl"`
+type FollowRequest struct {
+	FollowerID  int       `json:"followerId"`
+	FollowingID int       `json:"followingId"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 type Like struct {
@@ -68,11 +105,42 @@ type Like struct {
 	CreatedAt pgtype.Timestamptz `json:"createdAt"`
 }
 
+type Media struct {
+	MediaID             int              `json:"mediaId"`
+	Height              int              `json:"height"`
+	Width               int              `json:"width"`
+	BlobKey             string           `json:"blobKey"`
+	Blurhash            pgtype.Text      `json:"blurhash"`
+	Variants            db.ImageVariants `json:"variants"`
+	MediaType           string           `json:"mediaType"`
+	ProcessingStatus    string           `json:"processingStatus"`
+	ProcessingAttempts  int              `json:"processingAttempts"`
+	ProcessingStartedAt *time.Time       `json:"processingStartedAt"`
+	DurationMs          *int             `json:"durationMs"`
+	PosterKey           pgtype.Text      `json:"posterKey"`
+	AltText             pgtype.Text      `json:"altText"`
+}
+
 type Mute struct {
-	ID           int              `json:"id"`
-	UserID       int              `json:"userId"`
-	TargetUserID int              `json:"targetUserId"`
-	CreatedAt    pgtype.Timestamp `json:"createdAt"`
+	ID                int              `json:"id"`
+	UserID            int              `json:"userId"`
+	TargetUserID      int              `json:"targetUserId"`
+	CreatedAt         pgtype.Timestamp `json:"createdAt"`
+	HidePosts         bool             `json:"hidePosts"`
+	MuteNotifications bool             `json:"muteNotifications"`
+	ExpiresAt         *time.Time       `json:"expiresAt"`
+}
+
+type MutedWord struct {
+	ID                int        `json:"id"`
+	UserID            int        `json:"userId"`
+	Phrase            string     `json:"phrase"`
+	Pattern           string     `json:"pattern"`
+	MuteFeeds         bool       `json:"muteFeeds"`
+	MuteNotifications bool       `json:"muteNotifications"`
+	MuteComments      bool       `json:"muteComments"`
+	ExpiresAt         *time.Time `json:"expiresAt"`
+	CreatedAt         time.Time  `json:"createdAt"`
 }
 
 type Notification struct {
@@ -96,6 +164,12 @@ type NotificationActor struct {
 	CreatedAt      *time.Time `json:"createdAt"`
 }
 
+type PollDeadline struct {
+	PostID          int        `json:"postId"`
+	ClosesAt        time.Time  `json:"closesAt"`
+	EndedNotifiedAt *time.Time `json:"endedNotifiedAt"`
+}
+
 type PollVote struct {
 	ID          int              `json:"id"`
 	PostID      int              `json:"postId"`
@@ -112,14 +186,30 @@ type Post struct {
 	Facets         db.Facets        `json:"facets"`
 	Attributes     *db.Attributes   `json:"attributes"`
 	Visibilitytype int              `json:"visibilitytype"`
+	AudienceListID *int             `json:"audienceListId"`
+}
+
+type PostCounter struct {
+	PostID        int `json:"postId"`
+	LikeCount     int `json:"likeCount"`
+	CommentCount  int `json:"commentCount"`
+	PollVoteCount int `json:"pollVoteCount"`
 }
 
-type PostImage struct {
+type PostMedia struct {
 	PostID       int `json:"postId"`
-	ImageID      int `json:"imageId"`
+	MediaID      int `json:"mediaId"`
 	DisplayOrder int `json:"displayOrder"`
 }
 
+type Reaction struct {
+	PostID    int       `json:"postId"`
+	CommentID *int      `json:"commentId"`
+	UserID    int       `json:"userId"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 type Session struct {
 	ID        string           `json:"id"`
 	UserID    int              `json:"userId"`
@@ -136,6 +226,18 @@ type User struct {
 	PinnedPostID          *int                      `json:"pinnedPostId"`
 	UserDisplayProperties *db.UserDisplayProperties `json:"userDisplayProperties"`
 	ReferralCode          string                    `json:"referralCode"`
+	DeactivatedAt         *time.Time                `json:"deactivatedAt"`
+	IsPrivate             bool                      `json:"isPrivate"`
+	AvatarKey             pgtype.Text               `json:"avatarKey"`
+	BannerKey             pgtype.Text               `json:"bannerKey"`
+	RequireAltText        bool                      `json:"requireAltText"`
+}
+
+type UserCounter struct {
+	UserID         int `json:"userId"`
+	FollowerCount  int `json:"followerCount"`
+	FollowingCount int `json:"followingCount"`
+	PostCount      int `json:"postCount"`
 }
 
 type UserRelationship struct {
@@ -22,6 +22,23 @@ func (q *Queries) DeleteDeviceToken(ctx context.Context, token string) error {
 	return err
 }
 
+const deleteFollowRequestNotification = `-- name: DeleteFollowRequestNotification :exec
+DELETE FROM notifications
+WHERE user_id = $1
+  AND target_user_id = $2
+  AND notification_type = 'followRequest'
+`
+
+type DeleteFollowRequestNotificationParams struct {
+	UserID       int  `json:"userId"`
+	TargetUserID *int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteFollowRequestNotification(ctx context.Context, arg DeleteFollowRequestNotificationParams) error {
+	_, err := q.db.Exec(ctx, deleteFollowRequestNotification, arg.UserID, arg.TargetUserID)
+	return err
+}
+
 const deleteNotificationActor = `-- name: DeleteNotificationActor :exec
 DELETE FROM notification_actor
 WHERE notification_id = $1 AND user_id = $2
@@ -47,6 +64,34 @@ func (q *Queries) DeleteNotificationById(ctx context.Context, notificationID int
 	return err
 }
 
+const deleteNotificationsInvolvingUser = `-- name: DeleteNotificationsInvolvingUser :exec
+DELETE FROM notifications
+WHERE notifications.user_id = $1
+    AND (
+        notifications.target_user_id = $2
+        OR EXISTS (
+            SELECT 1
+            FROM posts
+            WHERE posts.post_id = notifications.post_id AND posts.user_id = $2
+        )
+        OR EXISTS (
+            SELECT 1
+            FROM comments
+            WHERE comments.comment_id = notifications.comment_id AND comments.user_id = $2
+        )
+    )
+`
+
+type DeleteNotificationsInvolvingUserParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteNotificationsInvolvingUser(ctx context.Context, arg DeleteNotificationsInvolvingUserParams) error {
+	_, err := q.db.Exec(ctx, deleteNotificationsInvolvingUser, arg.UserID, arg.TargetUserID)
+	return err
+}
+
 const findLikeNotificationForComment = `-- name: FindLikeNotificationForComment :one
 SELECT notification_id, user_id, post_id, comment_id, target_user_id, message, link, viewed, facets, notification_type, created_at
 FROM notifications
@@ -118,6 +163,81 @@ func (q *Queries) FindLikeNotificationForPost(ctx context.Context, arg FindLikeN
 	return i, err
 }
 
+const findPollVoteNotification = `-- name: FindPollVoteNotification :one
+SELECT notification_id, user_id, post_id, comment_id, target_user_id, message, link, viewed, facets, notification_type, created_at
+FROM notifications
+WHERE user_id = $1
+  AND notification_type = 'poll'
+  AND post_id = $2
+  AND EXISTS (
+    SELECT 1
+    FROM notification_actor
+    WHERE notification_actor.notification_id = notifications.notification_id
+  )
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type FindPollVoteNotificationParams struct {
+	UserID int  `json:"userId"`
+	PostID *int `json:"postId"`
+}
+
+func (q *Queries) FindPollVoteNotification(ctx context.Context, arg FindPollVoteNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, findPollVoteNotification, arg.UserID, arg.PostID)
+	var i Notification
+	err := row.Scan(
+		&i.NotificationID,
+		&i.UserID,
+		&i.PostID,
+		&i.CommentID,
+		&i.TargetUserID,
+		&i.Message,
+		&i.Link,
+		&i.Viewed,
+		&i.Facets,
+		&i.NotificationType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const findReactionNotification = `-- name: FindReactionNotification :one
+SELECT notification_id, user_id, post_id, comment_id, target_user_id, message, link, viewed, facets, notification_type, created_at
+FROM notifications
+WHERE user_id = $1
+  AND notification_type = 'reaction'
+  AND post_id = $2
+  AND comment_id IS NOT DISTINCT FROM $3
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type FindReactionNotificationParams struct {
+	UserID    int  `json:"userId"`
+	PostID    *int `json:"postId"`
+	CommentID *int `json:"commentId"`
+}
+
+func (q *Queries) FindReactionNotification(ctx context.Context, arg FindReactionNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, findReactionNotification, arg.UserID, arg.PostID, arg.CommentID)
+	var i Notification
+	err := row.Scan(
+		&i.NotificationID,
+		&i.UserID,
+		&i.PostID,
+		&i.CommentID,
+		&i.TargetUserID,
+		&i.Message,
+		&i.Link,
+		&i.Viewed,
+		&i.Facets,
+		&i.NotificationType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getDeviceTokensForUser = `-- name: GetDeviceTokensForUser :many
 SELECT id, user_id, token, is_enabled_mentions, is_enabled_comments, is_enabled_follows, created_at
 FROM device_token
@@ -205,6 +325,32 @@ func (q *Queries) GetNotificationById(ctx context.Context, notificationID int) (
 	return i, err
 }
 
+const getNotificationIdsWithActors = `-- name: GetNotificationIdsWithActors :many
+SELECT DISTINCT notification_id
+FROM notification_actor
+WHERE notification_id = ANY($1::int[])
+`
+
+func (q *Queries) GetNotificationIdsWithActors(ctx context.Context, notificationIds []int) ([]int, error) {
+	rows, err := q.db.Query(ctx, getNotificationIdsWithActors, notificationIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int
+	for rows.Next() {
+		var notification_id int
+		if err := rows.Scan(&notification_id); err != nil {
+			return nil, err
+		}
+		items = append(items, notification_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getNotificationsForUserId = `-- name: GetNotificationsForUserId :many
 SELECT notification_id, user_id, post_id, comment_id, target_user_id, message, link, viewed, facets, notification_type, created_at
 FROM notifications
@@ -253,6 +399,13 @@ func (q *Queries) GetNotificationsForUserId(ctx context.Context, arg GetNotifica
 }
 
 const getNotificationsForUserIdWithTimeOffset = `-- name: GetNotificationsForUserIdWithTimeOffset :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_notifications
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT notifications.notification_id, notifications.user_id, notifications.post_id, notifications.comment_id, notifications.target_user_id, notifications.message, notifications.link, notifications.viewed, notifications.facets, notifications.notification_type, notifications.created_at
 FROM notifications
 LEFT JOIN posts ON notifications.post_id = posts.post_id
@@ -274,17 +427,34 @@ WHERE notifications.user_id = $1 AND notifications.viewed = $4 AND notifications
         JOIN posts ON posts.post_id = notifications.post_id
         WHERE block.user_id = posts.user_id
             AND block.target_user_id = $1
+    ) AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+        SELECT 1 FROM muted_pattern
+        LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+        WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+            OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
     ) AND (
         notifications.post_id IS NULL
         OR posts.visibilityType = 0 -- public
         OR posts.user_id = $1
-        OR EXISTS (
+        OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
             SELECT 1
             FROM user_relationship
             WHERE user_id = posts.user_id
                 AND target_user_id = $1
                 AND user_relationship.created_at < posts.created_at
-        )
+        ))
+        OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+            SELECT 1
+            FROM audience_list_member
+            WHERE audience_list_member.list_id = posts.audience_list_id
+                AND audience_list_member.user_id = $1
+                AND audience_list_member.created_at < posts.created_at
+        ))
+        OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+            SELECT 1
+            FROM follows
+            WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+        ))
     )
 ORDER BY notifications.created_at DESC
 LIMIT $3
@@ -336,6 +506,50 @@ func (q *Queries) GetNotificationsForUserIdWithTimeOffset(ctx context.Context, a
 	return items, nil
 }
 
+const getNotificationsWithActor = `-- name: GetNotificationsWithActor :many
+SELECT notifications.notification_id, notifications.user_id, notifications.post_id, notifications.comment_id, notifications.target_user_id, notifications.message, notifications.link, notifications.viewed, notifications.facets, notifications.notification_type, notifications.created_at
+FROM notifications
+JOIN notification_actor ON notification_actor.notification_id = notifications.notification_id
+WHERE notifications.user_id = $1 AND notification_actor.user_id = $2
+`
+
+type GetNotificationsWithActorParams struct {
+	UserID  int `json:"userId"`
+	ActorID int `json:"actorId"`
+}
+
+func (q *Queries) GetNotificationsWithActor(ctx context.Context, arg GetNotificationsWithActorParams) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, getNotificationsWithActor, arg.UserID, arg.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.NotificationID,
+			&i.UserID,
+			&i.PostID,
+			&i.CommentID,
+			&i.TargetUserID,
+			&i.Message,
+			&i.Link,
+			&i.Viewed,
+			&i.Facets,
+			&i.NotificationType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnreadNotificationsForUserId = `-- name: GetUnreadNotificationsForUserId :many
 SELECT notification_id, user_id, post_id, comment_id, target_user_id, message, link, viewed, facets, notification_type, created_at
 FROM notifications
@@ -384,9 +598,23 @@ func (q *Queries) GetUnreadNotificationsForUserId(ctx context.Context, arg GetUn
 }
 
 const getUserUnreadNotificationCount = `-- name: GetUserUnreadNotificationCount :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = $1
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT COUNT(*)
 FROM notifications
 WHERE user_id = $1 AND viewed = FALSE
+  AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+    SELECT 1 FROM muted_pattern
+    LEFT JOIN posts ON posts.post_id = notifications.post_id
+    LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+    WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+      OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
+  )
 `
 
 func (q *Queries) GetUserUnreadNotificationCount(ctx context.Context, userID int) (int64, error) {
@@ -485,6 +713,43 @@ func (q *Queries) InsertNotificationActor(ctx context.Context, arg InsertNotific
 	return err
 }
 
+const isMutingNotificationsFrom = `-- name: IsMutingNotificationsFrom :one
+SELECT EXISTS (
+  SELECT 1
+  FROM mute
+  WHERE user_id = $1 AND target_user_id = $2
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
+`
+
+type IsMutingNotificationsFromParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) IsMutingNotificationsFrom(ctx context.Context, arg IsMutingNotificationsFromParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isMutingNotificationsFrom, arg.UserID, arg.TargetUserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const lockNotification = `-- name: LockNotification :exec
+SELECT pg_advisory_xact_lock(hashtextextended(format('notification:%s:%s:%s', $1::int, $2::int, $3::text), 0))
+`
+
+type LockNotificationParams struct {
+	UserID           int    `json:"userId"`
+	PostID           int    `json:"postId"`
+	NotificationType string `json:"notificationType"`
+}
+
+func (q *Queries) LockNotification(ctx context.Context, arg LockNotificationParams) error {
+	_, err := q.db.Exec(ctx, lockNotification, arg.UserID, arg.PostID, arg.NotificationType)
+	return err
+}
+
 const markAllNotificationsAsReadForUser = `-- name: MarkAllNotificationsAsReadForUser :exec
 UPDATE notifications
 SET viewed = TRUE
@@ -507,6 +772,48 @@ func (q *Queries) MarkNotificationAsReadById(ctx context.Context, notificationID
 	return err
 }
 
+const markNotificationsAsReadByIds = `-- name: MarkNotificationsAsReadByIds :exec
+UPDATE notifications
+SET viewed = TRUE
+WHERE notification_id = ANY($1::int[])
+`
+
+func (q *Queries) MarkNotificationsAsReadByIds(ctx context.Context, notificationIds []int) error {
+	_, err := q.db.Exec(ctx, markNotificationsAsReadByIds, notificationIds)
+	return err
+}
+
+const notificationContainsMutedWord = `-- name: NotificationContainsMutedWord :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = $1::int
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
+SELECT EXISTS (
+  SELECT 1
+  FROM muted_pattern
+  LEFT JOIN posts ON posts.post_id = $2::int
+  LEFT JOIN comments ON comments.comment_id = $3::int
+  WHERE (posts.user_id != $1::int AND posts.text ~* muted_pattern.pattern)
+    OR (comments.user_id != $1::int AND comments.text ~* muted_pattern.pattern)
+)
+`
+
+type NotificationContainsMutedWordParams struct {
+	PostID    *int `json:"postId"`
+	CommentID *int `json:"commentId"`
+	UserID    int  `json:"userId"`
+}
+
+func (q *Queries) NotificationContainsMutedWord(ctx context.Context, arg NotificationContainsMutedWordParams) (bool, error) {
+	row := q.db.QueryRow(ctx, notificationContainsMutedWord, arg.PostID, arg.CommentID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const updateNotificationMessage = `-- name: UpdateNotificationMessage :exec
 UPDATE notifications
 SET message = $2, facets = $3, created_at = CURRENT_TIMESTAMP, viewed = FALSE
@@ -542,10 +849,24 @@ func (q *Queries) UpdateNotificationMessageOnly(ctx context.Context, arg UpdateN
 }
 
 const userHasUnreadNotifications = `-- name: UserHasUnreadNotifications :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = $1
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT EXISTS (
   SELECT 1
   FROM notifications
   WHERE user_id = $1 AND viewed = FALSE
+    AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+      SELECT 1 FROM muted_pattern
+      LEFT JOIN posts ON posts.post_id = notifications.post_id
+      LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+      WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+        OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
+    )
 )
 `
 
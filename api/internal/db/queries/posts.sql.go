@@ -7,14 +7,63 @@ package queries
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	db "splajompy.com/api/v2/internal/db"
 )
 
+const claimEndedPolls = `-- name: ClaimEndedPolls :many
+UPDATE poll_deadline
+SET ended_notified_at = NOW()
+FROM posts
+WHERE poll_deadline.post_id = posts.post_id
+    AND poll_deadline.post_id IN (
+        SELECT post_id
+        FROM poll_deadline
+        WHERE ended_notified_at IS NULL AND closes_at <= NOW()
+        ORDER BY closes_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    )
+RETURNING posts.post_id, posts.user_id, posts.attributes
+`
+
+type ClaimEndedPollsRow struct {
+	PostID     int            `json:"postId"`
+	UserID     int            `json:"userId"`
+	Attributes *db.Attributes `json:"attributes"`
+}
+
+func (q *Queries) ClaimEndedPolls(ctx context.Context, limit int) ([]ClaimEndedPollsRow, error) {
+	rows, err := q.db.Query(ctx, claimEndedPolls, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimEndedPollsRow
+	for rows.Next() {
+		var i ClaimEndedPollsRow
+		if err := rows.Scan(&i.PostID, &i.UserID, &i.Attributes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deletePost = `-- name: DeletePost :exec
-DELETE FROM posts
-WHERE post_id = $1
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE post_id = $1
+    RETURNING user_id
+)
+UPDATE user_counter
+SET post_count = post_count - 1
+WHERE user_id IN (SELECT user_id FROM deleted)
 `
 
 func (q *Queries) DeletePost(ctx context.Context, postID int) error {
@@ -22,28 +71,59 @@ func (q *Queries) DeletePost(ctx context.Context, postID int) error {
 	return err
 }
 
-const getAllImagesByUserId = `-- name: GetAllImagesByUserId :many
-SELECT images.image_id, images.height, images.width, images.image_blob_url
-FROM images
-JOIN post_images ON images.image_id = post_images.image_id
-JOIN posts ON posts.post_id = post_images.post_id
+const deleteUserVoteInPoll = `-- name: DeleteUserVoteInPoll :exec
+WITH deleted AS (
+    DELETE FROM poll_vote
+    WHERE post_id = $1 AND user_id = $2 AND option_index = $3
+    RETURNING post_id
+)
+UPDATE post_counter
+SET poll_vote_count = poll_vote_count - 1
+WHERE post_id IN (SELECT post_id FROM deleted)
+`
+
+type DeleteUserVoteInPollParams struct {
+	PostID      int `json:"postId"`
+	UserID      int `json:"userId"`
+	OptionIndex int `json:"optionIndex"`
+}
+
+func (q *Queries) DeleteUserVoteInPoll(ctx context.Context, arg DeleteUserVoteInPollParams) error {
+	_, err := q.db.Exec(ctx, deleteUserVoteInPoll, arg.PostID, arg.UserID, arg.OptionIndex)
+	return err
+}
+
+const getAllMediaByUserId = `-- name: GetAllMediaByUserId :many
+SELECT media.media_id, media.height, media.width, media.blob_key, media.blurhash, media.variants, media.media_type, media.processing_status, media.processing_attempts, media.processing_started_at, media.duration_ms, media.poster_key, media.alt_text
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+JOIN posts ON posts.post_id = post_media.post_id
 WHERE posts.user_id = $1
 `
 
-func (q *Queries) GetAllImagesByUserId(ctx context.Context, userID int) ([]Image, error) {
-	rows, err := q.db.Query(ctx, getAllImagesByUserId, userID)
+func (q *Queries) GetAllMediaByUserId(ctx context.Context, userID int) ([]Media, error) {
+	rows, err := q.db.Query(ctx, getAllMediaByUserId, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Image
+	var items []Media
 	for rows.Next() {
-		var i Image
+		var i Media
 		if err := rows.Scan(
-			&i.ImageID,
+			&i.MediaID,
 			&i.Height,
 			&i.Width,
-			&i.ImageBlobUrl,
+			&i.BlobKey,
+			&i.Blurhash,
+			&i.Variants,
+			&i.MediaType,
+			&i.ProcessingStatus,
+			&i.ProcessingAttempts,
+			&i.ProcessingStartedAt,
+			&i.DurationMs,
+			&i.PosterKey,
+			&i.AltText,
 		); err != nil {
 			return nil, err
 		}
@@ -55,41 +135,85 @@ func (q *Queries) GetAllImagesByUserId(ctx context.Context, userID int) ([]Image
 	return items, nil
 }
 
-const getCommentCountByPostID = `-- name: GetCommentCountByPostID :one
-SELECT COUNT(*)
-FROM comments
-WHERE post_id = $1
+const getMediaByPostId = `-- name: GetMediaByPostId :many
+SELECT media.media_id, media.height, media.width, media.blob_key, media.blurhash, media.variants, media.media_type, media.processing_status, media.processing_attempts, media.processing_started_at, media.duration_ms, media.poster_key, media.alt_text
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+WHERE post_media.post_id = $1
+ORDER BY post_media.display_order ASC
 `
 
-func (q *Queries) GetCommentCountByPostID(ctx context.Context, postID int) (int64, error) {
-	row := q.db.QueryRow(ctx, getCommentCountByPostID, postID)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+func (q *Queries) GetMediaByPostId(ctx context.Context, postID int) ([]Media, error) {
+	rows, err := q.db.Query(ctx, getMediaByPostId, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Media
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Height,
+			&i.Width,
+			&i.BlobKey,
+			&i.Blurhash,
+			&i.Variants,
+			&i.MediaType,
+			&i.ProcessingStatus,
+			&i.ProcessingAttempts,
+			&i.ProcessingStartedAt,
+			&i.DurationMs,
+			&i.PosterKey,
+			&i.AltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getImagesByPostId = `-- name: GetImagesByPostId :many
-SELECT images.image_id, images.height, images.width, images.image_blob_url
-FROM images
-JOIN post_images ON images.image_id = post_images.image_id
-WHERE post_images.post_id = $1
-ORDER BY post_images.display_order ASC
+const getMediaByPostIds = `-- name: GetMediaByPostIds :many
+SELECT post_media.post_id, media.media_id, media.height, media.width, media.blob_key, media.blurhash, media.variants, media.media_type, media.processing_status, media.processing_attempts, media.processing_started_at, media.duration_ms, media.poster_key, media.alt_text
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+WHERE post_media.post_id = ANY($1::int[])
+ORDER BY post_media.post_id, post_media.display_order ASC
 `
 
-func (q *Queries) GetImagesByPostId(ctx context.Context, postID int) ([]Image, error) {
-	rows, err := q.db.Query(ctx, getImagesByPostId, postID)
+type GetMediaByPostIdsRow struct {
+	PostID int   `json:"postId"`
+	Media  Media `json:"media"`
+}
+
+func (q *Queries) GetMediaByPostIds(ctx context.Context, postIds []int) ([]GetMediaByPostIdsRow, error) {
+	rows, err := q.db.Query(ctx, getMediaByPostIds, postIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Image
+	var items []GetMediaByPostIdsRow
 	for rows.Next() {
-		var i Image
+		var i GetMediaByPostIdsRow
 		if err := rows.Scan(
-			&i.ImageID,
-			&i.Height,
-			&i.Width,
-			&i.ImageBlobUrl,
+			&i.PostID,
+			&i.Media.MediaID,
+			&i.Media.Height,
+			&i.Media.Width,
+			&i.Media.BlobKey,
+			&i.Media.Blurhash,
+			&i.Media.Variants,
+			&i.Media.MediaType,
+			&i.Media.ProcessingStatus,
+			&i.Media.ProcessingAttempts,
+			&i.Media.ProcessingStartedAt,
+			&i.Media.DurationMs,
+			&i.Media.PosterKey,
+			&i.Media.AltText,
 		); err != nil {
 			return nil, err
 		}
@@ -114,6 +238,77 @@ func (q *Queries) GetPinnedPostId(ctx context.Context, userID int) (*int, error)
 	return pinned_post_id, err
 }
 
+const getPinnedPostIds = `-- name: GetPinnedPostIds :many
+SELECT pinned_post_id::int
+FROM users
+WHERE pinned_post_id = ANY($1::int[])
+`
+
+func (q *Queries) GetPinnedPostIds(ctx context.Context, postIds []int) ([]int, error) {
+	rows, err := q.db.Query(ctx, getPinnedPostIds, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int
+	for rows.Next() {
+		var pinned_post_id int
+		if err := rows.Scan(&pinned_post_id); err != nil {
+			return nil, err
+		}
+		items = append(items, pinned_post_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollVoterCount = `-- name: GetPollVoterCount :one
+SELECT COUNT(DISTINCT user_id)
+FROM poll_vote
+WHERE post_id = $1
+`
+
+func (q *Queries) GetPollVoterCount(ctx context.Context, postID int) (int64, error) {
+	row := q.db.QueryRow(ctx, getPollVoterCount, postID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getPollVoterCounts = `-- name: GetPollVoterCounts :many
+SELECT post_id, COUNT(DISTINCT user_id) AS count
+FROM poll_vote
+WHERE post_id = ANY($1::int[])
+GROUP BY post_id
+`
+
+type GetPollVoterCountsRow struct {
+	PostID int   `json:"postId"`
+	Count  int64 `json:"count"`
+}
+
+func (q *Queries) GetPollVoterCounts(ctx context.Context, postIds []int) ([]GetPollVoterCountsRow, error) {
+	rows, err := q.db.Query(ctx, getPollVoterCounts, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollVoterCountsRow
+	for rows.Next() {
+		var i GetPollVoterCountsRow
+		if err := rows.Scan(&i.PostID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPollVotesGrouped = `-- name: GetPollVotesGrouped :many
 SELECT option_index, COUNT(*) AS count
 FROM poll_vote
@@ -146,52 +341,214 @@ func (q *Queries) GetPollVotesGrouped(ctx context.Context, postID int) ([]GetPol
 	return items, nil
 }
 
-const getUserVoteInPoll = `-- name: GetUserVoteInPoll :one
+const getPollVotesGroupedByPostIds = `-- name: GetPollVotesGroupedByPostIds :many
+SELECT post_id, option_index, COUNT(*) AS count
+FROM poll_vote
+WHERE post_id = ANY($1::int[])
+GROUP BY post_id, option_index
+`
+
+type GetPollVotesGroupedByPostIdsRow struct {
+	PostID      int   `json:"postId"`
+	OptionIndex int   `json:"optionIndex"`
+	Count       int64 `json:"count"`
+}
+
+func (q *Queries) GetPollVotesGroupedByPostIds(ctx context.Context, postIds []int) ([]GetPollVotesGroupedByPostIdsRow, error) {
+	rows, err := q.db.Query(ctx, getPollVotesGroupedByPostIds, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollVotesGroupedByPostIdsRow
+	for rows.Next() {
+		var i GetPollVotesGroupedByPostIdsRow
+		if err := rows.Scan(&i.PostID, &i.OptionIndex, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostCounters = `-- name: GetPostCounters :many
+SELECT post_id, like_count, comment_count, poll_vote_count
+FROM post_counter
+WHERE post_id = ANY($1::int[])
+`
+
+func (q *Queries) GetPostCounters(ctx context.Context, postIds []int) ([]PostCounter, error) {
+	rows, err := q.db.Query(ctx, getPostCounters, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostCounter
+	for rows.Next() {
+		var i PostCounter
+		if err := rows.Scan(
+			&i.PostID,
+			&i.LikeCount,
+			&i.CommentCount,
+			&i.PollVoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserVotesInPoll = `-- name: GetUserVotesInPoll :many
 SELECT option_index
 FROM poll_vote
 WHERE post_id = $1 AND user_id = $2
+ORDER BY option_index
 `
 
-type GetUserVoteInPollParams struct {
+type GetUserVotesInPollParams struct {
 	PostID int `json:"postId"`
 	UserID int `json:"userId"`
 }
 
-func (q *Queries) GetUserVoteInPoll(ctx context.Context, arg GetUserVoteInPollParams) (int, error) {
-	row := q.db.QueryRow(ctx, getUserVoteInPoll, arg.PostID, arg.UserID)
-	var option_index int
-	err := row.Scan(&option_index)
-	return option_index, err
+func (q *Queries) GetUserVotesInPoll(ctx context.Context, arg GetUserVotesInPollParams) ([]int, error) {
+	rows, err := q.db.Query(ctx, getUserVotesInPoll, arg.PostID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int
+	for rows.Next() {
+		var option_index int
+		if err := rows.Scan(&option_index); err != nil {
+			return nil, err
+		}
+		items = append(items, option_index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const insertImage = `-- name: InsertImage :one
-INSERT INTO images (height, width, image_blob_url)
-VALUES ($1, $2, $3)
-RETURNING image_id, height, width, image_blob_url
+const getUserVotesInPolls = `-- name: GetUserVotesInPolls :many
+SELECT post_id, option_index
+FROM poll_vote
+WHERE post_id = ANY($1::int[]) AND user_id = $2
+ORDER BY post_id, option_index
+`
+
+type GetUserVotesInPollsParams struct {
+	PostIds []int `json:"postIds"`
+	UserID  int   `json:"userId"`
+}
+
+type GetUserVotesInPollsRow struct {
+	PostID      int `json:"postId"`
+	OptionIndex int `json:"optionIndex"`
+}
+
+func (q *Queries) GetUserVotesInPolls(ctx context.Context, arg GetUserVotesInPollsParams) ([]GetUserVotesInPollsRow, error) {
+	rows, err := q.db.Query(ctx, getUserVotesInPolls, arg.PostIds, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserVotesInPollsRow
+	for rows.Next() {
+		var i GetUserVotesInPollsRow
+		if err := rows.Scan(&i.PostID, &i.OptionIndex); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertMedia = `-- name: InsertMedia :one
+INSERT INTO media (height, width, blob_key, blurhash, variants, media_type, processing_status, alt_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING media_id, height, width, blob_key, blurhash, variants, media_type, processing_status, processing_attempts, processing_started_at, duration_ms, poster_key, alt_text
 `
 
-type InsertImageParams struct {
-	Height       int    `json:"height"`
-	Width        int    `json:"width"`
-	ImageBlobUrl string `json:"imageBlobUrl"`
+type InsertMediaParams struct {
+	Height           int              `json:"height"`
+	Width            int              `json:"width"`
+	BlobKey          string           `json:"blobKey"`
+	Blurhash         pgtype.Text      `json:"blurhash"`
+	Variants         db.ImageVariants `json:"variants"`
+	MediaType        string           `json:"mediaType"`
+	ProcessingStatus string           `json:"processingStatus"`
+	AltText          pgtype.Text      `json:"altText"`
 }
 
-func (q *Queries) InsertImage(ctx context.Context, arg InsertImageParams) (Image, error) {
-	row := q.db.QueryRow(ctx, insertImage, arg.Height, arg.Width, arg.ImageBlobUrl)
-	var i Image
+func (q *Queries) InsertMedia(ctx context.Context, arg InsertMediaParams) (Media, error) {
+	row := q.db.QueryRow(ctx, insertMedia,
+		arg.Height,
+		arg.Width,
+		arg.BlobKey,
+		arg.Blurhash,
+		arg.Variants,
+		arg.MediaType,
+		arg.ProcessingStatus,
+		arg.AltText,
+	)
+	var i Media
 	err := row.Scan(
-		&i.ImageID,
+		&i.MediaID,
 		&i.Height,
 		&i.Width,
-		&i.ImageBlobUrl,
+		&i.BlobKey,
+		&i.Blurhash,
+		&i.Variants,
+		&i.MediaType,
+		&i.ProcessingStatus,
+		&i.ProcessingAttempts,
+		&i.ProcessingStartedAt,
+		&i.DurationMs,
+		&i.PosterKey,
+		&i.AltText,
 	)
 	return i, err
 }
 
+const insertPollDeadline = `-- name: InsertPollDeadline :exec
+INSERT INTO poll_deadline (post_id, closes_at)
+VALUES ($1, $2)
+`
+
+type InsertPollDeadlineParams struct {
+	PostID   int       `json:"postId"`
+	ClosesAt time.Time `json:"closesAt"`
+}
+
+func (q *Queries) InsertPollDeadline(ctx context.Context, arg InsertPollDeadlineParams) error {
+	_, err := q.db.Exec(ctx, insertPollDeadline, arg.PostID, arg.ClosesAt)
+	return err
+}
+
 const insertPost = `-- name: InsertPost :one
-INSERT INTO posts (user_id, text, facets, attributes, visibilityType)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING post_id, user_id, text, created_at, facets, attributes, visibilitytype
+WITH counter AS (
+    INSERT INTO user_counter (user_id, post_count)
+    VALUES ($1, 1)
+    ON CONFLICT (user_id) DO UPDATE
+    SET post_count = user_counter.post_count + 1
+)
+INSERT INTO posts (user_id, text, facets, attributes, visibilityType, audience_list_id, post_id)
+VALUES (
+    $1, $2, $3, $4, $5, $6,
+    COALESCE($7::int, nextval(pg_get_serial_sequence('posts', 'post_id')))
+)
+RETURNING post_id, user_id, text, created_at, facets, attributes, visibilitytype, audience_list_id
 `
 
 type InsertPostParams struct {
@@ -200,6 +557,8 @@ type InsertPostParams struct {
 	Facets         db.Facets      `json:"facets"`
 	Attributes     *db.Attributes `json:"attributes"`
 	Visibilitytype int            `json:"visibilitytype"`
+	AudienceListID *int           `json:"audienceListId"`
+	PostID         *int           `json:"postId"`
 }
 
 func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, error) {
@@ -209,6 +568,8 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		arg.Facets,
 		arg.Attributes,
 		arg.Visibilitytype,
+		arg.AudienceListID,
+		arg.PostID,
 	)
 	var i Post
 	err := row.Scan(
@@ -219,29 +580,37 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		&i.Facets,
 		&i.Attributes,
 		&i.Visibilitytype,
+		&i.AudienceListID,
 	)
 	return i, err
 }
 
-const insertPostImage = `-- name: InsertPostImage :exec
-INSERT INTO post_images (post_id, image_id, display_order)
+const insertPostMedia = `-- name: InsertPostMedia :exec
+INSERT INTO post_media (post_id, media_id, display_order)
 VALUES ($1, $2, $3)
 `
 
-type InsertPostImageParams struct {
+type InsertPostMediaParams struct {
 	PostID       int `json:"postId"`
-	ImageID      int `json:"imageId"`
+	MediaID      int `json:"mediaId"`
 	DisplayOrder int `json:"displayOrder"`
 }
 
-func (q *Queries) InsertPostImage(ctx context.Context, arg InsertPostImageParams) error {
-	_, err := q.db.Exec(ctx, insertPostImage, arg.PostID, arg.ImageID, arg.DisplayOrder)
+func (q *Queries) InsertPostMedia(ctx context.Context, arg InsertPostMediaParams) error {
+	_, err := q.db.Exec(ctx, insertPostMedia, arg.PostID, arg.MediaID, arg.DisplayOrder)
 	return err
 }
 
 const insertVote = `-- name: InsertVote :exec
-INSERT INTO poll_vote (post_id, user_id, option_index)
-VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+WITH inserted AS (
+    INSERT INTO poll_vote (post_id, user_id, option_index)
+    VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+    RETURNING post_id
+)
+INSERT INTO post_counter (post_id, poll_vote_count)
+SELECT post_id, 1 FROM inserted
+ON CONFLICT (post_id) DO UPDATE
+SET poll_vote_count = post_counter.poll_vote_count + 1
 `
 
 type InsertVoteParams struct {
@@ -255,6 +624,20 @@ func (q *Queries) InsertVote(ctx context.Context, arg InsertVoteParams) error {
 	return err
 }
 
+const lockPollVotesForUser = `-- name: LockPollVotesForUser :exec
+SELECT pg_advisory_xact_lock($1::int, $2::int)
+`
+
+type LockPollVotesForUserParams struct {
+	PostID int `json:"postId"`
+	UserID int `json:"userId"`
+}
+
+func (q *Queries) LockPollVotesForUser(ctx context.Context, arg LockPollVotesForUserParams) error {
+	_, err := q.db.Exec(ctx, lockPollVotesForUser, arg.PostID, arg.UserID)
+	return err
+}
+
 const pinPost = `-- name: PinPost :exec
 UPDATE users
 SET pinned_post_id = $2
@@ -271,6 +654,17 @@ func (q *Queries) PinPost(ctx context.Context, arg PinPostParams) error {
 	return err
 }
 
+const reservePostId = `-- name: ReservePostId :one
+SELECT nextval(pg_get_serial_sequence('posts', 'post_id'))::int AS post_id
+`
+
+func (q *Queries) ReservePostId(ctx context.Context) (int, error) {
+	row := q.db.QueryRow(ctx, reservePostId)
+	var post_id int
+	err := row.Scan(&post_id)
+	return post_id, err
+}
+
 const unpinPost = `-- name: UnpinPost :exec
 UPDATE users
 SET pinned_post_id = NULL
@@ -281,3 +675,26 @@ func (q *Queries) UnpinPost(ctx context.Context, userID int) error {
 	_, err := q.db.Exec(ctx, unpinPost, userID)
 	return err
 }
+
+const updatePostMediaAltText = `-- name: UpdatePostMediaAltText :execrows
+UPDATE media
+SET alt_text = $3
+FROM post_media
+WHERE media.media_id = post_media.media_id
+  AND post_media.post_id = $1
+  AND media.media_id = $2
+`
+
+type UpdatePostMediaAltTextParams struct {
+	PostID  int         `json:"postId"`
+	MediaID int         `json:"mediaId"`
+	AltText pgtype.Text `json:"altText"`
+}
+
+func (q *Queries) UpdatePostMediaAltText(ctx context.Context, arg UpdatePostMediaAltTextParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePostMediaAltText, arg.PostID, arg.MediaID, arg.AltText)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
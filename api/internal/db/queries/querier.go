@@ -6,63 +6,134 @@ package queries
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
+	AcceptAllFollowRequests(ctx context.Context, followingID int) error
+	AcceptFollowRequest(ctx context.Context, arg AcceptFollowRequestParams) (int64, error)
+	AddAudienceListMember(ctx context.Context, arg AddAudienceListMemberParams) error
 	AddCommentToPost(ctx context.Context, arg AddCommentToPostParams) (Comment, error)
 	AddLike(ctx context.Context, arg AddLikeParams) error
+	AddReaction(ctx context.Context, arg AddReactionParams) (int64, error)
 	AddUserRelationship(ctx context.Context, arg AddUserRelationshipParams) error
-	AttachImageToComment(ctx context.Context, arg AttachImageToCommentParams) error
+	AttachMediaToComment(ctx context.Context, arg AttachMediaToCommentParams) error
 	BlockUser(ctx context.Context, arg BlockUserParams) error
+	ClaimEndedPolls(ctx context.Context, limit int) ([]ClaimEndedPollsRow, error)
+	ClaimPendingDataExports(ctx context.Context, arg ClaimPendingDataExportsParams) ([]DataExport, error)
+	ClaimUnprocessedMedia(ctx context.Context, arg ClaimUnprocessedMediaParams) ([]Media, error)
+	CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error
+	CompleteMediaProcessing(ctx context.Context, arg CompleteMediaProcessingParams) error
+	CountOtherMutedWords(ctx context.Context, arg CountOtherMutedWordsParams) (int64, error)
+	CreateAudienceList(ctx context.Context, arg CreateAudienceListParams) (AudienceList, error)
+	CreateMissingCommentCounters(ctx context.Context, arg CreateMissingCommentCountersParams) error
+	CreateMissingPostCounters(ctx context.Context, arg CreateMissingPostCountersParams) error
+	CreateMissingUserCounters(ctx context.Context, arg CreateMissingUserCountersParams) error
 	CreateSession(ctx context.Context, arg CreateSessionParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVerificationCode(ctx context.Context, arg CreateVerificationCodeParams) error
+	DeactivateUser(ctx context.Context, userID int) error
+	DeleteAudienceList(ctx context.Context, listID int) error
+	DeleteAudienceListMembershipsBetweenUsers(ctx context.Context, arg DeleteAudienceListMembershipsBetweenUsersParams) error
 	DeleteComment(ctx context.Context, commentID int) error
 	DeleteDeviceToken(ctx context.Context, token string) error
+	DeleteExpiredMutedWords(ctx context.Context) error
+	DeleteExpiredMutes(ctx context.Context) error
 	DeleteFollow(ctx context.Context, arg DeleteFollowParams) error
+	DeleteFollowRequest(ctx context.Context, arg DeleteFollowRequestParams) error
+	DeleteFollowRequestNotification(ctx context.Context, arg DeleteFollowRequestNotificationParams) error
+	DeleteLikesBetweenUsers(ctx context.Context, arg DeleteLikesBetweenUsersParams) error
+	DeleteMutedWord(ctx context.Context, arg DeleteMutedWordParams) (int64, error)
 	DeleteNotificationActor(ctx context.Context, arg DeleteNotificationActorParams) error
 	DeleteNotificationById(ctx context.Context, notificationID int) error
+	DeleteNotificationsInvolvingUser(ctx context.Context, arg DeleteNotificationsInvolvingUserParams) error
+	DeletePollVotesBetweenUsers(ctx context.Context, arg DeletePollVotesBetweenUsersParams) error
 	DeletePost(ctx context.Context, postID int) error
+	DeleteReactionsBetweenUsers(ctx context.Context, arg DeleteReactionsBetweenUsersParams) error
+	DeleteRelationshipsBetweenUsers(ctx context.Context, arg DeleteRelationshipsBetweenUsersParams) error
 	DeleteSession(ctx context.Context, id string) error
+	DeleteSessionsForUser(ctx context.Context, userID int) error
 	DeleteUserById(ctx context.Context, userID int) error
+	DeleteUserVoteInPoll(ctx context.Context, arg DeleteUserVoteInPollParams) error
+	ExpireDataExports(ctx context.Context, ids []int) error
+	ExportGetBlockedUsersByUserId(ctx context.Context, userID int) ([]ExportGetBlockedUsersByUserIdRow, error)
+	ExportGetCloseFriendsByUserId(ctx context.Context, userID int) ([]ExportGetCloseFriendsByUserIdRow, error)
+	ExportGetCommentsByUserId(ctx context.Context, userID int) ([]Comment, error)
+	ExportGetFollowersByUserId(ctx context.Context, followingID int) ([]ExportGetFollowersByUserIdRow, error)
+	ExportGetFollowingByUserId(ctx context.Context, followerID int) ([]ExportGetFollowingByUserIdRow, error)
+	ExportGetLikesByUserId(ctx context.Context, userID int) ([]Like, error)
+	ExportGetMutedUsersByUserId(ctx context.Context, userID int) ([]ExportGetMutedUsersByUserIdRow, error)
+	ExportGetPollVotesByUserId(ctx context.Context, userID int) ([]PollVote, error)
+	ExportGetPostMediaIdsByUserId(ctx context.Context, userID int) ([]ExportGetPostMediaIdsByUserIdRow, error)
+	ExportGetPostsByUserId(ctx context.Context, userID int) ([]Post, error)
+	FailDataExport(ctx context.Context, id int) error
 	FindLikeNotificationForComment(ctx context.Context, arg FindLikeNotificationForCommentParams) (Notification, error)
 	FindLikeNotificationForPost(ctx context.Context, arg FindLikeNotificationForPostParams) (Notification, error)
-	GetAllImagesByUserId(ctx context.Context, userID int) ([]Image, error)
+	FindPollVoteNotification(ctx context.Context, arg FindPollVoteNotificationParams) (Notification, error)
+	FindReactionNotification(ctx context.Context, arg FindReactionNotificationParams) (Notification, error)
+	GetAllMediaByUserId(ctx context.Context, userID int) ([]Media, error)
 	GetAllPostIdsCursor(ctx context.Context, arg GetAllPostIdsCursorParams) ([]int, error)
+	GetAppleIdentityBySubject(ctx context.Context, subject string) (AppleIdentity, error)
+	GetAudienceListById(ctx context.Context, listID int) (AudienceList, error)
+	GetAudienceListMemberIds(ctx context.Context, listID int) ([]int, error)
 	GetBioByUserId(ctx context.Context, userID int) (string, error)
 	GetCommentById(ctx context.Context, commentID int) (Comment, error)
-	GetCommentCountByPostID(ctx context.Context, postID int) (int64, error)
+	GetCommentReactedBy(ctx context.Context, arg GetCommentReactedByParams) ([]GetCommentReactedByRow, error)
+	GetCommentReactionCounts(ctx context.Context, arg GetCommentReactionCountsParams) ([]GetCommentReactionCountsRow, error)
+	GetCommentsByIds(ctx context.Context, commentIds []int) ([]Comment, error)
 	GetCommentsByPostId(ctx context.Context, arg GetCommentsByPostIdParams) ([]GetCommentsByPostIdRow, error)
 	GetDeviceTokensForUser(ctx context.Context, userID int) ([]DeviceToken, error)
+	GetExpiredDataExports(ctx context.Context, before time.Time) ([]DataExport, error)
+	GetFollowRequestUserIds(ctx context.Context, arg GetFollowRequestUserIdsParams) ([]GetFollowRequestUserIdsRow, error)
+	GetFollowerUserIds(ctx context.Context, arg GetFollowerUserIdsParams) ([]GetFollowerUserIdsRow, error)
 	GetFollowersByUserId(ctx context.Context, arg GetFollowersByUserIdParams) ([]GetFollowersByUserIdRow, error)
 	GetFollowingByUserId(ctx context.Context, arg GetFollowingByUserIdParams) ([]GetFollowingByUserIdRow, error)
 	GetFollowingUserIds(ctx context.Context, arg GetFollowingUserIdsParams) ([]GetFollowingUserIdsRow, error)
-	GetImagesByCommentId(ctx context.Context, commentID int) ([]Image, error)
-	GetImagesByPostId(ctx context.Context, postID int) ([]Image, error)
+	GetHasRequestedToFollow(ctx context.Context, arg GetHasRequestedToFollowParams) (bool, error)
 	GetIsEmailInUse(ctx context.Context, email string) (bool, error)
 	GetIsLikedByUser(ctx context.Context, arg GetIsLikedByUserParams) (bool, error)
-	GetIsPostLikedByUser(ctx context.Context, arg GetIsPostLikedByUserParams) (bool, error)
 	GetIsReferralCodeInUse(ctx context.Context, referralCode string) (bool, error)
 	GetIsUserBlockingUser(ctx context.Context, arg GetIsUserBlockingUserParams) (bool, error)
 	GetIsUserFollowingUser(ctx context.Context, arg GetIsUserFollowingUserParams) (bool, error)
 	GetIsUserFriend(ctx context.Context, arg GetIsUserFriendParams) (bool, error)
 	GetIsUserMutingUser(ctx context.Context, arg GetIsUserMutingUserParams) (bool, error)
 	GetIsUsernameInUse(ctx context.Context, username string) (bool, error)
+	GetLatestDataExportForUser(ctx context.Context, userID int) (DataExport, error)
+	GetLikedPostIds(ctx context.Context, arg GetLikedPostIdsParams) ([]int, error)
+	GetLikerUserIds(ctx context.Context, arg GetLikerUserIdsParams) ([]GetLikerUserIdsRow, error)
+	GetMediaByCommentId(ctx context.Context, commentID int) ([]Media, error)
+	GetMediaByCommentIds(ctx context.Context, commentIds []int) ([]GetMediaByCommentIdsRow, error)
+	GetMediaByPostId(ctx context.Context, postID int) ([]Media, error)
+	GetMediaByPostIds(ctx context.Context, postIds []int) ([]GetMediaByPostIdsRow, error)
 	GetMutualConnectionsForUser(ctx context.Context, arg GetMutualConnectionsForUserParams) ([]string, error)
 	GetMutualsByUserId(ctx context.Context, arg GetMutualsByUserIdParams) ([]GetMutualsByUserIdRow, error)
 	GetMutualsByUserIdV2(ctx context.Context, arg GetMutualsByUserIdV2Params) ([]GetMutualsByUserIdV2Row, error)
 	GetNotificationActorUserIds(ctx context.Context, arg GetNotificationActorUserIdsParams) ([]GetNotificationActorUserIdsRow, error)
 	GetNotificationActors(ctx context.Context, notificationID int) ([]int, error)
 	GetNotificationById(ctx context.Context, notificationID int) (Notification, error)
+	GetNotificationIdsWithActors(ctx context.Context, notificationIds []int) ([]int, error)
 	GetNotificationsForUserId(ctx context.Context, arg GetNotificationsForUserIdParams) ([]Notification, error)
 	GetNotificationsForUserIdWithTimeOffset(ctx context.Context, arg GetNotificationsForUserIdWithTimeOffsetParams) ([]Notification, error)
+	GetNotificationsWithActor(ctx context.Context, arg GetNotificationsWithActorParams) ([]Notification, error)
 	GetPinnedPostId(ctx context.Context, userID int) (*int, error)
+	GetPinnedPostIds(ctx context.Context, postIds []int) ([]int, error)
+	GetPollVoterCount(ctx context.Context, postID int) (int64, error)
+	GetPollVoterCounts(ctx context.Context, postIds []int) ([]GetPollVoterCountsRow, error)
 	GetPollVotesGrouped(ctx context.Context, postID int) ([]GetPollVotesGroupedRow, error)
+	GetPollVotesGroupedByPostIds(ctx context.Context, postIds []int) ([]GetPollVotesGroupedByPostIdsRow, error)
 	GetPostById(ctx context.Context, arg GetPostByIdParams) (Post, error)
+	GetPostCounters(ctx context.Context, postIds []int) ([]PostCounter, error)
 	GetPostIdsByFollowingCursor(ctx context.Context, arg GetPostIdsByFollowingCursorParams) ([]int, error)
 	GetPostIdsByUserIdCursor(ctx context.Context, arg GetPostIdsByUserIdCursorParams) ([]int, error)
 	GetPostIdsForMutualFeedCursor(ctx context.Context, arg GetPostIdsForMutualFeedCursorParams) ([]GetPostIdsForMutualFeedCursorRow, error)
 	GetPostLikes(ctx context.Context, arg GetPostLikesParams) ([]GetPostLikesRow, error)
+	GetPostLikesByPostIds(ctx context.Context, arg GetPostLikesByPostIdsParams) ([]GetPostLikesByPostIdsRow, error)
+	GetPostReactedBy(ctx context.Context, arg GetPostReactedByParams) ([]GetPostReactedByRow, error)
+	GetPostReactionCounts(ctx context.Context, arg GetPostReactionCountsParams) ([]GetPostReactionCountsRow, error)
+	GetPostsByIds(ctx context.Context, arg GetPostsByIdsParams) ([]Post, error)
+	GetPublishedUploadNames(ctx context.Context, names []string) ([]string, error)
+	GetReactedBy(ctx context.Context, arg GetReactedByParams) ([]GetReactedByRow, error)
+	GetReactionCounts(ctx context.Context, arg GetReactionCountsParams) ([]GetReactionCountsRow, error)
 	GetSessionById(ctx context.Context, id string) (Session, error)
 	GetTotalComments(ctx context.Context) (int64, error)
 	GetTotalCommentsForUser(ctx context.Context, userID int) (int64, error)
@@ -74,37 +145,78 @@ type Querier interface {
 	GetTotalPostsForUser(ctx context.Context, userID int) (int64, error)
 	GetTotalUsers(ctx context.Context) (int64, error)
 	GetUnreadNotificationsForUserId(ctx context.Context, arg GetUnreadNotificationsForUserIdParams) ([]Notification, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserById(ctx context.Context, userID int) (User, error)
 	GetUserByIdentifier(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserCounter(ctx context.Context, userID int) (UserCounter, error)
+	GetUserIdsDeactivatedBefore(ctx context.Context, before time.Time) ([]int, error)
 	GetUserUnreadNotificationCount(ctx context.Context, userID int) (int64, error)
-	GetUserVoteInPoll(ctx context.Context, arg GetUserVoteInPollParams) (int, error)
+	GetUserVotesInPoll(ctx context.Context, arg GetUserVotesInPollParams) ([]int, error)
+	GetUserVotesInPolls(ctx context.Context, arg GetUserVotesInPollsParams) ([]GetUserVotesInPollsRow, error)
 	GetUserWithPasswordByIdentifier(ctx context.Context, email string) (User, error)
+	GetUsersByIds(ctx context.Context, userIds []int) ([]User, error)
 	GetVerificationCode(ctx context.Context, arg GetVerificationCodeParams) (VerificationCode, error)
+	HasUserReacted(ctx context.Context, arg HasUserReactedParams) (bool, error)
+	InsertAppleIdentity(ctx context.Context, arg InsertAppleIdentityParams) (int64, error)
+	InsertDataExport(ctx context.Context, arg InsertDataExportParams) (DataExport, error)
 	InsertDeviceToken(ctx context.Context, arg InsertDeviceTokenParams) error
 	InsertFollow(ctx context.Context, arg InsertFollowParams) error
-	InsertImage(ctx context.Context, arg InsertImageParams) (Image, error)
+	InsertFollowRequest(ctx context.Context, arg InsertFollowRequestParams) (int64, error)
+	InsertMedia(ctx context.Context, arg InsertMediaParams) (Media, error)
 	InsertNotification(ctx context.Context, arg InsertNotificationParams) (Notification, error)
 	InsertNotificationActor(ctx context.Context, arg InsertNotificationActorParams) error
+	InsertPollDeadline(ctx context.Context, arg InsertPollDeadlineParams) error
 	InsertPost(ctx context.Context, arg InsertPostParams) (Post, error)
-	InsertPostImage(ctx context.Context, arg InsertPostImageParams) error
+	InsertPostMedia(ctx context.Context, arg InsertPostMediaParams) error
 	InsertVote(ctx context.Context, arg InsertVoteParams) error
+	IsMutingNotificationsFrom(ctx context.Context, arg IsMutingNotificationsFromParams) (bool, error)
+	ListAudienceListsForUser(ctx context.Context, userID int) ([]ListAudienceListsForUserRow, error)
+	ListBlockedUserIds(ctx context.Context, arg ListBlockedUserIdsParams) ([]ListBlockedUserIdsRow, error)
+	ListMutedUsers(ctx context.Context, arg ListMutedUsersParams) ([]ListMutedUsersRow, error)
+	ListMutedWords(ctx context.Context, userID int) ([]MutedWord, error)
 	ListUserRelationships(ctx context.Context, arg ListUserRelationshipsParams) ([]ListUserRelationshipsRow, error)
+	LockCommentCounters(ctx context.Context, arg LockCommentCountersParams) ([]int, error)
+	LockNotification(ctx context.Context, arg LockNotificationParams) error
+	LockPollVotesForUser(ctx context.Context, arg LockPollVotesForUserParams) error
+	LockPostCounters(ctx context.Context, arg LockPostCountersParams) ([]int, error)
+	LockUserCounters(ctx context.Context, arg LockUserCountersParams) ([]int, error)
 	MarkAllNotificationsAsReadForUser(ctx context.Context, userID int) error
 	MarkNotificationAsReadById(ctx context.Context, notificationID int) error
+	MarkNotificationsAsReadByIds(ctx context.Context, notificationIds []int) error
 	MuteUser(ctx context.Context, arg MuteUserParams) error
+	NotificationContainsMutedWord(ctx context.Context, arg NotificationContainsMutedWordParams) (bool, error)
 	PinPost(ctx context.Context, arg PinPostParams) error
+	ReactivateUser(ctx context.Context, userID int) error
+	RecountCommentCounters(ctx context.Context, commentIds []int) (int64, error)
+	RecountPostCounters(ctx context.Context, postIds []int) (int64, error)
+	RecountUserCounters(ctx context.Context, userIds []int) (int64, error)
+	RehashSession(ctx context.Context, arg RehashSessionParams) (Session, error)
+	RemoveAudienceListMember(ctx context.Context, arg RemoveAudienceListMemberParams) error
 	RemoveLike(ctx context.Context, arg RemoveLikeParams) error
+	RemoveReaction(ctx context.Context, arg RemoveReactionParams) (int64, error)
 	RemoveUserRelationship(ctx context.Context, arg RemoveUserRelationshipParams) error
+	RenameAudienceList(ctx context.Context, arg RenameAudienceListParams) error
+	RequeueDataExport(ctx context.Context, id int) error
+	ReserveCommentId(ctx context.Context) (int, error)
+	ReservePostId(ctx context.Context) (int, error)
+	SetMediaProcessingStatus(ctx context.Context, arg SetMediaProcessingStatusParams) error
 	UnblockUser(ctx context.Context, arg UnblockUserParams) error
 	UnmuteUser(ctx context.Context, arg UnmuteUserParams) error
 	UnpinPost(ctx context.Context, userID int) error
+	UpdateCommentMediaAltText(ctx context.Context, arg UpdateCommentMediaAltTextParams) (int64, error)
 	UpdateNotificationMessage(ctx context.Context, arg UpdateNotificationMessageParams) error
 	UpdateNotificationMessageOnly(ctx context.Context, arg UpdateNotificationMessageOnlyParams) error
+	UpdatePostMediaAltText(ctx context.Context, arg UpdatePostMediaAltTextParams) (int64, error)
 	UpdateSessionExpiry(ctx context.Context, arg UpdateSessionExpiryParams) error
+	UpdateUserAvatarKey(ctx context.Context, arg UpdateUserAvatarKeyParams) error
+	UpdateUserBannerKey(ctx context.Context, arg UpdateUserBannerKeyParams) error
 	UpdateUserBio(ctx context.Context, arg UpdateUserBioParams) error
 	UpdateUserDisplayProperties(ctx context.Context, arg UpdateUserDisplayPropertiesParams) error
+	UpdateUserIsPrivate(ctx context.Context, arg UpdateUserIsPrivateParams) error
 	UpdateUserName(ctx context.Context, arg UpdateUserNameParams) error
+	UpdateUserRequireAltText(ctx context.Context, arg UpdateUserRequireAltTextParams) error
+	UpsertMutedWord(ctx context.Context, arg UpsertMutedWordParams) (MutedWord, error)
 	UserHasUnreadNotifications(ctx context.Context, userID int) (bool, error)
 	UserSearchWithHeuristics(ctx context.Context, arg UserSearchWithHeuristicsParams) ([]UserSearchWithHeuristicsRow, error)
 	WrappedDeleteAllStored(ctx context.Context) error
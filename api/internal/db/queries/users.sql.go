@@ -29,9 +29,31 @@ func (q *Queries) AddUserRelationship(ctx context.Context, arg AddUserRelationsh
 }
 
 const blockUser = `-- name: BlockUser :exec
-INSERT INTO block (user_id, target_user_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING
+WITH inserted AS (
+    INSERT INTO block (user_id, target_user_id)
+    VALUES ($1, $2)
+    ON CONFLICT DO NOTHING
+    RETURNING user_id, target_user_id
+), hidden AS ( -- comments either user left on the other's posts, unless a block the other way already hid them
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM inserted
+    JOIN posts ON posts.user_id IN (inserted.user_id, inserted.target_user_id)
+    JOIN comments ON comments.post_id = posts.post_id
+        AND comments.user_id IN (inserted.user_id, inserted.target_user_id)
+        AND comments.user_id <> posts.user_id
+    JOIN users ON users.user_id = comments.user_id
+    WHERE users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE block.user_id = inserted.target_user_id AND block.target_user_id = inserted.user_id
+    )
+    GROUP BY comments.post_id
+)
+UPDATE post_counter
+SET comment_count = post_counter.comment_count - hidden.count
+FROM hidden
+WHERE post_counter.post_id = hidden.post_id
 `
 
 type BlockUserParams struct {
@@ -63,7 +85,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) er
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, username, password, referral_code)
 VALUES ($1, $2, $3, $4)
-RETURNING user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code
+RETURNING user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
 `
 
 type CreateUserParams struct {
@@ -91,6 +113,11 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PinnedPostID,
 		&i.UserDisplayProperties,
 		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
 	)
 	return i, err
 }
@@ -113,6 +140,167 @@ func (q *Queries) CreateVerificationCode(ctx context.Context, arg CreateVerifica
 	return err
 }
 
+const deactivateUser = `-- name: DeactivateUser :exec
+WITH hidden AS ( -- the user's comments stop being counted while they're deactivated
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+    GROUP BY comments.post_id
+), counters AS (
+    UPDATE post_counter
+    SET comment_count = post_counter.comment_count - hidden.count
+    FROM hidden
+    WHERE post_counter.post_id = hidden.post_id
+)
+UPDATE users
+SET deactivated_at = NOW()
+WHERE user_id = $1
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, deactivateUser, userID)
+	return err
+}
+
+const deleteAudienceListMembershipsBetweenUsers = `-- name: DeleteAudienceListMembershipsBetweenUsers :exec
+DELETE FROM audience_list_member
+USING audience_list
+WHERE audience_list.list_id = audience_list_member.list_id
+    AND (
+        (audience_list.user_id = $1 AND audience_list_member.user_id = $2)
+        OR (audience_list.user_id = $2 AND audience_list_member.user_id = $1)
+    )
+`
+
+type DeleteAudienceListMembershipsBetweenUsersParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteAudienceListMembershipsBetweenUsers(ctx context.Context, arg DeleteAudienceListMembershipsBetweenUsersParams) error {
+	_, err := q.db.Exec(ctx, deleteAudienceListMembershipsBetweenUsers, arg.UserID, arg.TargetUserID)
+	return err
+}
+
+const deleteExpiredMutes = `-- name: DeleteExpiredMutes :exec
+DELETE FROM mute
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredMutes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredMutes)
+	return err
+}
+
+const deleteLikesBetweenUsers = `-- name: DeleteLikesBetweenUsers :exec
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE EXISTS (
+        SELECT 1
+        FROM posts
+        LEFT JOIN comments ON comments.comment_id = likes.comment_id
+        WHERE posts.post_id = likes.post_id
+            AND (
+                (likes.user_id = $1 AND COALESCE(comments.user_id, posts.user_id) = $2)
+                OR (likes.user_id = $2 AND COALESCE(comments.user_id, posts.user_id) = $1)
+            )
+    )
+    RETURNING post_id, comment_id
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id AND deleted.comment_id IS NULL)
+    WHERE post_id IN (SELECT post_id FROM deleted WHERE comment_id IS NULL)
+)
+UPDATE comment_counter
+SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.comment_id = comment_counter.comment_id)
+WHERE comment_id IN (SELECT comment_id FROM deleted)
+`
+
+type DeleteLikesBetweenUsersParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteLikesBetweenUsers(ctx context.Context, arg DeleteLikesBetweenUsersParams) error {
+	_, err := q.db.Exec(ctx, deleteLikesBetweenUsers, arg.UserID, arg.TargetUserID)
+	return err
+}
+
+const deletePollVotesBetweenUsers = `-- name: DeletePollVotesBetweenUsers :exec
+WITH deleted AS (
+    DELETE FROM poll_vote
+    USING posts
+    WHERE posts.post_id = poll_vote.post_id
+        AND (
+            (poll_vote.user_id = $1 AND posts.user_id = $2)
+            OR (poll_vote.user_id = $2 AND posts.user_id = $1)
+        )
+    RETURNING poll_vote.post_id
+)
+UPDATE post_counter
+SET poll_vote_count = poll_vote_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id)
+WHERE post_id IN (SELECT post_id FROM deleted)
+`
+
+type DeletePollVotesBetweenUsersParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeletePollVotesBetweenUsers(ctx context.Context, arg DeletePollVotesBetweenUsersParams) error {
+	_, err := q.db.Exec(ctx, deletePollVotesBetweenUsers, arg.UserID, arg.TargetUserID)
+	return err
+}
+
+const deleteReactionsBetweenUsers = `-- name: DeleteReactionsBetweenUsers :exec
+DELETE FROM reaction
+WHERE EXISTS (
+    SELECT 1
+    FROM posts
+    LEFT JOIN comments ON comments.comment_id = reaction.comment_id
+    WHERE posts.post_id = reaction.post_id
+        AND (
+            (reaction.user_id = $1 AND COALESCE(comments.user_id, posts.user_id) = $2)
+            OR (reaction.user_id = $2 AND COALESCE(comments.user_id, posts.user_id) = $1)
+        )
+)
+`
+
+type DeleteReactionsBetweenUsersParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteReactionsBetweenUsers(ctx context.Context, arg DeleteReactionsBetweenUsersParams) error {
+	_, err := q.db.Exec(ctx, deleteReactionsBetweenUsers, arg.UserID, arg.TargetUserID)
+	return err
+}
+
+const deleteRelationshipsBetweenUsers = `-- name: DeleteRelationshipsBetweenUsers :exec
+DELETE FROM user_relationship
+WHERE (user_id = $1 AND target_user_id = $2)
+    OR (user_id = $2 AND target_user_id = $1)
+`
+
+type DeleteRelationshipsBetweenUsersParams struct {
+	UserID       int `json:"userId"`
+	TargetUserID int `json:"targetUserId"`
+}
+
+func (q *Queries) DeleteRelationshipsBetweenUsers(ctx context.Context, arg DeleteRelationshipsBetweenUsersParams) error {
+	_, err := q.db.Exec(ctx, deleteRelationshipsBetweenUsers, arg.UserID, arg.TargetUserID)
+	return err
+}
+
 const deleteSession = `-- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = $1
@@ -123,7 +311,71 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 	return err
 }
 
+const deleteSessionsForUser = `-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteSessionsForUser(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, deleteSessionsForUser, userID)
+	return err
+}
+
 const deleteUserById = `-- name: DeleteUserById :exec
+WITH removed_follows AS (
+    -- the deleted user's follows, likes, comments and votes cascade away, so take them off everyone
+    -- else's counts. Counters of the user's own posts and comments cascade away with them.
+    SELECT follower_id, following_id
+    FROM follows
+    WHERE follower_id = $1 OR following_id = $1
+), user_counts AS (
+    UPDATE user_counter
+    SET follower_count = follower_count - (SELECT COUNT(*) FROM removed_follows WHERE removed_follows.following_id = user_counter.user_id),
+        following_count = following_count - (SELECT COUNT(*) FROM removed_follows WHERE removed_follows.follower_id = user_counter.user_id)
+    WHERE user_counter.user_id <> $1
+        AND user_counter.user_id IN (SELECT follower_id FROM removed_follows UNION SELECT following_id FROM removed_follows)
+), removed_likes AS (
+    SELECT likes.post_id, likes.comment_id
+    FROM likes
+    JOIN posts ON posts.post_id = likes.post_id
+    LEFT JOIN comments ON comments.comment_id = likes.comment_id
+    WHERE likes.user_id = $1
+        AND posts.user_id <> $1
+        AND (comments.user_id IS NULL OR comments.user_id <> $1)
+), removed_votes AS (
+    SELECT poll_vote.post_id
+    FROM poll_vote
+    JOIN posts ON posts.post_id = poll_vote.post_id
+    WHERE poll_vote.user_id = $1 AND posts.user_id <> $1
+), removed_comments AS ( -- only comments that are counted, the same ones DeactivateUser takes off
+    SELECT comments.post_id
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND posts.user_id <> $1
+    AND users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM removed_likes WHERE removed_likes.post_id = post_counter.post_id AND removed_likes.comment_id IS NULL),
+        comment_count = comment_count - (SELECT COUNT(*) FROM removed_comments WHERE removed_comments.post_id = post_counter.post_id),
+        poll_vote_count = poll_vote_count - (SELECT COUNT(*) FROM removed_votes WHERE removed_votes.post_id = post_counter.post_id)
+    WHERE post_id IN (
+        SELECT post_id FROM removed_likes WHERE comment_id IS NULL
+        UNION SELECT post_id FROM removed_comments
+        UNION SELECT post_id FROM removed_votes
+    )
+), comment_counts AS (
+    UPDATE comment_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM removed_likes WHERE removed_likes.comment_id = comment_counter.comment_id)
+    WHERE comment_id IN (SELECT comment_id FROM removed_likes)
+)
 DELETE FROM users
 WHERE user_id = $1
 `
@@ -133,6 +385,26 @@ func (q *Queries) DeleteUserById(ctx context.Context, userID int) error {
 	return err
 }
 
+const getAppleIdentityBySubject = `-- name: GetAppleIdentityBySubject :one
+SELECT id, user_id, subject, email, is_private_email, created_at
+FROM apple_identity
+WHERE subject = $1
+`
+
+func (q *Queries) GetAppleIdentityBySubject(ctx context.Context, subject string) (AppleIdentity, error) {
+	row := q.db.QueryRow(ctx, getAppleIdentityBySubject, subject)
+	var i AppleIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Subject,
+		&i.Email,
+		&i.IsPrivateEmail,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getBioByUserId = `-- name: GetBioByUserId :one
 SELECT text
 FROM bios
@@ -222,6 +494,7 @@ SELECT EXISTS (
   SELECT 1
   FROM mute
   WHERE user_id = $1 AND target_user_id = $2
+    AND (expires_at IS NULL OR expires_at > NOW())
 )
 `
 
@@ -304,8 +577,37 @@ func (q *Queries) GetSessionById(ctx context.Context, id string) (Session, error
 	return i, err
 }
 
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
+FROM users
+WHERE lower(email) = lower($1)
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Password,
+		&i.Username,
+		&i.CreatedAt,
+		&i.Name,
+		&i.PinnedPostID,
+		&i.UserDisplayProperties,
+		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
+	)
+	return i, err
+}
+
 const getUserById = `-- name: GetUserById :one
-SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
 FROM users
 WHERE user_id = $1
 LIMIT 1
@@ -324,12 +626,17 @@ func (q *Queries) GetUserById(ctx context.Context, userID int) (User, error) {
 		&i.PinnedPostID,
 		&i.UserDisplayProperties,
 		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
 	)
 	return i, err
 }
 
 const getUserByIdentifier = `-- name: GetUserByIdentifier :one
-SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
 FROM users
 WHERE email = $1 OR username = $1
 LIMIT 1
@@ -348,12 +655,17 @@ func (q *Queries) GetUserByIdentifier(ctx context.Context, email string) (User,
 		&i.PinnedPostID,
 		&i.UserDisplayProperties,
 		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
 FROM users
 WHERE username = $1
 LIMIT 1
@@ -372,12 +684,102 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.PinnedPostID,
 		&i.UserDisplayProperties,
 		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
 	)
 	return i, err
 }
 
+const getUserCounter = `-- name: GetUserCounter :one
+SELECT user_id, follower_count, following_count, post_count
+FROM user_counter
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserCounter(ctx context.Context, userID int) (UserCounter, error) {
+	row := q.db.QueryRow(ctx, getUserCounter, userID)
+	var i UserCounter
+	err := row.Scan(
+		&i.UserID,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+	)
+	return i, err
+}
+
+const getUserIdsDeactivatedBefore = `-- name: GetUserIdsDeactivatedBefore :many
+SELECT user_id
+FROM users
+WHERE deactivated_at < $1::timestamptz
+`
+
+func (q *Queries) GetUserIdsDeactivatedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	rows, err := q.db.Query(ctx, getUserIdsDeactivatedBefore, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int
+	for rows.Next() {
+		var user_id int
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersByIds = `-- name: GetUsersByIds :many
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
+FROM users
+WHERE user_id = ANY($1::int[])
+`
+
+func (q *Queries) GetUsersByIds(ctx context.Context, userIds []int) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersByIds, userIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.Password,
+			&i.Username,
+			&i.CreatedAt,
+			&i.Name,
+			&i.PinnedPostID,
+			&i.UserDisplayProperties,
+			&i.ReferralCode,
+			&i.DeactivatedAt,
+			&i.IsPrivate,
+			&i.AvatarKey,
+			&i.BannerKey,
+			&i.RequireAltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserWithPasswordByIdentifier = `-- name: GetUserWithPasswordByIdentifier :one
-SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code
+SELECT user_id, email, password, username, created_at, name, pinned_post_id, user_display_properties, referral_code, deactivated_at, is_private, avatar_key, banner_key, require_alt_text
 FROM users
 WHERE email = $1 OR username = $1
 LIMIT 1
@@ -396,6 +798,11 @@ func (q *Queries) GetUserWithPasswordByIdentifier(ctx context.Context, email str
 		&i.PinnedPostID,
 		&i.UserDisplayProperties,
 		&i.ReferralCode,
+		&i.DeactivatedAt,
+		&i.IsPrivate,
+		&i.AvatarKey,
+		&i.BannerKey,
+		&i.RequireAltText,
 	)
 	return i, err
 }
@@ -424,8 +831,124 @@ func (q *Queries) GetVerificationCode(ctx context.Context, arg GetVerificationCo
 	return i, err
 }
 
+const insertAppleIdentity = `-- name: InsertAppleIdentity :execrows
+INSERT INTO apple_identity (user_id, subject, email, is_private_email)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`
+
+type InsertAppleIdentityParams struct {
+	UserID         int         `json:"userId"`
+	Subject        string      `json:"subject"`
+	Email          pgtype.Text `json:"email"`
+	IsPrivateEmail bool        `json:"isPrivateEmail"`
+}
+
+func (q *Queries) InsertAppleIdentity(ctx context.Context, arg InsertAppleIdentityParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertAppleIdentity,
+		arg.UserID,
+		arg.Subject,
+		arg.Email,
+		arg.IsPrivateEmail,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listBlockedUserIds = `-- name: ListBlockedUserIds :many
+SELECT target_user_id AS user_id, created_at
+FROM block
+WHERE user_id = $1::int
+    AND ($2::timestamptz IS NULL OR created_at < $2)
+ORDER BY created_at DESC
+LIMIT $3::int
+`
+
+type ListBlockedUserIdsParams struct {
+	UserID int        `json:"userId"`
+	Before *time.Time `json:"before"`
+	Limit  int        `json:"limit"`
+}
+
+type ListBlockedUserIdsRow struct {
+	UserID    int              `json:"userId"`
+	CreatedAt pgtype.Timestamp `json:"createdAt"`
+}
+
+func (q *Queries) ListBlockedUserIds(ctx context.Context, arg ListBlockedUserIdsParams) ([]ListBlockedUserIdsRow, error) {
+	rows, err := q.db.Query(ctx, listBlockedUserIds, arg.UserID, arg.Before, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBlockedUserIdsRow
+	for rows.Next() {
+		var i ListBlockedUserIdsRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMutedUsers = `-- name: ListMutedUsers :many
+SELECT target_user_id AS user_id, hide_posts, mute_notifications, expires_at, created_at
+FROM mute
+WHERE user_id = $1::int
+    AND (expires_at IS NULL OR expires_at > NOW())
+    AND ($2::timestamptz IS NULL OR created_at < $2)
+ORDER BY created_at DESC
+LIMIT $3::int
+`
+
+type ListMutedUsersParams struct {
+	UserID int        `json:"userId"`
+	Before *time.Time `json:"before"`
+	Limit  int        `json:"limit"`
+}
+
+type ListMutedUsersRow struct {
+	UserID            int              `json:"userId"`
+	HidePosts         bool             `json:"hidePosts"`
+	MuteNotifications bool             `json:"muteNotifications"`
+	ExpiresAt         *time.Time       `json:"expiresAt"`
+	CreatedAt         pgtype.Timestamp `json:"createdAt"`
+}
+
+func (q *Queries) ListMutedUsers(ctx context.Context, arg ListMutedUsersParams) ([]ListMutedUsersRow, error) {
+	rows, err := q.db.Query(ctx, listMutedUsers, arg.UserID, arg.Before, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMutedUsersRow
+	for rows.Next() {
+		var i ListMutedUsersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.HidePosts,
+			&i.MuteNotifications,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserRelationships = `-- name: ListUserRelationships :many
-SELECT users.user_id, users.email, users.password, users.username, users.created_at, users.name, users.pinned_post_id, users.user_display_properties, users.referral_code, user_relationship.created_at AS relationship_created_at
+SELECT users.user_id, users.email, users.password, users.username, users.created_at, users.name, users.pinned_post_id, users.user_display_properties, users.referral_code, users.deactivated_at, users.is_private, users.avatar_key, users.banner_key, users.require_alt_text, user_relationship.created_at AS relationship_created_at
 FROM users
 JOIN user_relationship ON user_relationship.user_id = $1::int
 WHERE users.user_id = user_relationship.target_user_id
@@ -450,6 +973,11 @@ type ListUserRelationshipsRow struct {
 	PinnedPostID          *int                      `json:"pinnedPostId"`
 	UserDisplayProperties *db.UserDisplayProperties `json:"userDisplayProperties"`
 	ReferralCode          string                    `json:"referralCode"`
+	DeactivatedAt         *time.Time                `json:"deactivatedAt"`
+	IsPrivate             bool                      `json:"isPrivate"`
+	AvatarKey             pgtype.Text               `json:"avatarKey"`
+	BannerKey             pgtype.Text               `json:"bannerKey"`
+	RequireAltText        bool                      `json:"requireAltText"`
 	RelationshipCreatedAt pgtype.Timestamp          `json:"relationshipCreatedAt"`
 }
 
@@ -472,6 +1000,11 @@ func (q *Queries) ListUserRelationships(ctx context.Context, arg ListUserRelatio
 			&i.PinnedPostID,
 			&i.UserDisplayProperties,
 			&i.ReferralCode,
+			&i.DeactivatedAt,
+			&i.IsPrivate,
+			&i.AvatarKey,
+			&i.BannerKey,
+			&i.RequireAltText,
 			&i.RelationshipCreatedAt,
 		); err != nil {
 			return nil, err
@@ -485,21 +1018,83 @@ func (q *Queries) ListUserRelationships(ctx context.Context, arg ListUserRelatio
 }
 
 const muteUser = `-- name: MuteUser :exec
-INSERT INTO mute (user_id, target_user_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING
+INSERT INTO mute (user_id, target_user_id, hide_posts, mute_notifications, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, target_user_id) DO UPDATE
+SET hide_posts = EXCLUDED.hide_posts,
+    mute_notifications = EXCLUDED.mute_notifications,
+    expires_at = EXCLUDED.expires_at
 `
 
 type MuteUserParams struct {
-	UserID       int `json:"userId"`
-	TargetUserID int `json:"targetUserId"`
+	UserID            int        `json:"userId"`
+	TargetUserID      int        `json:"targetUserId"`
+	HidePosts         bool       `json:"hidePosts"`
+	MuteNotifications bool       `json:"muteNotifications"`
+	ExpiresAt         *time.Time `json:"expiresAt"`
 }
 
 func (q *Queries) MuteUser(ctx context.Context, arg MuteUserParams) error {
-	_, err := q.db.Exec(ctx, muteUser, arg.UserID, arg.TargetUserID)
+	_, err := q.db.Exec(ctx, muteUser,
+		arg.UserID,
+		arg.TargetUserID,
+		arg.HidePosts,
+		arg.MuteNotifications,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const reactivateUser = `-- name: ReactivateUser :exec
+WITH shown AS (
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND users.deactivated_at IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+    GROUP BY comments.post_id
+), counters AS (
+    INSERT INTO post_counter (post_id, comment_count)
+    SELECT post_id, count FROM shown
+    ON CONFLICT (post_id) DO UPDATE
+    SET comment_count = post_counter.comment_count + EXCLUDED.comment_count
+)
+UPDATE users
+SET deactivated_at = NULL
+WHERE user_id = $1
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, reactivateUser, userID)
 	return err
 }
 
+const rehashSession = `-- name: RehashSession :one
+UPDATE sessions
+SET id = $1
+WHERE id = $2
+RETURNING id, user_id, expires_at
+`
+
+type RehashSessionParams struct {
+	HashedID string `json:"hashedId"`
+	ID       string `json:"id"`
+}
+
+func (q *Queries) RehashSession(ctx context.Context, arg RehashSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, rehashSession, arg.HashedID, arg.ID)
+	var i Session
+	err := row.Scan(&i.ID, &i.UserID, &i.ExpiresAt)
+	return i, err
+}
+
 const removeUserRelationship = `-- name: RemoveUserRelationship :exec
 DELETE FROM user_relationship
 WHERE user_id = $1 AND target_user_id = $2
@@ -516,8 +1111,30 @@ func (q *Queries) RemoveUserRelationship(ctx context.Context, arg RemoveUserRela
 }
 
 const unblockUser = `-- name: UnblockUser :exec
-DELETE FROM block
-WHERE user_id = $1 AND target_user_id = $2
+WITH deleted AS (
+    DELETE FROM block
+    WHERE user_id = $1 AND target_user_id = $2
+    RETURNING user_id, target_user_id
+), shown AS ( -- comments either user left on the other's posts, unless a block the other way still hides them
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM deleted
+    JOIN posts ON posts.user_id IN (deleted.user_id, deleted.target_user_id)
+    JOIN comments ON comments.post_id = posts.post_id
+        AND comments.user_id IN (deleted.user_id, deleted.target_user_id)
+        AND comments.user_id <> posts.user_id
+    JOIN users ON users.user_id = comments.user_id
+    WHERE users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE block.user_id = deleted.target_user_id AND block.target_user_id = deleted.user_id
+    )
+    GROUP BY comments.post_id
+)
+INSERT INTO post_counter (post_id, comment_count)
+SELECT post_id, count FROM shown
+ON CONFLICT (post_id) DO UPDATE
+SET comment_count = post_counter.comment_count + EXCLUDED.comment_count
 `
 
 type UnblockUserParams struct {
@@ -561,6 +1178,38 @@ func (q *Queries) UpdateSessionExpiry(ctx context.Context, arg UpdateSessionExpi
 	return err
 }
 
+const updateUserAvatarKey = `-- name: UpdateUserAvatarKey :exec
+UPDATE users
+SET avatar_key = $2
+WHERE user_id = $1
+`
+
+type UpdateUserAvatarKeyParams struct {
+	UserID    int         `json:"userId"`
+	AvatarKey pgtype.Text `json:"avatarKey"`
+}
+
+func (q *Queries) UpdateUserAvatarKey(ctx context.Context, arg UpdateUserAvatarKeyParams) error {
+	_, err := q.db.Exec(ctx, updateUserAvatarKey, arg.UserID, arg.AvatarKey)
+	return err
+}
+
+const updateUserBannerKey = `-- name: UpdateUserBannerKey :exec
+UPDATE users
+SET banner_key = $2
+WHERE user_id = $1
+`
+
+type UpdateUserBannerKeyParams struct {
+	UserID    int         `json:"userId"`
+	BannerKey pgtype.Text `json:"bannerKey"`
+}
+
+func (q *Queries) UpdateUserBannerKey(ctx context.Context, arg UpdateUserBannerKeyParams) error {
+	_, err := q.db.Exec(ctx, updateUserBannerKey, arg.UserID, arg.BannerKey)
+	return err
+}
+
 const updateUserBio = `-- name: UpdateUserBio :exec
 INSERT INTO bios (user_id, text)
 VALUES ($1, $2)
@@ -594,6 +1243,22 @@ func (q *Queries) UpdateUserDisplayProperties(ctx context.Context, arg UpdateUse
 	return err
 }
 
+const updateUserIsPrivate = `-- name: UpdateUserIsPrivate :exec
+UPDATE users
+SET is_private = $2
+WHERE user_id = $1
+`
+
+type UpdateUserIsPrivateParams struct {
+	UserID    int  `json:"userId"`
+	IsPrivate bool `json:"isPrivate"`
+}
+
+func (q *Queries) UpdateUserIsPrivate(ctx context.Context, arg UpdateUserIsPrivateParams) error {
+	_, err := q.db.Exec(ctx, updateUserIsPrivate, arg.UserID, arg.IsPrivate)
+	return err
+}
+
 const updateUserName = `-- name: UpdateUserName :exec
 UPDATE users
 SET name = $2
@@ -610,6 +1275,22 @@ func (q *Queries) UpdateUserName(ctx context.Context, arg UpdateUserNameParams)
 	return err
 }
 
+const updateUserRequireAltText = `-- name: UpdateUserRequireAltText :exec
+UPDATE users
+SET require_alt_text = $2
+WHERE user_id = $1
+`
+
+type UpdateUserRequireAltTextParams struct {
+	UserID         int  `json:"userId"`
+	RequireAltText bool `json:"requireAltText"`
+}
+
+func (q *Queries) UpdateUserRequireAltText(ctx context.Context, arg UpdateUserRequireAltTextParams) error {
+	_, err := q.db.Exec(ctx, updateUserRequireAltText, arg.UserID, arg.RequireAltText)
+	return err
+}
+
 const userSearchWithHeuristics = `-- name: UserSearchWithHeuristics :many
 WITH results AS (
     SELECT DISTINCT ON (user_id)
@@ -653,6 +1334,10 @@ WHERE NOT EXISTS (
     SELECT 1
     FROM block
     WHERE block.user_id = r.user_id AND target_user_id = $3
+) AND NOT EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = r.user_id AND users.deactivated_at IS NOT NULL
 )
 ORDER BY r.tier, r.score DESC, r.username
     LIMIT $2
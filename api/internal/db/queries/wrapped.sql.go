@@ -170,7 +170,7 @@ func (q *Queries) WrappedGetAllUserLikesWithCursor(ctx context.Context, arg Wrap
 }
 
 const wrappedGetAllUserPostsWithCursor = `-- name: WrappedGetAllUserPostsWithCursor :many
-SELECT post_id, user_id, text, created_at, facets, attributes, visibilitytype
+SELECT post_id, user_id, text, created_at, facets, attributes, visibilitytype, audience_list_id
 FROM posts
 WHERE user_id = $1
   AND EXTRACT(YEAR FROM created_at) = 2025
@@ -202,6 +202,7 @@ func (q *Queries) WrappedGetAllUserPostsWithCursor(ctx context.Context, arg Wrap
 			&i.Facets,
 			&i.Attributes,
 			&i.Visibilitytype,
+			&i.AudienceListID,
 		); err != nil {
 			return nil, err
 		}
@@ -217,11 +218,11 @@ const wrappedGetAverageImageCountPerPost = `-- name: WrappedGetAverageImageCount
 SELECT AVG(image_count)
 FROM (
     SELECT COUNT(*) as image_count
-    FROM images
-    JOIN post_images ON images.image_id = post_images.post_id
-    JOIN posts ON images.post_id = posts.post_id
+    FROM media
+    JOIN post_media ON media.media_id = post_media.post_id
+    JOIN posts ON media.post_id = posts.post_id
     WHERE EXTRACT(YEAR FROM created_at) = 2025
-    GROUP BY post_images.post_id
+    GROUP BY post_media.post_id
 ) subquery
 `
 
@@ -236,11 +237,11 @@ const wrappedGetAverageImageCountPerPostForUser = `-- name: WrappedGetAverageIma
 SELECT COALESCE(AVG(image_count), 0)::int
 FROM (
     SELECT COUNT(*) as image_count
-    FROM images
-    JOIN post_images ON images.image_id = post_images.post_id
-    JOIN posts ON post_images.post_id = posts.post_id
+    FROM media
+    JOIN post_media ON media.media_id = post_media.post_id
+    JOIN posts ON post_media.post_id = posts.post_id
     WHERE posts.user_id = $1 AND EXTRACT(YEAR FROM posts.created_at) = 2025
-    GROUP BY post_images.post_id
+    GROUP BY post_media.post_id
 ) subquery
 `
 
@@ -331,7 +332,7 @@ func (q *Queries) WrappedGetMostLikedPostId(ctx context.Context, userID int) (Wr
 }
 
 const wrappedGetPollsThatUserVotedIn = `-- name: WrappedGetPollsThatUserVotedIn :many
-SELECT posts.post_id, posts.user_id, text, posts.created_at, facets, attributes, visibilitytype, id, poll_vote.post_id, poll_vote.user_id, option_index, poll_vote.created_at
+SELECT posts.post_id, posts.user_id, text, posts.created_at, facets, attributes, visibilitytype, audience_list_id, id, poll_vote.post_id, poll_vote.user_id, option_index, poll_vote.created_at
 FROM posts
 JOIN poll_vote ON posts.post_id = poll_vote.post_id
 WHERE attributes->'poll' IS NOT NULL AND poll_vote.user_id = $1
@@ -346,6 +347,7 @@ type WrappedGetPollsThatUserVotedInRow struct {
 	Facets         db.Facets        `json:"facets"`
 	Attributes     *db.Attributes   `json:"attributes"`
 	Visibilitytype int              `json:"visibilitytype"`
+	AudienceListID *int             `json:"audienceListId"`
 	ID             int              `json:"id"`
 	PostID_2       int              `json:"postId2"`
 	UserID_2       int              `json:"userId2"`
@@ -370,6 +372,7 @@ func (q *Queries) WrappedGetPollsThatUserVotedIn(ctx context.Context, userID int
 			&i.Facets,
 			&i.Attributes,
 			&i.Visibilitytype,
+			&i.AudienceListID,
 			&i.ID,
 			&i.PostID_2,
 			&i.UserID_2,
@@ -9,7 +9,12 @@ CREATE TABLE users (
     name text,
     pinned_post_id integer,
     user_display_properties jsonb NULL,
-    referral_code TEXT NOT NULL
+    referral_code TEXT NOT NULL,
+    deactivated_at TIMESTAMPTZ,
+    is_private BOOLEAN NOT NULL DEFAULT FALSE,
+    avatar_key TEXT,
+    banner_key TEXT,
+    require_alt_text BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 CREATE TABLE user_relationship (
@@ -34,6 +39,7 @@ CREATE TABLE posts (
     facets JSON,
     attributes JSON,
     visibilityType INT NOT NULL DEFAULT 0,
+    audience_list_id INT,
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
@@ -74,24 +80,33 @@ CREATE TABLE likes (
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
-CREATE TABLE images (
-    image_id SERIAL PRIMARY KEY NOT NULL,
+CREATE TABLE media (
+    media_id SERIAL PRIMARY KEY NOT NULL,
     height INT NOT NULL,
     width INT NOT NULL,
-    image_blob_url TEXT NOT NULL
+    blob_key TEXT NOT NULL,
+    blurhash TEXT,
+    variants JSONB NOT NULL DEFAULT '[]',
+    media_type TEXT NOT NULL DEFAULT 'image',
+    processing_status TEXT NOT NULL DEFAULT 'ready',
+    processing_attempts INT NOT NULL DEFAULT 0,
+    processing_started_at TIMESTAMPTZ,
+    duration_ms INT,
+    poster_key TEXT,
+    alt_text TEXT
 );
 
-CREATE TABLE post_images (
+CREATE TABLE post_media (
     post_id       INTEGER NOT NULL REFERENCES posts(post_id) ON DELETE CASCADE,
-    image_id      INTEGER NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
+    media_id      INTEGER NOT NULL REFERENCES media(media_id) ON DELETE CASCADE,
     display_order INTEGER NOT NULL DEFAULT 0,
-    PRIMARY KEY (post_id, image_id)
+    PRIMARY KEY (post_id, media_id)
 );
 
-CREATE TABLE comment_images (
+CREATE TABLE comment_media (
     comment_id INTEGER NOT NULL REFERENCES comments(comment_id) ON DELETE CASCADE,
-    image_id   INTEGER NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
-    PRIMARY KEY (comment_id, image_id)
+    media_id   INTEGER NOT NULL REFERENCES media(media_id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, media_id)
 );
 
 CREATE TABLE notifications (
@@ -111,6 +126,9 @@ CREATE TABLE notifications (
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
+CREATE INDEX idx_notifications_post_id ON notifications (post_id);
+CREATE INDEX idx_notifications_comment_id ON notifications (comment_id);
+
 CREATE TABLE notification_actor (
     id SERIAL PRIMARY KEY,
     notification_id INT NOT NULL REFERENCES notifications(notification_id) ON DELETE CASCADE ,
@@ -141,6 +159,9 @@ CREATE TABLE IF NOT EXISTS mute (
     user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     target_user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    hide_posts BOOLEAN NOT NULL DEFAULT TRUE,
+    mute_notifications BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMPTZ,
 
     CONSTRAINT unique_user_target_user_id_mute UNIQUE(user_id, target_user_id),
     CONSTRAINT check_no_self_mute CHECK (user_id != target_user_id)
@@ -152,7 +173,7 @@ CREATE TABLE IF NOT EXISTS poll_vote (
     user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     option_index INT NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
-    UNIQUE(post_id, user_id)
+    UNIQUE(post_id, user_id, option_index)
 );
 
 ALTER TABLE users ADD CONSTRAINT fk_users_pinned_post FOREIGN KEY (pinned_post_id) REFERENCES posts(post_id) ON DELETE SET NULL;
@@ -174,3 +195,94 @@ CREATE TABLE device_token (
     is_enabled_follows BOOLEAN NOT NULL DEFAULT TRUE,
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE TABLE apple_identity (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    subject TEXT NOT NULL UNIQUE,
+    email TEXT,
+    is_private_email BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE data_export (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    object_key TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ
+);
+
+CREATE TABLE follow_request (
+    follower_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    following_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, following_id)
+);
+
+CREATE TABLE audience_list (
+    list_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE audience_list_member (
+    list_id INT NOT NULL REFERENCES audience_list(list_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, user_id)
+);
+
+ALTER TABLE posts ADD FOREIGN KEY (audience_list_id) REFERENCES audience_list(list_id) ON DELETE SET NULL;
+
+CREATE TABLE user_counter (
+    user_id INT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    follower_count INT NOT NULL DEFAULT 0,
+    following_count INT NOT NULL DEFAULT 0,
+    post_count INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE poll_deadline (
+    post_id INT PRIMARY KEY REFERENCES posts(post_id) ON DELETE CASCADE,
+    closes_at TIMESTAMPTZ NOT NULL,
+    ended_notified_at TIMESTAMPTZ
+);
+
+CREATE TABLE reaction (
+    post_id INT NOT NULL REFERENCES posts(post_id) ON DELETE CASCADE,
+    comment_id INT REFERENCES comments(comment_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_reaction_target_user_emoji ON reaction (post_id, COALESCE(comment_id, 0), user_id, emoji);
+
+CREATE TABLE muted_word (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    phrase TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    mute_feeds BOOLEAN NOT NULL DEFAULT TRUE,
+    mute_notifications BOOLEAN NOT NULL DEFAULT TRUE,
+    mute_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, phrase)
+);
+
+CREATE TABLE post_counter (
+    post_id INT PRIMARY KEY REFERENCES posts(post_id) ON DELETE CASCADE,
+    like_count INT NOT NULL DEFAULT 0,
+    comment_count INT NOT NULL DEFAULT 0,
+    poll_vote_count INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE comment_counter (
+    comment_id INT PRIMARY KEY REFERENCES comments(comment_id) ON DELETE CASCADE,
+    like_count INT NOT NULL DEFAULT 0
+);
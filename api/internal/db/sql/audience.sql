@@ -0,0 +1,43 @@
+-- name: CreateAudienceList :one
+INSERT INTO audience_list (user_id, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetAudienceListById :one
+SELECT *
+FROM audience_list
+WHERE list_id = $1;
+
+-- name: ListAudienceListsForUser :many
+SELECT audience_list.*, (
+    SELECT COUNT(*)
+    FROM audience_list_member
+    WHERE audience_list_member.list_id = audience_list.list_id
+) AS member_count
+FROM audience_list
+WHERE audience_list.user_id = $1
+ORDER BY audience_list.created_at;
+
+-- name: RenameAudienceList :exec
+UPDATE audience_list
+SET name = $2
+WHERE list_id = $1;
+
+-- name: DeleteAudienceList :exec
+DELETE FROM audience_list
+WHERE list_id = $1;
+
+-- name: AddAudienceListMember :exec
+INSERT INTO audience_list_member (list_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveAudienceListMember :exec
+DELETE FROM audience_list_member
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetAudienceListMemberIds :many
+SELECT user_id
+FROM audience_list_member
+WHERE list_id = $1
+ORDER BY created_at DESC;
@@ -2,9 +2,27 @@
 SELECT *
 FROM comments
 WHERE comment_id = $1
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = comments.user_id AND users.deactivated_at IS NULL
+)
 LIMIT 1;
 
+-- name: GetCommentsByIds :many
+SELECT *
+FROM comments
+WHERE comment_id = ANY(@comment_ids::int[])
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = comments.user_id AND users.deactivated_at IS NULL
+);
+
 -- name: GetCommentsByPostId :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $2
+        AND mute_comments
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT
   comments.comment_id,
   comments.post_id,
@@ -13,11 +31,14 @@ SELECT
   comments.facets,
   comments.created_at,
   users.username,
-  users.name
+  users.name,
+  COALESCE(comment_counter.like_count, 0)::int AS like_count
 FROM comments
 JOIN users ON comments.user_id = users.user_id
 JOIN posts ON comments.post_id = posts.post_id
+LEFT JOIN comment_counter ON comment_counter.comment_id = comments.comment_id
 WHERE comments.post_id = $1
+AND users.deactivated_at IS NULL
 AND NOT EXISTS (
     SELECT 1
     FROM block
@@ -27,29 +48,94 @@ AND NOT EXISTS (
     FROM block
     WHERE block.user_id = comments.user_id AND target_user_id = $2
 )
+AND NOT EXISTS ( -- no comments from users on either side of a block with the post's author
+    SELECT 1
+    FROM block
+    WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+        OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1
     FROM mute
     WHERE mute.user_id = $2 AND target_user_id = comments.user_id
         AND posts.user_id != comments.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+)
+AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE comments.user_id != $2
+        AND comments.text ~* muted_pattern.pattern
 )
 ORDER BY comments.created_at DESC;
 
+-- name: ReserveCommentId :one
+SELECT nextval(pg_get_serial_sequence('comments', 'comment_id'))::int AS comment_id;
+
 -- name: AddCommentToPost :one
-INSERT INTO comments (post_id, user_id, text, facets)
-VALUES ($1, $2, $3, $4)
+WITH counter AS (
+    INSERT INTO post_counter (post_id, comment_count)
+    SELECT posts.post_id, 1
+    FROM posts
+    WHERE posts.post_id = @post_id
+    AND NOT EXISTS ( -- comments hidden by a block with the post's author aren't counted
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = @user_id)
+            OR (block.user_id = @user_id AND block.target_user_id = posts.user_id)
+    )
+    ON CONFLICT (post_id) DO UPDATE
+    SET comment_count = post_counter.comment_count + 1
+)
+INSERT INTO comments (post_id, user_id, text, facets, comment_id)
+VALUES (
+    @post_id, @user_id, @text, @facets,
+    COALESCE(sqlc.narg('comment_id')::int, nextval(pg_get_serial_sequence('comments', 'comment_id')))
+)
 RETURNING *;
 
 -- name: DeleteComment :exec
-DELETE FROM comments
-WHERE comment_id = $1;
+WITH deleted AS (
+    DELETE FROM comments
+    WHERE comment_id = $1
+    RETURNING post_id, user_id
+)
+UPDATE post_counter
+SET comment_count = post_counter.comment_count - 1
+FROM deleted
+JOIN posts ON posts.post_id = deleted.post_id
+JOIN users ON users.user_id = deleted.user_id
+WHERE post_counter.post_id = deleted.post_id
+AND users.deactivated_at IS NULL
+AND NOT EXISTS ( -- hidden comments were never counted
+    SELECT 1
+    FROM block
+    WHERE (block.user_id = posts.user_id AND block.target_user_id = deleted.user_id)
+        OR (block.user_id = deleted.user_id AND block.target_user_id = posts.user_id)
+);
 
--- name: AttachImageToComment :exec
-INSERT INTO comment_images (comment_id, image_id)
+-- name: AttachMediaToComment :exec
+INSERT INTO comment_media (comment_id, media_id)
 VALUES ($1, $2);
 
--- name: GetImagesByCommentId :many
-SELECT images.*
-FROM images
-JOIN comment_images ON images.image_id = comment_images.image_id
-WHERE comment_images.comment_id = $1;
+-- name: GetMediaByCommentId :many
+SELECT media.*
+FROM media
+JOIN comment_media ON media.media_id = comment_media.media_id
+WHERE comment_media.comment_id = $1;
+
+-- name: GetMediaByCommentIds :many
+SELECT comment_media.comment_id, sqlc.embed(media)
+FROM media
+JOIN comment_media ON media.media_id = comment_media.media_id
+WHERE comment_media.comment_id = ANY(@comment_ids::int[])
+ORDER BY comment_media.comment_id, media.media_id;
+
+-- name: UpdateCommentMediaAltText :execrows
+UPDATE media
+SET alt_text = $3
+FROM comment_media
+WHERE media.media_id = comment_media.media_id
+  AND comment_media.comment_id = $1
+  AND media.media_id = $2;
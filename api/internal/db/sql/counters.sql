@@ -0,0 +1,115 @@
+-- name: CreateMissingPostCounters :exec
+INSERT INTO post_counter (post_id)
+SELECT post_id
+FROM posts
+WHERE post_id > @after_post_id::int
+ORDER BY post_id
+LIMIT @batch_size::int
+ON CONFLICT (post_id) DO NOTHING;
+
+-- name: LockPostCounters :many
+SELECT post_id
+FROM post_counter
+WHERE post_id > @after_post_id::int
+ORDER BY post_id
+LIMIT @batch_size::int
+FOR UPDATE;
+
+-- name: RecountPostCounters :execrows
+WITH actual AS (
+    SELECT
+        posts.post_id,
+        (SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.post_id AND likes.comment_id IS NULL)::int AS like_count,
+        (
+            -- comments hidden by deactivation or by a block with the post's author aren't counted
+            SELECT COUNT(*)
+            FROM comments
+            JOIN users ON users.user_id = comments.user_id
+            WHERE comments.post_id = posts.post_id
+            AND users.deactivated_at IS NULL
+            AND NOT EXISTS (
+                SELECT 1
+                FROM block
+                WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+                    OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+            )
+        )::int AS comment_count,
+        (SELECT COUNT(*) FROM poll_vote WHERE poll_vote.post_id = posts.post_id)::int AS poll_vote_count
+    FROM posts
+    WHERE posts.post_id = ANY(@post_ids::int[])
+)
+UPDATE post_counter
+SET like_count = actual.like_count,
+    comment_count = actual.comment_count,
+    poll_vote_count = actual.poll_vote_count
+FROM actual
+WHERE post_counter.post_id = actual.post_id
+    AND (post_counter.like_count, post_counter.comment_count, post_counter.poll_vote_count)
+        IS DISTINCT FROM (actual.like_count, actual.comment_count, actual.poll_vote_count);
+
+-- name: CreateMissingCommentCounters :exec
+INSERT INTO comment_counter (comment_id)
+SELECT comment_id
+FROM comments
+WHERE comment_id > @after_comment_id::int
+ORDER BY comment_id
+LIMIT @batch_size::int
+ON CONFLICT (comment_id) DO NOTHING;
+
+-- name: LockCommentCounters :many
+SELECT comment_id
+FROM comment_counter
+WHERE comment_id > @after_comment_id::int
+ORDER BY comment_id
+LIMIT @batch_size::int
+FOR UPDATE;
+
+-- name: RecountCommentCounters :execrows
+WITH actual AS (
+    SELECT
+        comments.comment_id,
+        (SELECT COUNT(*) FROM likes WHERE likes.comment_id = comments.comment_id)::int AS like_count
+    FROM comments
+    WHERE comments.comment_id = ANY(@comment_ids::int[])
+)
+UPDATE comment_counter
+SET like_count = actual.like_count
+FROM actual
+WHERE comment_counter.comment_id = actual.comment_id
+    AND comment_counter.like_count <> actual.like_count;
+
+-- name: CreateMissingUserCounters :exec
+INSERT INTO user_counter (user_id)
+SELECT user_id
+FROM users
+WHERE user_id > @after_user_id::int
+ORDER BY user_id
+LIMIT @batch_size::int
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: LockUserCounters :many
+SELECT user_id
+FROM user_counter
+WHERE user_id > @after_user_id::int
+ORDER BY user_id
+LIMIT @batch_size::int
+FOR UPDATE;
+
+-- name: RecountUserCounters :execrows
+WITH actual AS (
+    SELECT
+        users.user_id,
+        (SELECT COUNT(*) FROM follows WHERE follows.following_id = users.user_id)::int AS follower_count,
+        (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.user_id)::int AS following_count,
+        (SELECT COUNT(*) FROM posts WHERE posts.user_id = users.user_id)::int AS post_count
+    FROM users
+    WHERE users.user_id = ANY(@user_ids::int[])
+)
+UPDATE user_counter
+SET follower_count = actual.follower_count,
+    following_count = actual.following_count,
+    post_count = actual.post_count
+FROM actual
+WHERE user_counter.user_id = actual.user_id
+    AND (user_counter.follower_count, user_counter.following_count, user_counter.post_count)
+        IS DISTINCT FROM (actual.follower_count, actual.following_count, actual.post_count);
@@ -0,0 +1,127 @@
+-- name: ExportGetPostsByUserId :many
+SELECT *
+FROM posts
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportGetPostMediaIdsByUserId :many
+SELECT post_media.post_id, post_media.media_id
+FROM post_media
+JOIN posts ON posts.post_id = post_media.post_id
+WHERE posts.user_id = $1
+ORDER BY post_media.post_id, post_media.display_order;
+
+-- name: ExportGetCommentsByUserId :many
+SELECT *
+FROM comments
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportGetLikesByUserId :many
+SELECT *
+FROM likes
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportGetPollVotesByUserId :many
+SELECT *
+FROM poll_vote
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportGetFollowingByUserId :many
+SELECT users.user_id, users.username, follows.created_at
+FROM follows
+JOIN users ON users.user_id = follows.following_id
+WHERE follows.follower_id = $1
+ORDER BY follows.created_at;
+
+-- name: ExportGetFollowersByUserId :many
+SELECT users.user_id, users.username, follows.created_at
+FROM follows
+JOIN users ON users.user_id = follows.follower_id
+WHERE follows.following_id = $1
+ORDER BY follows.created_at;
+
+-- name: ExportGetCloseFriendsByUserId :many
+SELECT users.user_id, users.username, user_relationship.created_at
+FROM user_relationship
+JOIN users ON users.user_id = user_relationship.target_user_id
+WHERE user_relationship.user_id = $1
+ORDER BY user_relationship.created_at;
+
+-- name: ExportGetBlockedUsersByUserId :many
+SELECT users.user_id, users.username, block.created_at
+FROM block
+JOIN users ON users.user_id = block.target_user_id
+WHERE block.user_id = $1
+ORDER BY block.created_at;
+
+-- name: ExportGetMutedUsersByUserId :many
+SELECT users.user_id, users.username, mute.created_at
+FROM mute
+JOIN users ON users.user_id = mute.target_user_id
+WHERE mute.user_id = $1
+ORDER BY mute.created_at;
+
+-- name: InsertDataExport :one
+INSERT INTO data_export (user_id)
+SELECT sqlc.arg('user_id')::int
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM data_export
+    WHERE user_id = sqlc.arg('user_id')::int
+    AND status != 'failed'
+    AND created_at > sqlc.arg('cooldown_start')::timestamptz
+)
+ON CONFLICT (user_id) WHERE status IN ('pending', 'processing') DO NOTHING
+RETURNING *;
+
+-- name: ClaimPendingDataExports :many
+UPDATE data_export
+SET status = 'processing',
+    attempts = attempts + 1,
+    started_at = NOW()
+WHERE id IN (
+    SELECT id
+    FROM data_export
+    WHERE status = 'pending'
+       OR (status = 'processing' AND started_at < @stale_before::timestamptz)
+    ORDER BY id
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RequeueDataExport :exec
+UPDATE data_export
+SET status = 'pending'
+WHERE id = $1;
+
+-- name: GetLatestDataExportForUser :one
+SELECT *
+FROM data_export
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CompleteDataExport :exec
+UPDATE data_export
+SET status = 'complete', object_key = $2, completed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_export
+SET status = 'failed', completed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetExpiredDataExports :many
+SELECT *
+FROM data_export
+WHERE object_key IS NOT NULL
+AND completed_at < sqlc.arg('before')::timestamptz;
+
+-- name: ExpireDataExports :exec
+UPDATE data_export
+SET status = 'expired', object_key = NULL
+WHERE id = ANY(@ids::int[]);
@@ -1,4 +1,11 @@
 -- name: GetAllPostIdsCursor :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = @user_id::int
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT post_id
 FROM posts
 WHERE NOT EXISTS (
@@ -13,21 +20,59 @@ WHERE NOT EXISTS (
     SELECT 1
     FROM mute
     WHERE mute.user_id = @user_id::int AND target_user_id = posts.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+) AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE posts.user_id != @user_id::int
+        AND posts.text ~* muted_pattern.pattern
 ) AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = @user_id::int
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = @user_id::int
             AND user_relationship.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = @user_id::int
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = @user_id::int AND follows.following_id = posts.user_id
+    ))
+) AND EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+) AND (
+    posts.user_id = @user_id::int
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = @user_id::int AND follows.following_id = posts.user_id
     )
 ) AND (@before::timestamp IS NULL OR posts.created_at < @before::timestamp)
 ORDER BY posts.created_at DESC
 LIMIT sqlc.arg('limit')::int;
 
 -- name: GetPostIdsByFollowingCursor :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT post_id
 FROM posts
 WHERE (posts.user_id = $1 OR EXISTS (
@@ -46,22 +91,52 @@ WHERE (posts.user_id = $1 OR EXISTS (
     SELECT 1
     FROM mute
     WHERE user_id = $1 AND target_user_id = posts.user_id
+        AND mute.hide_posts
+        AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+) AND NOT EXISTS (
+    SELECT 1
+    FROM muted_pattern
+    WHERE posts.user_id != $1
+        AND posts.text ~* muted_pattern.pattern
 ) AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $1
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $1
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $1
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+    ))
+) AND EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
 ) AND ($3::timestamp IS NULL OR posts.created_at < $3::timestamp)
 ORDER BY posts.created_at DESC
 LIMIT $2;
 
 -- name: GetPostIdsForMutualFeedCursor :many
-WITH user_relationships AS (
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_feeds
+        AND (expires_at IS NULL OR expires_at > NOW())
+),
+user_relationships AS (
   SELECT posts.post_id, posts.user_id,
     CASE
       WHEN posts.user_id = $1 THEN 'own'
@@ -76,17 +151,46 @@ WITH user_relationships AS (
                  WHERE f1.follower_id = $1 AND f2.following_id = posts.user_id))
     AND NOT EXISTS (SELECT 1 FROM block WHERE user_id = $1 AND target_user_id = posts.user_id)
     AND NOT EXISTS (SELECT 1 FROM block WHERE user_id = posts.user_id AND target_user_id = $1)
-    AND NOT EXISTS (SELECT 1 FROM mute WHERE user_id = $1 AND target_user_id = posts.user_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM mute
+        WHERE user_id = $1 AND target_user_id = posts.user_id
+            AND mute.hide_posts
+            AND (mute.expires_at IS NULL OR mute.expires_at > NOW())
+    )
+    AND NOT EXISTS (
+        SELECT 1
+        FROM muted_pattern
+        WHERE posts.user_id != $1
+            AND posts.text ~* muted_pattern.pattern
+    )
+    AND EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL)
+    AND (
+        posts.user_id = $1
+        OR f.follower_id IS NOT NULL
+        OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    )
     AND (
         posts.visibilityType = 0 -- public
         OR posts.user_id = $1
-        OR EXISTS (
+        OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
             SELECT 1
             FROM user_relationship
             WHERE user_id = posts.user_id
                 AND target_user_id = $1
                 AND user_relationship.created_at < posts.created_at
-        )
+        ))
+        OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+            SELECT 1
+            FROM audience_list_member
+            WHERE audience_list_member.list_id = posts.audience_list_id
+                AND audience_list_member.user_id = $1
+                AND audience_list_member.created_at < posts.created_at
+        ))
+        OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+            SELECT 1
+            FROM follows
+            WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+        ))
     )
     AND ($3::timestamp IS NULL OR posts.created_at < $3::timestamp)
 )
@@ -105,6 +209,14 @@ LIMIT $2;
 SELECT *
 FROM posts
 WHERE post_id = $1
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+)
+AND (
+    posts.user_id = $2
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = $2
 )
@@ -114,13 +226,67 @@ AND NOT EXISTS (
 AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = $2
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = $2
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = $2
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = $2 AND follows.following_id = posts.user_id
+    ))
+);
+
+-- name: GetPostsByIds :many
+SELECT *
+FROM posts
+WHERE post_id = ANY(@post_ids::int[])
+AND EXISTS (
+    SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.deactivated_at IS NULL
+)
+AND (
+    posts.user_id = @target_user_id
+    OR NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = posts.user_id AND users.is_private)
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = @target_user_id AND follows.following_id = posts.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = @target_user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM block WHERE block.user_id = @target_user_id AND block.target_user_id = posts.user_id
+)
+AND (
+    posts.visibilityType = 0 -- public
+    OR posts.user_id = @target_user_id
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
+        SELECT 1
+        FROM user_relationship
+        WHERE user_id = posts.user_id
+            AND target_user_id = @target_user_id
+            AND user_relationship.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = @target_user_id
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = @target_user_id AND follows.following_id = posts.user_id
+    ))
 );
 
 -- name: GetPostIdsByUserIdCursor :many
@@ -130,6 +296,12 @@ JOIN users ON users.user_id = @target_user_id::int
 WHERE posts.user_id = @target_user_id::int
 AND (@before::timestamp IS NULL OR posts.created_at < @before::timestamp)
 AND (users.pinned_post_id IS NULL OR posts.post_id != users.pinned_post_id)
+AND users.deactivated_at IS NULL
+AND (
+    NOT users.is_private
+    OR posts.user_id = @user_id
+    OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = @user_id AND follows.following_id = posts.user_id)
+)
 AND NOT EXISTS (
     SELECT 1 FROM block WHERE block.user_id = posts.user_id AND block.target_user_id = @user_id
 )
@@ -139,13 +311,25 @@ AND NOT EXISTS (
 AND (
     posts.visibilityType = 0 -- public
     OR posts.user_id = @user_id
-    OR EXISTS (
+    OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
         SELECT 1
         FROM user_relationship
         WHERE user_id = posts.user_id
             AND target_user_id = @user_id
             AND user_relationship.created_at < posts.created_at
-    )
+    ))
+    OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+        SELECT 1
+        FROM audience_list_member
+        WHERE audience_list_member.list_id = posts.audience_list_id
+            AND audience_list_member.user_id = @user_id
+            AND audience_list_member.created_at < posts.created_at
+    ))
+    OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+        SELECT 1
+        FROM follows
+        WHERE follows.follower_id = @user_id AND follows.following_id = posts.user_id
+    ))
 )
 ORDER BY posts.created_at DESC
 LIMIT sqlc.arg('limit')::int;
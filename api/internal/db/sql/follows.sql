@@ -6,13 +6,41 @@ SELECT EXISTS (
 );
 
 -- name: InsertFollow :exec
-INSERT INTO follows (follower_id, following_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING;
+WITH inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    VALUES ($1, $2)
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count;
 
 -- name: DeleteFollow :exec
-DELETE FROM follows
-WHERE following_id = $1 AND follower_id = $2;
+WITH deleted AS (
+    DELETE FROM follows
+    WHERE following_id = $1 AND follower_id = $2
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, -1 AS follower_delta, 0 AS following_delta FROM deleted
+    UNION ALL
+    SELECT follower_id, 0, -1 FROM deleted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count;
 
 -- name: GetMutualConnectionsForUser :many
 SELECT u.username
@@ -46,6 +74,15 @@ WHERE follows.follower_id = @user_id::int
 ORDER BY follows.created_at DESC
 LIMIT sqlc.arg('limit')::int;
 
+-- name: GetFollowerUserIds :many
+SELECT users.user_id, follows.created_at
+FROM users
+INNER JOIN follows ON users.user_id = follows.follower_id
+WHERE follows.following_id = @user_id::int
+    AND (sqlc.narg('before')::timestamptz IS NULL OR follows.created_at < sqlc.narg('before'))
+ORDER BY follows.created_at DESC
+LIMIT sqlc.arg('limit')::int;
+
 -- name: GetMutualsByUserId :many
 SELECT DISTINCT u.user_id, u.email, u.username, u.created_at, u.name
 FROM users u
@@ -62,3 +99,75 @@ INNER JOIN follows f2 ON f2.following_id = u.user_id AND f2.follower_id = @targe
 WHERE sqlc.narg('before')::timestamptz IS NULL OR f1.created_at < sqlc.narg('before')
 ORDER BY f1.created_at DESC
 LIMIT sqlc.arg('limit')::int;
+
+-- name: InsertFollowRequest :execrows
+INSERT INTO follow_request (follower_id, following_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :exec
+DELETE FROM follow_request
+WHERE follower_id = $1 AND following_id = $2;
+
+-- name: GetHasRequestedToFollow :one
+SELECT EXISTS (
+  SELECT 1
+  FROM follow_request
+  WHERE follower_id = $1 AND following_id = $2
+);
+
+-- name: AcceptFollowRequest :execrows
+WITH accepted AS (
+    DELETE FROM follow_request
+    WHERE follow_request.follower_id = $1 AND follow_request.following_id = $2
+    RETURNING follower_id, following_id
+), inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    SELECT accepted.follower_id, accepted.following_id
+    FROM accepted
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count;
+
+-- name: AcceptAllFollowRequests :exec
+WITH accepted AS (
+    DELETE FROM follow_request
+    WHERE follow_request.following_id = $1
+    RETURNING follower_id, following_id
+), inserted AS (
+    INSERT INTO follows (follower_id, following_id)
+    SELECT accepted.follower_id, accepted.following_id
+    FROM accepted
+    ON CONFLICT DO NOTHING
+    RETURNING follower_id, following_id
+)
+INSERT INTO user_counter (user_id, follower_count, following_count)
+SELECT deltas.user_id, SUM(deltas.follower_delta), SUM(deltas.following_delta)
+FROM (
+    SELECT following_id AS user_id, 1 AS follower_delta, 0 AS following_delta FROM inserted
+    UNION ALL
+    SELECT follower_id, 0, 1 FROM inserted
+) AS deltas
+GROUP BY deltas.user_id
+ON CONFLICT (user_id) DO UPDATE
+SET follower_count = user_counter.follower_count + EXCLUDED.follower_count,
+    following_count = user_counter.following_count + EXCLUDED.following_count;
+
+-- name: GetFollowRequestUserIds :many
+SELECT follower_id, created_at
+FROM follow_request
+WHERE following_id = @user_id::int
+    AND (sqlc.narg('before')::timestamptz IS NULL OR created_at < sqlc.narg('before'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit')::int;
@@ -1,21 +1,41 @@
 -- name: AddLike :exec
-INSERT INTO likes (post_id, comment_id, user_id)
-VALUES ($1, $2, $3);
+WITH inserted AS (
+    INSERT INTO likes (post_id, comment_id, user_id)
+    VALUES ($1, $2, $3)
+    RETURNING post_id, comment_id
+), post_counts AS (
+    INSERT INTO post_counter (post_id, like_count)
+    SELECT post_id, 1 FROM inserted WHERE comment_id IS NULL
+    ON CONFLICT (post_id) DO UPDATE
+    SET like_count = post_counter.like_count + 1
+)
+INSERT INTO comment_counter (comment_id, like_count)
+SELECT comment_id, 1 FROM inserted WHERE comment_id IS NOT NULL
+ON CONFLICT (comment_id) DO UPDATE
+SET like_count = comment_counter.like_count + 1;
 
 -- name: RemoveLike :exec
-DELETE FROM likes
-WHERE post_id = $1
-AND user_id = $2
-AND ($3 = TRUE OR comment_id = $4);
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE post_id = $1
+    AND user_id = $2
+    AND ($3 = TRUE OR comment_id = $4)
+    RETURNING post_id, comment_id
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id AND deleted.comment_id IS NULL)
+    WHERE post_id IN (SELECT post_id FROM deleted WHERE comment_id IS NULL)
+)
+UPDATE comment_counter
+SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.comment_id = comment_counter.comment_id)
+WHERE comment_id IN (SELECT comment_id FROM deleted);
 
--- name: GetIsPostLikedByUser :one
-SELECT EXISTS (
-  SELECT 1
-  FROM likes
-  WHERE user_id = $1
-    AND post_id = $2
-    AND comment_id IS NULL
-);
+-- name: GetLikedPostIds :many
+SELECT post_id
+FROM likes
+WHERE user_id = @user_id
+    AND post_id = ANY(@post_ids::int[])
+    AND comment_id IS NULL;
 
 -- name: GetIsLikedByUser :one
 SELECT EXISTS (
@@ -47,3 +67,43 @@ AND NOT EXISTS (
         AND block.target_user_id = $2
 )
 LIMIT 3;
+
+-- name: GetPostLikesByPostIds :many
+SELECT post_id, username, user_id
+FROM (
+    SELECT likes.post_id, users.username, users.user_id,
+        ROW_NUMBER() OVER (PARTITION BY likes.post_id) AS position
+    FROM likes
+    JOIN users ON likes.user_id = users.user_id
+    WHERE likes.post_id = ANY(@post_ids::int[]) AND likes.comment_id IS NULL
+    AND likes.user_id != @user_id
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = @user_id
+            AND likes.user_id = block.target_user_id
+    ) AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = likes.user_id
+            AND block.target_user_id = @user_id
+    )
+) AS ranked
+WHERE position <= 3;
+
+-- name: GetLikerUserIds :many
+SELECT likes.user_id, likes.created_at
+FROM likes
+WHERE likes.post_id = @post_id::int
+    AND likes.comment_id IS NOT DISTINCT FROM sqlc.narg('comment_id')::int
+    AND (sqlc.narg('before')::timestamptz IS NULL OR likes.created_at < sqlc.narg('before'))
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = @current_user_id::int
+            AND block.target_user_id = likes.user_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = likes.user_id
+            AND block.target_user_id = @current_user_id::int
+    )
+ORDER BY likes.created_at DESC
+LIMIT sqlc.arg('limit')::int;
@@ -0,0 +1,44 @@
+-- name: ClaimUnprocessedMedia :many
+UPDATE media
+SET processing_status = 'processing',
+    processing_attempts = processing_attempts + 1,
+    processing_started_at = NOW()
+WHERE media_id IN (
+    SELECT media_id
+    FROM media
+    WHERE processing_status = 'pending'
+       OR (processing_status = 'processing' AND processing_started_at < @stale_before::timestamptz)
+    ORDER BY media_id
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteMediaProcessing :exec
+UPDATE media
+SET processing_status = 'ready',
+    width = $2,
+    height = $3,
+    duration_ms = $4,
+    poster_key = $5,
+    blurhash = $6
+WHERE media_id = $1;
+
+-- name: SetMediaProcessingStatus :exec
+UPDATE media
+SET processing_status = $2
+WHERE media_id = $1;
+
+-- name: GetPublishedUploadNames :many
+SELECT name::text
+FROM unnest(@names::text[]) AS name
+WHERE EXISTS (
+    SELECT 1
+    FROM media
+    WHERE media.blob_key LIKE '%/' || name || '.%'
+) OR EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.avatar_key LIKE '%/' || name || '.%'
+       OR users.banner_key LIKE '%/' || name || '.%'
+);
@@ -0,0 +1,31 @@
+-- name: UpsertMutedWord :one
+INSERT INTO muted_word (user_id, phrase, pattern, mute_feeds, mute_notifications, mute_comments, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, phrase) DO UPDATE
+SET mute_feeds = EXCLUDED.mute_feeds,
+    mute_notifications = EXCLUDED.mute_notifications,
+    mute_comments = EXCLUDED.mute_comments,
+    expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListMutedWords :many
+SELECT *
+FROM muted_word
+WHERE user_id = $1
+    AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC;
+
+-- name: CountOtherMutedWords :one
+SELECT COUNT(*)
+FROM muted_word
+WHERE user_id = $1
+    AND phrase != $2
+    AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_word
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteExpiredMutedWords :exec
+DELETE FROM muted_word
+WHERE expires_at <= NOW();
@@ -17,22 +17,55 @@ UPDATE notifications
 SET viewed = TRUE
 WHERE notification_id = $1;
 
+-- name: MarkNotificationsAsReadByIds :exec
+UPDATE notifications
+SET viewed = TRUE
+WHERE notification_id = ANY(@notification_ids::int[]);
+
 -- name: MarkAllNotificationsAsReadForUser :exec
 UPDATE notifications
 SET viewed = TRUE
 WHERE user_id = $1;
 
 -- name: UserHasUnreadNotifications :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = $1
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT EXISTS (
   SELECT 1
   FROM notifications
   WHERE user_id = $1 AND viewed = FALSE
+    AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+      SELECT 1 FROM muted_pattern
+      LEFT JOIN posts ON posts.post_id = notifications.post_id
+      LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+      WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+        OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
+    )
 );
 
 -- name: GetUserUnreadNotificationCount :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = $1
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT COUNT(*)
 FROM notifications
-WHERE user_id = $1 AND viewed = FALSE;
+WHERE user_id = $1 AND viewed = FALSE
+  AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+    SELECT 1 FROM muted_pattern
+    LEFT JOIN posts ON posts.post_id = notifications.post_id
+    LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+    WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+      OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
+  );
 
 -- name: GetUnreadNotificationsForUserId :many
 SELECT *
@@ -48,6 +81,13 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: GetNotificationsForUserIdWithTimeOffset :many
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+    SELECT string_agg('(' || pattern || ')', '|') AS pattern
+    FROM muted_word
+    WHERE user_id = $1
+        AND mute_notifications
+        AND (expires_at IS NULL OR expires_at > NOW())
+)
 SELECT notifications.*
 FROM notifications
 LEFT JOIN posts ON notifications.post_id = posts.post_id
@@ -69,17 +109,34 @@ WHERE notifications.user_id = $1 AND notifications.viewed = $4 AND notifications
         JOIN posts ON posts.post_id = notifications.post_id
         WHERE block.user_id = posts.user_id
             AND block.target_user_id = $1
+    ) AND NOT EXISTS ( -- no notifications about posts or comments containing muted words
+        SELECT 1 FROM muted_pattern
+        LEFT JOIN comments ON comments.comment_id = notifications.comment_id
+        WHERE (posts.user_id != $1 AND posts.text ~* muted_pattern.pattern)
+            OR (comments.user_id != $1 AND comments.text ~* muted_pattern.pattern)
     ) AND (
         notifications.post_id IS NULL
         OR posts.visibilityType = 0 -- public
         OR posts.user_id = $1
-        OR EXISTS (
+        OR (posts.visibilityType = 1 AND EXISTS ( -- close friends
             SELECT 1
             FROM user_relationship
             WHERE user_id = posts.user_id
                 AND target_user_id = $1
                 AND user_relationship.created_at < posts.created_at
-        )
+        ))
+        OR (posts.visibilityType = 2 AND EXISTS ( -- audience list
+            SELECT 1
+            FROM audience_list_member
+            WHERE audience_list_member.list_id = posts.audience_list_id
+                AND audience_list_member.user_id = $1
+                AND audience_list_member.created_at < posts.created_at
+        ))
+        OR (posts.visibilityType = 3 AND EXISTS ( -- followers
+            SELECT 1
+            FROM follows
+            WHERE follows.follower_id = $1 AND follows.following_id = posts.user_id
+        ))
     )
 ORDER BY notifications.created_at DESC
 LIMIT $3;
@@ -104,15 +161,71 @@ WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT 1;
 
+-- name: FindReactionNotification :one
+SELECT *
+FROM notifications
+WHERE user_id = $1
+  AND notification_type = 'reaction'
+  AND post_id = $2
+  AND comment_id IS NOT DISTINCT FROM $3
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: FindPollVoteNotification :one
+SELECT *
+FROM notifications
+WHERE user_id = $1
+  AND notification_type = 'poll'
+  AND post_id = $2
+  AND EXISTS (
+    SELECT 1
+    FROM notification_actor
+    WHERE notification_actor.notification_id = notifications.notification_id
+  )
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: LockNotification :exec
+SELECT pg_advisory_xact_lock(hashtextextended(format('notification:%s:%s:%s', @user_id::int, @post_id::int, @notification_type::text), 0));
+
 -- name: DeleteNotificationById :exec
 DELETE FROM notifications
 WHERE notification_id = $1;
 
+-- name: DeleteNotificationsInvolvingUser :exec
+DELETE FROM notifications
+WHERE notifications.user_id = @user_id
+    AND (
+        notifications.target_user_id = @target_user_id
+        OR EXISTS (
+            SELECT 1
+            FROM posts
+            WHERE posts.post_id = notifications.post_id AND posts.user_id = @target_user_id
+        )
+        OR EXISTS (
+            SELECT 1
+            FROM comments
+            WHERE comments.comment_id = notifications.comment_id AND comments.user_id = @target_user_id
+        )
+    );
+
+-- name: GetNotificationsWithActor :many
+SELECT notifications.*
+FROM notifications
+JOIN notification_actor ON notification_actor.notification_id = notifications.notification_id
+WHERE notifications.user_id = @user_id AND notification_actor.user_id = @actor_id;
+
 -- name: InsertNotificationActor :exec
 INSERT INTO notification_actor (notification_id, user_id)
 VALUES ($1, $2)
 ON CONFLICT (notification_id, user_id) DO NOTHING;
 
+-- name: DeleteFollowRequestNotification :exec
+DELETE FROM notifications
+WHERE user_id = $1
+  AND target_user_id = $2
+  AND notification_type = 'followRequest';
+
 -- name: DeleteNotificationActor :exec
 DELETE FROM notification_actor
 WHERE notification_id = $1 AND user_id = $2;
@@ -123,6 +236,11 @@ FROM notification_actor
 WHERE notification_id = $1
 ORDER BY created_at DESC;
 
+-- name: GetNotificationIdsWithActors :many
+SELECT DISTINCT notification_id
+FROM notification_actor
+WHERE notification_id = ANY(@notification_ids::int[]);
+
 -- name: UpdateNotificationMessage :exec
 UPDATE notifications
 SET message = $2, facets = $3, created_at = CURRENT_TIMESTAMP, viewed = FALSE
@@ -133,6 +251,32 @@ UPDATE notifications
 SET message = $2, facets = $3
 WHERE notification_id = $1;
 
+-- name: IsMutingNotificationsFrom :one
+SELECT EXISTS (
+  SELECT 1
+  FROM mute
+  WHERE user_id = $1 AND target_user_id = $2
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+);
+
+-- name: NotificationContainsMutedWord :one
+WITH muted_pattern AS MATERIALIZED ( -- all muted words as one regex, compiled once instead of once per word
+  SELECT string_agg('(' || pattern || ')', '|') AS pattern
+  FROM muted_word
+  WHERE user_id = @user_id::int
+    AND mute_notifications
+    AND (expires_at IS NULL OR expires_at > NOW())
+)
+SELECT EXISTS (
+  SELECT 1
+  FROM muted_pattern
+  LEFT JOIN posts ON posts.post_id = sqlc.narg('post_id')::int
+  LEFT JOIN comments ON comments.comment_id = sqlc.narg('comment_id')::int
+  WHERE (posts.user_id != @user_id::int AND posts.text ~* muted_pattern.pattern)
+    OR (comments.user_id != @user_id::int AND comments.text ~* muted_pattern.pattern)
+);
+
 -- name: InsertDeviceToken :exec
 INSERT INTO device_token (user_id, token, is_enabled_mentions, is_enabled_comments, is_enabled_follows)
 VALUES ($1, $2, $3, $4, $5)
@@ -1,38 +1,58 @@
--- name: GetCommentCountByPostID :one
-SELECT COUNT(*)
-FROM comments
-WHERE post_id = $1;
+-- name: ReservePostId :one
+SELECT nextval(pg_get_serial_sequence('posts', 'post_id'))::int AS post_id;
 
 -- name: InsertPost :one
-INSERT INTO posts (user_id, text, facets, attributes, visibilityType)
-VALUES ($1, $2, $3, $4, $5)
+WITH counter AS (
+    INSERT INTO user_counter (user_id, post_count)
+    VALUES (@user_id, 1)
+    ON CONFLICT (user_id) DO UPDATE
+    SET post_count = user_counter.post_count + 1
+)
+INSERT INTO posts (user_id, text, facets, attributes, visibilityType, audience_list_id, post_id)
+VALUES (
+    @user_id, @text, @facets, @attributes, @visibilitytype, @audience_list_id,
+    COALESCE(sqlc.narg('post_id')::int, nextval(pg_get_serial_sequence('posts', 'post_id')))
+)
 RETURNING *;
 
 -- name: DeletePost :exec
-DELETE FROM posts
-WHERE post_id = $1;
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE post_id = $1
+    RETURNING user_id
+)
+UPDATE user_counter
+SET post_count = post_count - 1
+WHERE user_id IN (SELECT user_id FROM deleted);
 
--- name: InsertImage :one
-INSERT INTO images (height, width, image_blob_url)
-VALUES ($1, $2, $3)
+-- name: InsertMedia :one
+INSERT INTO media (height, width, blob_key, blurhash, variants, media_type, processing_status, alt_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
--- name: InsertPostImage :exec
-INSERT INTO post_images (post_id, image_id, display_order)
+-- name: InsertPostMedia :exec
+INSERT INTO post_media (post_id, media_id, display_order)
 VALUES ($1, $2, $3);
 
--- name: GetImagesByPostId :many
-SELECT images.*
-FROM images
-JOIN post_images ON images.image_id = post_images.image_id
-WHERE post_images.post_id = $1
-ORDER BY post_images.display_order ASC;
-
--- name: GetAllImagesByUserId :many
-SELECT images.*
-FROM images
-JOIN post_images ON images.image_id = post_images.image_id
-JOIN posts ON posts.post_id = post_images.post_id
+-- name: GetMediaByPostId :many
+SELECT media.*
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+WHERE post_media.post_id = $1
+ORDER BY post_media.display_order ASC;
+
+-- name: GetMediaByPostIds :many
+SELECT post_media.post_id, sqlc.embed(media)
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+WHERE post_media.post_id = ANY(@post_ids::int[])
+ORDER BY post_media.post_id, post_media.display_order ASC;
+
+-- name: GetAllMediaByUserId :many
+SELECT media.*
+FROM media
+JOIN post_media ON media.media_id = post_media.media_id
+JOIN posts ON posts.post_id = post_media.post_id
 WHERE posts.user_id = $1;
 
 -- name: GetPollVotesGrouped :many
@@ -41,14 +61,82 @@ FROM poll_vote
 WHERE post_id = $1
 GROUP BY option_index;
 
--- name: GetUserVoteInPoll :one
+-- name: GetPollVotesGroupedByPostIds :many
+SELECT post_id, option_index, COUNT(*) AS count
+FROM poll_vote
+WHERE post_id = ANY(@post_ids::int[])
+GROUP BY post_id, option_index;
+
+-- name: GetUserVotesInPoll :many
 SELECT option_index
 FROM poll_vote
-WHERE post_id = $1 AND user_id = $2;
+WHERE post_id = $1 AND user_id = $2
+ORDER BY option_index;
+
+-- name: GetUserVotesInPolls :many
+SELECT post_id, option_index
+FROM poll_vote
+WHERE post_id = ANY(@post_ids::int[]) AND user_id = @user_id
+ORDER BY post_id, option_index;
+
+-- name: GetPollVoterCount :one
+SELECT COUNT(DISTINCT user_id)
+FROM poll_vote
+WHERE post_id = $1;
+
+-- name: GetPollVoterCounts :many
+SELECT post_id, COUNT(DISTINCT user_id) AS count
+FROM poll_vote
+WHERE post_id = ANY(@post_ids::int[])
+GROUP BY post_id;
+
+-- name: LockPollVotesForUser :exec
+SELECT pg_advisory_xact_lock(@post_id::int, @user_id::int);
+
+-- name: DeleteUserVoteInPoll :exec
+WITH deleted AS (
+    DELETE FROM poll_vote
+    WHERE post_id = $1 AND user_id = $2 AND option_index = $3
+    RETURNING post_id
+)
+UPDATE post_counter
+SET poll_vote_count = poll_vote_count - 1
+WHERE post_id IN (SELECT post_id FROM deleted);
 
 -- name: InsertVote :exec
-INSERT INTO poll_vote (post_id, user_id, option_index)
-VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;
+WITH inserted AS (
+    INSERT INTO poll_vote (post_id, user_id, option_index)
+    VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+    RETURNING post_id
+)
+INSERT INTO post_counter (post_id, poll_vote_count)
+SELECT post_id, 1 FROM inserted
+ON CONFLICT (post_id) DO UPDATE
+SET poll_vote_count = post_counter.poll_vote_count + 1;
+
+-- name: GetPostCounters :many
+SELECT *
+FROM post_counter
+WHERE post_id = ANY(@post_ids::int[]);
+
+-- name: InsertPollDeadline :exec
+INSERT INTO poll_deadline (post_id, closes_at)
+VALUES ($1, $2);
+
+-- name: ClaimEndedPolls :many
+UPDATE poll_deadline
+SET ended_notified_at = NOW()
+FROM posts
+WHERE poll_deadline.post_id = posts.post_id
+    AND poll_deadline.post_id IN (
+        SELECT post_id
+        FROM poll_deadline
+        WHERE ended_notified_at IS NULL AND closes_at <= NOW()
+        ORDER BY closes_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    )
+RETURNING posts.post_id, posts.user_id, posts.attributes;
 
 -- name: PinPost :exec
 UPDATE users
@@ -64,3 +152,16 @@ WHERE user_id = $1;
 SELECT pinned_post_id
 FROM users
 WHERE user_id = $1;
+
+-- name: GetPinnedPostIds :many
+SELECT pinned_post_id::int
+FROM users
+WHERE pinned_post_id = ANY(@post_ids::int[]);
+
+-- name: UpdatePostMediaAltText :execrows
+UPDATE media
+SET alt_text = $3
+FROM post_media
+WHERE media.media_id = post_media.media_id
+  AND post_media.post_id = $1
+  AND media.media_id = $2;
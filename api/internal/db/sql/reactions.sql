@@ -0,0 +1,152 @@
+-- name: AddReaction :execrows
+INSERT INTO reaction (post_id, comment_id, user_id, emoji)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveReaction :execrows
+DELETE FROM reaction
+WHERE post_id = $1
+  AND comment_id IS NOT DISTINCT FROM $2
+  AND user_id = $3
+  AND emoji = $4;
+
+-- name: HasUserReacted :one
+SELECT EXISTS (
+  SELECT 1
+  FROM reaction
+  WHERE post_id = $1
+    AND comment_id IS NOT DISTINCT FROM $2
+    AND user_id = $3
+);
+
+-- name: GetReactionCounts :many
+SELECT emoji, COUNT(DISTINCT user_id) AS count, BOOL_OR(user_id = @current_user_id) AS is_reacted
+FROM (
+    SELECT @heart::text AS emoji, user_id
+    FROM likes
+    WHERE post_id = @post_id AND comment_id IS NOT DISTINCT FROM @comment_id
+    UNION ALL
+    SELECT emoji, user_id
+    FROM reaction
+    WHERE post_id = @post_id AND comment_id IS NOT DISTINCT FROM @comment_id
+) AS reactions
+GROUP BY emoji
+ORDER BY count DESC, emoji;
+
+-- name: GetPostReactionCounts :many
+SELECT post_id, emoji, COUNT(DISTINCT user_id) AS count, BOOL_OR(user_id = @current_user_id) AS is_reacted
+FROM (
+    SELECT post_id, @heart::text AS emoji, user_id
+    FROM likes
+    WHERE post_id = ANY(@post_ids::int[]) AND comment_id IS NULL
+    UNION ALL
+    SELECT post_id, emoji, user_id
+    FROM reaction
+    WHERE post_id = ANY(@post_ids::int[]) AND comment_id IS NULL
+) AS reactions
+GROUP BY post_id, emoji
+ORDER BY post_id, count DESC, emoji;
+
+-- name: GetReactedBy :many
+SELECT emoji, user_id, username
+FROM (
+    SELECT reactions.emoji, users.user_id, users.username,
+        ROW_NUMBER() OVER (PARTITION BY reactions.emoji ORDER BY reactions.created_at DESC) AS position
+    FROM (
+        SELECT @heart::text AS emoji, user_id, MAX(created_at) AS created_at
+        FROM likes
+        WHERE post_id = @post_id AND comment_id IS NOT DISTINCT FROM @comment_id
+        GROUP BY user_id
+        UNION ALL
+        SELECT emoji, user_id, created_at
+        FROM reaction
+        WHERE post_id = @post_id AND comment_id IS NOT DISTINCT FROM @comment_id
+    ) AS reactions
+    JOIN users ON reactions.user_id = users.user_id
+    WHERE reactions.user_id != @current_user_id
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = @current_user_id
+            AND reactions.user_id = block.target_user_id
+    ) AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = reactions.user_id
+            AND block.target_user_id = @current_user_id
+    )
+) AS ranked
+WHERE position <= @per_emoji
+ORDER BY emoji, position;
+
+-- name: GetPostReactedBy :many
+SELECT post_id, emoji, user_id, username
+FROM (
+    SELECT reactions.post_id, reactions.emoji, users.user_id, users.username,
+        ROW_NUMBER() OVER (PARTITION BY reactions.post_id, reactions.emoji ORDER BY reactions.created_at DESC) AS position
+    FROM (
+        SELECT post_id, @heart::text AS emoji, user_id, MAX(created_at) AS created_at
+        FROM likes
+        WHERE post_id = ANY(@post_ids::int[]) AND comment_id IS NULL
+        GROUP BY post_id, user_id
+        UNION ALL
+        SELECT post_id, emoji, user_id, created_at
+        FROM reaction
+        WHERE post_id = ANY(@post_ids::int[]) AND comment_id IS NULL
+    ) AS reactions
+    JOIN users ON reactions.user_id = users.user_id
+    WHERE reactions.user_id != @current_user_id
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = @current_user_id
+            AND reactions.user_id = block.target_user_id
+    ) AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = reactions.user_id
+            AND block.target_user_id = @current_user_id
+    )
+) AS ranked
+WHERE position <= @per_emoji
+ORDER BY post_id, emoji, position;
+
+-- name: GetCommentReactionCounts :many
+SELECT comment_id, emoji, COUNT(DISTINCT user_id) AS count, BOOL_OR(user_id = @current_user_id) AS is_reacted
+FROM (
+    SELECT comment_id, @heart::text AS emoji, user_id
+    FROM likes
+    WHERE comment_id = ANY(@comment_ids::int[])
+    UNION ALL
+    SELECT comment_id, emoji, user_id
+    FROM reaction
+    WHERE comment_id = ANY(@comment_ids::int[])
+) AS reactions
+GROUP BY comment_id, emoji
+ORDER BY comment_id, count DESC, emoji;
+
+-- name: GetCommentReactedBy :many
+SELECT comment_id, emoji, user_id, username
+FROM (
+    SELECT reactions.comment_id, reactions.emoji, users.user_id, users.username,
+        ROW_NUMBER() OVER (PARTITION BY reactions.comment_id, reactions.emoji ORDER BY reactions.created_at DESC) AS position
+    FROM (
+        SELECT comment_id, @heart::text AS emoji, user_id, MAX(created_at) AS created_at
+        FROM likes
+        WHERE comment_id = ANY(@comment_ids::int[])
+        GROUP BY comment_id, user_id
+        UNION ALL
+        SELECT comment_id, emoji, user_id, created_at
+        FROM reaction
+        WHERE comment_id = ANY(@comment_ids::int[])
+    ) AS reactions
+    JOIN users ON reactions.user_id = users.user_id
+    WHERE reactions.user_id != @current_user_id
+    AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = @current_user_id
+            AND reactions.user_id = block.target_user_id
+    ) AND NOT EXISTS (
+        SELECT 1 FROM block
+        WHERE block.user_id = reactions.user_id
+            AND block.target_user_id = @current_user_id
+    )
+) AS ranked
+WHERE position <= @per_emoji
+ORDER BY comment_id, emoji, position;
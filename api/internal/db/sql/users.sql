@@ -29,6 +29,11 @@ FROM users
 WHERE user_id = $1
 LIMIT 1;
 
+-- name: GetUsersByIds :many
+SELECT *
+FROM users
+WHERE user_id = ANY(@user_ids::int[]);
+
 -- name: GetUserByUsername :one
 SELECT *
 FROM users
@@ -55,6 +60,10 @@ VALUES ($1, $2, $3);
 DELETE FROM sessions
 WHERE id = $1;
 
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
 -- name: GetSessionById :one
 SELECT *
 FROM sessions
@@ -65,6 +74,12 @@ UPDATE sessions
 SET expires_at = $2
 WHERE id = $1;
 
+-- name: RehashSession :one
+UPDATE sessions
+SET id = sqlc.arg('hashed_id')
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
 -- name: CreateVerificationCode :exec
 INSERT INTO "verificationCodes" (code, user_id, expires_at)
 VALUES ($1, $2, $3)
@@ -88,19 +103,148 @@ VALUES ($1, $2)
 ON CONFLICT (user_id)
 DO UPDATE SET text = $2;
 
+-- name: UpdateUserIsPrivate :exec
+UPDATE users
+SET is_private = $2
+WHERE user_id = $1;
+
+-- name: UpdateUserRequireAltText :exec
+UPDATE users
+SET require_alt_text = $2
+WHERE user_id = $1;
+
+-- name: UpdateUserAvatarKey :exec
+UPDATE users
+SET avatar_key = $2
+WHERE user_id = $1;
+
+-- name: UpdateUserBannerKey :exec
+UPDATE users
+SET banner_key = $2
+WHERE user_id = $1;
+
 -- name: UpdateUserDisplayProperties :exec
 UPDATE users
 SET user_display_properties = $2
 WHERE user_id = $1;
 
 -- name: BlockUser :exec
-INSERT INTO block (user_id, target_user_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING;
+WITH inserted AS (
+    INSERT INTO block (user_id, target_user_id)
+    VALUES ($1, $2)
+    ON CONFLICT DO NOTHING
+    RETURNING user_id, target_user_id
+), hidden AS ( -- comments either user left on the other's posts, unless a block the other way already hid them
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM inserted
+    JOIN posts ON posts.user_id IN (inserted.user_id, inserted.target_user_id)
+    JOIN comments ON comments.post_id = posts.post_id
+        AND comments.user_id IN (inserted.user_id, inserted.target_user_id)
+        AND comments.user_id <> posts.user_id
+    JOIN users ON users.user_id = comments.user_id
+    WHERE users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE block.user_id = inserted.target_user_id AND block.target_user_id = inserted.user_id
+    )
+    GROUP BY comments.post_id
+)
+UPDATE post_counter
+SET comment_count = post_counter.comment_count - hidden.count
+FROM hidden
+WHERE post_counter.post_id = hidden.post_id;
 
 -- name: UnblockUser :exec
-DELETE FROM block
-WHERE user_id = $1 AND target_user_id = $2;
+WITH deleted AS (
+    DELETE FROM block
+    WHERE user_id = $1 AND target_user_id = $2
+    RETURNING user_id, target_user_id
+), shown AS ( -- comments either user left on the other's posts, unless a block the other way still hides them
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM deleted
+    JOIN posts ON posts.user_id IN (deleted.user_id, deleted.target_user_id)
+    JOIN comments ON comments.post_id = posts.post_id
+        AND comments.user_id IN (deleted.user_id, deleted.target_user_id)
+        AND comments.user_id <> posts.user_id
+    JOIN users ON users.user_id = comments.user_id
+    WHERE users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE block.user_id = deleted.target_user_id AND block.target_user_id = deleted.user_id
+    )
+    GROUP BY comments.post_id
+)
+INSERT INTO post_counter (post_id, comment_count)
+SELECT post_id, count FROM shown
+ON CONFLICT (post_id) DO UPDATE
+SET comment_count = post_counter.comment_count + EXCLUDED.comment_count;
+
+-- name: DeleteLikesBetweenUsers :exec
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE EXISTS (
+        SELECT 1
+        FROM posts
+        LEFT JOIN comments ON comments.comment_id = likes.comment_id
+        WHERE posts.post_id = likes.post_id
+            AND (
+                (likes.user_id = @user_id AND COALESCE(comments.user_id, posts.user_id) = @target_user_id)
+                OR (likes.user_id = @target_user_id AND COALESCE(comments.user_id, posts.user_id) = @user_id)
+            )
+    )
+    RETURNING post_id, comment_id
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id AND deleted.comment_id IS NULL)
+    WHERE post_id IN (SELECT post_id FROM deleted WHERE comment_id IS NULL)
+)
+UPDATE comment_counter
+SET like_count = like_count - (SELECT COUNT(*) FROM deleted WHERE deleted.comment_id = comment_counter.comment_id)
+WHERE comment_id IN (SELECT comment_id FROM deleted);
+
+-- name: DeleteReactionsBetweenUsers :exec
+DELETE FROM reaction
+WHERE EXISTS (
+    SELECT 1
+    FROM posts
+    LEFT JOIN comments ON comments.comment_id = reaction.comment_id
+    WHERE posts.post_id = reaction.post_id
+        AND (
+            (reaction.user_id = @user_id AND COALESCE(comments.user_id, posts.user_id) = @target_user_id)
+            OR (reaction.user_id = @target_user_id AND COALESCE(comments.user_id, posts.user_id) = @user_id)
+        )
+);
+
+-- name: DeletePollVotesBetweenUsers :exec
+WITH deleted AS (
+    DELETE FROM poll_vote
+    USING posts
+    WHERE posts.post_id = poll_vote.post_id
+        AND (
+            (poll_vote.user_id = @user_id AND posts.user_id = @target_user_id)
+            OR (poll_vote.user_id = @target_user_id AND posts.user_id = @user_id)
+        )
+    RETURNING poll_vote.post_id
+)
+UPDATE post_counter
+SET poll_vote_count = poll_vote_count - (SELECT COUNT(*) FROM deleted WHERE deleted.post_id = post_counter.post_id)
+WHERE post_id IN (SELECT post_id FROM deleted);
+
+-- name: DeleteRelationshipsBetweenUsers :exec
+DELETE FROM user_relationship
+WHERE (user_id = @user_id AND target_user_id = @target_user_id)
+    OR (user_id = @target_user_id AND target_user_id = @user_id);
+
+-- name: DeleteAudienceListMembershipsBetweenUsers :exec
+DELETE FROM audience_list_member
+USING audience_list
+WHERE audience_list.list_id = audience_list_member.list_id
+    AND (
+        (audience_list.user_id = @user_id AND audience_list_member.user_id = @target_user_id)
+        OR (audience_list.user_id = @target_user_id AND audience_list_member.user_id = @user_id)
+    );
 
 -- name: GetIsUserBlockingUser :one
 SELECT EXISTS (
@@ -110,9 +254,12 @@ SELECT EXISTS (
 );
 
 -- name: MuteUser :exec
-INSERT INTO mute (user_id, target_user_id)
-VALUES ($1, $2)
-ON CONFLICT DO NOTHING;
+INSERT INTO mute (user_id, target_user_id, hide_posts, mute_notifications, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, target_user_id) DO UPDATE
+SET hide_posts = EXCLUDED.hide_posts,
+    mute_notifications = EXCLUDED.mute_notifications,
+    expires_at = EXCLUDED.expires_at;
 
 -- name: UnmuteUser :exec
 DELETE FROM mute
@@ -123,12 +270,148 @@ SELECT EXISTS (
   SELECT 1
   FROM mute
   WHERE user_id = $1 AND target_user_id = $2
+    AND (expires_at IS NULL OR expires_at > NOW())
 );
 
+-- name: ListMutedUsers :many
+SELECT target_user_id AS user_id, hide_posts, mute_notifications, expires_at, created_at
+FROM mute
+WHERE user_id = @user_id::int
+    AND (expires_at IS NULL OR expires_at > NOW())
+    AND (sqlc.narg('before')::timestamptz IS NULL OR created_at < sqlc.narg('before'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit')::int;
+
+-- name: ListBlockedUserIds :many
+SELECT target_user_id AS user_id, created_at
+FROM block
+WHERE user_id = @user_id::int
+    AND (sqlc.narg('before')::timestamptz IS NULL OR created_at < sqlc.narg('before'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit')::int;
+
+-- name: DeleteExpiredMutes :exec
+DELETE FROM mute
+WHERE expires_at <= NOW();
+
 -- name: DeleteUserById :exec
+WITH removed_follows AS (
+    -- the deleted user's follows, likes, comments and votes cascade away, so take them off everyone
+    -- else's counts. Counters of the user's own posts and comments cascade away with them.
+    SELECT follower_id, following_id
+    FROM follows
+    WHERE follower_id = $1 OR following_id = $1
+), user_counts AS (
+    UPDATE user_counter
+    SET follower_count = follower_count - (SELECT COUNT(*) FROM removed_follows WHERE removed_follows.following_id = user_counter.user_id),
+        following_count = following_count - (SELECT COUNT(*) FROM removed_follows WHERE removed_follows.follower_id = user_counter.user_id)
+    WHERE user_counter.user_id <> $1
+        AND user_counter.user_id IN (SELECT follower_id FROM removed_follows UNION SELECT following_id FROM removed_follows)
+), removed_likes AS (
+    SELECT likes.post_id, likes.comment_id
+    FROM likes
+    JOIN posts ON posts.post_id = likes.post_id
+    LEFT JOIN comments ON comments.comment_id = likes.comment_id
+    WHERE likes.user_id = $1
+        AND posts.user_id <> $1
+        AND (comments.user_id IS NULL OR comments.user_id <> $1)
+), removed_votes AS (
+    SELECT poll_vote.post_id
+    FROM poll_vote
+    JOIN posts ON posts.post_id = poll_vote.post_id
+    WHERE poll_vote.user_id = $1 AND posts.user_id <> $1
+), removed_comments AS ( -- only comments that are counted, the same ones DeactivateUser takes off
+    SELECT comments.post_id
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND posts.user_id <> $1
+    AND users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+), post_counts AS (
+    UPDATE post_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM removed_likes WHERE removed_likes.post_id = post_counter.post_id AND removed_likes.comment_id IS NULL),
+        comment_count = comment_count - (SELECT COUNT(*) FROM removed_comments WHERE removed_comments.post_id = post_counter.post_id),
+        poll_vote_count = poll_vote_count - (SELECT COUNT(*) FROM removed_votes WHERE removed_votes.post_id = post_counter.post_id)
+    WHERE post_id IN (
+        SELECT post_id FROM removed_likes WHERE comment_id IS NULL
+        UNION SELECT post_id FROM removed_comments
+        UNION SELECT post_id FROM removed_votes
+    )
+), comment_counts AS (
+    UPDATE comment_counter
+    SET like_count = like_count - (SELECT COUNT(*) FROM removed_likes WHERE removed_likes.comment_id = comment_counter.comment_id)
+    WHERE comment_id IN (SELECT comment_id FROM removed_likes)
+)
 DELETE FROM users
 WHERE user_id = $1;
 
+-- name: GetUserCounter :one
+SELECT *
+FROM user_counter
+WHERE user_id = $1;
+
+-- name: DeactivateUser :exec
+WITH hidden AS ( -- the user's comments stop being counted while they're deactivated
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND users.deactivated_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+    GROUP BY comments.post_id
+), counters AS (
+    UPDATE post_counter
+    SET comment_count = post_counter.comment_count - hidden.count
+    FROM hidden
+    WHERE post_counter.post_id = hidden.post_id
+)
+UPDATE users
+SET deactivated_at = NOW()
+WHERE user_id = $1;
+
+-- name: ReactivateUser :exec
+WITH shown AS (
+    SELECT comments.post_id, COUNT(*)::int AS count
+    FROM comments
+    JOIN users ON users.user_id = comments.user_id
+    JOIN posts ON posts.post_id = comments.post_id
+    WHERE comments.user_id = $1
+    AND users.deactivated_at IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM block
+        WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+            OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+    )
+    GROUP BY comments.post_id
+), counters AS (
+    INSERT INTO post_counter (post_id, comment_count)
+    SELECT post_id, count FROM shown
+    ON CONFLICT (post_id) DO UPDATE
+    SET comment_count = post_counter.comment_count + EXCLUDED.comment_count
+)
+UPDATE users
+SET deactivated_at = NULL
+WHERE user_id = $1;
+
+-- name: GetUserIdsDeactivatedBefore :many
+SELECT user_id
+FROM users
+WHERE deactivated_at < sqlc.arg('before')::timestamptz;
+
 -- name: UserSearchWithHeuristics :many
 WITH results AS (
     SELECT DISTINCT ON (user_id)
@@ -172,6 +455,10 @@ WHERE NOT EXISTS (
     SELECT 1
     FROM block
     WHERE block.user_id = r.user_id AND target_user_id = $3
+) AND NOT EXISTS (
+    SELECT 1
+    FROM users
+    WHERE users.user_id = r.user_id AND users.deactivated_at IS NOT NULL
 )
 ORDER BY r.tier, r.score DESC, r.username
     LIMIT $2;
@@ -213,3 +500,19 @@ FROM notification_actor
 WHERE notification_id = $1 AND (sqlc.narg('before')::timestamptz IS NULL OR created_at < sqlc.narg('before'))
 ORDER BY created_at DESC
 LIMIT $2;
+
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE lower(email) = lower(sqlc.arg('email'))
+LIMIT 1;
+
+-- name: GetAppleIdentityBySubject :one
+SELECT *
+FROM apple_identity
+WHERE subject = $1;
+
+-- name: InsertAppleIdentity :execrows
+INSERT INTO apple_identity (user_id, subject, email, is_private_email)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
@@ -53,22 +53,22 @@ WHERE user_id = $1 AND EXTRACT(YEAR FROM created_at) = 2025;
 SELECT AVG(image_count)
 FROM (
     SELECT COUNT(*) as image_count
-    FROM images
-    JOIN post_images ON images.image_id = post_images.post_id
-    JOIN posts ON images.post_id = posts.post_id
+    FROM media
+    JOIN post_media ON media.media_id = post_media.post_id
+    JOIN posts ON media.post_id = posts.post_id
     WHERE EXTRACT(YEAR FROM created_at) = 2025
-    GROUP BY post_images.post_id
+    GROUP BY post_media.post_id
 ) subquery;
 
 -- name: WrappedGetAverageImageCountPerPostForUser :one
 SELECT COALESCE(AVG(image_count), 0)::int
 FROM (
     SELECT COUNT(*) as image_count
-    FROM images
-    JOIN post_images ON images.image_id = post_images.post_id
-    JOIN posts ON post_images.post_id = posts.post_id
+    FROM media
+    JOIN post_media ON media.media_id = post_media.post_id
+    JOIN posts ON post_media.post_id = posts.post_id
     WHERE posts.user_id = $1 AND EXTRACT(YEAR FROM posts.created_at) = 2025
-    GROUP BY post_images.post_id
+    GROUP BY post_media.post_id
 ) subquery;
 
 -- name: WrappedGetMostLikedPostId :one
@@ -0,0 +1,40 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+
+	"splajompy.com/api/v2/internal/utilities"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWriter, *http.Request))) {
+	withAuth("POST /account/export", h.RequestExport)
+}
+
+// RequestExport POST /account/export
+//
+// Starts an export of the current user's data. A download link is emailed once the export is ready.
+// Returns 429 if an export was already requested in the last day.
+func (h *Handler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	err := h.svc.RequestExport(r.Context(), *currentUser)
+	if errors.Is(err, ErrExportRecentlyRequested) {
+		utilities.HandleError(w, http.StatusTooManyRequests, "You can only request one export per day")
+		return
+	}
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
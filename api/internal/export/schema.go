@@ -0,0 +1,102 @@
+package export
+
+import (
+	"time"
+
+	"splajompy.com/api/v2/internal/db"
+)
+
+// SchemaVersion identifies the layout of an export archive. It is incremented whenever a field is
+// removed or changes meaning, so that an importer can tell which layout it is reading. Adding
+// fields does not change the version.
+const SchemaVersion = 1
+
+// DataFileName is the name of the JSON document at the root of an export archive.
+const DataFileName = "data.json"
+
+// ImagesDir is the directory within an export archive that holds the user's images.
+const ImagesDir = "images/"
+
+// Archive is the JSON document stored at data.json in an export archive. The archive is a ZIP file
+// containing data.json and an images/ directory with the user's uploaded images.
+//
+// All timestamps are RFC 3339 in UTC. Users other than the exporter are referenced by both ID and
+// username, since usernames can change and IDs don't survive being imported elsewhere.
+type Archive struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+
+	Profile   Profile    `json:"profile"`
+	Posts     []Post     `json:"posts"`
+	Comments  []Comment  `json:"comments"`
+	Likes     []Like     `json:"likes"`
+	PollVotes []PollVote `json:"pollVotes"`
+	Images    []Image    `json:"images"`
+
+	// Following is the users the exporter follows, and Followers is the users following them.
+	Following    []UserReference `json:"following"`
+	Followers    []UserReference `json:"followers"`
+	CloseFriends []UserReference `json:"closeFriends"`
+	Blocks       []UserReference `json:"blocks"`
+	Mutes        []UserReference `json:"mutes"`
+}
+
+type Profile struct {
+	UserID    int       `json:"userId"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Post is a post written by the exporter. Facets index into Text by byte offset, and ImageIDs
+// refer to entries in Archive.Images, in display order.
+type Post struct {
+	PostID     int       `json:"postId"`
+	Text       string    `json:"text"`
+	Facets     db.Facets `json:"facets"`
+	Poll       *db.Poll  `json:"poll,omitempty"`
+	Visibility int       `json:"visibility"`
+	ImageIDs   []int     `json:"imageIds"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Comment is a comment written by the exporter, on any user's post.
+type Comment struct {
+	CommentID int       `json:"commentId"`
+	PostID    int       `json:"postId"`
+	Text      string    `json:"text"`
+	Facets    db.Facets `json:"facets"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Like is a like the exporter left on a post, or on a comment when CommentID is set.
+type Like struct {
+	PostID    int       `json:"postId"`
+	CommentID *int      `json:"commentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PollVote is the exporter's vote in a poll. OptionIndex is zero-based.
+type PollVote struct {
+	PostID      int       `json:"postId"`
+	OptionIndex int       `json:"optionIndex"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Image is an image attached to one of the exporter's posts. File is the image's path within the
+// archive, and is empty if the image couldn't be retrieved when the export was made.
+type Image struct {
+	ImageID int    `json:"imageId"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	File    string `json:"file,omitempty"`
+}
+
+// UserReference is a relationship between the exporter and another user, along with when it began.
+type UserReference struct {
+	UserID   int       `json:"userId"`
+	Username string    `json:"username"`
+	Since    time.Time `json:"since"`
+}
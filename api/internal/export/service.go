@@ -0,0 +1,318 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/resend/resend-go/v3"
+	"golang.org/x/sync/errgroup"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/templates"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusComplete   = "complete"
+	StatusFailed     = "failed"
+	// StatusExpired exports have had their archive deleted once the download link expired
+	StatusExpired = "expired"
+)
+
+const (
+	// exportCooldown is how long a user has to wait between export requests
+	exportCooldown = 24 * time.Hour
+	// linkExpiryDays is how long the emailed download link stays valid
+	linkExpiryDays = 7
+	// processingTimeout is how long an export can be processing before it's assumed abandoned
+	processingTimeout = 30 * time.Minute
+	// processingBatchSize is how many exports are built per run
+	processingBatchSize = 5
+	// maxProcessingAttempts is how many times an export is tried before it's marked failed
+	maxProcessingAttempts = 3
+)
+
+var ErrExportRecentlyRequested = errors.New("an export was requested recently")
+
+type Service struct {
+	store            Store
+	bucketRepository bucket.Repository
+	resendClient     *resend.Client
+}
+
+func NewService(store Store, bucketRepository bucket.Repository, resendClient *resend.Client) *Service {
+	return &Service{
+		store:            store,
+		bucketRepository: bucketRepository,
+		resendClient:     resendClient,
+	}
+}
+
+// RequestExport queues an export of the current user's data. The archive is built by
+// ProcessPending and a download link is emailed to the user once it's ready.
+func (s *Service) RequestExport(ctx context.Context, currentUser models.PublicUser) error {
+	_, err := s.store.CreateExport(ctx, currentUser.UserID, time.Now().UTC().Add(-exportCooldown))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrExportRecentlyRequested
+	}
+	return err
+}
+
+// ProcessPending builds and emails one batch of queued exports.
+func (s *Service) ProcessPending(ctx context.Context) error {
+	pending, err := s.store.ClaimPending(ctx, time.Now().UTC().Add(-processingTimeout), processingBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range pending {
+		if export.Attempts > maxProcessingAttempts {
+			// the export was abandoned too many times, most likely by a crash
+			if err := s.store.FailExport(ctx, export.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.runExport(ctx, export); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredExports deletes the archives of exports whose download links have expired.
+func (s *Service) DeleteExpiredExports(ctx context.Context) error {
+	exports, err := s.store.GetExpiredExports(ctx, time.Now().AddDate(0, 0, -linkExpiryDays))
+	if err != nil {
+		return err
+	}
+	if len(exports) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(exports))
+	ids := make([]int, len(exports))
+	for i, export := range exports {
+		keys[i] = export.ObjectKey.String
+		ids[i] = export.ID
+	}
+
+	if err := s.bucketRepository.DeleteObjects(ctx, keys); err != nil {
+		return fmt.Errorf("failed to delete %d expired exports: %w", len(keys), err)
+	}
+
+	return s.store.ExpireExports(ctx, ids)
+}
+
+// runExport builds and emails a claimed export. A failed build is queued again until it runs out of
+// attempts; only errors updating the export itself are returned.
+func (s *Service) runExport(ctx context.Context, export queries.DataExport) error {
+	archive, objectKey, err := s.buildAndUpload(ctx, export.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "data export failed", "export_id", export.ID, "user_id", export.UserID, "attempt", export.Attempts, "error", err)
+		if export.Attempts >= maxProcessingAttempts {
+			return s.store.FailExport(ctx, export.ID)
+		}
+		return s.store.RequeueExport(ctx, export.ID)
+	}
+
+	if err := s.store.CompleteExport(ctx, export.ID, objectKey); err != nil {
+		return err
+	}
+
+	if err := s.sendExportEmail(ctx, archive.Profile, objectKey); err != nil {
+		slog.ErrorContext(ctx, "failed to send data export email", "export_id", export.ID, "error", err)
+	}
+
+	return nil
+}
+
+func (s *Service) buildAndUpload(ctx context.Context, userId int) (*Archive, string, error) {
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	archive, err := s.WriteArchive(ctx, userId, file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+
+	objectKey := bucket.ExportPrefix(os.Getenv("ENVIRONMENT"), userId) + uuid.NewString() + ".zip"
+	if err := s.bucketRepository.PutObject(ctx, objectKey, "application/zip", file); err != nil {
+		return nil, "", fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	return archive, objectKey, nil
+}
+
+// WriteArchive writes a ZIP archive of all of a user's data to w, laid out as described by Archive.
+func (s *Service) WriteArchive(ctx context.Context, userId int, w io.Writer) (*Archive, error) {
+	archive, err := s.collect(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := s.store.GetImages(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	zw := zip.NewWriter(w)
+
+	archive.Images = make([]Image, len(images))
+	for i, image := range images {
+		archive.Images[i] = Image{
+			ImageID: image.MediaID,
+			Width:   image.Width,
+			Height:  image.Height,
+		}
+
+		file := ImagesDir + fmt.Sprintf("%d%s", image.MediaID, path.Ext(image.BlobKey))
+		if err := s.copyImage(ctx, zw, image.BlobKey, file); err != nil {
+			// a missing image shouldn't prevent the rest of the data from being exported
+			slog.WarnContext(ctx, "failed to add image to data export", "image_id", image.MediaID, "error", err)
+			continue
+		}
+		archive.Images[i].File = file
+	}
+
+	data, err := zw.Create(DataFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func (s *Service) collect(ctx context.Context, userId int) (*Archive, error) {
+	archive := Archive{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() (err error) {
+		archive.Profile, err = s.store.GetProfile(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Posts, err = s.store.GetPosts(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Comments, err = s.store.GetComments(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Likes, err = s.store.GetLikes(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.PollVotes, err = s.store.GetPollVotes(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Following, err = s.store.GetFollowing(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Followers, err = s.store.GetFollowers(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.CloseFriends, err = s.store.GetCloseFriends(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Blocks, err = s.store.GetBlocks(gCtx, userId)
+		return err
+	})
+	g.Go(func() (err error) {
+		archive.Mutes, err = s.store.GetMutes(gCtx, userId)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &archive, nil
+}
+
+func (s *Service) copyImage(ctx context.Context, zw *zip.Writer, key string, file string) error {
+	body, err := s.bucketRepository.GetObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+
+	// images are already compressed, so store them as-is
+	dst, err := zw.CreateHeader(&zip.FileHeader{
+		Name:   file,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, body)
+	return err
+}
+
+func (s *Service) sendExportEmail(ctx context.Context, profile Profile, objectKey string) error {
+	// the link is a CDN url signed with the long-lived CloudFront key pair, so it stays valid for the
+	// whole expiry window rather than expiring with the server's temporary AWS credentials
+	url, err := s.bucketRepository.GetPresignedGetObjectWithExpiry(ctx, objectKey, linkExpiryDays*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	text, err := templates.GenerateDataExportEmail(profile.Username, url, linkExpiryDays)
+	if err != nil {
+		return err
+	}
+
+	params := &resend.SendEmailRequest{
+		From:    "Splajompy <no-reply@splajompy.com>",
+		To:      []string{profile.Email},
+		Subject: "Your Splajompy data is ready to download",
+		Text:    text,
+	}
+
+	_, err = s.resendClient.Emails.Send(params)
+	return err
+}
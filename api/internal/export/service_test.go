@@ -0,0 +1,289 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/export"
+	"splajompy.com/api/v2/internal/media"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/testutil"
+)
+
+type exportServiceTestEnv struct {
+	svc    *export.Service
+	db     *testutil.TestDB
+	bucket *bucket.FakeBucketRepository
+	resend *testutil.FakeResend
+}
+
+func setupExportTest(t *testing.T) exportServiceTestEnv {
+	t.Helper()
+	testDB := testutil.StartPostgres(t)
+
+	_ = os.Setenv("ENVIRONMENT", "test")
+
+	fakeBucket := &bucket.FakeBucketRepository{}
+	fakeResend := testutil.NewFakeResend(t)
+	svc := export.NewService(export.NewStore(testDB.Queries), fakeBucket, fakeResend.Client)
+
+	return exportServiceTestEnv{
+		svc:    svc,
+		db:     testDB,
+		bucket: fakeBucket,
+		resend: fakeResend,
+	}
+}
+
+func readArchive(t *testing.T, data []byte) (export.Archive, map[string][]byte) {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		contents, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		files[f.Name] = contents
+	}
+
+	var archive export.Archive
+	require.Contains(t, files, export.DataFileName)
+	require.NoError(t, json.Unmarshal(files[export.DataFileName], &archive))
+
+	return archive, files
+}
+
+func TestWriteArchive(t *testing.T) {
+	env := setupExportTest(t)
+	ctx := t.Context()
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.db.UserRepository, "user1")
+	user2 := testutil.CreateTestUser(t, env.db.UserRepository, "user2")
+
+	require.NoError(t, env.db.UserRepository.UpdateBio(ctx, user0.UserID, "hello"))
+
+	poll := &db.Attributes{Poll: db.Poll{Title: "best?", Options: []string{"a", "b"}}}
+	post0, err := env.db.PostRepository.InsertPost(ctx, user0.UserID, "post with poll", db.Facets{}, poll, nil, nil)
+	require.NoError(t, err)
+	post1, err := env.db.PostRepository.InsertPost(ctx, user1.UserID, "someone else's post", db.Facets{}, nil, nil, nil)
+	require.NoError(t, err)
+
+	_, err = env.db.PostRepository.InsertImage(ctx, post0.PostID, media.PublishedMedia{Key: "test/0/posts/1/a.jpg", Width: 200, Height: 100}, 0)
+	require.NoError(t, err)
+	_, err = env.db.PostRepository.InsertImage(ctx, post0.PostID, media.PublishedMedia{Key: "test/0/posts/1/missing.png", Width: 200, Height: 100}, 1)
+	require.NoError(t, err)
+	require.NoError(t, env.bucket.PutObject(ctx, "test/0/posts/1/a.jpg", "image/jpeg", strings.NewReader("jpeg bytes")))
+
+	comment, err := env.db.CommentRepository.AddCommentToPost(ctx, user0.UserID, post1.PostID, "nice", db.Facets{})
+	require.NoError(t, err)
+	require.NoError(t, env.db.LikeRepository.AddLike(ctx, user0.UserID, post1.PostID, nil))
+	require.NoError(t, env.db.LikeRepository.AddLike(ctx, user0.UserID, post1.PostID, &comment.CommentID))
+	require.NoError(t, env.db.PostRepository.InsertVote(ctx, post0.PostID, user0.UserID, 1))
+
+	require.NoError(t, env.db.UserRepository.FollowUser(ctx, user0.UserID, user1.UserID))
+	require.NoError(t, env.db.UserRepository.FollowUser(ctx, user1.UserID, user0.UserID))
+	require.NoError(t, env.db.UserRepository.AddUserRelationship(ctx, user0.UserID, user1.UserID))
+	require.NoError(t, env.db.UserRepository.BlockUser(ctx, user0.UserID, user2.UserID))
+	require.NoError(t, env.db.UserRepository.MuteUser(ctx, user0.UserID, user2.UserID, models.MuteModePosts, nil))
+
+	var buf bytes.Buffer
+	_, err = env.svc.WriteArchive(ctx, user0.UserID, &buf)
+	require.NoError(t, err)
+
+	archive, files := readArchive(t, buf.Bytes())
+
+	assert.Equal(t, export.SchemaVersion, archive.SchemaVersion)
+	assert.Equal(t, "user0", archive.Profile.Username)
+	assert.Equal(t, "user0@splajompy.com", archive.Profile.Email)
+	assert.Equal(t, "hello", archive.Profile.Bio)
+
+	require.Len(t, archive.Posts, 1)
+	assert.Equal(t, "post with poll", archive.Posts[0].Text)
+	require.NotNil(t, archive.Posts[0].Poll)
+	assert.Equal(t, []string{"a", "b"}, archive.Posts[0].Poll.Options)
+	assert.Len(t, archive.Posts[0].ImageIDs, 2)
+
+	require.Len(t, archive.Images, 2)
+	byFile := make(map[int]string)
+	for _, image := range archive.Images {
+		byFile[image.ImageID] = image.File
+	}
+	firstImage := byFile[archive.Posts[0].ImageIDs[0]]
+	assert.NotEmpty(t, firstImage)
+	assert.Equal(t, []byte("jpeg bytes"), files[firstImage])
+	assert.Empty(t, byFile[archive.Posts[0].ImageIDs[1]], "images missing from the bucket are listed without a file")
+
+	require.Len(t, archive.Comments, 1)
+	assert.Equal(t, "nice", archive.Comments[0].Text)
+	assert.Len(t, archive.Likes, 2)
+	require.Len(t, archive.PollVotes, 1)
+	assert.Equal(t, 1, archive.PollVotes[0].OptionIndex)
+
+	require.Len(t, archive.Following, 1)
+	assert.Equal(t, "user1", archive.Following[0].Username)
+	require.Len(t, archive.Followers, 1)
+	assert.Equal(t, "user1", archive.Followers[0].Username)
+	require.Len(t, archive.CloseFriends, 1)
+	require.Len(t, archive.Blocks, 1)
+	assert.Equal(t, "user2", archive.Blocks[0].Username)
+	require.Len(t, archive.Mutes, 1)
+}
+
+func TestWriteArchive_EmptyAccount(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+
+	var buf bytes.Buffer
+	_, err := env.svc.WriteArchive(t.Context(), user0.UserID, &buf)
+	require.NoError(t, err)
+
+	_, files := readArchive(t, buf.Bytes())
+
+	// empty collections are written as [] rather than null, so importers don't need to special-case them
+	assert.NotContains(t, string(files[export.DataFileName]), "null")
+}
+
+func TestRequestExport(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+
+	require.NoError(t, env.svc.RequestExport(t.Context(), user0))
+
+	latest, err := env.db.Queries.GetLatestDataExportForUser(t.Context(), user0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, export.StatusPending, latest.Status)
+	assert.Empty(t, env.resend.Emails(), "exports are built by ProcessPending")
+
+	require.NoError(t, env.svc.ProcessPending(t.Context()))
+	require.Len(t, env.resend.Emails(), 1)
+
+	email := env.resend.Emails()[0]
+	assert.Equal(t, []string{"user0@splajompy.com"}, email.To)
+
+	latest, err = env.db.Queries.GetLatestDataExportForUser(t.Context(), user0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, export.StatusComplete, latest.Status)
+	assert.Contains(t, email.Text, latest.ObjectKey.String)
+
+	// the fake bucket hands back the key as the signed url, so the archive can be read from it
+	body, err := env.bucket.GetObject(t.Context(), latest.ObjectKey.String)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	archive, _ := readArchive(t, data)
+	assert.Equal(t, "user0", archive.Profile.Username)
+}
+
+func TestRequestExport_RateLimited(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+
+	require.NoError(t, env.svc.RequestExport(t.Context(), user0))
+
+	err := env.svc.RequestExport(t.Context(), user0)
+	assert.ErrorIs(t, err, export.ErrExportRecentlyRequested)
+
+	require.NoError(t, env.svc.ProcessPending(t.Context()))
+	err = env.svc.RequestExport(t.Context(), user0)
+	assert.ErrorIs(t, err, export.ErrExportRecentlyRequested, "completed exports still count toward the cooldown")
+}
+
+func TestRequestExport_ConcurrentRequests(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+
+	const requests = 10
+	errs := make(chan error, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- env.svc.RequestExport(t.Context(), user0)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, export.ErrExportRecentlyRequested)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	var count int
+	require.NoError(t, env.db.Pool.QueryRow(t.Context(), "SELECT COUNT(*) FROM data_export WHERE user_id = $1", user0.UserID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestProcessPending_FailsAbandonedExports(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+	require.NoError(t, env.svc.RequestExport(t.Context(), user0))
+
+	// an export that was claimed and abandoned more times than allowed is given up on
+	_, err := env.db.Pool.Exec(t.Context(), "UPDATE data_export SET status = 'processing', attempts = 3, started_at = NOW() - INTERVAL '1 hour' WHERE user_id = $1", user0.UserID)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.ProcessPending(t.Context()))
+
+	latest, err := env.db.Queries.GetLatestDataExportForUser(t.Context(), user0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, export.StatusFailed, latest.Status)
+	assert.Empty(t, env.resend.Emails())
+
+	require.NoError(t, env.svc.RequestExport(t.Context(), user0), "failed exports don't count toward the cooldown")
+}
+
+func TestDeleteExpiredExports(t *testing.T) {
+	env := setupExportTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.db.UserRepository, "user0")
+	require.NoError(t, env.svc.RequestExport(t.Context(), user0))
+	require.NoError(t, env.svc.ProcessPending(t.Context()))
+
+	latest, err := env.db.Queries.GetLatestDataExportForUser(t.Context(), user0.UserID)
+	require.NoError(t, err)
+	objectKey := latest.ObjectKey.String
+
+	require.NoError(t, env.svc.DeleteExpiredExports(t.Context()))
+	_, err = env.bucket.GetObject(t.Context(), objectKey)
+	require.NoError(t, err, "exports are kept while their link is valid")
+
+	_, err = env.db.Pool.Exec(t.Context(), "UPDATE data_export SET completed_at = NOW() - INTERVAL '8 days' WHERE id = $1", latest.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.DeleteExpiredExports(t.Context()))
+	_, err = env.bucket.GetObject(t.Context(), objectKey)
+	assert.ErrorIs(t, err, bucket.ErrFakeObjectNotFound)
+
+	latest, err = env.db.Queries.GetLatestDataExportForUser(t.Context(), user0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, export.StatusExpired, latest.Status)
+	assert.False(t, latest.ObjectKey.Valid)
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"splajompy.com/api/v2/internal/db/queries"
+)
+
+type Store struct {
+	querier queries.Querier
+}
+
+func NewStore(querier queries.Querier) Store {
+	return Store{querier: querier}
+}
+
+// GetProfile retrieves the profile and bio of a user
+func (s Store) GetProfile(ctx context.Context, userId int) (Profile, error) {
+	user, err := s.querier.GetUserById(ctx, userId)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	bio, err := s.querier.GetBioByUserId(ctx, userId)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return Profile{}, err
+	}
+
+	return Profile{
+		UserID:    user.UserID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Name:      user.Name.String,
+		Bio:       bio,
+		CreatedAt: user.CreatedAt.Time.UTC(),
+	}, nil
+}
+
+// GetPosts retrieves every post written by a user, oldest first
+func (s Store) GetPosts(ctx context.Context, userId int) ([]Post, error) {
+	rows, err := s.querier.ExportGetPostsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRows, err := s.querier.ExportGetPostMediaIdsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	imageIds := make(map[int][]int)
+	for _, row := range imageRows {
+		imageIds[row.PostID] = append(imageIds[row.PostID], row.MediaID)
+	}
+
+	posts := make([]Post, len(rows))
+	for i, row := range rows {
+		posts[i] = Post{
+			PostID:     row.PostID,
+			Text:       row.Text.String,
+			Facets:     row.Facets,
+			Visibility: row.Visibilitytype,
+			ImageIDs:   imageIds[row.PostID],
+			CreatedAt:  row.CreatedAt.Time.UTC(),
+		}
+		if row.Attributes != nil {
+			posts[i].Poll = &row.Attributes.Poll
+		}
+		if posts[i].ImageIDs == nil {
+			posts[i].ImageIDs = []int{}
+		}
+	}
+
+	return posts, nil
+}
+
+// GetImages retrieves every image attached to a user's posts
+func (s Store) GetImages(ctx context.Context, userId int) ([]queries.Media, error) {
+	return s.querier.GetAllMediaByUserId(ctx, userId)
+}
+
+// GetComments retrieves every comment written by a user, oldest first
+func (s Store) GetComments(ctx context.Context, userId int) ([]Comment, error) {
+	rows, err := s.querier.ExportGetCommentsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, len(rows))
+	for i, row := range rows {
+		comments[i] = Comment{
+			CommentID: row.CommentID,
+			PostID:    row.PostID,
+			Text:      row.Text,
+			Facets:    row.Facets,
+			CreatedAt: row.CreatedAt.Time.UTC(),
+		}
+	}
+
+	return comments, nil
+}
+
+// GetLikes retrieves every like left by a user, oldest first
+func (s Store) GetLikes(ctx context.Context, userId int) ([]Like, error) {
+	rows, err := s.querier.ExportGetLikesByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	likes := make([]Like, len(rows))
+	for i, row := range rows {
+		likes[i] = Like{
+			PostID:    row.PostID,
+			CommentID: row.CommentID,
+			CreatedAt: row.CreatedAt.Time.UTC(),
+		}
+	}
+
+	return likes, nil
+}
+
+// GetPollVotes retrieves every poll vote cast by a user, oldest first
+func (s Store) GetPollVotes(ctx context.Context, userId int) ([]PollVote, error) {
+	rows, err := s.querier.ExportGetPollVotesByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]PollVote, len(rows))
+	for i, row := range rows {
+		votes[i] = PollVote{
+			PostID:      row.PostID,
+			OptionIndex: row.OptionIndex,
+			CreatedAt:   row.CreatedAt.Time.UTC(),
+		}
+	}
+
+	return votes, nil
+}
+
+// GetFollowing retrieves the users a user follows
+func (s Store) GetFollowing(ctx context.Context, userId int) ([]UserReference, error) {
+	rows, err := s.querier.ExportGetFollowingByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserReference, len(rows))
+	for i, row := range rows {
+		refs[i] = userReference(row.UserID, row.Username, row.CreatedAt)
+	}
+
+	return refs, nil
+}
+
+// GetFollowers retrieves the users following a user
+func (s Store) GetFollowers(ctx context.Context, userId int) ([]UserReference, error) {
+	rows, err := s.querier.ExportGetFollowersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserReference, len(rows))
+	for i, row := range rows {
+		refs[i] = userReference(row.UserID, row.Username, row.CreatedAt)
+	}
+
+	return refs, nil
+}
+
+// GetCloseFriends retrieves the users on a user's close friends list
+func (s Store) GetCloseFriends(ctx context.Context, userId int) ([]UserReference, error) {
+	rows, err := s.querier.ExportGetCloseFriendsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserReference, len(rows))
+	for i, row := range rows {
+		refs[i] = userReference(row.UserID, row.Username, row.CreatedAt)
+	}
+
+	return refs, nil
+}
+
+// GetBlocks retrieves the users a user has blocked
+func (s Store) GetBlocks(ctx context.Context, userId int) ([]UserReference, error) {
+	rows, err := s.querier.ExportGetBlockedUsersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserReference, len(rows))
+	for i, row := range rows {
+		refs[i] = userReference(row.UserID, row.Username, row.CreatedAt)
+	}
+
+	return refs, nil
+}
+
+// GetMutes retrieves the users a user has muted
+func (s Store) GetMutes(ctx context.Context, userId int) ([]UserReference, error) {
+	rows, err := s.querier.ExportGetMutedUsersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserReference, len(rows))
+	for i, row := range rows {
+		refs[i] = userReference(row.UserID, row.Username, row.CreatedAt)
+	}
+
+	return refs, nil
+}
+
+func userReference(userId int, username string, since pgtype.Timestamp) UserReference {
+	return UserReference{
+		UserID:   userId,
+		Username: username,
+		Since:    since.Time.UTC(),
+	}
+}
+
+// CreateExport records a new pending export for a user unless one is already in progress or was
+// requested after cooldownStart. It returns pgx.ErrNoRows when no export was created.
+func (s Store) CreateExport(ctx context.Context, userId int, cooldownStart time.Time) (queries.DataExport, error) {
+	return s.querier.InsertDataExport(ctx, queries.InsertDataExportParams{
+		UserID:        userId,
+		CooldownStart: cooldownStart,
+	})
+}
+
+// ClaimPending marks a batch of pending exports as being processed and returns them.
+// Exports whose processing started before staleBefore are assumed abandoned and claimed again.
+func (s Store) ClaimPending(ctx context.Context, staleBefore time.Time, batchSize int) ([]queries.DataExport, error) {
+	return s.querier.ClaimPendingDataExports(ctx, queries.ClaimPendingDataExportsParams{
+		StaleBefore: staleBefore,
+		BatchSize:   batchSize,
+	})
+}
+
+// RequeueExport marks an export as pending so it's picked up again
+func (s Store) RequeueExport(ctx context.Context, exportId int) error {
+	return s.querier.RequeueDataExport(ctx, exportId)
+}
+
+// CompleteExport marks an export as complete, stored at the given key
+func (s Store) CompleteExport(ctx context.Context, exportId int, objectKey string) error {
+	return s.querier.CompleteDataExport(ctx, queries.CompleteDataExportParams{
+		ID:        exportId,
+		ObjectKey: pgtype.Text{String: objectKey, Valid: true},
+	})
+}
+
+// FailExport marks an export as failed
+func (s Store) FailExport(ctx context.Context, exportId int) error {
+	return s.querier.FailDataExport(ctx, exportId)
+}
+
+// GetExpiredExports retrieves the exports completed before the given time whose archives are still stored
+func (s Store) GetExpiredExports(ctx context.Context, before time.Time) ([]queries.DataExport, error) {
+	return s.querier.GetExpiredDataExports(ctx, before)
+}
+
+// ExpireExports marks exports as expired once their archives have been deleted
+func (s Store) ExpireExports(ctx context.Context, exportIds []int) error {
+	return s.querier.ExpireDataExports(ctx, exportIds)
+}
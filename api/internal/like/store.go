@@ -46,6 +46,34 @@ func (r Store) GetOtherPostLikes(ctx context.Context, postId int, currentUserId
 	})
 }
 
+// GetOtherPostLikesForPosts is GetOtherPostLikes for each of postIds, keyed by post ID
+func (r Store) GetOtherPostLikesForPosts(ctx context.Context, postIds []int, currentUserId int) (map[int][]queries.GetPostLikesRow, error) {
+	rows, err := r.querier.GetPostLikesByPostIds(ctx, queries.GetPostLikesByPostIdsParams{
+		PostIds: postIds,
+		UserID:  currentUserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	likes := make(map[int][]queries.GetPostLikesRow)
+	for _, row := range rows {
+		likes[row.PostID] = append(likes[row.PostID], queries.GetPostLikesRow{
+			Username: row.Username,
+			UserID:   row.UserID,
+		})
+	}
+	return likes, nil
+}
+
+// GetLikedPostIds returns which of postIds a user has liked
+func (r Store) GetLikedPostIds(ctx context.Context, userId int, postIds []int) ([]int, error) {
+	return r.querier.GetLikedPostIds(ctx, queries.GetLikedPostIdsParams{
+		UserID:  userId,
+		PostIds: postIds,
+	})
+}
+
 // NewStore creates a new like repository
 func NewStore(querier queries.Querier) Store {
 	return Store{
@@ -0,0 +1,43 @@
+package media
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"splajompy.com/api/v2/internal/models"
+)
+
+// MaxAltTextLength is the longest description an attachment can have, in characters
+const MaxAltTextLength = 1500
+
+var (
+	ErrAltTextTooLong  = errors.New("image descriptions can be at most 1500 characters")
+	ErrAltTextRequired = errors.New("add a description to every image before posting")
+)
+
+// NormalizeAltText trims an attachment's description and checks its length. An empty result
+// means the attachment has no description.
+func NormalizeAltText(altText string) (string, error) {
+	altText = strings.TrimSpace(altText)
+	if utf8.RuneCountInString(altText) > MaxAltTextLength {
+		return "", ErrAltTextTooLong
+	}
+	return altText, nil
+}
+
+// RequireAltText returns ErrAltTextRequired if any attachment is missing a description.
+func RequireAltText(keymap map[int]models.ImageData) error {
+	for _, data := range keymap {
+		if strings.TrimSpace(data.AltText) == "" {
+			return ErrAltTextRequired
+		}
+	}
+	return nil
+}
+
+// AltTextParam converts a normalized description to its column value, storing no description as NULL.
+func AltTextParam(altText string) pgtype.Text {
+	return pgtype.Text{String: altText, Valid: altText != ""}
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const (
+	blurhashComponentsX = 4
+	blurhashComponentsY = 3
+	// blurhashSampleWidth is the width images are shrunk to before computing their blurhash;
+	// the placeholder is too blurry for more detail to matter.
+	blurhashSampleWidth = 32
+
+	base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+)
+
+// blurhash encodes a compact placeholder for an image (see https://blurha.sh).
+func blurhash(img *image.RGBA) string {
+	bounds := img.Bounds()
+	if bounds.Dx() > blurhashSampleWidth {
+		height := max(1, bounds.Dy()*blurhashSampleWidth/bounds.Dx())
+		img = resize(img, blurhashSampleWidth, height)
+		bounds = img.Bounds()
+	}
+	width, height := bounds.Dx(), bounds.Dy()
+
+	factors := make([][3]float64, 0, blurhashComponentsX*blurhashComponentsY)
+	for j := 0; j < blurhashComponentsY; j++ {
+		for i := 0; i < blurhashComponentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i*x)/float64(width)) *
+						math.Cos(math.Pi*float64(j*y)/float64(height))
+					offset := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+					factor[0] += basis * srgbToLinear(img.Pix[offset])
+					factor[1] += basis * srgbToLinear(img.Pix[offset+1])
+					factor[2] += basis * srgbToLinear(img.Pix[offset+2])
+				}
+			}
+
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	sizeFlag := (blurhashComponentsX - 1) + (blurhashComponentsY-1)*9
+	hash.WriteString(encodeBase83(sizeFlag, 1))
+
+	dc, ac := factors[0], factors[1:]
+
+	actualMaximum := 0.0
+	for _, factor := range ac {
+		for _, component := range factor {
+			actualMaximum = math.Max(actualMaximum, math.Abs(component))
+		}
+	}
+	quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+	maximumValue := float64(quantisedMaximum+1) / 166
+	hash.WriteString(encodeBase83(quantisedMaximum, 1))
+
+	dcValue := linearToSrgb(dc[0])<<16 + linearToSrgb(dc[1])<<8 + linearToSrgb(dc[2])
+	hash.WriteString(encodeBase83(dcValue, 4))
+
+	for _, factor := range ac {
+		quantised := [3]int{}
+		for c, component := range factor {
+			quantised[c] = int(math.Max(0, math.Min(18, math.Floor(signPow(component/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quantised[0]*19*19+quantised[1]*19+quantised[2], 2))
+	}
+
+	return hash.String()
+}
+
+func encodeBase83(value, length int) string {
+	result := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		result[i-1] = base83Characters[digit]
+	}
+	return string(result)
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSrgb(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
@@ -0,0 +1,120 @@
+package media
+
+import (
+	"context"
+
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+)
+
+// MapImage builds the response for a stored image, signing the URLs of the image and its variants.
+func MapImage(ctx context.Context, bucketRepository bucket.Repository, image queries.Media, postId int, displayOrder int) (models.DetailedImage, error) {
+	url, err := bucketRepository.GetPresignedGetObject(ctx, image.BlobKey)
+	if err != nil {
+		return models.DetailedImage{}, err
+	}
+
+	variants, err := mapVariants(ctx, bucketRepository, image)
+	if err != nil {
+		return models.DetailedImage{}, err
+	}
+
+	return models.DetailedImage{
+		ImageID:      image.MediaID,
+		PostId:       postId,
+		Height:       image.Height,
+		Width:        image.Width,
+		ImageBlobUrl: url,
+		DisplayOrder: displayOrder,
+		Blurhash:     textPointer(image.Blurhash.String, image.Blurhash.Valid),
+		Variants:     variants,
+		AltText:      textPointer(image.AltText.String, image.AltText.Valid),
+	}, nil
+}
+
+// MapMedia builds the response for a stored attachment of any type.
+func MapMedia(ctx context.Context, bucketRepository bucket.Repository, image queries.Media, displayOrder int) (models.DetailedMedia, error) {
+	url, err := bucketRepository.GetPresignedGetObject(ctx, image.BlobKey)
+	if err != nil {
+		return models.DetailedMedia{}, err
+	}
+
+	variants, err := mapVariants(ctx, bucketRepository, image)
+	if err != nil {
+		return models.DetailedMedia{}, err
+	}
+
+	var posterUrl *string
+	if image.PosterKey.Valid {
+		signed, err := bucketRepository.GetPresignedGetObject(ctx, image.PosterKey.String)
+		if err != nil {
+			return models.DetailedMedia{}, err
+		}
+		posterUrl = &signed
+	}
+
+	return models.DetailedMedia{
+		MediaID:      image.MediaID,
+		Type:         models.MediaType(image.MediaType),
+		Status:       models.MediaStatus(image.ProcessingStatus),
+		URL:          url,
+		Width:        image.Width,
+		Height:       image.Height,
+		DurationMs:   image.DurationMs,
+		PosterURL:    posterUrl,
+		Blurhash:     textPointer(image.Blurhash.String, image.Blurhash.Valid),
+		Variants:     variants,
+		DisplayOrder: displayOrder,
+		AltText:      textPointer(image.AltText.String, image.AltText.Valid),
+	}, nil
+}
+
+func mapVariants(ctx context.Context, bucketRepository bucket.Repository, image queries.Media) ([]models.DetailedImageVariant, error) {
+	variants := make([]models.DetailedImageVariant, 0, len(image.Variants))
+	for _, variant := range image.Variants {
+		url, err := bucketRepository.GetPresignedGetObject(ctx, variant.Key)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, models.DetailedImageVariant{
+			Width:  variant.Width,
+			Height: variant.Height,
+			URL:    url,
+		})
+	}
+	return variants, nil
+}
+
+// IsImage reports whether a stored attachment is a still image.
+func IsImage(image queries.Media) bool {
+	return models.MediaType(image.MediaType) == models.MediaTypeImage
+}
+
+// ThumbnailKey returns the key of a still image representing an attachment: the image itself,
+// or the poster frame of a processed video or GIF.
+func ThumbnailKey(image queries.Media) (string, bool) {
+	if IsImage(image) {
+		return image.BlobKey, true
+	}
+	return image.PosterKey.String, image.PosterKey.Valid
+}
+
+// BlobKeys returns every key stored for an attachment, including its variants and poster.
+func BlobKeys(image queries.Media) []string {
+	keys := []string{image.BlobKey}
+	for _, variant := range image.Variants {
+		keys = append(keys, variant.Key)
+	}
+	if image.PosterKey.Valid {
+		keys = append(keys, image.PosterKey.String)
+	}
+	return keys
+}
+
+func textPointer(value string, valid bool) *string {
+	if !valid {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,48 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+)
+
+// PosterExtractor grabs a still frame from a video to show before it plays.
+type PosterExtractor interface {
+	ExtractPoster(ctx context.Context, videoPath string) (image.Image, error)
+}
+
+// FFmpegPosterExtractor extracts posters with the ffmpeg command line tool, which also applies
+// the video's rotation.
+type FFmpegPosterExtractor struct{}
+
+// CheckFFmpeg reports an error when the ffmpeg binary that remuxes videos and extracts their
+// posters can't be found.
+func CheckFFmpeg() error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("ffmpeg is required to process videos: %w", err)
+	}
+	return nil
+}
+
+func (FFmpegPosterExtractor) ExtractPoster(ctx context.Context, videoPath string) (image.Image, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-f", "image2pipe",
+		"-c:v", "png",
+		"-",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, stderr.String())
+	}
+
+	return png.Decode(&stdout)
+}
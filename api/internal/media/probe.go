@@ -0,0 +1,217 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrInvalidMedia = errors.New("this file could not be processed")
+
+// Metadata describes an animated upload without decoding it.
+type Metadata struct {
+	Width    int
+	Height   int
+	Duration time.Duration
+}
+
+// ProbeGIF reads the dimensions and total frame delay of a GIF by walking its blocks, so that
+// limits can be checked before any frame is decoded.
+func ProbeGIF(data []byte) (Metadata, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return Metadata{}, ErrInvalidMedia
+	}
+
+	metadata := Metadata{
+		Width:  int(binary.LittleEndian.Uint16(data[6:])),
+		Height: int(binary.LittleEndian.Uint16(data[8:])),
+	}
+
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension
+			if i+2 > len(data) {
+				return Metadata{}, ErrInvalidMedia
+			}
+			// graphic control extension: the frame delay is in hundredths of a second
+			if data[i+1] == 0xF9 && i+6 <= len(data) {
+				metadata.Duration += time.Duration(binary.LittleEndian.Uint16(data[i+4:])) * 10 * time.Millisecond
+			}
+			next, err := skipSubBlocks(data, i+2)
+			if err != nil {
+				return Metadata{}, err
+			}
+			i = next
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return Metadata{}, ErrInvalidMedia
+			}
+			packed := data[i+9]
+			i += 10
+			if packed&0x80 != 0 {
+				i += 3 << (packed&0x07 + 1)
+			}
+			// skip the LZW minimum code size, then the image data
+			next, err := skipSubBlocks(data, i+1)
+			if err != nil {
+				return Metadata{}, err
+			}
+			i = next
+			frames++
+		case 0x3B: // trailer
+			i = len(data)
+		default:
+			return Metadata{}, ErrInvalidMedia
+		}
+	}
+
+	if frames == 0 || metadata.Width == 0 || metadata.Height == 0 {
+		return Metadata{}, ErrInvalidMedia
+	}
+	return metadata, nil
+}
+
+func skipSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, ErrInvalidMedia
+		}
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, nil
+		}
+		i += size
+	}
+}
+
+// ProbeMP4 reads the duration and display dimensions of an MP4 or QuickTime video from its
+// moov box. Rotated videos report their dimensions as displayed.
+func ProbeMP4(r io.ReaderAt, size int64) (Metadata, error) {
+	moov, ok, err := findBox(r, 0, size, "moov")
+	if err != nil || !ok {
+		return Metadata{}, ErrInvalidMedia
+	}
+
+	var metadata Metadata
+
+	mvhd, ok, err := findBox(r, moov.start, moov.end, "mvhd")
+	if err != nil || !ok {
+		return Metadata{}, ErrInvalidMedia
+	}
+	header := make([]byte, min(mvhd.end-mvhd.start, 32))
+	if _, err := r.ReadAt(header, mvhd.start); err != nil {
+		return Metadata{}, ErrInvalidMedia
+	}
+	var timescale, duration uint64
+	if header[0] == 1 && len(header) >= 32 {
+		timescale = uint64(binary.BigEndian.Uint32(header[20:]))
+		duration = binary.BigEndian.Uint64(header[24:])
+	} else if len(header) >= 20 {
+		timescale = uint64(binary.BigEndian.Uint32(header[12:]))
+		duration = uint64(binary.BigEndian.Uint32(header[16:]))
+	}
+	if timescale == 0 {
+		return Metadata{}, ErrInvalidMedia
+	}
+	metadata.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+
+	// the first track with dimensions is the video track
+	for offset := moov.start; offset < moov.end; {
+		trak, ok, err := findBox(r, offset, moov.end, "trak")
+		if err != nil || !ok {
+			break
+		}
+		offset = trak.end
+
+		tkhd, ok, err := findBox(r, trak.start, trak.end, "tkhd")
+		if err != nil || !ok {
+			continue
+		}
+		width, height, err := readTrackDimensions(r, tkhd)
+		if err != nil {
+			return Metadata{}, err
+		}
+		if width > 0 && height > 0 {
+			metadata.Width, metadata.Height = width, height
+			break
+		}
+	}
+
+	if metadata.Width == 0 || metadata.Height == 0 {
+		return Metadata{}, ErrInvalidMedia
+	}
+	return metadata, nil
+}
+
+func readTrackDimensions(r io.ReaderAt, tkhd box) (int, int, error) {
+	data := make([]byte, tkhd.end-tkhd.start)
+	if _, err := r.ReadAt(data, tkhd.start); err != nil || len(data) < 84 {
+		return 0, 0, ErrInvalidMedia
+	}
+
+	// version 1 uses 64 bit times and duration
+	matrix := 40
+	if data[0] == 1 {
+		matrix = 52
+	}
+	if len(data) < matrix+44 {
+		return 0, 0, ErrInvalidMedia
+	}
+
+	width := int(binary.BigEndian.Uint32(data[matrix+36:]) >> 16)
+	height := int(binary.BigEndian.Uint32(data[matrix+40:]) >> 16)
+
+	// a quarter turn in the transformation matrix means the video is displayed sideways
+	a := int32(binary.BigEndian.Uint32(data[matrix:]))
+	b := int32(binary.BigEndian.Uint32(data[matrix+4:]))
+	if a == 0 && b != 0 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// box is the payload of an ISO base media file box.
+type box struct {
+	start int64
+	end   int64
+}
+
+// findBox returns the first box of the given type between start and end, without descending
+// into other boxes.
+func findBox(r io.ReaderAt, start, end int64, boxType string) (box, bool, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return box{}, false, err
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0: // the box extends to the end of its parent
+			size = end - offset
+		case 1: // 64 bit size
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return box{}, false, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return box{}, false, ErrInvalidMedia
+		}
+
+		if string(header[4:8]) == boxType {
+			return box{start: offset + headerSize, end: offset + size}, true, nil
+		}
+		offset += size
+	}
+	return box{}, false, nil
+}
@@ -0,0 +1,101 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/media"
+)
+
+func encodeGIF(t *testing.T, width, height int, delays ...int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{}
+	for _, delay := range delays {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, width, height), palette))
+		animation.Delay = append(animation.Delay, delay)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, animation))
+	return buf.Bytes()
+}
+
+func mp4Box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(body)+8))
+	copy(header[4:], boxType)
+	return append(header, body...)
+}
+
+// testMP4 builds a version 0 moov box with a movie header and a single track header.
+func testMP4(timescale, duration uint32, width, height int, rotated bool) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:], duration)
+
+	tkhd := make([]byte, 84)
+	a, b := uint32(0x00010000), uint32(0)
+	if rotated {
+		a, b = 0, 0x00010000
+	}
+	binary.BigEndian.PutUint32(tkhd[40:], a)
+	binary.BigEndian.PutUint32(tkhd[44:], b)
+	binary.BigEndian.PutUint32(tkhd[76:], uint32(width)<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], uint32(height)<<16)
+
+	return append(
+		mp4Box("ftyp", []byte("isom\x00\x00\x02\x00")),
+		mp4Box("moov", mp4Box("mvhd", mvhd), mp4Box("trak", mp4Box("tkhd", tkhd)))...,
+	)
+}
+
+func TestProbeGIF_SumsFrameDelays(t *testing.T) {
+	metadata, err := media.ProbeGIF(encodeGIF(t, 30, 20, 50, 50, 25))
+	require.NoError(t, err)
+
+	assert.Equal(t, 30, metadata.Width)
+	assert.Equal(t, 20, metadata.Height)
+	assert.Equal(t, 1250*time.Millisecond, metadata.Duration)
+}
+
+func TestProbeGIF_RejectsInvalidData(t *testing.T) {
+	_, err := media.ProbeGIF([]byte("GIF89a but not really"))
+	assert.ErrorIs(t, err, media.ErrInvalidMedia)
+
+	data := encodeGIF(t, 10, 10, 10)
+	_, err = media.ProbeGIF(data[:len(data)/2])
+	assert.ErrorIs(t, err, media.ErrInvalidMedia)
+}
+
+func TestProbeMP4_ReadsDurationAndDimensions(t *testing.T) {
+	data := testMP4(600, 4500, 1920, 1080, false)
+	metadata, err := media.ProbeMP4(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1920, metadata.Width)
+	assert.Equal(t, 1080, metadata.Height)
+	assert.Equal(t, 7500*time.Millisecond, metadata.Duration)
+}
+
+func TestProbeMP4_SwapsDimensionsOfRotatedVideos(t *testing.T) {
+	data := testMP4(1000, 1000, 1920, 1080, true)
+	metadata, err := media.ProbeMP4(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1080, metadata.Width)
+	assert.Equal(t, 1920, metadata.Height)
+}
+
+func TestProbeMP4_RejectsFilesWithoutAMovieBox(t *testing.T) {
+	data := mp4Box("ftyp", []byte("isom\x00\x00\x02\x00"))
+	_, err := media.ProbeMP4(bytes.NewReader(data), int64(len(data)))
+	assert.ErrorIs(t, err, media.ErrInvalidMedia)
+}
@@ -0,0 +1,456 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+)
+
+const (
+	// MaxImageBytes is the largest upload that will be downloaded for processing
+	MaxImageBytes = 20 << 20
+	// MaxImagePixels bounds the memory needed to decode an upload
+	MaxImagePixels = 25_000_000
+
+	jpegQuality = 85
+)
+
+// variantWidths are the widths resized copies are generated at. Only widths smaller than the
+// original image are generated.
+var variantWidths = []int{320, 640, 1280}
+
+var (
+	ErrInvalidImage     = errors.New("this image could not be processed")
+	ErrUnsupportedImage = errors.New("only JPEG and PNG images are supported")
+	ErrImageTooLarge    = errors.New("this image is too large")
+)
+
+// IsRejected reports whether err means an upload was unacceptable, as opposed to a failure
+// while processing it.
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrInvalidImage) || errors.Is(err, ErrUnsupportedImage) || errors.Is(err, ErrImageTooLarge) ||
+		errors.Is(err, ErrAltTextTooLong) || errors.Is(err, ErrAltTextRequired) || isMediaRejected(err)
+}
+
+// Upload is a staged upload that has been validated and is ready to be published. Images are
+// re-encoded and videos remuxed up front; videos and GIFs get their posters later from the Worker.
+type Upload struct {
+	StagedKey string
+	Type      models.MediaType
+	Width     int
+	Height    int
+	Blurhash  string
+	AltText   string
+
+	format   string
+	original []byte
+	variants []encodedVariant
+	// file is the remuxed video, which is too large to keep in memory
+	file string
+}
+
+type encodedVariant struct {
+	width  int
+	height int
+	data   []byte
+}
+
+// PublishedMedia is an upload that has been written to its permanent location.
+type PublishedMedia struct {
+	Key      string
+	Type     models.MediaType
+	Status   models.MediaStatus
+	Width    int
+	Height   int
+	Blurhash string
+	Variants db.ImageVariants
+	AltText  string
+}
+
+// InsertParams returns the row to store for the published media.
+func (p PublishedMedia) InsertParams() queries.InsertMediaParams {
+	mediaType, status, variants := p.Type, p.Status, p.Variants
+	if mediaType == "" {
+		mediaType = models.MediaTypeImage
+	}
+	if status == "" {
+		status = models.MediaStatusReady
+	}
+	if variants == nil {
+		variants = db.ImageVariants{}
+	}
+
+	return queries.InsertMediaParams{
+		Height:           p.Height,
+		Width:            p.Width,
+		BlobKey:          p.Key,
+		Blurhash:         pgtype.Text{String: p.Blurhash, Valid: p.Blurhash != ""},
+		Variants:         variants,
+		MediaType:        string(mediaType),
+		ProcessingStatus: string(status),
+		AltText:          AltTextParam(p.AltText),
+	}
+}
+
+// Keys returns every key written when the media was published, including its variants.
+func (p PublishedMedia) Keys() []string {
+	keys := []string{p.Key}
+	for _, variant := range p.Variants {
+		keys = append(keys, variant.Key)
+	}
+	return keys
+}
+
+// PublishedKeys returns every key written when the media was published.
+func PublishedKeys(published map[int]PublishedMedia) []string {
+	var keys []string
+	for _, item := range published {
+		keys = append(keys, item.Keys()...)
+	}
+	return keys
+}
+
+// Processor turns staged uploads into media that is safe to serve: metadata is stripped,
+// dimensions are measured rather than trusted, and images get resized variants.
+type Processor struct {
+	bucketRepository bucket.Repository
+}
+
+func NewProcessor(bucketRepository bucket.Repository) *Processor {
+	return &Processor{bucketRepository: bucketRepository}
+}
+
+// Prepare downloads and processes a user's staged images. Nothing is written to the bucket,
+// so callers can reject a request with invalid images before creating anything.
+func (p *Processor) Prepare(ctx context.Context, userId int, imageKeymap map[int]models.ImageData) (map[int]*Upload, error) {
+	return p.prepare(ctx, userId, imageKeymap, false)
+}
+
+// PrepareMedia is Prepare for attachments that may also be videos or GIFs.
+func (p *Processor) PrepareMedia(ctx context.Context, userId int, mediaKeymap map[int]models.ImageData) (map[int]*Upload, error) {
+	return p.prepare(ctx, userId, mediaKeymap, true)
+}
+
+func (p *Processor) prepare(ctx context.Context, userId int, keymap map[int]models.ImageData, allowAnimated bool) (map[int]*Upload, error) {
+	uploads := make(map[int]*Upload, len(keymap))
+	for i, data := range keymap {
+		upload, err := p.prepareUpload(ctx, userId, data, allowAnimated)
+		if err != nil {
+			Discard(uploads)
+			return nil, err
+		}
+		uploads[i] = upload
+	}
+	return uploads, nil
+}
+
+func (p *Processor) prepareUpload(ctx context.Context, userId int, data models.ImageData, allowAnimated bool) (*Upload, error) {
+	if !bucket.IsStagedKeyForUser(userId, data.S3Key) {
+		return nil, ErrInvalidImage
+	}
+
+	altText, err := NormalizeAltText(data.AltText)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType := bucket.MediaTypeForKey(data.S3Key)
+	if mediaType != models.MediaTypeImage {
+		if !allowAnimated {
+			return nil, ErrUnsupportedImage
+		}
+		upload, err := p.prepareAnimated(ctx, data.S3Key, mediaType)
+		if err != nil {
+			return nil, err
+		}
+		upload.StagedKey = data.S3Key
+		upload.AltText = altText
+		return upload, nil
+	}
+
+	encoded, err := p.download(ctx, data.S3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := Process(encoded)
+	if err != nil {
+		return nil, err
+	}
+	upload.StagedKey = data.S3Key
+	upload.AltText = altText
+	return upload, nil
+}
+
+// prepareAnimated checks a staged GIF or video against the size and duration limits. Videos are
+// remuxed so they're published without their metadata.
+func (p *Processor) prepareAnimated(ctx context.Context, key string, mediaType models.MediaType) (*Upload, error) {
+	if mediaType == models.MediaTypeGIF {
+		data, err := download(ctx, p.bucketRepository, key, MaxGIFBytes)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := checkGIF(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Upload{Type: mediaType, Width: metadata.Width, Height: metadata.Height, original: data}, nil
+	}
+
+	file, err := downloadToFile(ctx, p.bucketRepository, key, MaxVideoBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	metadata, err := checkVideo(file)
+	if err != nil {
+		return nil, err
+	}
+	remuxed, err := remux(ctx, file.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Upload{Type: mediaType, Width: metadata.Width, Height: metadata.Height, file: remuxed}, nil
+}
+
+// Discard removes the temporary files held by uploads from PrepareMedia. It must be called once
+// they've been published or abandoned.
+func Discard(uploads map[int]*Upload) {
+	for _, upload := range uploads {
+		if upload.file != "" {
+			os.Remove(upload.file)
+		}
+	}
+}
+
+// Publish writes prepared uploads next to where the staged upload would have been published,
+// e.g. production/{userId}/post/{postId}/{fileName}.jpg, along with any image variants. If any
+// upload can't be published, whatever was already written is deleted again.
+func (p *Processor) Publish(ctx context.Context, userId int, blobType string, identifier int, uploads map[int]*Upload) (map[int]PublishedMedia, error) {
+	published := make(map[int]PublishedMedia, len(uploads))
+	var written []string
+	for i, upload := range uploads {
+		item, keys, err := p.publish(ctx, userId, blobType, identifier, upload)
+		written = append(written, keys...)
+		if err != nil {
+			if deleteErr := p.bucketRepository.DeleteObjects(context.WithoutCancel(ctx), written); deleteErr != nil {
+				slog.ErrorContext(ctx, "unable to delete partially published media", "error", deleteErr)
+			}
+			return nil, err
+		}
+		published[i] = item
+	}
+	return published, nil
+}
+
+// PublishOriginal is Publish for a single image that's only ever stored at its original size,
+// such as an avatar or banner, so no variants are written.
+func (p *Processor) PublishOriginal(ctx context.Context, userId int, blobType string, identifier int, upload *Upload) (string, error) {
+	original := *upload
+	original.variants = nil
+
+	published, err := p.Publish(ctx, userId, blobType, identifier, map[int]*Upload{0: &original})
+	if err != nil {
+		return "", err
+	}
+	return published[0].Key, nil
+}
+
+// publish writes a single upload, returning every key written even if it fails partway.
+func (p *Processor) publish(ctx context.Context, userId int, blobType string, identifier int, upload *Upload) (PublishedMedia, []string, error) {
+	destinationKey := bucket.GetDestinationKey(userId, blobType, identifier, upload.StagedKey)
+	if upload.Type != models.MediaTypeImage {
+		if err := p.putAnimated(ctx, destinationKey, upload); err != nil {
+			return PublishedMedia{}, nil, err
+		}
+		return PublishedMedia{
+			Key:     destinationKey,
+			Type:    upload.Type,
+			Status:  models.MediaStatusPending,
+			Width:   upload.Width,
+			Height:  upload.Height,
+			AltText: upload.AltText,
+		}, []string{destinationKey}, nil
+	}
+
+	base := strings.TrimSuffix(destinationKey, path.Ext(destinationKey))
+	contentType := "image/" + upload.format
+
+	key := base + "." + extension(upload.format)
+	if err := p.bucketRepository.PutObject(ctx, key, contentType, bytes.NewReader(upload.original)); err != nil {
+		return PublishedMedia{}, nil, err
+	}
+	written := []string{key}
+
+	variants := make(db.ImageVariants, 0, len(upload.variants))
+	for _, variant := range upload.variants {
+		variantKey := fmt.Sprintf("%s_%d.%s", base, variant.width, extension(upload.format))
+		if err := p.bucketRepository.PutObject(ctx, variantKey, contentType, bytes.NewReader(variant.data)); err != nil {
+			return PublishedMedia{}, written, err
+		}
+		written = append(written, variantKey)
+		variants = append(variants, db.ImageVariant{
+			Key:    variantKey,
+			Width:  variant.width,
+			Height: variant.height,
+		})
+	}
+
+	return PublishedMedia{
+		Key:      key,
+		Type:     models.MediaTypeImage,
+		Status:   models.MediaStatusReady,
+		Width:    upload.Width,
+		Height:   upload.Height,
+		Blurhash: upload.Blurhash,
+		Variants: variants,
+		AltText:  upload.AltText,
+	}, written, nil
+}
+
+func (p *Processor) putAnimated(ctx context.Context, key string, upload *Upload) error {
+	if upload.Type == models.MediaTypeGIF {
+		return p.bucketRepository.PutObject(ctx, key, "image/gif", bytes.NewReader(upload.original))
+	}
+
+	file, err := os.Open(upload.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	contentType := "video/mp4"
+	if strings.ToLower(path.Ext(key)) == ".mov" {
+		contentType = "video/quicktime"
+	}
+	return p.bucketRepository.PutObject(ctx, key, contentType, file)
+}
+
+func (p *Processor) download(ctx context.Context, key string) ([]byte, error) {
+	body, err := p.bucketRepository.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read staged image: %w", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read staged image: %w", err)
+	}
+	if len(data) > MaxImageBytes {
+		return nil, ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// Process validates an encoded image and re-encodes it upright and without metadata, along
+// with its resized variants and blurhash placeholder.
+func Process(data []byte) (*Upload, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	pixels := toRGBA(decoded)
+	if format == "jpeg" {
+		pixels = applyOrientation(pixels, jpegOrientation(data))
+	}
+
+	bounds := pixels.Bounds()
+	result := &Upload{
+		Type:   models.MediaTypeImage,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		format: format,
+	}
+
+	result.original, err = encode(pixels, format)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, width := range variantWidths {
+		if width >= result.Width {
+			break
+		}
+		height := max(1, result.Height*width/result.Width)
+
+		data, err := encode(resize(pixels, width, height), format)
+		if err != nil {
+			return nil, err
+		}
+		result.variants = append(result.variants, encodedVariant{
+			width:  width,
+			height: height,
+			data:   data,
+		})
+	}
+
+	result.Blurhash = blurhash(pixels)
+
+	return result, nil
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+func encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func extension(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
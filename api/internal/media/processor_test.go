@@ -0,0 +1,264 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/media"
+	"splajompy.com/api/v2/internal/models"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment with the given orientation and a GPS tag marker
+// right after the JPEG's start of image marker.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12*2+4)
+	binary.BigEndian.PutUint16(ifd[0:], 2)
+	// orientation, SHORT
+	binary.BigEndian.PutUint16(ifd[2:], 0x0112)
+	binary.BigEndian.PutUint16(ifd[4:], 3)
+	binary.BigEndian.PutUint32(ifd[6:], 1)
+	binary.BigEndian.PutUint16(ifd[10:], orientation)
+	// GPS IFD pointer, LONG
+	binary.BigEndian.PutUint16(ifd[14:], 0x8825)
+	binary.BigEndian.PutUint16(ifd[16:], 4)
+	binary.BigEndian.PutUint32(ifd[18:], 1)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestProcess_StripsMetadataAndAppliesOrientation(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, testImage(400, 200)), 6)
+
+	processed, err := media.Process(data)
+	require.NoError(t, err)
+
+	// orientation 6 rotates the image a quarter turn
+	assert.Equal(t, 200, processed.Width)
+	assert.Equal(t, 400, processed.Height)
+
+	fake := &bucket.FakeBucketRepository{}
+	published, err := media.NewProcessor(fake).Publish(t.Context(), 1, "post", 1, map[int]*media.Upload{0: processed})
+	require.NoError(t, err)
+
+	body, err := fake.GetObject(t.Context(), published[0].Key)
+	require.NoError(t, err)
+	stored, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stored), "Exif")
+	config, format, err := image.DecodeConfig(bytes.NewReader(stored))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 200, config.Width)
+	assert.Equal(t, 400, config.Height)
+}
+
+func TestProcess_GeneratesVariantsAndBlurhash(t *testing.T) {
+	processed, err := media.Process(encodeJPEG(t, testImage(1000, 500)))
+	require.NoError(t, err)
+
+	published, err := media.NewProcessor(&bucket.FakeBucketRepository{}).Publish(t.Context(), 1, "post", 1, map[int]*media.Upload{0: processed})
+	require.NoError(t, err)
+
+	// only variants smaller than the original are generated
+	variants := published[0].Variants
+	require.Len(t, variants, 2)
+	assert.Equal(t, 320, variants[0].Width)
+	assert.Equal(t, 160, variants[0].Height)
+	assert.Equal(t, 640, variants[1].Width)
+	assert.Equal(t, 320, variants[1].Height)
+
+	// 4x3 components: a size flag of "L" followed by 27 characters
+	assert.Len(t, published[0].Blurhash, 28)
+	assert.Equal(t, byte('L'), published[0].Blurhash[0])
+}
+
+func TestProcess_KeepsPNGs(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(50, 40)))
+
+	processed, err := media.Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 50, processed.Width)
+	assert.Equal(t, 40, processed.Height)
+
+	published, err := media.NewProcessor(&bucket.FakeBucketRepository{}).Publish(t.Context(), 1, "post", 1, map[int]*media.Upload{0: processed})
+	require.NoError(t, err)
+	assert.Equal(t, ".png", published[0].Key[len(published[0].Key)-4:])
+	assert.Empty(t, published[0].Variants)
+}
+
+func TestProcess_RejectsInvalidImages(t *testing.T) {
+	_, err := media.Process([]byte("definitely not an image"))
+	assert.ErrorIs(t, err, media.ErrInvalidImage)
+
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, testImage(10, 10), nil))
+	_, err = media.Process(buf.Bytes())
+	assert.ErrorIs(t, err, media.ErrUnsupportedImage)
+
+	// the header claims far more pixels than allowed, so the body is never decoded
+	huge := encodeJPEG(t, testImage(8, 8))
+	sof := bytes.Index(huge, []byte{0xFF, 0xC0})
+	require.NotEqual(t, -1, sof)
+	binary.BigEndian.PutUint16(huge[sof+5:], 10000)
+	binary.BigEndian.PutUint16(huge[sof+7:], 10000)
+	_, err = media.Process(huge)
+	assert.ErrorIs(t, err, media.ErrImageTooLarge)
+}
+
+func TestPrepare_OnlyAcceptsTheUsersStagedUploads(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+
+	fake := &bucket.FakeBucketRepository{}
+	key := bucket.StagingPrefix("test", 2) + "posts/a.jpg"
+	require.NoError(t, fake.PutObject(t.Context(), key, "image/jpeg", bytes.NewReader(encodeJPEG(t, testImage(10, 10)))))
+
+	processor := media.NewProcessor(fake)
+
+	_, err := processor.Prepare(t.Context(), 1, map[int]models.ImageData{0: {S3Key: key}})
+	assert.ErrorIs(t, err, media.ErrInvalidImage)
+
+	images, err := processor.Prepare(t.Context(), 2, map[int]models.ImageData{0: {S3Key: key, Width: 1, Height: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, 10, images[0].Width)
+	assert.Equal(t, 10, images[0].Height)
+
+	published, err := processor.Publish(t.Context(), 2, "post", 7, images)
+	require.NoError(t, err)
+	assert.Equal(t, "test/2/post/7/a.jpg", published[0].Key)
+}
+
+func TestPrepare_KeepsAltText(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+
+	fake := &bucket.FakeBucketRepository{}
+	key := bucket.StagingPrefix("test", 1) + "posts/a.jpg"
+	require.NoError(t, fake.PutObject(t.Context(), key, "image/jpeg", bytes.NewReader(encodeJPEG(t, testImage(10, 10)))))
+
+	processor := media.NewProcessor(fake)
+
+	_, err := processor.Prepare(t.Context(), 1, map[int]models.ImageData{0: {S3Key: key, AltText: strings.Repeat("a", media.MaxAltTextLength+1)}})
+	assert.ErrorIs(t, err, media.ErrAltTextTooLong)
+
+	images, err := processor.Prepare(t.Context(), 1, map[int]models.ImageData{0: {S3Key: key, AltText: "  a gradient  "}})
+	require.NoError(t, err)
+
+	published, err := processor.Publish(t.Context(), 1, "post", 1, images)
+	require.NoError(t, err)
+	assert.Equal(t, "a gradient", published[0].AltText)
+	assert.Equal(t, "a gradient", published[0].InsertParams().AltText.String)
+}
+
+func TestRequireAltText(t *testing.T) {
+	assert.NoError(t, media.RequireAltText(map[int]models.ImageData{0: {AltText: "a cat"}}))
+	assert.ErrorIs(t, media.RequireAltText(map[int]models.ImageData{0: {AltText: "a cat"}, 1: {AltText: " "}}), media.ErrAltTextRequired)
+}
+
+// failingBucket fails every write after the first few.
+type failingBucket struct {
+	*bucket.FakeBucketRepository
+	writesLeft int
+}
+
+func (b *failingBucket) PutObject(ctx context.Context, key string, contentType string, body io.ReadSeeker) error {
+	if b.writesLeft == 0 {
+		return errors.New("write failed")
+	}
+	b.writesLeft--
+	return b.FakeBucketRepository.PutObject(ctx, key, contentType, body)
+}
+
+func TestPublish_DeletesPartiallyPublishedMedia(t *testing.T) {
+	processed, err := media.Process(encodeJPEG(t, testImage(1000, 500)))
+	require.NoError(t, err)
+
+	fake := &failingBucket{FakeBucketRepository: &bucket.FakeBucketRepository{}, writesLeft: 2}
+	_, err = media.NewProcessor(fake).Publish(t.Context(), 1, "post", 1, map[int]*media.Upload{0: processed})
+	require.Error(t, err)
+
+	objects, err := fake.ListObjects(t.Context(), "")
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestPrepareMedia_ChecksGIFsBeforePublishing(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+
+	fake := &bucket.FakeBucketRepository{}
+	processor := media.NewProcessor(fake)
+
+	tooLong := bucket.StagingPrefix("test", 1) + "posts/long.gif"
+	require.NoError(t, fake.PutObject(t.Context(), tooLong, "image/gif", bytes.NewReader(encodeGIF(t, 10, 10, 10000, 10000))))
+	_, err := processor.PrepareMedia(t.Context(), 1, map[int]models.ImageData{0: {S3Key: tooLong}})
+	assert.ErrorIs(t, err, media.ErrMediaTooLong)
+	assert.True(t, media.IsRejected(err))
+
+	key := bucket.StagingPrefix("test", 1) + "posts/short.gif"
+	require.NoError(t, fake.PutObject(t.Context(), key, "image/gif", bytes.NewReader(encodeGIF(t, 40, 30, 50, 50))))
+	uploads, err := processor.PrepareMedia(t.Context(), 1, map[int]models.ImageData{0: {S3Key: key}})
+	require.NoError(t, err)
+	defer media.Discard(uploads)
+
+	published, err := processor.Publish(t.Context(), 1, "post", 1, uploads)
+	require.NoError(t, err)
+	assert.Equal(t, "test/1/post/1/short.gif", published[0].Key)
+	assert.Equal(t, models.MediaStatusPending, published[0].Status)
+	assert.Equal(t, 40, published[0].Width)
+	assert.Equal(t, 30, published[0].Height)
+}
+
+func TestPublishOriginal_WritesNoVariants(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+
+	processed, err := media.Process(encodeJPEG(t, testImage(1000, 500)))
+	require.NoError(t, err)
+	processed.StagedKey = bucket.StagingPrefix("test", 1) + "avatar/a.jpg"
+
+	fake := &bucket.FakeBucketRepository{}
+	key, err := media.NewProcessor(fake).PublishOriginal(t.Context(), 1, "avatar", 1, processed)
+	require.NoError(t, err)
+	assert.Equal(t, "test/1/avatar/1/a.jpg", key)
+
+	objects, err := fake.ListObjects(t.Context(), "")
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+)
+
+// remux copies a video's first video and audio streams into a new temporary file, which the
+// caller must remove. Nothing is re-encoded, but the container's metadata, such as where and
+// when the video was recorded, and any other streams, such as GPS tracks, are left behind.
+func remux(ctx context.Context, videoPath string) (string, error) {
+	output, err := os.CreateTemp("", "remuxed-*"+path.Ext(videoPath))
+	if err != nil {
+		return "", err
+	}
+	output.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-i", videoPath,
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+		"-c", "copy",
+		"-map_metadata", "-1",
+		"-map_chapters", "-1",
+		"-movflags", "+faststart",
+		"-y", output.Name(),
+	)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(output.Name())
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%w: ffmpeg failed: %v: %s", ErrInvalidMedia, err, stderr.String())
+	}
+	return output.Name(), nil
+}
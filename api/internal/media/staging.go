@@ -0,0 +1,86 @@
+package media
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"splajompy.com/api/v2/internal/bucket"
+)
+
+const (
+	// StagedUploadTTL is how long an upload that was never published is kept in staging
+	StagedUploadTTL = 24 * time.Hour
+
+	// stagingLookupBatchSize bounds how many file names are looked up in one query
+	stagingLookupBatchSize = 500
+)
+
+// StagingCleaner removes uploads from the staging area. Publishing copies an upload out of
+// staging, so published uploads are deleted right away, and uploads that were never published
+// are deleted once they expire.
+type StagingCleaner struct {
+	store            Store
+	bucketRepository bucket.Repository
+}
+
+func NewStagingCleaner(store Store, bucketRepository bucket.Repository) *StagingCleaner {
+	return &StagingCleaner{
+		store:            store,
+		bucketRepository: bucketRepository,
+	}
+}
+
+// CleanStaging deletes every staged upload that has been published or has expired.
+func (c *StagingCleaner) CleanStaging(ctx context.Context) error {
+	objects, err := c.bucketRepository.ListObjects(ctx, bucket.StagingRoot(os.Getenv("ENVIRONMENT")))
+	if err != nil {
+		return err
+	}
+
+	expireBefore := time.Now().Add(-StagedUploadTTL)
+
+	var expired []string
+	for start := 0; start < len(objects); start += stagingLookupBatchSize {
+		batch := objects[start:min(start+stagingLookupBatchSize, len(objects))]
+
+		names := make([]string, 0, len(batch))
+		for _, object := range batch {
+			names = append(names, uploadName(object.Key))
+		}
+		published, err := c.store.GetPublishedUploadNames(ctx, names)
+		if err != nil {
+			return err
+		}
+		isPublished := make(map[string]bool, len(published))
+		for _, name := range published {
+			isPublished[name] = true
+		}
+
+		for _, object := range batch {
+			if isPublished[uploadName(object.Key)] || object.LastModified.Before(expireBefore) {
+				expired = append(expired, object.Key)
+			}
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+	if err := c.bucketRepository.DeleteObjects(ctx, expired); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "cleaned staged uploads", "deleted", len(expired), "remaining", len(objects)-len(expired))
+	return nil
+}
+
+// uploadName returns the file name of a staged upload without its extension, which published
+// copies keep.
+func uploadName(key string) string {
+	name := path.Base(key)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
@@ -0,0 +1,46 @@
+package media_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/media"
+	"splajompy.com/api/v2/internal/testutil"
+)
+
+func TestCleanStaging_DeletesPublishedAndExpiredUploads(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+	testDB := testutil.StartPostgres(t)
+	fake := &bucket.FakeBucketRepository{}
+
+	staging := bucket.StagingPrefix("test", 1) + "posts/"
+	for _, name := range []string{"published.jpg", "abandoned.jpg", "pending.jpg"} {
+		require.NoError(t, fake.PutObject(t.Context(), staging+name, "image/jpeg", bytes.NewReader([]byte("data"))))
+	}
+	fake.SetLastModified(staging+"abandoned.jpg", time.Now().Add(-2*media.StagedUploadTTL))
+
+	// processed images keep the name of the staged upload, but not necessarily its extension
+	_, err := testDB.Queries.InsertMedia(t.Context(), queries.InsertMediaParams{
+		Height:           1,
+		Width:            1,
+		BlobKey:          "test/1/post/1/published.png",
+		Variants:         db.ImageVariants{},
+		MediaType:        "image",
+		ProcessingStatus: "ready",
+	})
+	require.NoError(t, err)
+
+	cleaner := media.NewStagingCleaner(media.NewStore(testDB.Queries), fake)
+	require.NoError(t, cleaner.CleanStaging(t.Context()))
+
+	objects, err := fake.ListObjects(t.Context(), bucket.StagingRoot("test"))
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, staging+"pending.jpg", objects[0].Key)
+}
@@ -0,0 +1,54 @@
+package media
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+)
+
+type Store struct {
+	querier queries.Querier
+}
+
+func NewStore(querier queries.Querier) Store {
+	return Store{querier: querier}
+}
+
+// ClaimUnprocessed marks a batch of pending attachments as being processed and returns them.
+// Attachments whose processing started before staleBefore are assumed abandoned and claimed again.
+func (s Store) ClaimUnprocessed(ctx context.Context, staleBefore time.Time, batchSize int) ([]queries.Media, error) {
+	return s.querier.ClaimUnprocessedMedia(ctx, queries.ClaimUnprocessedMediaParams{
+		StaleBefore: staleBefore,
+		BatchSize:   batchSize,
+	})
+}
+
+// Complete stores the probed details of an attachment and marks it ready
+func (s Store) Complete(ctx context.Context, imageId int, metadata Metadata, posterKey string, blurhash string) error {
+	durationMs := int(metadata.Duration.Milliseconds())
+	return s.querier.CompleteMediaProcessing(ctx, queries.CompleteMediaProcessingParams{
+		MediaID:    imageId,
+		Width:      metadata.Width,
+		Height:     metadata.Height,
+		DurationMs: &durationMs,
+		PosterKey:  pgtype.Text{String: posterKey, Valid: posterKey != ""},
+		Blurhash:   pgtype.Text{String: blurhash, Valid: blurhash != ""},
+	})
+}
+
+// SetStatus changes the processing status of an attachment
+func (s Store) SetStatus(ctx context.Context, imageId int, status models.MediaStatus) error {
+	return s.querier.SetMediaProcessingStatus(ctx, queries.SetMediaProcessingStatusParams{
+		MediaID:          imageId,
+		ProcessingStatus: string(status),
+	})
+}
+
+// GetPublishedUploadNames returns which of the given upload file names, without extensions, have
+// been published as an attachment, avatar or banner.
+func (s Store) GetPublishedUploadNames(ctx context.Context, names []string) ([]string, error) {
+	return s.querier.GetPublishedUploadNames(ctx, names)
+}
@@ -0,0 +1,234 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+)
+
+const (
+	// MaxGIFBytes is the largest GIF that can be attached
+	MaxGIFBytes = 15 << 20
+	// MaxVideoBytes is the largest video that can be attached
+	MaxVideoBytes = 200 << 20
+	// MaxDuration is the longest video or GIF that can be attached
+	MaxDuration = 2 * time.Minute
+	// maxVideoDimension is the longest side a video can have, enough for 4K
+	maxVideoDimension = 4096
+
+	// processingTimeout is how long an attachment can be processing before another worker retries it
+	processingTimeout = 15 * time.Minute
+	// maxProcessingAttempts is how many times processing is tried before an attachment is marked failed
+	maxProcessingAttempts = 3
+	processingBatchSize   = 10
+)
+
+var (
+	ErrMediaTooLarge = errors.New("this file is too large")
+	ErrMediaTooLong  = errors.New("this file is too long")
+)
+
+// Worker processes attached videos and GIFs in the background: it records their dimensions and
+// duration and extracts a poster frame. They're checked against the size and duration limits
+// before being published, and again here so nothing over the limits is marked ready.
+type Worker struct {
+	store            Store
+	bucketRepository bucket.Repository
+	posterExtractor  PosterExtractor
+}
+
+func NewWorker(store Store, bucketRepository bucket.Repository, posterExtractor PosterExtractor) *Worker {
+	return &Worker{
+		store:            store,
+		bucketRepository: bucketRepository,
+		posterExtractor:  posterExtractor,
+	}
+}
+
+// ProcessPending processes one batch of unprocessed attachments.
+func (w *Worker) ProcessPending(ctx context.Context) error {
+	pending, err := w.store.ClaimUnprocessed(ctx, time.Now().UTC().Add(-processingTimeout), processingBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range pending {
+		status := models.MediaStatusReady
+		if item.ProcessingAttempts > maxProcessingAttempts {
+			// processing was abandoned too many times, most likely by a crash
+			status = models.MediaStatusFailed
+		} else if err := w.process(ctx, item); err != nil {
+			slog.WarnContext(ctx, "failed to process media", "media_id", item.MediaID, "attempt", item.ProcessingAttempts, "error", err)
+
+			status = models.MediaStatusPending
+			if isMediaRejected(err) || item.ProcessingAttempts >= maxProcessingAttempts {
+				status = models.MediaStatusFailed
+			}
+		}
+
+		if status == models.MediaStatusReady {
+			continue
+		}
+		if err := w.store.SetStatus(ctx, item.MediaID, status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) process(ctx context.Context, item queries.Media) error {
+	var metadata Metadata
+	var poster image.Image
+
+	switch models.MediaType(item.MediaType) {
+	case models.MediaTypeGIF:
+		data, err := download(ctx, w.bucketRepository, item.BlobKey, MaxGIFBytes)
+		if err != nil {
+			return err
+		}
+
+		metadata, err = checkGIF(data)
+		if err != nil {
+			return err
+		}
+
+		// only the first frame is decoded
+		poster, err = gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return ErrInvalidMedia
+		}
+	case models.MediaTypeVideo:
+		file, err := downloadToFile(ctx, w.bucketRepository, item.BlobKey, MaxVideoBytes)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		metadata, err = checkVideo(file)
+		if err != nil {
+			return err
+		}
+
+		// a missing poster shouldn't stop the video from being shown
+		poster, err = w.posterExtractor.ExtractPoster(ctx, file.Name())
+		if err != nil {
+			slog.WarnContext(ctx, "failed to extract video poster", "media_id", item.MediaID, "error", err)
+			poster = nil
+		}
+	default:
+		return fmt.Errorf("unexpected media type %q", item.MediaType)
+	}
+
+	var posterKey, hash string
+	if poster != nil {
+		pixels := toRGBA(poster)
+		data, err := encode(pixels, "jpeg")
+		if err != nil {
+			return err
+		}
+
+		posterKey = strings.TrimSuffix(item.BlobKey, path.Ext(item.BlobKey)) + "_poster.jpg"
+		if err := w.bucketRepository.PutObject(ctx, posterKey, "image/jpeg", bytes.NewReader(data)); err != nil {
+			return err
+		}
+		hash = blurhash(pixels)
+	}
+
+	return w.store.Complete(ctx, item.MediaID, metadata, posterKey, hash)
+}
+
+// checkGIF measures a GIF and checks it against the size and duration limits.
+func checkGIF(data []byte) (Metadata, error) {
+	metadata, err := ProbeGIF(data)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if metadata.Width*metadata.Height > MaxImagePixels {
+		return Metadata{}, ErrMediaTooLarge
+	}
+	if metadata.Duration > MaxDuration {
+		return Metadata{}, ErrMediaTooLong
+	}
+	return metadata, nil
+}
+
+// checkVideo measures a video and checks it against the size and duration limits.
+func checkVideo(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	metadata, err := ProbeMP4(file, info.Size())
+	if err != nil {
+		return Metadata{}, err
+	}
+	if metadata.Width > maxVideoDimension || metadata.Height > maxVideoDimension {
+		return Metadata{}, ErrMediaTooLarge
+	}
+	if metadata.Duration > MaxDuration {
+		return Metadata{}, ErrMediaTooLong
+	}
+	return metadata, nil
+}
+
+func download(ctx context.Context, bucketRepository bucket.Repository, key string, limit int64) ([]byte, error) {
+	body, err := bucketRepository.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrMediaTooLarge
+	}
+	return data, nil
+}
+
+// downloadToFile copies an object to a temporary file, which the caller must remove.
+func downloadToFile(ctx context.Context, bucketRepository bucket.Repository, key string, limit int64) (*os.File, error) {
+	body, err := bucketRepository.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	file, err := os.CreateTemp("", "media-*"+path.Ext(key))
+	if err != nil {
+		return nil, err
+	}
+
+	written, err := io.Copy(file, io.LimitReader(body, limit+1))
+	if err == nil && written > limit {
+		err = ErrMediaTooLarge
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// isMediaRejected reports whether retrying processing can't succeed.
+func isMediaRejected(err error) bool {
+	return errors.Is(err, ErrInvalidMedia) || errors.Is(err, ErrMediaTooLarge) || errors.Is(err, ErrMediaTooLong)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.opentelemetry.io/otel"
@@ -16,12 +17,13 @@ import (
 	"golang.org/x/mod/semver"
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
 var tracer = otel.Tracer("splajompy.com/api/v2/internal/middleware")
 
-func AuthMiddleware(q *queries.Queries) func(http.Handler) http.Handler {
+func AuthMiddleware(q *queries.Queries, userRepository user.Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -46,7 +48,7 @@ func AuthMiddleware(q *queries.Queries) func(http.Handler) http.Handler {
 
 			token := strings.ReplaceAll(strings.TrimSpace(parts[1]), `\/`, `/`)
 
-			session, err := q.GetSessionById(ctx, token)
+			session, err := getSession(ctx, q, token)
 			if err != nil {
 				span.SetStatus(codes.Error, "unable to grab user session")
 				span.RecordError(err)
@@ -99,6 +101,13 @@ func AuthMiddleware(q *queries.Queries) func(http.Handler) http.Handler {
 				return
 			}
 
+			// sessions are removed on deactivation, but don't let a stray one through
+			if dbUser.DeactivatedAt != nil {
+				slog.InfoContext(ctx, "auth: user is deactivated", "user_id", dbUser.UserID)
+				http.Error(w, "account deactivated", http.StatusUnauthorized)
+				return
+			}
+
 			// log latest app version in profile
 			versionAny := ctx.Value(utilities.AppVersionKey)
 			version, ok := versionAny.(string)
@@ -128,7 +137,12 @@ func AuthMiddleware(q *queries.Queries) func(http.Handler) http.Handler {
 				span.RecordError(err)
 			}
 
-			publicUser := utilities.MapUserToPublicUser(dbUser)
+			publicUser, err := userRepository.MapPublicUser(ctx, dbUser)
+			if err != nil {
+				slog.ErrorContext(ctx, "auth: failed to sign profile images", "user_id", dbUser.UserID, "error", err)
+				http.Error(w, "something went wrong", http.StatusInternalServerError)
+				return
+			}
 
 			span.SetAttributes(attribute.Int("user.id", session.UserID))
 
@@ -139,3 +153,24 @@ func AuthMiddleware(q *queries.Queries) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// getSession looks up the session for a token. Sessions are stored under a hash of their token;
+// legacy tokens whose session is still stored under the raw token are rehashed on first use.
+func getSession(ctx context.Context, q *queries.Queries, token string) (queries.Session, error) {
+	hashedId := utilities.HashSessionToken(token)
+
+	session, err := q.GetSessionById(ctx, hashedId)
+	if err == nil || !utilities.IsLegacySessionToken(token) || !errors.Is(err, pgx.ErrNoRows) {
+		return session, err
+	}
+
+	session, err = q.RehashSession(ctx, queries.RehashSessionParams{
+		HashedID: hashedId,
+		ID:       token,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// a concurrent request with the same token may have rehashed it since the first lookup
+		return q.GetSessionById(ctx, hashedId)
+	}
+	return session, err
+}
@@ -21,12 +21,14 @@ type PublicUserDisplayProperties struct {
 type NotificationType string
 
 const (
-	NotificationTypeMention      NotificationType = "mention"
-	NotificationTypeLike         NotificationType = "like"
-	NotificationTypeComment      NotificationType = "comment"
-	NotificationTypeAnnouncement NotificationType = "announcement"
-	NotificationTypeFollowers    NotificationType = "followers"
-	NotificationTypePoll         NotificationType = "poll"
+	NotificationTypeMention       NotificationType = "mention"
+	NotificationTypeLike          NotificationType = "like"
+	NotificationTypeComment       NotificationType = "comment"
+	NotificationTypeAnnouncement  NotificationType = "announcement"
+	NotificationTypeFollowers     NotificationType = "followers"
+	NotificationTypePoll          NotificationType = "poll"
+	NotificationTypeFollowRequest NotificationType = "followRequest"
+	NotificationTypeReaction      NotificationType = "reaction"
 )
 
 type APIResponse struct {
@@ -40,6 +42,15 @@ type RelevantLike struct {
 	UserID   int    `json:"userId"`
 }
 
+// ReactionSummary is how many users reacted to a post or comment with an emoji, along with a few
+// of the most recent of them.
+type ReactionSummary struct {
+	Emoji     string         `json:"emoji"`
+	Count     int            `json:"count"`
+	IsReacted bool           `json:"isReacted"`
+	ReactedBy []RelevantLike `json:"reactedBy"`
+}
+
 type Notification struct {
 	NotificationID        int              `json:"notificationId"`
 	UserID                int              `json:"userId"`
@@ -56,34 +67,82 @@ type Notification struct {
 }
 
 type Post struct {
-	PostID     int                 `json:"postId"`
-	UserID     int                 `json:"userId"`
-	Text       string              `json:"text"`
-	CreatedAt  time.Time           `json:"createdAt"`
-	Facets     db.Facets           `json:"facets"`
-	Attributes *db.Attributes      `json:"attributes"`
-	Visibility *VisibilityTypeEnum `json:"visibility"`
+	PostID         int                 `json:"postId"`
+	UserID         int                 `json:"userId"`
+	Text           string              `json:"text"`
+	CreatedAt      time.Time           `json:"createdAt"`
+	Facets         db.Facets           `json:"facets"`
+	Attributes     *db.Attributes      `json:"attributes"`
+	Visibility     *VisibilityTypeEnum `json:"visibility"`
+	AudienceListID *int                `json:"audienceListId,omitempty"`
 }
 
 type DetailedPost struct {
-	Post          Post            `json:"post"`
-	User          PublicUser      `json:"user"`
-	IsLiked       bool            `json:"isLiked"`
-	Images        []DetailedImage `json:"images"`
-	CommentCount  int             `json:"commentCount"`
-	RelevantLikes []RelevantLike  `json:"relevantLikes"`
-	HasOtherLikes bool            `json:"hasOtherLikes"`
-	Poll          *DetailedPoll   `json:"poll"`
-	IsPinned      bool            `json:"isPinned"`
+	Post          Post              `json:"post"`
+	User          PublicUser        `json:"user"`
+	IsLiked       bool              `json:"isLiked"`
+	Images        []DetailedImage   `json:"images"`
+	Media         []DetailedMedia   `json:"media"`
+	CommentCount  int               `json:"commentCount"`
+	LikeCount     int               `json:"likeCount"`
+	RelevantLikes []RelevantLike    `json:"relevantLikes"`
+	HasOtherLikes bool              `json:"hasOtherLikes"`
+	Poll          *DetailedPoll     `json:"poll"`
+	IsPinned      bool              `json:"isPinned"`
+	Reactions     []ReactionSummary `json:"reactions"`
 }
 
 type DetailedImage struct {
-	ImageID      int    `json:"imageId"`
-	PostId       int    `json:"postId"`
-	Height       int    `json:"height"`
-	Width        int    `json:"width"`
-	ImageBlobUrl string `json:"imageBlobUrl"`
-	DisplayOrder int    `json:"displayOrder"`
+	ImageID      int                    `json:"imageId"`
+	PostId       int                    `json:"postId"`
+	Height       int                    `json:"height"`
+	Width        int                    `json:"width"`
+	ImageBlobUrl string                 `json:"imageBlobUrl"`
+	DisplayOrder int                    `json:"displayOrder"`
+	Blurhash     *string                `json:"blurhash"`
+	Variants     []DetailedImageVariant `json:"variants"`
+	AltText      *string                `json:"altText"`
+}
+
+// DetailedImageVariant is a resized copy of an image, smallest first.
+type DetailedImageVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+}
+
+type MediaType string
+
+const (
+	MediaTypeImage MediaType = "image"
+	MediaTypeGIF   MediaType = "gif"
+	MediaTypeVideo MediaType = "video"
+)
+
+type MediaStatus string
+
+const (
+	MediaStatusPending    MediaStatus = "pending"
+	MediaStatusProcessing MediaStatus = "processing"
+	MediaStatusReady      MediaStatus = "ready"
+	MediaStatusFailed     MediaStatus = "failed"
+)
+
+// DetailedMedia is an attachment of any type. Videos and GIFs are processed in the background,
+// so their dimensions, duration and poster are only known once Status is ready.
+type DetailedMedia struct {
+	MediaID      int                    `json:"mediaId"`
+	Type         MediaType              `json:"type"`
+	Status       MediaStatus            `json:"status"`
+	URL          string                 `json:"url"`
+	Width        int                    `json:"width"`
+	Height       int                    `json:"height"`
+	DurationMs   *int                   `json:"durationMs"`
+	PosterURL    *string                `json:"posterUrl"`
+	Blurhash     *string                `json:"blurhash"`
+	Variants     []DetailedImageVariant `json:"variants"`
+	DisplayOrder int                    `json:"displayOrder"`
+	AltText      *string                `json:"altText"`
 }
 
 type DetailedPollOption struct {
@@ -92,22 +151,31 @@ type DetailedPollOption struct {
 }
 
 type DetailedPoll struct {
-	Title           string               `json:"title"`
-	VoteTotal       int                  `json:"voteTotal"`
-	CurrentUserVote *int                 `json:"currentUserVote"`
-	Options         []DetailedPollOption `json:"options"`
+	Title     string `json:"title"`
+	VoteTotal int    `json:"voteTotal"`
+	// CurrentUserVote is the first of CurrentUserVotes, kept for clients without multi-select
+	CurrentUserVote  *int                 `json:"currentUserVote"`
+	CurrentUserVotes []int                `json:"currentUserVotes"`
+	VoterCount       int                  `json:"voterCount"`
+	MaxSelections    int                  `json:"maxSelections"`
+	ClosesAt         *time.Time           `json:"closesAt"`
+	IsClosed         bool                 `json:"isClosed"`
+	ResultsHidden    bool                 `json:"resultsHidden"`
+	Options          []DetailedPollOption `json:"options"`
 }
 
 type DetailedComment struct {
-	CommentID int             `json:"commentId"`
-	PostID    int             `json:"postId"`
-	UserID    int             `json:"userId"`
-	Text      string          `json:"text"`
-	Facets    db.Facets       `json:"facets"`
-	CreatedAt time.Time       `json:"createdAt"`
-	User      PublicUser      `json:"user"`
-	IsLiked   bool            `json:"isLiked"`
-	Images    []DetailedImage `json:"images"`
+	CommentID int               `json:"commentId"`
+	PostID    int               `json:"postId"`
+	UserID    int               `json:"userId"`
+	Text      string            `json:"text"`
+	Facets    db.Facets         `json:"facets"`
+	CreatedAt time.Time         `json:"createdAt"`
+	User      PublicUser        `json:"user"`
+	IsLiked   bool              `json:"isLiked"`
+	LikeCount int               `json:"likeCount"`
+	Images    []DetailedImage   `json:"images"`
+	Reactions []ReactionSummary `json:"reactions"`
 }
 
 type DetailedNotification struct {
@@ -126,7 +194,11 @@ type FullUser struct {
 	Username          string                      `json:"username"`
 	CreatedAt         time.Time                   `json:"createdAt"`
 	Name              string                      `json:"name"`
+	IsPrivate         bool                        `json:"isPrivate"`
+	AvatarURL         *string                     `json:"avatarUrl"`
+	BannerURL         *string                     `json:"bannerUrl"`
 	DisplayProperties PublicUserDisplayProperties `json:"displayProperties"`
+	RequireAltText    bool                        `json:"requireAltText"`
 }
 
 type PublicUser struct {
@@ -135,6 +207,9 @@ type PublicUser struct {
 	CreatedAt         time.Time                   `json:"createdAt"`
 	Name              string                      `json:"name"`
 	IsVerified        bool                        `json:"isVerified"`
+	IsPrivate         bool                        `json:"isPrivate"`
+	AvatarURL         *string                     `json:"avatarUrl"`
+	BannerURL         *string                     `json:"bannerUrl"`
 	DisplayProperties PublicUserDisplayProperties `json:"displayProperties"`
 	IsFriend          *bool                       `json:"isFriend,omitempty"`
 }
@@ -150,9 +225,16 @@ type DetailedUser struct {
 	IsBlocking        bool                        `json:"isBlocking"`
 	IsMuting          bool                        `json:"isMuting"`
 	IsFriend          bool                        `json:"isFriend"`
+	IsFollowRequested bool                        `json:"isFollowRequested"`
 	Mutuals           []string                    `json:"mutuals"`
 	MutualCount       int                         `json:"mutualCount"`
 	IsVerified        bool                        `json:"isVerified"`
+	IsPrivate         bool                        `json:"isPrivate"`
+	FollowerCount     int                         `json:"followerCount"`
+	FollowingCount    int                         `json:"followingCount"`
+	PostCount         int                         `json:"postCount"`
+	AvatarURL         *string                     `json:"avatarUrl"`
+	BannerURL         *string                     `json:"bannerUrl"`
 	DisplayProperties PublicUserDisplayProperties `json:"displayProperties"`
 }
 
@@ -161,10 +243,50 @@ type PaginatedUserList struct {
 	NextCursor *time.Time     `json:"nextCursor,omitempty"`
 }
 
+// MuteMode is what muting a user hides: their posts and comments, notifications caused by them,
+// or both.
+type MuteMode string
+
+const (
+	MuteModePosts         MuteMode = "posts"
+	MuteModeNotifications MuteMode = "notifications"
+	MuteModeAll           MuteMode = "all"
+)
+
+type MutedUser struct {
+	User      DetailedUser `json:"user"`
+	Mode      MuteMode     `json:"mode"`
+	ExpiresAt *time.Time   `json:"expiresAt"`
+}
+
+type PaginatedMutedUserList struct {
+	Users      []MutedUser `json:"users"`
+	NextCursor *time.Time  `json:"nextCursor,omitempty"`
+}
+
+type MutedWordScope string
+
+const (
+	MutedWordScopeFeeds         MutedWordScope = "feeds"
+	MutedWordScopeNotifications MutedWordScope = "notifications"
+	MutedWordScopeComments      MutedWordScope = "comments"
+)
+
+// MutedWord is a word, phrase or hashtag a user doesn't want to see. Posts, comments and
+// notifications containing it as a whole word are hidden in the muted scopes until it expires.
+type MutedWord struct {
+	ID        int              `json:"id"`
+	Phrase    string           `json:"phrase"`
+	Scopes    []MutedWordScope `json:"scopes"`
+	ExpiresAt *time.Time       `json:"expiresAt"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
 type ImageData struct {
-	S3Key  string `json:"s3Key"`
-	Width  int    `json:"width"`
-	Height int    `json:"height"`
+	S3Key   string `json:"s3Key"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	AltText string `json:"altText"`
 }
 
 type AppStats struct {
@@ -176,13 +298,31 @@ type AppStats struct {
 	TotalNotifications int64 `json:"totalNotifications"`
 }
 
+type AudienceList struct {
+	ListID      int       `json:"listId"`
+	Name        string    `json:"name"`
+	MemberCount int       `json:"memberCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
 type VisibilityTypeEnum int
 
 const (
 	VisibilityPublic       VisibilityTypeEnum = 0
 	VisibilityCloseFriends VisibilityTypeEnum = 1
+	VisibilityAudienceList VisibilityTypeEnum = 2
+	VisibilityFollowers    VisibilityTypeEnum = 3
 )
 
+// IsValid reports whether v is one of the known visibility types.
+func (v VisibilityTypeEnum) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityCloseFriends, VisibilityAudienceList, VisibilityFollowers:
+		return true
+	}
+	return false
+}
+
 type Device struct {
 	UserID            int    `json:"userId"`
 	Token             string `json:"token"`
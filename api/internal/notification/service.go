@@ -2,7 +2,6 @@ package notification
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -12,6 +11,8 @@ import (
 	"splajompy.com/api/v2/internal/apns"
 	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/media"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/utilities"
 
 	"splajompy.com/api/v2/internal/models"
@@ -24,22 +25,29 @@ type Service struct {
 	userRepository         userReader
 	bucketRepository       bucket.Repository
 	apnsClient             apns.Client
+
+	// uow and parent are set on copies made by WithTx
+	uow    *transaction.UnitOfWork
+	parent *Service
 }
 
 type postReader interface {
 	GetPostById(ctx context.Context, postId int, currentUserId int) (*models.Post, error)
-	GetImagesForPost(ctx context.Context, postId int) ([]queries.Image, error)
+	GetPostsByIds(ctx context.Context, postIds []int, currentUserId int) (map[int]*models.Post, error)
+	GetImagesForPosts(ctx context.Context, postIds []int) (map[int][]queries.Media, error)
 }
 
 type userReader interface {
 	GetUserById(ctx context.Context, userId int) (models.PublicUser, error)
 	GetUserByUsername(ctx context.Context, username string) (models.PublicUser, error)
 	GetUserLatestAppVersion(ctx context.Context, userId int) (*string, error)
+	GetUsersByIds(ctx context.Context, userIds []int) (map[int]models.PublicUser, error)
 }
 
 type commentReader interface {
 	GetCommentById(ctx context.Context, commentId int) (queries.Comment, error)
-	GetImagesByCommentId(ctx context.Context, commentId int) ([]queries.Image, error)
+	GetCommentsByIds(ctx context.Context, commentIds []int) (map[int]queries.Comment, error)
+	GetImagesByCommentIds(ctx context.Context, commentIds []int) (map[int][]queries.Media, error)
 }
 
 func NewService(notificationRepository Store, postRepository postReader, commentRepository commentReader, userRepository userReader, bucketRepository bucket.Repository, apnClient apns.Client) *Service {
@@ -53,6 +61,16 @@ func NewService(notificationRepository Store, postRepository postReader, comment
 	}
 }
 
+// WithTx returns a copy of the service that stores notifications in the unit of work's
+// transaction and only sends their push notifications once it commits.
+func (s *Service) WithTx(uow *transaction.UnitOfWork) *Service {
+	txService := *s
+	txService.notificationRepository = s.notificationRepository.WithTx(uow)
+	txService.uow = uow
+	txService.parent = s
+	return &txService
+}
+
 func (s *Service) MarkNotificationAsReadById(ctx context.Context, user models.PublicUser, notificationId int) error {
 	notification, err := s.notificationRepository.GetNotificationById(ctx, notificationId)
 	if err != nil {
@@ -108,78 +126,173 @@ func (s *Service) GetUnreadNotificationsByUserIdWithTimeOffset(ctx context.Conte
 	return s.buildDetailedNotifications(ctx, user.UserID, notifications)
 }
 
+// errReferencedContentHidden means a notification references a post or comment the user can't
+// see. Deleting posts and comments deletes their notifications, so a reference that can't be
+// loaded is one that is hidden, e.g. because its author blocked the user, went private or
+// deactivated their account.
+var errReferencedContentHidden = errors.New("notification references content the user can't see")
+
+// notificationContent is everything a page of notifications references, keyed by ID.
+type notificationContent struct {
+	posts         map[int]*models.Post
+	postImages    map[int][]queries.Media
+	comments      map[int]queries.Comment
+	commentImages map[int][]queries.Media
+	targetUsers   map[int]models.PublicUser
+	withActors    map[int]bool
+}
+
+// buildDetailedNotifications hydrates each notification with the content it references, which is
+// loaded for the whole page up front so the number of queries doesn't grow with its size.
+// Notifications referencing content the user can no longer see are left out and marked as read so
+// they don't count towards the unread badge. Any other failure fails the page so it can be retried.
 func (s *Service) buildDetailedNotifications(ctx context.Context, currentUserId int, notifications []*models.Notification) ([]models.DetailedNotification, error) {
+	content, err := s.loadNotificationContent(ctx, currentUserId, notifications)
+	if err != nil {
+		return nil, err
+	}
+
 	detailedNotifications := make([]models.DetailedNotification, 0, len(notifications))
+	var hiddenUnreadIds []int
 
 	for _, notification := range notifications {
-		var detailedNotification models.DetailedNotification
-		detailedNotification.Notification = *notification
+		detailedNotification, err := s.buildDetailedNotification(ctx, notification, content)
+		if errors.Is(err, errReferencedContentHidden) {
+			if !notification.Viewed {
+				hiddenUnreadIds = append(hiddenUnreadIds, notification.NotificationID)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		detailedNotifications = append(detailedNotifications, *detailedNotification)
+	}
+
+	if len(hiddenUnreadIds) > 0 {
+		if err := s.notificationRepository.MarkNotificationsAsRead(ctx, hiddenUnreadIds); err != nil {
+			slog.ErrorContext(ctx, "unable to mark hidden notifications as read", "error", err)
+		}
+	}
 
+	return detailedNotifications, nil
+}
+
+// loadNotificationContent loads the posts, comments, images, target users and actors referenced
+// by notifications, with one query for each.
+func (s *Service) loadNotificationContent(ctx context.Context, currentUserId int, notifications []*models.Notification) (*notificationContent, error) {
+	var postIds, commentIds, targetUserIds, aggregatedIds []int
+	for _, notification := range notifications {
 		if notification.PostID != nil {
-			post, err := s.postRepository.GetPostById(ctx, *notification.PostID, currentUserId)
-			if err != nil {
-				return nil, errors.New("unable to retrieve post referenced in notification")
-			}
-			detailedNotification.Post = post
+			postIds = append(postIds, *notification.PostID)
+		}
+		if notification.CommentID != nil {
+			commentIds = append(commentIds, *notification.CommentID)
+		}
+		if notification.TargetUserId != nil {
+			targetUserIds = append(targetUserIds, *notification.TargetUserId)
+		}
+		switch notification.NotificationType {
+		case models.NotificationTypeLike, models.NotificationTypePoll, models.NotificationTypeReaction:
+			aggregatedIds = append(aggregatedIds, notification.NotificationID)
+		}
+	}
 
-			images, err := s.postRepository.GetImagesForPost(ctx, *notification.PostID)
-			if err != nil && !errors.Is(err, sql.ErrNoRows) {
-				return nil, errors.New("unable to retrieve image blob")
-			}
+	var content notificationContent
+	var err error
+
+	content.posts, err = s.postRepository.GetPostsByIds(ctx, postIds, currentUserId)
+	if err != nil {
+		return nil, errors.New("unable to retrieve posts referenced in notifications")
+	}
+
+	content.postImages, err = s.postRepository.GetImagesForPosts(ctx, postIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve image blobs")
+	}
 
-			if len(images) > 0 {
-				url, err := s.bucketRepository.GetPresignedGetObject(ctx, images[0].ImageBlobUrl)
+	content.comments, err = s.commentRepository.GetCommentsByIds(ctx, commentIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve comments")
+	}
+
+	content.commentImages, err = s.commentRepository.GetImagesByCommentIds(ctx, commentIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve comment images")
+	}
+
+	content.targetUsers, err = s.userRepository.GetUsersByIds(ctx, targetUserIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve users")
+	}
+
+	withActors, err := s.notificationRepository.GetNotificationIdsWithActors(ctx, aggregatedIds)
+	if err != nil {
+		return nil, errors.New("unable to retrieve notification actors")
+	}
+	content.withActors = make(map[int]bool, len(withActors))
+	for _, notificationId := range withActors {
+		content.withActors[notificationId] = true
+	}
+
+	return &content, nil
+}
+
+func (s *Service) buildDetailedNotification(ctx context.Context, notification *models.Notification, content *notificationContent) (*models.DetailedNotification, error) {
+	var detailedNotification models.DetailedNotification
+	detailedNotification.Notification = *notification
+
+	if notification.PostID != nil {
+		post, ok := content.posts[*notification.PostID]
+		if !ok {
+			return nil, errReferencedContentHidden
+		}
+		detailedNotification.Post = post
+
+		if images := content.postImages[*notification.PostID]; len(images) > 0 {
+			// a video or GIF that hasn't been processed yet has no thumbnail to show
+			if key, ok := media.ThumbnailKey(images[0]); ok {
+				url, err := s.bucketRepository.GetPresignedGetObject(ctx, key)
 				if err != nil {
-					return nil, errors.New("unable to retrieve image blob")
+					return nil, fmt.Errorf("unable to retrieve image blob: %w", err)
 				}
 				detailedNotification.ImageBlob = &url
 				detailedNotification.ImageWidth = &images[0].Width
 				detailedNotification.ImageHeight = &images[0].Height
 			}
 		}
+	}
 
-		if notification.CommentID != nil {
-			comment, err := s.commentRepository.GetCommentById(ctx, *notification.CommentID)
-			if err != nil {
-				return nil, errors.New("unable to retrieve comment")
-			}
-			detailedNotification.Comment = &comment
-
-			commentImages, err := s.commentRepository.GetImagesByCommentId(ctx, *notification.CommentID)
-			if err != nil {
-				return nil, errors.New("unable to retrieve comment images")
-			}
-			if len(commentImages) > 0 {
-				presignedUrl, err := s.bucketRepository.GetPresignedGetObject(ctx, commentImages[0].ImageBlobUrl)
-				if err != nil {
-					return nil, errors.New("unable to presign comment image")
-				}
-				detailedNotification.ImageBlob = &presignedUrl
-				detailedNotification.ImageWidth = &commentImages[0].Width
-				detailedNotification.ImageHeight = &commentImages[0].Height
-			}
+	if notification.CommentID != nil {
+		comment, ok := content.comments[*notification.CommentID]
+		if !ok {
+			return nil, errReferencedContentHidden
 		}
+		detailedNotification.Comment = &comment
 
-		if notification.TargetUserId != nil {
-			user, err := s.userRepository.GetUserById(ctx, *notification.TargetUserId)
+		if commentImages := content.commentImages[*notification.CommentID]; len(commentImages) > 0 {
+			presignedUrl, err := s.bucketRepository.GetPresignedGetObject(ctx, commentImages[0].BlobKey)
 			if err != nil {
-				return nil, errors.New("unable to retrieve user")
+				return nil, fmt.Errorf("unable to presign comment image: %w", err)
 			}
-			detailedNotification.TargetUserUsername = &user.Username
+			detailedNotification.ImageBlob = &presignedUrl
+			detailedNotification.ImageWidth = &commentImages[0].Width
+			detailedNotification.ImageHeight = &commentImages[0].Height
 		}
+	}
 
-		if notification.NotificationType == models.NotificationTypeLike {
-			actors, err := s.notificationRepository.GetNotificationActors(ctx, notification.NotificationID)
-			if err != nil {
-				return nil, errors.New("unable to retrieve notification actors")
-			}
-			detailedNotification.HasNotificationActors = len(actors) > 0
+	if notification.TargetUserId != nil {
+		user, ok := content.targetUsers[*notification.TargetUserId]
+		if !ok {
+			return nil, errors.New("unable to retrieve user")
 		}
-
-		detailedNotifications = append(detailedNotifications, detailedNotification)
+		detailedNotification.TargetUserUsername = &user.Username
 	}
 
-	return detailedNotifications, nil
+	detailedNotification.HasNotificationActors = content.withActors[notification.NotificationID]
+
+	return &detailedNotification, nil
 }
 
 // AddLikeNotification creates a like notification for the owner of the target post or comment
@@ -222,20 +335,15 @@ func (s *Service) AddLikeNotification(ctx context.Context, currentUserId int, po
 		if err != nil {
 			return err
 		}
-		notification, err := s.AddNotification(ctx, recipientId, &postId, commentId, nil, *message, models.NotificationTypeLike, nil)
-		if err != nil {
+		notification, err := s.AddNotification(ctx, recipientId, &currentUserId, &postId, commentId, nil, *message, models.NotificationTypeLike, nil)
+		if err != nil || notification == nil {
 			return err
 		}
 		return s.notificationRepository.InsertNotificationActor(ctx, notification.NotificationID, currentUserId)
 	}
 
-	err = s.notificationRepository.InsertNotificationActor(ctx, existingLikeNotification.NotificationID, currentUserId)
-	if err != nil {
-		return err
-	}
-
-	actors, err := s.notificationRepository.GetNotificationActors(ctx, existingLikeNotification.NotificationID)
-	if err != nil {
+	actors, err := s.joinNotification(ctx, existingLikeNotification, recipientId, currentUserId)
+	if err != nil || actors == nil {
 		return err
 	}
 
@@ -285,21 +393,27 @@ func (s *Service) RemoveLikeNotification(ctx context.Context, currentUserId int,
 		return s.notificationRepository.DeleteNotificationById(ctx, existingLikeNotification.NotificationID)
 	}
 
-	err = s.notificationRepository.DeleteNotificationActor(ctx, existingLikeNotification.NotificationID, currentUserId)
+	return s.removeLikeNotificationActor(ctx, existingLikeNotification, currentUserId)
+}
+
+// removeLikeNotificationActor removes actorId from a like notification, deleting the notification
+// once nobody is left in it.
+func (s *Service) removeLikeNotificationActor(ctx context.Context, existing *models.Notification, actorId int) error {
+	err := s.notificationRepository.DeleteNotificationActor(ctx, existing.NotificationID, actorId)
 	if err != nil {
 		return err
 	}
 
-	actors, err := s.notificationRepository.GetNotificationActors(ctx, existingLikeNotification.NotificationID)
+	actors, err := s.notificationRepository.GetNotificationActors(ctx, existing.NotificationID)
 	if err != nil {
 		return err
 	}
 
 	if len(actors) == 0 {
-		return s.notificationRepository.DeleteNotificationById(ctx, existingLikeNotification.NotificationID)
+		return s.notificationRepository.DeleteNotificationById(ctx, existing.NotificationID)
 	}
 
-	message, err := s.buildLikedMessage(ctx, actors, commentId != nil)
+	message, err := s.buildLikedMessage(ctx, actors, existing.CommentID != nil)
 	if err != nil {
 		return err
 	}
@@ -309,11 +423,269 @@ func (s *Service) RemoveLikeNotification(ctx context.Context, currentUserId int,
 		return err
 	}
 
-	return s.notificationRepository.UpdateNotificationMessageOnly(ctx, existingLikeNotification.NotificationID, *message, facets)
+	return s.notificationRepository.UpdateNotificationMessageOnly(ctx, existing.NotificationID, *message, facets)
+}
+
+// AddPollVoteNotification adds the current user to the vote notification for a poll, creating it
+// for the poll's author if this is the first vote. Run it in a transaction, so concurrent voters
+// wait for each other instead of each creating a notification.
+func (s *Service) AddPollVoteNotification(ctx context.Context, currentUserId int, postId int) error {
+	post, err := s.postRepository.GetPostById(ctx, postId, currentUserId)
+	if err != nil {
+		return err
+	}
+
+	// do not self-notify
+	if currentUserId == post.UserID {
+		return nil
+	}
+
+	err = s.notificationRepository.LockNotification(ctx, post.UserID, postId, models.NotificationTypePoll)
+	if err != nil {
+		return err
+	}
+
+	existingVoteNotification, err := s.notificationRepository.FindPollVoteNotification(ctx, post.UserID, postId)
+	if err != nil {
+		return err
+	}
+
+	return s.addNotificationActor(ctx, existingVoteNotification, post.UserID, postId, nil, currentUserId, models.NotificationTypePoll, pollVotedAction)
+}
+
+// RemovePollVoteNotification removes the current user from the vote notification for a poll after
+// they retract their vote, and removes the notification entirely if they were the only voter.
+// Like AddPollVoteNotification, it should run in a transaction.
+func (s *Service) RemovePollVoteNotification(ctx context.Context, currentUserId int, postId int) error {
+	post, err := s.postRepository.GetPostById(ctx, postId, currentUserId)
+	if err != nil {
+		return err
+	}
+
+	err = s.notificationRepository.LockNotification(ctx, post.UserID, postId, models.NotificationTypePoll)
+	if err != nil {
+		return err
+	}
+
+	existingVoteNotification, err := s.notificationRepository.FindPollVoteNotification(ctx, post.UserID, postId)
+	if err != nil || existingVoteNotification == nil {
+		return err
+	}
+
+	return s.removeNotificationActor(ctx, existingVoteNotification, currentUserId, pollVotedAction)
+}
+
+// AddReactionNotification adds the current user to the reaction notification for a post or
+// comment, creating it for the author if nobody else has reacted yet.
+// Pass nil for commentId when reacting to a post directly.
+func (s *Service) AddReactionNotification(ctx context.Context, currentUserId int, postId int, commentId *int) error {
+	recipientId, err := s.getAuthorId(ctx, currentUserId, postId, commentId)
+	if err != nil {
+		return err
+	}
+
+	// do not self-notify
+	if currentUserId == recipientId {
+		return nil
+	}
+
+	existingReactionNotification, err := s.notificationRepository.FindReactionNotification(ctx, recipientId, postId, commentId)
+	if err != nil {
+		return err
+	}
+
+	return s.addNotificationActor(ctx, existingReactionNotification, recipientId, postId, commentId, currentUserId, models.NotificationTypeReaction, reactedAction(commentId))
+}
+
+// RemoveReactionNotification removes the current user from the reaction notification for a post
+// or comment, and removes the notification entirely if they were the only one who reacted.
+func (s *Service) RemoveReactionNotification(ctx context.Context, currentUserId int, postId int, commentId *int) error {
+	recipientId, err := s.getAuthorId(ctx, currentUserId, postId, commentId)
+	if err != nil {
+		return err
+	}
+
+	existingReactionNotification, err := s.notificationRepository.FindReactionNotification(ctx, recipientId, postId, commentId)
+	if err != nil || existingReactionNotification == nil {
+		return err
+	}
+
+	return s.removeNotificationActor(ctx, existingReactionNotification, currentUserId, reactedAction(commentId))
+}
+
+// RemoveFollowRequestNotification deletes the notification asking a user to review another user's
+// follow request, once the request has been answered.
+func (s *Service) RemoveFollowRequestNotification(ctx context.Context, userId int, requesterId int) error {
+	return s.notificationRepository.DeleteFollowRequestNotification(ctx, userId, requesterId)
+}
+
+// RemoveNotificationsBetween deletes the notifications each user has that involve the other or
+// reference their posts or comments, and takes each of them out of the other's aggregated
+// notifications.
+func (s *Service) RemoveNotificationsBetween(ctx context.Context, userId int, otherUserId int) error {
+	for _, pair := range [][2]int{{userId, otherUserId}, {otherUserId, userId}} {
+		recipientId, actorId := pair[0], pair[1]
+
+		err := s.notificationRepository.DeleteNotificationsInvolvingUser(ctx, recipientId, actorId)
+		if err != nil {
+			return err
+		}
+
+		notifications, err := s.notificationRepository.GetNotificationsWithActor(ctx, recipientId, actorId)
+		if err != nil {
+			return err
+		}
+
+		for _, notification := range notifications {
+			switch notification.NotificationType {
+			case models.NotificationTypeLike:
+				err = s.removeLikeNotificationActor(ctx, notification, actorId)
+			case models.NotificationTypePoll:
+				err = s.removeNotificationActor(ctx, notification, actorId, pollVotedAction)
+			case models.NotificationTypeReaction:
+				err = s.removeNotificationActor(ctx, notification, actorId, reactedAction(notification.CommentID))
+			default:
+				err = s.notificationRepository.DeleteNotificationActor(ctx, notification.NotificationID, actorId)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+const pollVotedAction = "voted in your poll"
+
+func reactedAction(commentId *int) string {
+	if commentId != nil {
+		return "reacted to your comment"
+	}
+	return "reacted to your post"
+}
+
+// getAuthorId returns the author of the comment, or of the post when commentId is nil.
+func (s *Service) getAuthorId(ctx context.Context, currentUserId int, postId int, commentId *int) (int, error) {
+	if commentId != nil {
+		comment, err := s.commentRepository.GetCommentById(ctx, *commentId)
+		if err != nil {
+			return 0, err
+		}
+		return comment.UserID, nil
+	}
+
+	post, err := s.postRepository.GetPostById(ctx, postId, currentUserId)
+	if err != nil {
+		return 0, err
+	}
+	return post.UserID, nil
+}
+
+// addNotificationActor adds actorId to an aggregated notification, creating the notification for
+// recipientId if there isn't one yet, and names the latest actors in its message.
+func (s *Service) addNotificationActor(ctx context.Context, existing *models.Notification, recipientId int, postId int, commentId *int, actorId int, notificationType models.NotificationType, action string) error {
+	if existing == nil {
+		message, err := s.buildActorMessage(ctx, []int{actorId}, action)
+		if err != nil {
+			return err
+		}
+		notification, err := s.AddNotification(ctx, recipientId, &actorId, &postId, commentId, nil, message, notificationType, nil)
+		if err != nil || notification == nil {
+			return err
+		}
+		return s.notificationRepository.InsertNotificationActor(ctx, notification.NotificationID, actorId)
+	}
+
+	actors, err := s.joinNotification(ctx, existing, recipientId, actorId)
+	if err != nil || actors == nil {
+		return err
+	}
+
+	message, err := s.buildActorMessage(ctx, actors, action)
+	if err != nil {
+		return err
+	}
+
+	facets, err := utilities.GenerateFacets(ctx, s.userRepository, message)
+	if err != nil {
+		return err
+	}
+
+	return s.notificationRepository.UpdateNotificationMessage(ctx, existing.NotificationID, message, facets)
+}
+
+// joinNotification adds actorId to an existing aggregated notification and returns everyone in
+// it, or nil if the recipient has muted notifications from actorId.
+func (s *Service) joinNotification(ctx context.Context, existing *models.Notification, recipientId int, actorId int) ([]int, error) {
+	// only new notifications go through AddNotification, so check mutes before joining this one
+	muted, err := s.notificationRepository.IsMutingNotificationsFrom(ctx, recipientId, actorId)
+	if err != nil || muted {
+		return nil, err
+	}
+
+	err = s.notificationRepository.InsertNotificationActor(ctx, existing.NotificationID, actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.notificationRepository.GetNotificationActors(ctx, existing.NotificationID)
 }
 
-// AddNotification will enrich the notification message with facets, then store.
-func (s *Service) AddNotification(ctx context.Context, userId int, postId *int, commentId *int, targetUserId *int, message string, notificationType models.NotificationType, notificationBody *string) (*models.Notification, error) {
+// removeNotificationActor removes actorId from an aggregated notification, deleting the
+// notification once nobody is left in it.
+func (s *Service) removeNotificationActor(ctx context.Context, existing *models.Notification, actorId int, action string) error {
+	err := s.notificationRepository.DeleteNotificationActor(ctx, existing.NotificationID, actorId)
+	if err != nil {
+		return err
+	}
+
+	actors, err := s.notificationRepository.GetNotificationActors(ctx, existing.NotificationID)
+	if err != nil {
+		return err
+	}
+
+	if len(actors) == 0 {
+		return s.notificationRepository.DeleteNotificationById(ctx, existing.NotificationID)
+	}
+
+	message, err := s.buildActorMessage(ctx, actors, action)
+	if err != nil {
+		return err
+	}
+
+	facets, err := utilities.GenerateFacets(ctx, s.userRepository, message)
+	if err != nil {
+		return err
+	}
+
+	return s.notificationRepository.UpdateNotificationMessageOnly(ctx, existing.NotificationID, message, facets)
+}
+
+// AddNotification will enrich the notification message with facets, then store. Nothing is stored
+// or pushed when the recipient has muted notifications from actorId, or muted a word in the post or
+// comment it's about, in which case the returned notification is nil.
+func (s *Service) AddNotification(ctx context.Context, userId int, actorId *int, postId *int, commentId *int, targetUserId *int, message string, notificationType models.NotificationType, notificationBody *string) (*models.Notification, error) {
+	if actorId != nil {
+		muted, err := s.notificationRepository.IsMutingNotificationsFrom(ctx, userId, *actorId)
+		if err != nil {
+			return nil, err
+		}
+		if muted {
+			return nil, nil
+		}
+	}
+
+	if postId != nil || commentId != nil {
+		muted, err := s.notificationRepository.ContainsMutedWord(ctx, userId, postId, commentId)
+		if err != nil {
+			return nil, err
+		}
+		if muted {
+			return nil, nil
+		}
+	}
+
 	facets, err := utilities.GenerateFacets(ctx, s.userRepository, message)
 	if err != nil {
 		return nil, err
@@ -341,7 +713,7 @@ func (s *Service) AddNotification(ctx context.Context, userId int, postId *int,
 			return nil, errors.New("post id cannot be null for a comment notification")
 		}
 		identifier = postId
-	case models.NotificationTypeFollowers:
+	case models.NotificationTypeFollowers, models.NotificationTypeFollowRequest:
 		identifier = targetUserId
 		username = targetUsername
 	default:
@@ -350,7 +722,14 @@ func (s *Service) AddNotification(ctx context.Context, userId int, postId *int,
 
 	// execute in background ctx to avoid cancellation, but still use current span as parent
 	traceCtx := trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
-	go s.sendPush(traceCtx, notification.NotificationID, userId, message, notificationBody, notificationType, identifier, username)
+	if s.uow == nil {
+		go s.sendPush(traceCtx, notification.NotificationID, userId, message, notificationBody, notificationType, identifier, username)
+	} else {
+		// the transaction is finished by the time the push is sent, so it's sent outside of it
+		s.uow.AfterCommit(func() {
+			go s.parent.sendPush(traceCtx, notification.NotificationID, userId, message, notificationBody, notificationType, identifier, username)
+		})
+	}
 
 	return notification, nil
 }
@@ -369,7 +748,7 @@ func (s *Service) sendPush(ctx context.Context, notificationId int, recipientId
 			enabled = device.IsEnabledMentions
 		case models.NotificationTypeComment:
 			enabled = device.IsEnabledComments
-		case models.NotificationTypeFollowers:
+		case models.NotificationTypeFollowers, models.NotificationTypeFollowRequest:
 			enabled = device.IsEnabledFollows
 		}
 
@@ -448,6 +827,29 @@ func (s *Service) buildLikedMessage(ctx context.Context, userIds []int, isCommen
 	return new(fmt.Sprintf("@%s, @%s, @%s, and others liked your %s", users[0].Username, users[1].Username, users[2].Username, noun)), nil
 }
 
+// buildActorMessage names the two most recent actors, e.g. "@a, @b and 12 others voted in your poll".
+func (s *Service) buildActorMessage(ctx context.Context, userIds []int, action string) (string, error) {
+	var usernames []string
+	for _, userId := range userIds[:min(2, len(userIds))] {
+		user, err := s.userRepository.GetUserById(ctx, userId)
+		if err != nil {
+			return "", err
+		}
+		usernames = append(usernames, "@"+user.Username)
+	}
+
+	switch others := len(userIds) - len(usernames); {
+	case len(usernames) == 1:
+		return fmt.Sprintf("%s %s", usernames[0], action), nil
+	case others == 0:
+		return fmt.Sprintf("%s and %s %s", usernames[0], usernames[1], action), nil
+	case others == 1:
+		return fmt.Sprintf("%s, %s and 1 other %s", usernames[0], usernames[1], action), nil
+	default:
+		return fmt.Sprintf("%s, %s and %d others %s", usernames[0], usernames[1], others, action), nil
+	}
+}
+
 func (s *Service) RegisterDevice(ctx context.Context, userId int, token string, mentionsEnabled bool, commentsEnabled bool, followsEnabled bool) error {
 	return s.notificationRepository.InsertDeviceToken(ctx, userId, token, mentionsEnabled, commentsEnabled, followsEnabled)
 }
@@ -2,14 +2,19 @@ package notification_test
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/comment"
 	db "splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/post"
@@ -26,6 +31,7 @@ type notificationTestEnv struct {
 	userRepository         user.Store
 	postRepository         post.Store
 	commentRepository      comment.Store
+	bucketRepository       bucket.Repository
 }
 
 func setupNotificationService(t *testing.T) notificationTestEnv {
@@ -33,8 +39,8 @@ func setupNotificationService(t *testing.T) notificationTestEnv {
 	db := testutil.StartPostgres(t)
 
 	notificationService := notification.NewService(db.NotificationStore, db.PostRepository, &db.CommentRepository, db.UserRepository, db.BucketRepository, apns.Client{})
-	commentService := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.BucketRepository)
-	postService := post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, *notificationService, db.BucketRepository, nil)
+	commentService := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.ReactionStore, db.BucketRepository, db.TxManager)
+	postService := post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, db.ReactionStore, *notificationService, db.BucketRepository, nil, db.TxManager)
 
 	return notificationTestEnv{
 		svc:                    notificationService,
@@ -44,6 +50,7 @@ func setupNotificationService(t *testing.T) notificationTestEnv {
 		userRepository:         db.UserRepository,
 		postRepository:         db.PostRepository,
 		commentRepository:      db.CommentRepository,
+		bucketRepository:       db.BucketRepository,
 	}
 }
 
@@ -168,7 +175,7 @@ func TestFindLikeNotification_PostNotification(t *testing.T) {
 	user := testutil.CreateTestUser(t, env.userRepository, "testUser")
 
 	visibility := models.VisibilityPublic
-	post, err := env.postRepository.InsertPost(t.Context(), user.UserID, "test post", nil, nil, &visibility)
+	post, err := env.postRepository.InsertPost(t.Context(), user.UserID, "test post", nil, nil, &visibility, nil)
 	require.NoError(t, err)
 
 	_, err = env.notificationRepository.InsertNotification(t.Context(), user.UserID, &post.PostID, nil, nil, "@user liked your post.", models.NotificationTypeLike, nil)
@@ -185,7 +192,7 @@ func TestFindLikeNotification_CommentNotification(t *testing.T) {
 	user := testutil.CreateTestUser(t, env.userRepository, "testUser")
 
 	visibility := models.VisibilityPublic
-	post, err := env.postRepository.InsertPost(t.Context(), user.UserID, "test post", nil, nil, &visibility)
+	post, err := env.postRepository.InsertPost(t.Context(), user.UserID, "test post", nil, nil, &visibility, nil)
 	require.NoError(t, err)
 
 	comment, err := env.commentRepository.AddCommentToPost(t.Context(), user.UserID, post.PostID, "test comment", nil)
@@ -250,15 +257,15 @@ func TestCommentNotification_ImagePopulatedFromComment(t *testing.T) {
 	commenter := testutil.CreateTestUser(t, env.userRepository, "commenter")
 
 	visibility := models.VisibilityPublic
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, &visibility)
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, &visibility, nil)
 	require.NoError(t, err)
 
-	imageKey := "images/test-image.jpg"
 	imageKeyMap := map[int]models.ImageData{
-		0: {S3Key: imageKey, Width: 640, Height: 480},
+		0: testutil.StageTestImage(t, env.bucketRepository, commenter.UserID, 640, 480),
 	}
-	_, err = env.commentSvc.AddCommentToPost(t.Context(), commenter, post.PostID, "test comment with image", imageKeyMap)
+	comment, err := env.commentSvc.AddCommentToPost(t.Context(), commenter, post.PostID, "test comment with image", imageKeyMap)
 	require.NoError(t, err)
+	imageKey := bucket.GetDestinationKey(commenter.UserID, "comment", comment.CommentID, imageKeyMap[0].S3Key)
 
 	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), postOwner, time.Now().UTC(), 10, nil)
 	require.NoError(t, err)
@@ -289,7 +296,7 @@ func TestGetNotifications_MentionUserInUnreachablePost(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	_, err := env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityCloseFriends)))
+	_, err := env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityCloseFriends)), nil)
 	require.NoError(t, err)
 
 	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), user1, time.Now().UTC(), 10, nil)
@@ -306,7 +313,7 @@ func TestGetNotifications_MentionUserInNewlyUnreachablePost(t *testing.T) {
 	err := env.userRepository.AddUserRelationship(t.Context(), user0.UserID, user1.UserID)
 	require.NoError(t, err)
 
-	_, err = env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityCloseFriends)))
+	_, err = env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityCloseFriends)), nil)
 	require.NoError(t, err)
 
 	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), user1, time.Now().UTC(), 10, nil)
@@ -329,7 +336,7 @@ func TestAddLikeNotification_MultipleNotificationsCombine(t *testing.T) {
 	err := env.userRepository.UpdateUserDisplayProperties(t.Context(), postOwner.UserID, &db.UserDisplayProperties{LatestAppVersion: &appVersion})
 	require.NoError(t, err)
 
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	liker0 := testutil.CreateTestUser(t, env.userRepository, "liker0")
@@ -396,7 +403,7 @@ func TestAddLikeNotification_HandlesRemovedLikes(t *testing.T) {
 	err := env.userRepository.UpdateUserDisplayProperties(t.Context(), postOwner.UserID, &db.UserDisplayProperties{LatestAppVersion: &appVersion})
 	require.NoError(t, err)
 
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	liker0 := testutil.CreateTestUser(t, env.userRepository, "liker0")
@@ -444,7 +451,7 @@ func TestRemoveLikeNotification_WithSelfLikes(t *testing.T) {
 	env := setupNotificationService(t)
 
 	postOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	err = env.svc.AddLikeNotification(t.Context(), postOwner.UserID, post.PostID, nil)
@@ -480,7 +487,7 @@ func TestAddLikeNotification_Comment_MultipleNotificationsCombine(t *testing.T)
 	require.NoError(t, err)
 
 	postOwner := testutil.CreateTestUser(t, env.userRepository, "postOwner")
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.commentRepository.AddCommentToPost(t.Context(), commenter.UserID, post.PostID, "test comment", nil)
@@ -523,7 +530,7 @@ func TestAddLikeNotification_Comment_HandlesRemovedLikes(t *testing.T) {
 	require.NoError(t, err)
 
 	postOwner := testutil.CreateTestUser(t, env.userRepository, "postOwner")
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.commentRepository.AddCommentToPost(t.Context(), commenter.UserID, post.PostID, "test comment", nil)
@@ -563,7 +570,7 @@ func TestAddLikeNotification_Comment_SelfLikeIgnored(t *testing.T) {
 
 	commenter := testutil.CreateTestUser(t, env.userRepository, "commenter")
 	postOwner := testutil.CreateTestUser(t, env.userRepository, "postOwner")
-	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic))
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
 	require.NoError(t, err)
 
 	comment, err := env.commentRepository.AddCommentToPost(t.Context(), commenter.UserID, post.PostID, "test comment", nil)
@@ -616,3 +623,350 @@ func TestRegisterDevice_UpdatePreferences(t *testing.T) {
 	assert.False(t, device.IsEnabledComments)
 	assert.True(t, device.IsEnabledFollows)
 }
+
+func TestAddPollVoteNotification_VotesCombine(t *testing.T) {
+	env := setupNotificationService(t)
+
+	pollOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
+	poll, err := env.postSvc.NewPost(t.Context(), pollOwner, "test poll", nil, &db.Poll{Title: "poll", Options: []string{"a", "b"}}, nil, nil)
+	require.NoError(t, err)
+
+	var voters []models.PublicUser
+	for i := range 4 {
+		voter := testutil.CreateTestUser(t, env.userRepository, fmt.Sprintf("voter%d", i))
+		require.NoError(t, env.postSvc.VoteOnPoll(t.Context(), voter, poll.PostID, i%2))
+		voters = append(voters, voter)
+	}
+
+	// changing a vote doesn't notify again
+	require.NoError(t, env.postSvc.VoteOnPoll(t.Context(), voters[0], poll.PostID, 1))
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1, "votes should be combined into 1 notification")
+
+	assert.Equal(t, "@voter3, @voter2 and 2 others voted in your poll", notifications[0].Message)
+	assert.Equal(t, models.NotificationTypePoll, notifications[0].NotificationType)
+	assert.True(t, notifications[0].HasNotificationActors)
+
+	expectedFacets, err := utilities.GenerateFacets(t.Context(), env.userRepository, notifications[0].Message)
+	require.NoError(t, err)
+	assert.Equal(t, expectedFacets, notifications[0].Facets)
+
+	actors, err := env.notificationRepository.GetNotificationActors(t.Context(), notifications[0].NotificationID)
+	require.NoError(t, err)
+	assert.Len(t, actors, 4)
+}
+
+func TestAddPollVoteNotification_ConcurrentVotes(t *testing.T) {
+	env := setupNotificationService(t)
+
+	pollOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
+	poll, err := env.postSvc.NewPost(t.Context(), pollOwner, "test poll", nil, &db.Poll{Title: "poll", Options: []string{"a", "b"}}, nil, nil)
+	require.NoError(t, err)
+
+	var voters []models.PublicUser
+	for i := range 8 {
+		voters = append(voters, testutil.CreateTestUser(t, env.userRepository, fmt.Sprintf("voter%d", i)))
+	}
+
+	var wg sync.WaitGroup
+	for _, voter := range voters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, env.postSvc.VoteOnPoll(t.Context(), voter, poll.PostID, 0))
+		}()
+	}
+	wg.Wait()
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1, "concurrent votes should still be combined into 1 notification")
+
+	actors, err := env.notificationRepository.GetNotificationActors(t.Context(), notifications[0].NotificationID)
+	require.NoError(t, err)
+	assert.Len(t, actors, len(voters))
+}
+
+func TestAddPollVoteNotification_HandlesRetractedVotes(t *testing.T) {
+	env := setupNotificationService(t)
+
+	pollOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
+	poll, err := env.postSvc.NewPost(t.Context(), pollOwner, "test poll", nil, &db.Poll{Title: "poll", Options: []string{"a", "b"}}, nil, nil)
+	require.NoError(t, err)
+
+	voter0 := testutil.CreateTestUser(t, env.userRepository, "voter0")
+	voter1 := testutil.CreateTestUser(t, env.userRepository, "voter1")
+	voter2 := testutil.CreateTestUser(t, env.userRepository, "voter2")
+	for _, voter := range []models.PublicUser{voter0, voter1, voter2} {
+		require.NoError(t, env.postSvc.VoteOnPoll(t.Context(), voter, poll.PostID, 0))
+	}
+
+	// voting on your own poll never notifies
+	require.NoError(t, env.postSvc.VoteOnPoll(t.Context(), pollOwner, poll.PostID, 0))
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@voter2, @voter1 and 1 other voted in your poll", notifications[0].Message)
+
+	require.NoError(t, env.postSvc.SetPollVotes(t.Context(), voter2, poll.PostID, nil))
+
+	notifications, err = env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@voter1 and @voter0 voted in your poll", notifications[0].Message)
+
+	require.NoError(t, env.postSvc.RemovePollVote(t.Context(), voter1, poll.PostID, 0))
+
+	notifications, err = env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@voter0 voted in your poll", notifications[0].Message)
+
+	require.NoError(t, env.postSvc.SetPollVotes(t.Context(), voter0, poll.PostID, nil))
+
+	notifications, err = env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), pollOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 0)
+}
+
+func TestAddNotification_SkipsNotificationMutedActors(t *testing.T) {
+	env := setupNotificationService(t)
+
+	postOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
+	muted := testutil.CreateTestUser(t, env.userRepository, "muted")
+	postsOnly := testutil.CreateTestUser(t, env.userRepository, "postsonly")
+
+	require.NoError(t, env.userRepository.MuteUser(t.Context(), postOwner.UserID, muted.UserID, models.MuteModeNotifications, nil))
+	require.NoError(t, env.userRepository.MuteUser(t.Context(), postOwner.UserID, postsOnly.UserID, models.MuteModePosts, nil))
+
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	notification, err := env.svc.AddNotification(t.Context(), postOwner.UserID, &muted.UserID, &post.PostID, nil, nil, "@muted mentioned you", models.NotificationTypeMention, nil)
+	require.NoError(t, err)
+	assert.Nil(t, notification)
+
+	require.NoError(t, env.svc.AddLikeNotification(t.Context(), muted.UserID, post.PostID, nil))
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), postOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	// muting only posts still lets notifications through
+	require.NoError(t, env.svc.AddLikeNotification(t.Context(), postsOnly.UserID, post.PostID, nil))
+	require.NoError(t, env.svc.AddLikeNotification(t.Context(), muted.UserID, post.PostID, nil))
+
+	notifications, err = env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), postOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@postsonly liked your post", notifications[0].Message)
+}
+
+func TestAddNotification_DeliversAfterMuteExpires(t *testing.T) {
+	env := setupNotificationService(t)
+
+	recipient := testutil.CreateTestUser(t, env.userRepository, "user0")
+	actor := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.userRepository.MuteUser(t.Context(), recipient.UserID, actor.UserID, models.MuteModeAll, new(time.Now().Add(-time.Minute))))
+
+	notification, err := env.svc.AddNotification(t.Context(), recipient.UserID, &actor.UserID, nil, nil, &actor.UserID, "@user1 started following you", models.NotificationTypeFollowers, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, notification)
+}
+
+func TestAddNotification_SkipsMutedWords(t *testing.T) {
+	env := setupNotificationService(t)
+
+	recipient := testutil.CreateTestUser(t, env.userRepository, "user0")
+	author := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	post, err := env.postRepository.InsertPost(t.Context(), author.UserID, "big spoilers ahead", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	// notified before the word was muted
+	_, err = env.notificationRepository.InsertNotification(t.Context(), recipient.UserID, &post.PostID, nil, nil, "@user1 mentioned you", models.NotificationTypeMention, nil)
+	require.NoError(t, err)
+
+	_, err = env.userRepository.UpsertMutedWord(t.Context(), recipient.UserID, "spoilers", `(^|[^[:alnum:]_])spoilers($|[^[:alnum:]_])`, []models.MutedWordScope{models.MutedWordScopeNotifications}, nil)
+	require.NoError(t, err)
+
+	notification, err := env.svc.AddNotification(t.Context(), recipient.UserID, &author.UserID, &post.PostID, nil, nil, "@user1 mentioned you", models.NotificationTypeMention, nil)
+	require.NoError(t, err)
+	assert.Nil(t, notification)
+
+	hasUnread, err := env.svc.UserHasUnreadNotifications(t.Context(), recipient)
+	require.NoError(t, err)
+	assert.False(t, hasUnread)
+
+	count, err := env.svc.GetUserUnreadNotificationCount(t.Context(), recipient)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// muting a word doesn't hide notifications about your own posts
+	_, err = env.userRepository.UpsertMutedWord(t.Context(), author.UserID, "spoilers", `(^|[^[:alnum:]_])spoilers($|[^[:alnum:]_])`, []models.MutedWordScope{models.MutedWordScopeNotifications}, nil)
+	require.NoError(t, err)
+	notification, err = env.svc.AddNotification(t.Context(), author.UserID, &recipient.UserID, &post.PostID, nil, nil, "@user0 liked your post", models.NotificationTypeLike, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, notification)
+}
+
+func TestGetNotifications_SkipsPostsThatAreNoLongerVisible(t *testing.T) {
+	env := setupNotificationService(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	_, err := env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityPublic)), nil)
+	require.NoError(t, err)
+	_, err = env.notificationRepository.InsertNotification(t.Context(), user1.UserID, nil, nil, nil, "welcome", models.NotificationTypeAnnouncement, nil)
+	require.NoError(t, err)
+
+	err = env.userRepository.UpdateIsPrivate(t.Context(), user0.UserID, true)
+	require.NoError(t, err)
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), user1, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "welcome", notifications[0].Message)
+}
+
+func TestRemoveNotificationsBetween(t *testing.T) {
+	env := setupNotificationService(t)
+
+	postOwner := testutil.CreateTestUser(t, env.userRepository, "user0")
+	appVersion := "v1.8.2"
+	err := env.userRepository.UpdateUserDisplayProperties(t.Context(), postOwner.UserID, &db.UserDisplayProperties{LatestAppVersion: &appVersion})
+	require.NoError(t, err)
+
+	post, err := env.postRepository.InsertPost(t.Context(), postOwner.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	liker0 := testutil.CreateTestUser(t, env.userRepository, "liker0")
+	err = env.svc.AddLikeNotification(t.Context(), liker0.UserID, post.PostID, nil)
+	require.NoError(t, err)
+
+	liker1 := testutil.CreateTestUser(t, env.userRepository, "liker1")
+	err = env.svc.AddLikeNotification(t.Context(), liker1.UserID, post.PostID, nil)
+	require.NoError(t, err)
+
+	comment, err := env.commentRepository.AddCommentToPost(t.Context(), liker1.UserID, post.PostID, "hi", nil)
+	require.NoError(t, err)
+	_, err = env.notificationRepository.InsertNotification(t.Context(), postOwner.UserID, &post.PostID, &comment.CommentID, nil, "@liker1 commented on your post", models.NotificationTypeComment, nil)
+	require.NoError(t, err)
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), postOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+
+	err = env.svc.RemoveNotificationsBetween(t.Context(), liker1.UserID, postOwner.UserID)
+	require.NoError(t, err)
+
+	notifications, err = env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), postOwner, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@liker0 liked your post", notifications[0].Message)
+}
+
+func TestGetNotifications_MarksHiddenNotificationsAsRead(t *testing.T) {
+	env := setupNotificationService(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	_, err := env.postSvc.NewPost(t.Context(), user0, "hello @user1", nil, nil, new(int(models.VisibilityPublic)), nil)
+	require.NoError(t, err)
+
+	count, err := env.svc.GetUserUnreadNotificationCount(t.Context(), user1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = env.userRepository.UpdateIsPrivate(t.Context(), user0.UserID, true)
+	require.NoError(t, err)
+
+	notifications, err := env.svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), user1, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	count, err = env.svc.GetUserUnreadNotificationCount(t.Context(), user1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestDeleteComment_DeletesNotifications(t *testing.T) {
+	env := setupNotificationService(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	post, err := env.postRepository.InsertPost(t.Context(), user0.UserID, "test post", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	comment, err := env.commentRepository.AddCommentToPost(t.Context(), user1.UserID, post.PostID, "hi", nil)
+	require.NoError(t, err)
+	notification, err := env.notificationRepository.InsertNotification(t.Context(), user0.UserID, &post.PostID, &comment.CommentID, nil, "@user1 commented on your post", models.NotificationTypeComment, nil)
+	require.NoError(t, err)
+
+	err = env.commentRepository.DeleteComment(t.Context(), comment.CommentID)
+	require.NoError(t, err)
+
+	_, err = env.notificationRepository.GetNotificationById(t.Context(), notification.NotificationID)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestGetNotifications_QueryCountDoesNotGrowWithPageSize(t *testing.T) {
+	testDB := testutil.StartPostgres(t)
+	counter := testutil.NewQueryCounter(testDB.Pool)
+	q := queries.New(counter)
+	svc := notification.NewService(notification.NewNotificationStore(q), post.NewDBPostRepository(q), comment.NewStore(q), user.NewUserRepository(q, testDB.BucketRepository), testDB.BucketRepository, apns.Client{})
+
+	recipient := testutil.CreateTestUser(t, testDB.UserRepository, "recipient")
+	addNotifications := func(prefix string, n int) {
+		for i := range n {
+			actor := testutil.CreateTestUser(t, testDB.UserRepository, fmt.Sprintf("%s%d", prefix, i))
+			post, err := testDB.PostRepository.InsertPost(t.Context(), recipient.UserID, "post", nil, nil, new(models.VisibilityPublic), nil)
+			require.NoError(t, err)
+			comment, err := testDB.CommentRepository.AddCommentToPost(t.Context(), actor.UserID, post.PostID, "comment", nil)
+			require.NoError(t, err)
+
+			_, err = testDB.NotificationStore.InsertNotification(t.Context(), recipient.UserID, &post.PostID, &comment.CommentID, nil, "comment", models.NotificationTypeComment, nil)
+			require.NoError(t, err)
+			_, err = testDB.NotificationStore.InsertNotification(t.Context(), recipient.UserID, nil, nil, nil, "follow", models.NotificationTypeFollowers, &actor.UserID)
+			require.NoError(t, err)
+			like, err := testDB.NotificationStore.InsertNotification(t.Context(), recipient.UserID, &post.PostID, nil, nil, "like", models.NotificationTypeLike, nil)
+			require.NoError(t, err)
+			err = testDB.NotificationStore.InsertNotificationActor(t.Context(), like.NotificationID, actor.UserID)
+			require.NoError(t, err)
+		}
+	}
+
+	addNotifications("first", 1)
+	counter.Reset()
+	notifications, err := svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), recipient, time.Now().UTC(), 50, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	singlePage := counter.Count()
+
+	addNotifications("more", 15)
+	counter.Reset()
+	notifications, err = svc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), recipient, time.Now().UTC(), 50, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 48)
+	for _, n := range notifications {
+		switch n.NotificationType {
+		case models.NotificationTypeComment:
+			assert.NotNil(t, n.Comment)
+			assert.NotNil(t, n.Post)
+		case models.NotificationTypeFollowers:
+			assert.NotNil(t, n.TargetUserUsername)
+		case models.NotificationTypeLike:
+			assert.True(t, n.HasNotificationActors)
+		}
+	}
+
+	// the page itself, then posts, post images, comments, comment images, target users and actors
+	assert.Equal(t, 7, singlePage)
+	assert.Equal(t, singlePage, counter.Count())
+}
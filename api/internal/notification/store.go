@@ -10,6 +10,7 @@ import (
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
@@ -178,11 +179,108 @@ func (r Store) FindLikeNotification(ctx context.Context, userId int, postId int,
 	return new(utilities.MapNotification(notification)), nil
 }
 
+// FindReactionNotification finds the most recent reaction notification for a user on a post or comment
+func (r Store) FindReactionNotification(ctx context.Context, userId int, postId int, commentId *int) (*models.Notification, error) {
+	notification, err := r.querier.FindReactionNotification(ctx, queries.FindReactionNotificationParams{
+		UserID:    userId,
+		PostID:    &postId,
+		CommentID: commentId,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return new(utilities.MapNotification(notification)), nil
+}
+
+// IsMutingNotificationsFrom checks whether a user has muted notifications from another user.
+func (r Store) IsMutingNotificationsFrom(ctx context.Context, userId int, actorId int) (bool, error) {
+	return r.querier.IsMutingNotificationsFrom(ctx, queries.IsMutingNotificationsFromParams{
+		UserID:       userId,
+		TargetUserID: actorId,
+	})
+}
+
+// ContainsMutedWord checks whether the post or comment a notification for userId would reference
+// contains one of the words they've muted in notifications. Their own posts and comments never do.
+func (r Store) ContainsMutedWord(ctx context.Context, userId int, postId *int, commentId *int) (bool, error) {
+	return r.querier.NotificationContainsMutedWord(ctx, queries.NotificationContainsMutedWordParams{
+		PostID:    postId,
+		CommentID: commentId,
+		UserID:    userId,
+	})
+}
+
+// FindPollVoteNotification finds the most recent vote notification for a user's poll. Poll
+// notifications that track no voters, like the one sent when a poll ends, aren't vote notifications.
+func (r Store) FindPollVoteNotification(ctx context.Context, userId int, postId int) (*models.Notification, error) {
+	notification, err := r.querier.FindPollVoteNotification(ctx, queries.FindPollVoteNotificationParams{
+		UserID: userId,
+		PostID: &postId,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return new(utilities.MapNotification(notification)), nil
+}
+
+// LockNotification waits for any other transaction changing a user's aggregated notification of the
+// given type about a post to finish, and holds the lock until the current transaction ends
+func (r Store) LockNotification(ctx context.Context, userId int, postId int, notificationType models.NotificationType) error {
+	return r.querier.LockNotification(ctx, queries.LockNotificationParams{
+		UserID:           userId,
+		PostID:           postId,
+		NotificationType: string(notificationType),
+	})
+}
+
 // DeleteNotificationById deletes a notification by its ID
 func (r Store) DeleteNotificationById(ctx context.Context, notificationId int) error {
 	return r.querier.DeleteNotificationById(ctx, notificationId)
 }
 
+// DeleteFollowRequestNotification deletes the notification telling a user about another user's follow request
+func (r Store) DeleteFollowRequestNotification(ctx context.Context, userId int, requesterId int) error {
+	return r.querier.DeleteFollowRequestNotification(ctx, queries.DeleteFollowRequestNotificationParams{
+		UserID:       userId,
+		TargetUserID: &requesterId,
+	})
+}
+
+// DeleteNotificationsInvolvingUser deletes a user's notifications that target another user or
+// reference their posts or comments.
+func (r Store) DeleteNotificationsInvolvingUser(ctx context.Context, userId int, otherUserId int) error {
+	return r.querier.DeleteNotificationsInvolvingUser(ctx, queries.DeleteNotificationsInvolvingUserParams{
+		UserID:       userId,
+		TargetUserID: otherUserId,
+	})
+}
+
+// GetNotificationsWithActor retrieves a user's aggregated notifications that actorId is one of the actors in.
+func (r Store) GetNotificationsWithActor(ctx context.Context, userId int, actorId int) ([]*models.Notification, error) {
+	notifications, err := r.querier.GetNotificationsWithActor(ctx, queries.GetNotificationsWithActorParams{
+		UserID:  userId,
+		ActorID: actorId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Notification, len(notifications))
+	for i, notification := range notifications {
+		result[i] = new(utilities.MapNotification(notification))
+	}
+
+	return result, nil
+}
+
 func (r Store) InsertNotificationActor(ctx context.Context, notificationId int, userId int) error {
 	return r.querier.InsertNotificationActor(ctx, queries.InsertNotificationActorParams{
 		NotificationID: notificationId,
@@ -200,6 +298,16 @@ func (r Store) DeleteNotificationActor(ctx context.Context, notificationId int,
 func (r Store) GetNotificationActors(ctx context.Context, notificationId int) ([]int, error) {
 	return r.querier.GetNotificationActors(ctx, notificationId)
 }
+
+// GetNotificationIdsWithActors returns which of notificationIds have at least one actor
+func (r Store) GetNotificationIdsWithActors(ctx context.Context, notificationIds []int) ([]int, error) {
+	return r.querier.GetNotificationIdsWithActors(ctx, notificationIds)
+}
+
+// MarkNotificationsAsRead marks several notifications as read at once
+func (r Store) MarkNotificationsAsRead(ctx context.Context, notificationIds []int) error {
+	return r.querier.MarkNotificationsAsReadByIds(ctx, notificationIds)
+}
 func (r Store) UpdateNotificationMessage(ctx context.Context, notificationId int, message string, facets db.Facets) error {
 	return r.querier.UpdateNotificationMessage(ctx, queries.UpdateNotificationMessageParams{
 		NotificationID: notificationId,
@@ -248,3 +356,8 @@ func (r Store) RemoveDeviceToken(ctx context.Context, token string) error {
 func NewNotificationStore(querier queries.Querier) Store {
 	return Store{querier: querier}
 }
+
+// WithTx returns a copy of the repository that runs its queries in the unit of work's transaction
+func (r Store) WithTx(uow *transaction.UnitOfWork) Store {
+	return Store{querier: uow.Querier()}
+}
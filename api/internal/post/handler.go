@@ -7,6 +7,7 @@ import (
 
 	"splajompy.com/api/v2/internal/db"
 
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/utilities"
 )
@@ -32,9 +33,13 @@ func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWrit
 	withAuth("GET /post/{id}", h.GetPostById)
 	withAuth("DELETE /post/{id}", h.DeletePostById)
 	withAuth("POST /post/{id}/report", h.ReportPost)
+	withAuth("PUT /post/{id}/images/{image_id}/alt-text", h.UpdateImageAltText)
 
 	// polls
 	withAuth("POST /post/{post_id}/vote/{option_index}", h.VoteOnPost)
+	withAuth("DELETE /post/{post_id}/vote/{option_index}", h.RemovePollVote)
+	withAuth("PUT /post/{post_id}/vote", h.SetPollVotes)
+	withAuth("DELETE /post/{post_id}/vote", h.RetractPollVote)
 
 	// likes
 	withAuth("POST /post/{id}/liked", h.AddPostLike)
@@ -49,10 +54,11 @@ func (h *Handler) CreateNewPostV2(w http.ResponseWriter, r *http.Request) {
 	currentUser := utilities.GetAuthenticatedUser(r)
 
 	var requestBody struct {
-		Text        string                   `json:"text"`
-		ImageKeymap map[int]models.ImageData `json:"imageKeymap"`
-		Visibility  *int                     `json:"visibility"`
-		Poll        *db.Poll                 `json:"poll"`
+		Text           string                   `json:"text"`
+		ImageKeymap    map[int]models.ImageData `json:"imageKeymap"`
+		Visibility     *int                     `json:"visibility"`
+		AudienceListID *int                     `json:"audienceListId"`
+		Poll           *db.Poll                 `json:"poll"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
@@ -65,7 +71,12 @@ func (h *Handler) CreateNewPostV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := h.svc.NewPost(r.Context(), *currentUser, requestBody.Text, requestBody.ImageKeymap, requestBody.Poll, requestBody.Visibility)
+	_, err := h.svc.NewPost(r.Context(), *currentUser, requestBody.Text, requestBody.ImageKeymap, requestBody.Poll, requestBody.Visibility, requestBody.AudienceListID)
+	if errors.Is(err, ErrInvalidAudienceList) || errors.Is(err, ErrInvalidVisibility) || errors.Is(err, ErrInvalidPollSelections) ||
+		errors.Is(err, ErrInvalidPollDeadline) || media.IsRejected(err) {
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
@@ -116,6 +127,44 @@ func (h *Handler) GetPostById(w http.ResponseWriter, r *http.Request) {
 	utilities.HandleSuccess(w, post)
 }
 
+// UpdateImageAltText changes the description of one of the current user's post images.
+func (h *Handler) UpdateImageAltText(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	postId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	imageId, err := utilities.GetIntPathParam(r, "image_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid image ID")
+		return
+	}
+
+	var requestBody struct {
+		AltText string `json:"altText"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Bad request format")
+		return
+	}
+
+	err = h.svc.UpdateImageAltText(r.Context(), *currentUser, postId, imageId, requestBody.AltText)
+	switch {
+	case errors.Is(err, ErrPostNotFound), errors.Is(err, ErrImageNotFound):
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrNotPostAuthor):
+		utilities.HandleError(w, http.StatusForbidden, err.Error())
+	case media.IsRejected(err):
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+	default:
+		utilities.HandleEmptySuccess(w)
+	}
+}
+
 func (h *Handler) DeletePostById(w http.ResponseWriter, r *http.Request) {
 	currentUser := utilities.GetAuthenticatedUser(r)
 
@@ -230,12 +279,75 @@ func (h *Handler) VoteOnPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = h.svc.VoteOnPoll(r.Context(), *currentUser, postId, optionIndex)
+	handlePollVoteError(w, err)
+}
+
+func (h *Handler) RemovePollVote(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	postId, err := utilities.GetIntPathParam(r, "post_id")
 	if err != nil {
-		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
-	utilities.HandleEmptySuccess(w)
+	optionIndex, err := utilities.GetIntPathParam(r, "option_index")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid option index")
+		return
+	}
+
+	err = h.svc.RemovePollVote(r.Context(), *currentUser, postId, optionIndex)
+	handlePollVoteError(w, err)
+}
+
+func (h *Handler) SetPollVotes(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	postId, err := utilities.GetIntPathParam(r, "post_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var requestBody struct {
+		Options []int `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Bad request format")
+		return
+	}
+
+	err = h.svc.SetPollVotes(r.Context(), *currentUser, postId, requestBody.Options)
+	handlePollVoteError(w, err)
+}
+
+func (h *Handler) RetractPollVote(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	postId, err := utilities.GetIntPathParam(r, "post_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	err = h.svc.SetPollVotes(r.Context(), *currentUser, postId, nil)
+	handlePollVoteError(w, err)
+}
+
+func handlePollVoteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPostNotFound), errors.Is(err, ErrPollNotFound):
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrPollClosed):
+		utilities.HandleError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidPollOption), errors.Is(err, ErrTooManySelections):
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+	default:
+		utilities.HandleEmptySuccess(w)
+	}
 }
 
 func (h *Handler) GetAllPostsWithTimeOffset(w http.ResponseWriter, r *http.Request) {
@@ -5,46 +5,74 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/resend/resend-go/v3"
-	"golang.org/x/sync/errgroup"
 	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/templates"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
 var ErrPostNotFound = errors.New("this post does not exist")
+var ErrInvalidAudienceList = errors.New("this audience list does not exist")
+var ErrInvalidVisibility = errors.New("this visibility is not supported")
+var ErrImageNotFound = errors.New("this image does not exist")
+var ErrNotPostAuthor = errors.New("only the author can edit this post")
+var ErrPollNotFound = errors.New("this post does not have a poll")
+var ErrInvalidPollOption = errors.New("this poll option does not exist")
+var ErrInvalidPollSelections = errors.New("a poll can't allow more selections than it has options")
+var ErrInvalidPollDeadline = errors.New("a poll must close in the future")
+var ErrPollClosed = errors.New("this poll has closed")
+var ErrTooManySelections = errors.New("this poll doesn't allow that many selections")
+
+// endedPollBatchSize bounds how many ended polls are notified in one transaction
+const endedPollBatchSize = 100
 
 type Service struct {
 	postRepository      Store
 	userRepository      user.Store
 	likeRepository      like.Store
+	reactionRepository  reactionReader
 	notificationService notification.Service
 	bucketRepository    bucket.Repository
+	imageProcessor      *media.Processor
 	emailService        *resend.Client
+	txManager           *transaction.Manager
 }
 
-func NewService(postRepository Store, userRepository user.Store, likeRepository like.Store, notificationService notification.Service, bucketRepo bucket.Repository, emailService *resend.Client) *Service {
+// reactionReader summarizes reactions. It's satisfied by reaction.Store, which can't be imported
+// here since the reaction package depends on this one.
+type reactionReader interface {
+	GetReactionSummaries(ctx context.Context, currentUserId int, postId int, commentId *int) ([]models.ReactionSummary, error)
+	GetPostReactionSummaries(ctx context.Context, currentUserId int, postIds []int) (map[int][]models.ReactionSummary, error)
+}
+
+func NewService(postRepository Store, userRepository user.Store, likeRepository like.Store, reactionRepository reactionReader, notificationService notification.Service, bucketRepo bucket.Repository, emailService *resend.Client, txManager *transaction.Manager) *Service {
 	return &Service{
 		postRepository:      postRepository,
 		userRepository:      userRepository,
 		likeRepository:      likeRepository,
+		reactionRepository:  reactionRepository,
 		notificationService: notificationService,
 		bucketRepository:    bucketRepo,
+		imageProcessor:      media.NewProcessor(bucketRepo),
 		emailService:        emailService,
+		txManager:           txManager,
 	}
 }
 
 // NewPost preprocesses a new post and stores it in the database.
-func (s *Service) NewPost(ctx context.Context, currentUser models.PublicUser, text string, imageKeymap map[int]models.ImageData, poll *db.Poll, visibilityEnum *int) (*models.Post, error) {
+func (s *Service) NewPost(ctx context.Context, currentUser models.PublicUser, text string, imageKeymap map[int]models.ImageData, poll *db.Poll, visibilityEnum *int, audienceListId *int) (*models.Post, error) {
 	facets, err := utilities.GenerateFacets(ctx, s.userRepository, text)
 	if err != nil {
 		return nil, err
@@ -52,6 +80,9 @@ func (s *Service) NewPost(ctx context.Context, currentUser models.PublicUser, te
 
 	var attributes *db.Attributes
 	if poll != nil {
+		if err := validatePoll(*poll, time.Now()); err != nil {
+			return nil, err
+		}
 		attributes = &db.Attributes{
 			Poll: *poll,
 		}
@@ -61,39 +92,104 @@ func (s *Service) NewPost(ctx context.Context, currentUser models.PublicUser, te
 	if visibilityEnum != nil {
 		visibilityType = models.VisibilityTypeEnum(*visibilityEnum)
 	}
+	if !visibilityType.IsValid() {
+		return nil, ErrInvalidVisibility
+	}
+
+	// only posts shared with a list keep a reference to it
+	if visibilityType != models.VisibilityAudienceList {
+		audienceListId = nil
+	} else {
+		if audienceListId == nil {
+			return nil, ErrInvalidAudienceList
+		}
+		isOwner, err := s.postRepository.IsAudienceListOwnedByUser(ctx, *audienceListId, currentUser.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOwner {
+			return nil, ErrInvalidAudienceList
+		}
+	}
 
-	post, err := s.postRepository.InsertPost(ctx, currentUser.UserID, text, facets, attributes, &visibilityType)
+	if len(imageKeymap) > 0 {
+		requiresAltText, err := s.userRepository.GetUserRequiresAltText(ctx, currentUser.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if requiresAltText {
+			if err := media.RequireAltText(imageKeymap); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	uploads, err := s.imageProcessor.PrepareMedia(ctx, currentUser.UserID, imageKeymap)
 	if err != nil {
-		return nil, errors.New("unable to create post")
+		return nil, err
 	}
-	postId := post.PostID
+	defer media.Discard(uploads)
 
-	imageBlobKeys, err := s.bucketRepository.PublishStagedImages(ctx, currentUser.UserID, "post", postId, imageKeymap)
+	// media is published before the transaction so uploading doesn't hold a connection, or the
+	// author's counter row, for its duration. Its keys include the post's ID, so that's reserved first.
+	postId, err := s.postRepository.ReservePostId(ctx)
+	if err != nil {
+		return nil, errors.New("unable to create post")
+	}
+	publishedMedia, err := s.imageProcessor.Publish(ctx, currentUser.UserID, "post", postId, uploads)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, blobKey := range imageBlobKeys {
-		_, err = s.postRepository.InsertImage(ctx, post.PostID, imageKeymap[i].Height, imageKeymap[i].Width, blobKey, i)
+	// the post, its attachments and its notifications are created together or not at all
+	var post *models.Post
+	err = s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		uow.OnRollback(func(ctx context.Context) error {
+			return s.bucketRepository.DeleteObjects(ctx, media.PublishedKeys(publishedMedia))
+		})
+
+		postRepository := s.postRepository.WithTx(uow)
+		notificationService := s.notificationService.WithTx(uow)
+
+		post, err = postRepository.InsertReservedPost(ctx, postId, currentUser.UserID, text, facets, attributes, &visibilityType, audienceListId)
 		if err != nil {
-			return nil, errors.New("unable to create post")
+			return errors.New("unable to create post")
 		}
-	}
 
-	// send notifications to users who are mentioned in post
-	usersToNotify := map[int]bool{}
-	for _, facet := range facets {
-		if facet.UserId != currentUser.UserID {
-			usersToNotify[facet.UserId] = true
+		if attributes != nil && attributes.Poll.ClosesAt != nil {
+			err = postRepository.InsertPollDeadline(ctx, postId, *attributes.Poll.ClosesAt)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	for userId := range usersToNotify {
-		text := fmt.Sprintf("@%s mentioned you", currentUser.Username)
-		_, err = s.notificationService.AddNotification(ctx, userId, &postId, nil, nil, text, models.NotificationTypeMention, &post.Text)
-		if err != nil {
-			return nil, err
+		for i, published := range publishedMedia {
+			_, err = postRepository.InsertImage(ctx, post.PostID, published, i)
+			if err != nil {
+				return errors.New("unable to create post")
+			}
+		}
+
+		// send notifications to users who are mentioned in post
+		usersToNotify := map[int]bool{}
+		for _, facet := range facets {
+			if facet.UserId != currentUser.UserID {
+				usersToNotify[facet.UserId] = true
+			}
 		}
+
+		for userId := range usersToNotify {
+			text := fmt.Sprintf("@%s mentioned you", currentUser.Username)
+			_, err = notificationService.AddNotification(ctx, userId, &currentUser.UserID, &postId, nil, nil, text, models.NotificationTypeMention, &post.Text)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return post, nil
@@ -105,93 +201,131 @@ func (s *Service) NewPresignedStagingUrl(ctx context.Context, currentUser models
 
 // GetPostById fetches a post by its id.
 func (s *Service) GetPostById(ctx context.Context, userId int, postId int) (*models.DetailedPost, error) {
-	post, err := s.postRepository.GetPostById(ctx, postId, userId)
-	if errors.Is(err, ErrPostNotFound) {
-		return nil, ErrPostNotFound
-	}
+	posts, err := s.getPostsByPostIDs(ctx, userId, []int{postId})
 	if err != nil {
 		return nil, err
 	}
+	if len(posts) == 0 {
+		return nil, ErrPostNotFound
+	}
+	return &posts[0], nil
+}
 
-	user, err := s.userRepository.GetUserById(ctx, post.UserID)
+// getPostsByPostIDs hydrates postIDs in order for userId, leaving out posts that don't exist or
+// that the user can't see. Each kind of detail is loaded for every post in a single query, so the
+// number of queries doesn't depend on how many posts are requested.
+func (s *Service) getPostsByPostIDs(ctx context.Context, userId int, postIDs []int) ([]models.DetailedPost, error) {
+	postsById, err := s.postRepository.GetPostsByIds(ctx, postIDs, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	isLiked, _ := s.postRepository.IsPostLikedByUserId(ctx, userId, post.PostID)
-
-	images, _ := s.postRepository.GetImagesForPost(ctx, post.PostID)
-	if images == nil {
-		images = []queries.Image{}
+	visibleIds := make([]int, 0, len(postsById))
+	authorIds := make([]int, 0, len(postsById))
+	hasPolls := false
+	for _, postId := range postIDs {
+		post, ok := postsById[postId]
+		if !ok || slices.Contains(visibleIds, postId) {
+			continue
+		}
+		visibleIds = append(visibleIds, postId)
+		authorIds = append(authorIds, post.UserID)
+		hasPolls = hasPolls || post.Attributes != nil
 	}
-	detailedImages := []models.DetailedImage{}
-	for i, image := range images {
-		url, err := s.bucketRepository.GetPresignedGetObject(ctx, images[i].ImageBlobUrl)
-		if err != nil {
-			return nil, errors.New("unable to generate presigned url for post image")
-		}
-		detailedImages = append(detailedImages, models.DetailedImage{
-			ImageID:      image.ImageID,
-			PostId:       postId,
-			Height:       image.Height,
-			Width:        image.Width,
-			ImageBlobUrl: url,
-			DisplayOrder: i,
-		})
+	if len(visibleIds) == 0 {
+		return []models.DetailedPost{}, nil
 	}
 
-	commentCount, _ := s.postRepository.GetCommentCountForPost(ctx, post.PostID)
-	relevantLikes, hasOtherLikes, _ := s.getRelevantLikes(ctx, userId, postId)
+	authors, err := s.userRepository.GetUsersByIds(ctx, authorIds)
+	if err != nil {
+		return nil, err
+	}
 
-	var pollDetails *models.DetailedPoll
-	if post.Attributes != nil {
-		pollDetails, err = s.GetPollDetails(ctx, userId, postId, post.Attributes.Poll)
-		if err != nil {
+	likedIds, _ := s.likeRepository.GetLikedPostIds(ctx, userId, visibleIds)
+	images, _ := s.postRepository.GetImagesForPosts(ctx, visibleIds)
+	counters, _ := s.postRepository.GetPostCounters(ctx, visibleIds)
+	otherLikes, _ := s.likeRepository.GetOtherPostLikesForPosts(ctx, visibleIds, userId)
+	reactions, _ := s.reactionRepository.GetPostReactionSummaries(ctx, userId, visibleIds)
+	pinnedIds, _ := s.postRepository.GetPinnedPostIds(ctx, visibleIds)
+
+	var userVotes map[int][]int
+	var voteCounts map[int]map[int]int64
+	var voterCounts map[int]int
+	if hasPolls {
+		if userVotes, err = s.postRepository.GetUserVotesInPolls(ctx, visibleIds, userId); err != nil {
+			return nil, err
+		}
+		if voteCounts, err = s.postRepository.GetPollVoteCountsForPosts(ctx, visibleIds); err != nil {
+			return nil, err
+		}
+		if voterCounts, err = s.postRepository.GetPollVoterCounts(ctx, visibleIds); err != nil {
 			return nil, err
 		}
 	}
 
-	pinnedPostId, _ := s.postRepository.GetPinnedPostId(ctx, post.UserID)
-	isPinned := pinnedPostId != nil && *pinnedPostId == postId
+	showsPolls := utilities.IsAppUpdatedToVersion(ctx, "v1.3.0")
 
-	if pollDetails != nil && !utilities.IsAppUpdatedToVersion(ctx, "v1.3.0") {
-		if post.Text != "" {
-			post.Text += "\n\n"
+	posts := make([]models.DetailedPost, 0, len(visibleIds))
+	for _, postId := range visibleIds {
+		post := *postsById[postId]
+
+		author, ok := authors[post.UserID]
+		if !ok {
+			continue
 		}
-		post.Text += "This post contains a poll. Please update your app to view it."
-	}
 
-	return &models.DetailedPost{
-		Post:          *post,
-		User:          user,
-		IsLiked:       isLiked,
-		Images:        detailedImages,
-		CommentCount:  commentCount,
-		RelevantLikes: relevantLikes,
-		HasOtherLikes: hasOtherLikes,
-		Poll:          pollDetails,
-		IsPinned:      isPinned,
-	}, nil
-}
+		detailedImages := []models.DetailedImage{}
+		detailedMedia := []models.DetailedMedia{}
+		for i, image := range images[postId] {
+			attachment, err := media.MapMedia(ctx, s.bucketRepository, image, i)
+			if err != nil {
+				return nil, errors.New("unable to generate presigned url for post media")
+			}
+			detailedMedia = append(detailedMedia, attachment)
 
-func (s *Service) getPostsByPostIDs(ctx context.Context, currentUser models.PublicUser, postIDs []int) ([]models.DetailedPost, error) {
-	posts := make([]models.DetailedPost, len(postIDs))
+			// clients that predate video and GIF support only know about images
+			if !media.IsImage(image) {
+				continue
+			}
+			detailedImage, err := media.MapImage(ctx, s.bucketRepository, image, postId, i)
+			if err != nil {
+				return nil, errors.New("unable to generate presigned url for post image")
+			}
+			detailedImages = append(detailedImages, detailedImage)
+		}
 
-	g, ctx := errgroup.WithContext(ctx)
+		relevantLikes, hasOtherLikes := pickRelevantLikes(userId, postId, otherLikes[postId])
 
-	for i, postID := range postIDs {
-		g.Go(func() error {
-			post, err := s.GetPostById(ctx, currentUser.UserID, postID)
-			if err != nil {
-				return fmt.Errorf("unable to retrieve post %d", postID)
+		postReactions := reactions[postId]
+		if postReactions == nil {
+			postReactions = []models.ReactionSummary{}
+		}
+
+		var pollDetails *models.DetailedPoll
+		if post.Attributes != nil {
+			pollDetails = buildPollDetails(userId, post.UserID, post.Attributes.Poll, userVotes[postId], voteCounts[postId], counters[postId].PollVoteCount, voterCounts[postId])
+			if !showsPolls {
+				if post.Text != "" {
+					post.Text += "\n\n"
+				}
+				post.Text += "This post contains a poll. Please update your app to view it."
 			}
-			posts[i] = *post
-			return nil
-		})
-	}
+		}
 
-	if err := g.Wait(); err != nil {
-		return nil, err
+		posts = append(posts, models.DetailedPost{
+			Post:          post,
+			User:          author,
+			IsLiked:       slices.Contains(likedIds, postId),
+			Images:        detailedImages,
+			Media:         detailedMedia,
+			CommentCount:  counters[postId].CommentCount,
+			LikeCount:     counters[postId].LikeCount,
+			RelevantLikes: relevantLikes,
+			HasOtherLikes: hasOtherLikes,
+			Poll:          pollDetails,
+			IsPinned:      slices.Contains(pinnedIds, postId),
+			Reactions:     postReactions,
+		})
 	}
 
 	return posts, nil
@@ -217,6 +351,32 @@ func (s *Service) RemoveLikeFromPost(ctx context.Context, currentUser models.Pub
 	return s.notificationService.RemoveLikeNotification(ctx, currentUser.UserID, postId, nil)
 }
 
+// UpdateImageAltText changes the description of one of the current user's post images. An empty
+// description removes it.
+func (s *Service) UpdateImageAltText(ctx context.Context, currentUser models.PublicUser, postId int, imageId int, altText string) error {
+	post, err := s.postRepository.GetPostById(ctx, postId, currentUser.UserID)
+	if err != nil {
+		return err
+	}
+	if post.UserID != currentUser.UserID {
+		return ErrNotPostAuthor
+	}
+
+	altText, err = media.NormalizeAltText(altText)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.postRepository.UpdateImageAltText(ctx, postId, imageId, altText)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrImageNotFound
+	}
+	return nil
+}
+
 func (s *Service) DeletePost(ctx context.Context, currentUser models.PublicUser, postId int) error {
 	post, err := s.postRepository.GetPostById(ctx, postId, currentUser.UserID)
 	if err != nil {
@@ -230,14 +390,10 @@ func (s *Service) DeletePost(ctx context.Context, currentUser models.PublicUser,
 	return s.postRepository.DeletePost(ctx, postId)
 }
 
-// getRelevantLikes deterministically returns a short list of other users who have liked a given post,
-// along with a bool indicating whether there are more likers beyond the returned slice.
-func (s *Service) getRelevantLikes(ctx context.Context, userId int, postId int) ([]models.RelevantLike, bool, error) {
-	likes, err := s.likeRepository.GetOtherPostLikes(ctx, postId, userId)
-	if err != nil {
-		return nil, false, err
-	}
-
+// pickRelevantLikes deterministically picks a short list of the other users who have liked a given
+// post, along with a bool indicating whether there are more likers beyond the returned slice.
+func pickRelevantLikes(userId int, postId int, likes []queries.GetPostLikesRow) ([]models.RelevantLike, bool) {
+	likes = slices.Clone(likes)
 	sort.SliceStable(likes, func(i, j int) bool {
 		return utilities.SeededRandom(postId+likes[i].UserID) < utilities.SeededRandom(postId+likes[j].UserID)
 	})
@@ -253,7 +409,7 @@ func (s *Service) getRelevantLikes(ctx context.Context, userId int, postId int)
 		}
 	}
 
-	return mappedLikes, hasOtherLikes, nil
+	return mappedLikes, hasOtherLikes
 }
 
 func (s *Service) ReportPost(ctx context.Context, currentUser *models.PublicUser, postId int) error {
@@ -271,21 +427,23 @@ func (s *Service) ReportPost(ctx context.Context, currentUser *models.PublicUser
 	if err != nil {
 		return err
 	}
-	if images == nil {
-		images = []queries.Image{}
-	}
-
-	for i := range images {
-		url, err := s.bucketRepository.GetPresignedGetObject(ctx, images[i].ImageBlobUrl)
+	reportImages := []queries.Media{}
+	for _, image := range images {
+		key, ok := media.ThumbnailKey(image)
+		if !ok {
+			continue
+		}
+		url, err := s.bucketRepository.GetPresignedGetObject(ctx, key)
 		if err != nil {
 			slog.ErrorContext(ctx, "unable to generate presigned url")
 			return nil
 		}
 
-		images[i].ImageBlobUrl = url
+		image.BlobKey = url
+		reportImages = append(reportImages, image)
 	}
 
-	html, err := templates.GeneratePostReportEmail(currentUser.Username, author.Username, author.UserID, *post, images)
+	html, err := templates.GeneratePostReportEmail(currentUser.Username, author.Username, author.UserID, *post, reportImages)
 	if err != nil {
 		return err
 	}
@@ -301,8 +459,20 @@ func (s *Service) ReportPost(ctx context.Context, currentUser *models.PublicUser
 	return err
 }
 
-func (s *Service) GetPollDetails(ctx context.Context, userId int, postId int, poll db.Poll) (*models.DetailedPoll, error) {
-	currentUserVote, err := s.postRepository.GetUserVoteInPoll(ctx, postId, userId)
+// validatePoll checks the settings of a poll that's being created.
+func validatePoll(poll db.Poll, now time.Time) error {
+	if poll.MaxSelections < 0 || poll.MaxSelections > len(poll.Options) {
+		return ErrInvalidPollSelections
+	}
+	if poll.ClosesAt != nil && !poll.ClosesAt.After(now) {
+		return ErrInvalidPollDeadline
+	}
+	return nil
+}
+
+// GetPollDetails summarizes a poll's votes for userId.
+func (s *Service) GetPollDetails(ctx context.Context, userId int, postId int, authorId int, poll db.Poll) (*models.DetailedPoll, error) {
+	currentUserVotes, err := s.postRepository.GetUserVotesInPoll(ctx, postId, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -312,56 +482,196 @@ func (s *Service) GetPollDetails(ctx context.Context, userId int, postId int, po
 		return nil, err
 	}
 
-	voteCountMap := make(map[int]int64)
-	totalVotes := int64(0)
+	voterCount, err := s.postRepository.GetPollVoterCount(ctx, postId)
+	if err != nil {
+		return nil, err
+	}
+
+	voteCounts := make(map[int]int64)
+	totalVotes := 0
 	for _, voteRow := range voteTotals {
-		voteCountMap[voteRow.OptionIndex] = voteRow.Count
-		totalVotes += voteRow.Count
+		voteCounts[voteRow.OptionIndex] = voteRow.Count
+		totalVotes += int(voteRow.Count)
 	}
 
+	return buildPollDetails(userId, authorId, poll, currentUserVotes, voteCounts, totalVotes, voterCount), nil
+}
+
+// buildPollDetails summarizes a poll from its per-option vote counts. Polls that hide their results
+// leave out the per-option counts until the user votes or the poll closes; the author always sees them.
+func buildPollDetails(userId int, authorId int, poll db.Poll, currentUserVotes []int, voteCounts map[int]int64, totalVotes int, voterCount int) *models.DetailedPoll {
+	if currentUserVotes == nil {
+		currentUserVotes = []int{}
+	}
+
+	isClosed := poll.IsClosed(time.Now())
+	resultsHidden := poll.HideResultsUntilVoted && len(currentUserVotes) == 0 && userId != authorId && !isClosed
+
 	options := make([]models.DetailedPollOption, len(poll.Options))
 	for i, option := range poll.Options {
-		voteCount := voteCountMap[i]
+		voteCount := voteCounts[i]
+		if resultsHidden {
+			voteCount = 0
+		}
 		options[i] = models.DetailedPollOption{
 			Title:     option,
 			VoteTotal: int(voteCount),
 		}
 	}
 
+	var currentUserVote *int
+	if len(currentUserVotes) > 0 {
+		currentUserVote = new(currentUserVotes[0])
+	}
+
 	return &models.DetailedPoll{
-		Title:           poll.Title,
-		VoteTotal:       int(totalVotes),
-		CurrentUserVote: currentUserVote,
-		Options:         options,
-	}, nil
+		Title:            poll.Title,
+		VoteTotal:        totalVotes,
+		CurrentUserVote:  currentUserVote,
+		CurrentUserVotes: currentUserVotes,
+		VoterCount:       voterCount,
+		MaxSelections:    poll.SelectionLimit(),
+		ClosesAt:         poll.ClosesAt,
+		IsClosed:         isClosed,
+		ResultsHidden:    resultsHidden,
+		Options:          options,
+	}
 }
 
+// VoteOnPoll votes for an option in a poll. In single-choice polls this replaces the user's
+// previous vote; in multiple-choice polls the option is added to their selections.
 func (s *Service) VoteOnPoll(ctx context.Context, currentUser models.PublicUser, postId int, optionIndex int) error {
+	return s.changePollVotes(ctx, currentUser, postId, func(poll db.Poll, votes []int) ([]int, error) {
+		if slices.Contains(votes, optionIndex) {
+			return votes, nil
+		}
+		if poll.SelectionLimit() == 1 {
+			return []int{optionIndex}, nil
+		}
+		if len(votes) >= poll.SelectionLimit() {
+			return nil, ErrTooManySelections
+		}
+		return append(votes, optionIndex), nil
+	})
+}
+
+// SetPollVotes replaces the user's selections in a poll. No selections retracts their vote.
+func (s *Service) SetPollVotes(ctx context.Context, currentUser models.PublicUser, postId int, optionIndexes []int) error {
+	return s.changePollVotes(ctx, currentUser, postId, func(poll db.Poll, _ []int) ([]int, error) {
+		selected := slices.Compact(slices.Sorted(slices.Values(optionIndexes)))
+		if len(selected) > poll.SelectionLimit() {
+			return nil, ErrTooManySelections
+		}
+		return selected, nil
+	})
+}
+
+// RemovePollVote removes a single option from the user's selections in a poll.
+func (s *Service) RemovePollVote(ctx context.Context, currentUser models.PublicUser, postId int, optionIndex int) error {
+	return s.changePollVotes(ctx, currentUser, postId, func(_ db.Poll, votes []int) ([]int, error) {
+		return slices.DeleteFunc(votes, func(vote int) bool { return vote == optionIndex }), nil
+	})
+}
+
+// changePollVotes replaces the user's selections in an open poll with the result of change.
+func (s *Service) changePollVotes(ctx context.Context, currentUser models.PublicUser, postId int, change func(poll db.Poll, votes []int) ([]int, error)) error {
 	post, err := s.postRepository.GetPostById(ctx, postId, currentUser.UserID)
 	if err != nil {
 		return err
 	}
-
-	if optionIndex < 0 || len(post.Attributes.Poll.Options) <= optionIndex {
-		return errors.New("option index is out of range")
+	if post.Attributes == nil || len(post.Attributes.Poll.Options) == 0 {
+		return ErrPollNotFound
 	}
-
-	err = s.postRepository.InsertVote(ctx, postId, currentUser.UserID, optionIndex)
-	if err != nil {
-		return err
+	poll := post.Attributes.Poll
+	if poll.IsClosed(time.Now()) {
+		return ErrPollClosed
 	}
 
-	// send notification to poll owner (unless voting on own poll)
-	if currentUser.UserID != post.UserID {
-		optionTitle := post.Attributes.Poll.Options[optionIndex]
-		text := fmt.Sprintf("@%s voted \"%s\" in your poll", currentUser.Username, optionTitle)
-		_, err = s.notificationService.AddNotification(ctx, post.UserID, &postId, nil, nil, text, models.NotificationTypePoll, nil)
+	return s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		postRepository := s.postRepository.WithTx(uow)
+
+		// concurrent changes would each see the same votes and could together leave a single-choice
+		// poll with several
+		if err := postRepository.LockPollVotes(ctx, postId, currentUser.UserID); err != nil {
+			return err
+		}
+
+		votes, err := postRepository.GetUserVotesInPoll(ctx, postId, currentUser.UserID)
 		if err != nil {
 			return err
 		}
-	}
 
-	return nil
+		selected, err := change(poll, slices.Clone(votes))
+		if err != nil {
+			return err
+		}
+		for _, optionIndex := range selected {
+			if optionIndex < 0 || len(poll.Options) <= optionIndex {
+				return ErrInvalidPollOption
+			}
+		}
+
+		for _, optionIndex := range votes {
+			if !slices.Contains(selected, optionIndex) {
+				if err := postRepository.DeleteVote(ctx, postId, currentUser.UserID, optionIndex); err != nil {
+					return err
+				}
+			}
+		}
+		for _, optionIndex := range selected {
+			if !slices.Contains(votes, optionIndex) {
+				if err := postRepository.InsertVote(ctx, postId, currentUser.UserID, optionIndex); err != nil {
+					return err
+				}
+			}
+		}
+
+		// the author is told about each voter once, and they're forgotten again if they retract their vote
+		switch {
+		case len(votes) == 0 && len(selected) > 0:
+			return s.notificationService.WithTx(uow).AddPollVoteNotification(ctx, currentUser.UserID, postId)
+		case len(votes) > 0 && len(selected) == 0:
+			return s.notificationService.WithTx(uow).RemovePollVoteNotification(ctx, currentUser.UserID, postId)
+		}
+
+		return nil
+	})
+}
+
+// NotifyEndedPolls tells authors that their polls have closed. Polls are claimed in the same
+// transaction as their notifications, so each author is notified exactly once.
+func (s *Service) NotifyEndedPolls(ctx context.Context) error {
+	for {
+		var claimed int
+		err := s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+			notificationService := s.notificationService.WithTx(uow)
+
+			polls, err := s.postRepository.WithTx(uow).ClaimEndedPolls(ctx, endedPollBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(polls)
+
+			for _, poll := range polls {
+				postId := poll.PostID
+				text := "Your poll has ended"
+				if poll.Attributes != nil && poll.Attributes.Poll.Title != "" {
+					text = fmt.Sprintf("Your poll \"%s\" has ended", poll.Attributes.Poll.Title)
+				}
+				_, err = notificationService.AddNotification(ctx, poll.UserID, nil, &postId, nil, nil, text, models.NotificationTypePoll, nil)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if claimed < endedPollBatchSize {
+			return nil
+		}
+	}
 }
 
 type FeedType string
@@ -416,7 +726,7 @@ func (s *Service) GetPosts(ctx context.Context, currentUser models.PublicUser, f
 		return nil, err
 	}
 
-	return s.getPostsByPostIDs(ctx, currentUser, postIDs)
+	return s.getPostsByPostIDs(ctx, currentUser.UserID, postIDs)
 }
 
 // PinPost pins a post for the current user
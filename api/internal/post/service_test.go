@@ -1,28 +1,49 @@
 package post_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/audience"
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/comment"
+	pgdb "splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/reaction"
 	"splajompy.com/api/v2/internal/testutil"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
 type postServiceTestEnv struct {
-	svc            *post.Service
-	commentSvc     *comment.Service
-	userRepository user.Store
+	svc              *post.Service
+	commentSvc       *comment.Service
+	userSvc          *user.Service
+	userRepository   user.Store
+	audienceStore    audience.Store
+	bucketRepository bucket.Repository
+	mediaWorker      *media.Worker
+	notifications    notification.Store
+	pool             *pgxpool.Pool
 }
 
 func setupPostTest(t *testing.T) postServiceTestEnv {
@@ -32,13 +53,19 @@ func setupPostTest(t *testing.T) postServiceTestEnv {
 	_ = os.Setenv("ENVIRONMENT", "test")
 
 	notificationService := notification.NewService(db.NotificationStore, db.PostRepository, &db.CommentRepository, db.UserRepository, db.BucketRepository, apns.Client{})
-	svc := post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, *notificationService, db.BucketRepository, nil)
-	commentSvc := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.BucketRepository)
+	svc := post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, db.ReactionStore, *notificationService, db.BucketRepository, nil, db.TxManager)
+	commentSvc := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.ReactionStore, db.BucketRepository, db.TxManager)
 
 	return postServiceTestEnv{
-		svc:            svc,
-		commentSvc:     commentSvc,
-		userRepository: db.UserRepository,
+		svc:              svc,
+		commentSvc:       commentSvc,
+		userSvc:          user.NewUserService(db.UserRepository, *notificationService, db.BucketRepository, nil, db.TxManager),
+		userRepository:   db.UserRepository,
+		audienceStore:    audience.NewStore(db.Queries, db.UserRepository),
+		bucketRepository: db.BucketRepository,
+		mediaWorker:      media.NewWorker(media.NewStore(db.Queries), db.BucketRepository, media.FFmpegPosterExtractor{}),
+		notifications:    db.NotificationStore,
+		pool:             db.Pool,
 	}
 }
 
@@ -47,7 +74,7 @@ func TestGetPostById(t *testing.T) {
 
 	user := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.svc.NewPost(t.Context(), user, "post 1", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user, "post 1", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	post_returned, err := env.svc.GetPostById(t.Context(), user.UserID, post.PostID)
@@ -75,7 +102,7 @@ func TestDeletePost(t *testing.T) {
 
 	user := testutil.CreateTestUser(t, env.userRepository, "user0")
 
-	post, err := env.svc.NewPost(t.Context(), user, "post 0", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user, "post 0", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	err = env.svc.DeletePost(t.Context(), user, post.PostID)
@@ -91,12 +118,12 @@ func TestCreatePostWithImages_ReturnsImages(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 
 	images := map[int]models.ImageData{
-		1: {S3Key: "images/photo1.jpg", Width: 1920, Height: 1080},
-		2: {S3Key: "images/photo2.jpg", Width: 800, Height: 600},
-		3: {S3Key: "images/photo3.jpg", Width: 400, Height: 400},
+		1: testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 1600, 900),
+		2: testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 800, 600),
+		3: testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 200, 200),
 	}
 
-	post_initial, err := env.svc.NewPost(t.Context(), user0, "test post with images", images, nil, nil)
+	post_initial, err := env.svc.NewPost(t.Context(), user0, "test post with images", images, nil, nil, nil)
 	require.NoError(t, err)
 
 	post, err := env.svc.GetPostById(t.Context(), user0.UserID, post_initial.PostID)
@@ -106,13 +133,160 @@ func TestCreatePostWithImages_ReturnsImages(t *testing.T) {
 	assert.Len(t, post.Images, len(images))
 }
 
+func TestCreatePostWithImages_ProcessesUploads(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	staged := testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 1600, 900)
+	// dimensions sent by the client are ignored
+	staged.Width, staged.Height = 1, 1
+
+	newPost, err := env.svc.NewPost(t.Context(), user0, "test post with images", map[int]models.ImageData{0: staged}, nil, nil, nil)
+	require.NoError(t, err)
+
+	post, err := env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	require.Len(t, post.Images, 1)
+
+	image := post.Images[0]
+	assert.Equal(t, 1600, image.Width)
+	assert.Equal(t, 900, image.Height)
+	require.NotNil(t, image.Blurhash)
+	assert.NotEmpty(t, *image.Blurhash)
+	require.Len(t, image.Variants, 3)
+	assert.Equal(t, 320, image.Variants[0].Width)
+	assert.Equal(t, 180, image.Variants[0].Height)
+
+	// the fake bucket signs URLs by returning the key
+	for _, key := range []string{image.ImageBlobUrl, image.Variants[0].URL} {
+		_, err = env.bucketRepository.GetObject(t.Context(), key)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCreatePostWithGIF_IsPendingUntilProcessed(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 40, 30), palette), image.NewPaletted(image.Rect(0, 0, 40, 30), palette)},
+		Delay: []int{50, 50},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, animation))
+	key := bucket.StagingPrefix("test", user0.UserID) + "posts/animation.gif"
+	require.NoError(t, env.bucketRepository.PutObject(t.Context(), key, "image/gif", bytes.NewReader(buf.Bytes())))
+
+	newPost, err := env.svc.NewPost(t.Context(), user0, "test post with a gif", map[int]models.ImageData{0: {S3Key: key}}, nil, nil, nil)
+	require.NoError(t, err)
+
+	post, err := env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	require.Len(t, post.Media, 1)
+	assert.Equal(t, models.MediaTypeGIF, post.Media[0].Type)
+	assert.Equal(t, models.MediaStatusPending, post.Media[0].Status)
+	// older clients only understand still images
+	assert.Empty(t, post.Images)
+
+	require.NoError(t, env.mediaWorker.ProcessPending(t.Context()))
+
+	post, err = env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	require.Len(t, post.Media, 1)
+	attachment := post.Media[0]
+	assert.Equal(t, models.MediaStatusReady, attachment.Status)
+	assert.Equal(t, 40, attachment.Width)
+	assert.Equal(t, 30, attachment.Height)
+	require.NotNil(t, attachment.DurationMs)
+	assert.Equal(t, 1000, *attachment.DurationMs)
+	require.NotNil(t, attachment.PosterURL)
+	_, err = env.bucketRepository.GetObject(t.Context(), *attachment.PosterURL)
+	assert.NoError(t, err)
+}
+
+func TestCreatePostWithImages_RejectsInvalidUploads(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	othersImage := testutil.StageTestImage(t, env.bucketRepository, user1.UserID, 100, 100)
+	_, err := env.svc.NewPost(t.Context(), user0, "post", map[int]models.ImageData{0: othersImage}, nil, nil, nil)
+	assert.ErrorIs(t, err, media.ErrInvalidImage)
+
+	notAnImage := bucket.StagingPrefix(os.Getenv("ENVIRONMENT"), user0.UserID) + "posts/notes.jpg"
+	require.NoError(t, env.bucketRepository.PutObject(t.Context(), notAnImage, "image/jpeg", strings.NewReader("not an image")))
+	_, err = env.svc.NewPost(t.Context(), user0, "post", map[int]models.ImageData{0: {S3Key: notAnImage}}, nil, nil, nil)
+	assert.ErrorIs(t, err, media.ErrInvalidImage)
+
+	posts, err := env.svc.GetPosts(t.Context(), user0, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+func TestCreatePostWithImages_AltText(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	staged := testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 100, 100)
+	staged.AltText = "a gradient"
+	newPost, err := env.svc.NewPost(t.Context(), user0, "post", map[int]models.ImageData{0: staged}, nil, nil, nil)
+	require.NoError(t, err)
+
+	detailed, err := env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	require.Len(t, detailed.Images, 1)
+	require.NotNil(t, detailed.Images[0].AltText)
+	assert.Equal(t, "a gradient", *detailed.Images[0].AltText)
+	imageId := detailed.Images[0].ImageID
+
+	err = env.svc.UpdateImageAltText(t.Context(), user1, newPost.PostID, imageId, "something else")
+	assert.ErrorIs(t, err, post.ErrNotPostAuthor)
+
+	require.NoError(t, env.svc.UpdateImageAltText(t.Context(), user0, newPost.PostID, imageId, "a blue gradient"))
+	detailed, err = env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	require.NotNil(t, detailed.Images[0].AltText)
+	assert.Equal(t, "a blue gradient", *detailed.Images[0].AltText)
+
+	// an empty description removes it
+	require.NoError(t, env.svc.UpdateImageAltText(t.Context(), user0, newPost.PostID, imageId, " "))
+	detailed, err = env.svc.GetPostById(t.Context(), user0.UserID, newPost.PostID)
+	require.NoError(t, err)
+	assert.Nil(t, detailed.Images[0].AltText)
+}
+
+func TestCreatePostWithImages_RequiresAltTextWhenEnabled(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	require.NoError(t, env.userRepository.UpdateRequireAltText(t.Context(), user0.UserID, true))
+
+	staged := testutil.StageTestImage(t, env.bucketRepository, user0.UserID, 100, 100)
+	_, err := env.svc.NewPost(t.Context(), user0, "post", map[int]models.ImageData{0: staged}, nil, nil, nil)
+	assert.ErrorIs(t, err, media.ErrAltTextRequired)
+
+	// posts without images are unaffected
+	_, err = env.svc.NewPost(t.Context(), user0, "post", nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	staged.AltText = "a gradient"
+	_, err = env.svc.NewPost(t.Context(), user0, "post", map[int]models.ImageData{0: staged}, nil, nil, nil)
+	require.NoError(t, err)
+}
+
 func TestGetPosts_DoesNotReturnPrivatePosts(t *testing.T) {
 	env := setupPostTest(t)
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	new_post, err := env.svc.NewPost(t.Context(), user0, "test post please ignore", nil, nil, new(int(models.VisibilityCloseFriends)))
+	new_post, err := env.svc.NewPost(t.Context(), user0, "test post please ignore", nil, nil, new(int(models.VisibilityCloseFriends)), nil)
 	assert.NoError(t, err)
 
 	// user1 should not be able to see the private post
@@ -137,13 +311,75 @@ func TestGetPosts_DoesNotReturnPrivatePosts(t *testing.T) {
 	assert.Len(t, profile_posts, 0)
 }
 
+func TestGetPosts_HidesMutedWords(t *testing.T) {
+	env := setupPostTest(t)
+
+	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	_, err := env.userSvc.MuteWord(t.Context(), user0, "Spoiler  Alert", nil, nil)
+	require.NoError(t, err)
+	_, err = env.userSvc.MuteWord(t.Context(), user0, "#tv", []models.MutedWordScope{models.MutedWordScopeFeeds}, nil)
+	require.NoError(t, err)
+	_, err = env.userSvc.MuteWord(t.Context(), user0, "finale", []models.MutedWordScope{models.MutedWordScopeComments}, nil)
+	require.NoError(t, err)
+
+	for _, text := range []string{"SPOILER\nalert: it was him", "watching #TV tonight", "#tvshow", "spoileralert", "the finale"} {
+		_, err = env.svc.NewPost(t.Context(), user1, text, nil, nil, nil, nil)
+		require.NoError(t, err)
+	}
+	_, err = env.svc.NewPost(t.Context(), user0, "no spoiler alert here", nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	posts, err := env.svc.GetPosts(t.Context(), user0, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+
+	texts := make([]string, len(posts))
+	for i, p := range posts {
+		texts[i] = p.Post.Text
+	}
+	assert.ElementsMatch(t, []string{"#tvshow", "spoileralert", "the finale", "no spoiler alert here"}, texts)
+
+	// other users' feeds aren't affected
+	posts, err = env.svc.GetPosts(t.Context(), user1, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 6)
+}
+
+func TestGetPosts_MuteModesAndExpiry(t *testing.T) {
+	env := setupPostTest(t)
+
+	viewer := testutil.CreateTestUser(t, env.userRepository, "user0")
+	notificationsOnly := testutil.CreateTestUser(t, env.userRepository, "user1")
+	expired := testutil.CreateTestUser(t, env.userRepository, "user2")
+	timed := testutil.CreateTestUser(t, env.userRepository, "user3")
+
+	for _, poster := range []models.PublicUser{notificationsOnly, expired, timed} {
+		_, err := env.svc.NewPost(t.Context(), poster, "post", nil, nil, nil, nil)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, env.userSvc.MuteUser(t.Context(), viewer, notificationsOnly.UserID, models.MuteModeNotifications, nil))
+	require.NoError(t, env.userRepository.MuteUser(t.Context(), viewer.UserID, expired.UserID, models.MuteModeAll, new(time.Now().Add(-time.Minute))))
+	require.NoError(t, env.userSvc.MuteUser(t.Context(), viewer, timed.UserID, models.MuteModeAll, new(time.Now().Add(time.Hour))))
+
+	posts, err := env.svc.GetPosts(t.Context(), viewer, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+
+	posterIds := make([]int, len(posts))
+	for i, p := range posts {
+		posterIds[i] = p.User.UserID
+	}
+	assert.ElementsMatch(t, []int{notificationsOnly.UserID, expired.UserID}, posterIds)
+}
+
 func TestGetPostById_HiddenWhenPosterBlockedViewer(t *testing.T) {
 	env := setupPostTest(t)
 
 	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
 	viewer := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	returned_post, err := env.svc.GetPostById(t.Context(), viewer.UserID, post.PostID)
@@ -164,7 +400,7 @@ func TestGetPosts_HiddenWhenPosterBlockedViewer(t *testing.T) {
 	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
 	viewer := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	_, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, nil)
+	_, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	err = env.userRepository.BlockUser(t.Context(), poster.UserID, viewer.UserID)
@@ -204,7 +440,7 @@ func TestGetPosts_ProfilePinnedPostDoesNotReduceSubsequentPageSize(t *testing.T)
 
 	created := make([]*models.Post, 3*limit)
 	for i := range created {
-		p, err := env.svc.NewPost(ctx, user, fmt.Sprintf("post %d", i), nil, nil, nil)
+		p, err := env.svc.NewPost(ctx, user, fmt.Sprintf("post %d", i), nil, nil, nil, nil)
 		require.NoError(t, err)
 		created[i] = p
 	}
@@ -231,7 +467,7 @@ func TestGetPost_RelevantLikes(t *testing.T) {
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 
-	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	full_post, err := env.svc.GetPostById(t.Context(), user0.UserID, post.PostID)
@@ -265,6 +501,7 @@ func TestGetPost_RelevantLikes(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, full_post.RelevantLikes, 2)
 	assert.True(t, full_post.HasOtherLikes)
+	assert.Equal(t, 3, full_post.LikeCount)
 }
 
 func TestGetPost_DoesNotReturnRelevantLikesForBlockingUser(t *testing.T) {
@@ -273,7 +510,7 @@ func TestGetPost_DoesNotReturnRelevantLikesForBlockingUser(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	err = env.svc.AddLikeToPost(t.Context(), user1, post.PostID)
@@ -301,7 +538,7 @@ func TestGetPost_DoesNotReturnRelevantLikesForBlockedUser(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	err = env.svc.AddLikeToPost(t.Context(), user1, post.PostID)
@@ -328,7 +565,7 @@ func TestGetPost_DoesNotReturnRelevantLikesCurrentUser(t *testing.T) {
 
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 
-	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	err = env.svc.AddLikeToPost(t.Context(), user0, post.PostID)
@@ -345,7 +582,7 @@ func TestGetPost_RelevantLikesDoesNotCountCommentLikes(t *testing.T) {
 	user0 := testutil.CreateTestUser(t, env.userRepository, "user0")
 	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
 
-	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil)
+	post, err := env.svc.NewPost(t.Context(), user0, "test post", nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	comment, err := env.commentSvc.AddCommentToPost(t.Context(), user0, post.PostID, "test comment", nil)
@@ -357,4 +594,464 @@ func TestGetPost_RelevantLikesDoesNotCountCommentLikes(t *testing.T) {
 	full_post, err := env.svc.GetPostById(t.Context(), user0.UserID, post.PostID)
 	require.NoError(t, err)
 	assert.Empty(t, full_post.RelevantLikes)
+	assert.Zero(t, full_post.LikeCount)
+
+	comments, err := env.commentSvc.GetCommentsByPostId(t.Context(), user0, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, 1, comments[0].LikeCount)
+}
+
+func TestGetPosts_PrivateAccountVisibleOnlyToFollowers(t *testing.T) {
+	env := setupPostTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	follower := testutil.CreateTestUser(t, env.userRepository, "user1")
+	stranger := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.userRepository.UpdateIsPrivate(t.Context(), poster.UserID, true))
+	require.NoError(t, env.userRepository.FollowUser(t.Context(), follower.UserID, poster.UserID))
+
+	newPost, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	for _, viewer := range []models.PublicUser{poster, follower} {
+		_, err = env.svc.GetPostById(t.Context(), viewer.UserID, newPost.PostID)
+		assert.NoError(t, err)
+
+		posts, err := env.svc.GetPosts(t.Context(), viewer, post.FeedTypeAll, nil, 10, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+
+		posts, err = env.svc.GetPosts(t.Context(), viewer, post.FeedTypeProfile, &poster.UserID, 10, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+	}
+
+	_, err = env.svc.GetPostById(t.Context(), stranger.UserID, newPost.PostID)
+	assert.Error(t, err)
+
+	posts, err := env.svc.GetPosts(t.Context(), stranger, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 0)
+
+	posts, err = env.svc.GetPosts(t.Context(), stranger, post.FeedTypeProfile, &poster.UserID, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 0)
+}
+
+func TestGetPosts_AudienceListVisibleOnlyToMembers(t *testing.T) {
+	env := setupPostTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	member := testutil.CreateTestUser(t, env.userRepository, "user1")
+	stranger := testutil.CreateTestUser(t, env.userRepository, "user2")
+	lateMember := testutil.CreateTestUser(t, env.userRepository, "user3")
+
+	for _, u := range []models.PublicUser{member, stranger, lateMember} {
+		require.NoError(t, env.userRepository.FollowUser(t.Context(), u.UserID, poster.UserID))
+	}
+
+	list, err := env.audienceStore.CreateList(t.Context(), poster.UserID, "list")
+	require.NoError(t, err)
+	require.NoError(t, env.audienceStore.AddMember(t.Context(), list.ListID, member.UserID))
+
+	newPost, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, new(int(models.VisibilityAudienceList)), &list.ListID)
+	require.NoError(t, err)
+
+	// members added after the post was made don't see it
+	require.NoError(t, env.audienceStore.AddMember(t.Context(), list.ListID, lateMember.UserID))
+
+	for _, viewer := range []models.PublicUser{poster, member} {
+		_, err = env.svc.GetPostById(t.Context(), viewer.UserID, newPost.PostID)
+		assert.NoError(t, err)
+
+		posts, err := env.svc.GetPosts(t.Context(), viewer, post.FeedTypeAll, nil, 10, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+	}
+
+	for _, viewer := range []models.PublicUser{stranger, lateMember} {
+		_, err = env.svc.GetPostById(t.Context(), viewer.UserID, newPost.PostID)
+		assert.ErrorIs(t, err, post.ErrPostNotFound)
+
+		posts, err := env.svc.GetPosts(t.Context(), viewer, post.FeedTypeFollowing, nil, 10, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 0)
+	}
+}
+
+func TestNewPost_AudienceListMustBelongToAuthor(t *testing.T) {
+	env := setupPostTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	other := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	list, err := env.audienceStore.CreateList(t.Context(), other.UserID, "list")
+	require.NoError(t, err)
+
+	visibility := new(int(models.VisibilityAudienceList))
+
+	_, err = env.svc.NewPost(t.Context(), poster, "post0", nil, nil, visibility, &list.ListID)
+	assert.ErrorIs(t, err, post.ErrInvalidAudienceList)
+
+	_, err = env.svc.NewPost(t.Context(), poster, "post0", nil, nil, visibility, nil)
+	assert.ErrorIs(t, err, post.ErrInvalidAudienceList)
+}
+
+func TestGetPosts_FollowersVisibility(t *testing.T) {
+	env := setupPostTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	follower := testutil.CreateTestUser(t, env.userRepository, "user1")
+	stranger := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.userRepository.FollowUser(t.Context(), follower.UserID, poster.UserID))
+
+	newPost, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, new(int(models.VisibilityFollowers)), nil)
+	require.NoError(t, err)
+
+	for _, viewer := range []models.PublicUser{poster, follower} {
+		_, err = env.svc.GetPostById(t.Context(), viewer.UserID, newPost.PostID)
+		assert.NoError(t, err)
+
+		posts, err := env.svc.GetPosts(t.Context(), viewer, post.FeedTypeProfile, &poster.UserID, 10, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+	}
+
+	_, err = env.svc.GetPostById(t.Context(), stranger.UserID, newPost.PostID)
+	assert.ErrorIs(t, err, post.ErrPostNotFound)
+
+	posts, err := env.svc.GetPosts(t.Context(), stranger, post.FeedTypeAll, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 0)
+
+	posts, err = env.svc.GetPosts(t.Context(), stranger, post.FeedTypeProfile, &poster.UserID, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 0)
+}
+
+func TestNewPost_RejectsUnknownVisibility(t *testing.T) {
+	env := setupPostTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	_, err := env.svc.NewPost(t.Context(), poster, "post0", nil, nil, new(42), nil)
+	assert.ErrorIs(t, err, post.ErrInvalidVisibility)
+}
+
+func newTestPoll(t *testing.T, env postServiceTestEnv, author models.PublicUser, poll pgdb.Poll) int {
+	t.Helper()
+	if poll.Options == nil {
+		poll.Options = []string{"a", "b", "c"}
+	}
+	created, err := env.svc.NewPost(t.Context(), author, "poll", nil, &poll, nil, nil)
+	require.NoError(t, err)
+	return created.PostID
+}
+
+// closePoll moves a poll's close time into the past.
+func closePoll(t *testing.T, env postServiceTestEnv, postId int) {
+	t.Helper()
+	_, err := env.pool.Exec(t.Context(), `UPDATE posts
+		SET attributes = jsonb_set(attributes::jsonb, '{poll,closesAt}', to_jsonb(NOW() - INTERVAL '1 minute'))::json
+		WHERE post_id = $1`, postId)
+	require.NoError(t, err)
+	_, err = env.pool.Exec(t.Context(), `UPDATE poll_deadline SET closes_at = NOW() - INTERVAL '1 minute' WHERE post_id = $1`, postId)
+	require.NoError(t, err)
+}
+
+func getPoll(t *testing.T, env postServiceTestEnv, userId int, postId int) *models.DetailedPoll {
+	t.Helper()
+	detailed, err := env.svc.GetPostById(t.Context(), userId, postId)
+	require.NoError(t, err)
+	require.NotNil(t, detailed.Poll)
+	return detailed.Poll
+}
+
+func TestVoteOnPoll_ChangesSingleChoiceVote(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	postId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll"})
+
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 0))
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 1))
+
+	poll := getPoll(t, env, voter.UserID, postId)
+	assert.Equal(t, []int{1}, poll.CurrentUserVotes)
+	require.NotNil(t, poll.CurrentUserVote)
+	assert.Equal(t, 1, *poll.CurrentUserVote)
+	assert.Equal(t, 0, poll.Options[0].VoteTotal)
+	assert.Equal(t, 1, poll.Options[1].VoteTotal)
+	assert.Equal(t, 1, poll.VoterCount)
+
+	notifications, err := env.notifications.GetNotificationsForUserId(t.Context(), author.UserID, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, notifications, 1, "only the first vote notifies the author")
+
+	assert.ErrorIs(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 3), post.ErrInvalidPollOption)
+}
+
+func TestVoteOnPoll_ConcurrentVotesKeepSingleChoice(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	postId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll"})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Go(func() {
+			errs[i] = env.svc.VoteOnPoll(t.Context(), voter, postId, i%2)
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	poll := getPoll(t, env, voter.UserID, postId)
+	assert.Len(t, poll.CurrentUserVotes, 1)
+	assert.Equal(t, 1, poll.VoteTotal)
+	assert.Equal(t, 1, poll.VoterCount)
+
+	notifications, err := env.notifications.GetNotificationsForUserId(t.Context(), author.UserID, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, notifications, 1)
+}
+
+func TestVoteOnPoll_RetractVote(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	postId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll"})
+
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 2))
+	require.NoError(t, env.svc.SetPollVotes(t.Context(), voter, postId, nil))
+
+	poll := getPoll(t, env, voter.UserID, postId)
+	assert.Empty(t, poll.CurrentUserVotes)
+	assert.Nil(t, poll.CurrentUserVote)
+	assert.Equal(t, 0, poll.VoteTotal)
+}
+
+func TestVoteOnPoll_EnforcesMaxSelections(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	postId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll", MaxSelections: 2})
+
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 0))
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 2))
+	assert.ErrorIs(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 1), post.ErrTooManySelections)
+
+	require.NoError(t, env.svc.RemovePollVote(t.Context(), voter, postId, 0))
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 1))
+
+	poll := getPoll(t, env, voter.UserID, postId)
+	assert.Equal(t, []int{1, 2}, poll.CurrentUserVotes)
+	assert.Equal(t, 2, poll.MaxSelections)
+	assert.Equal(t, 2, poll.VoteTotal)
+	assert.Equal(t, 1, poll.VoterCount)
+
+	assert.ErrorIs(t, env.svc.SetPollVotes(t.Context(), voter, postId, []int{0, 1, 2}), post.ErrTooManySelections)
+	require.NoError(t, env.svc.SetPollVotes(t.Context(), voter, postId, []int{0, 0}))
+	assert.Equal(t, []int{0}, getPoll(t, env, voter.UserID, postId).CurrentUserVotes)
+}
+
+func TestGetPollDetails_HidesResultsUntilVoted(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	viewer := testutil.CreateTestUser(t, env.userRepository, "user2")
+	postId := newTestPoll(t, env, author, pgdb.Poll{
+		Title:                 "poll",
+		ClosesAt:              new(time.Now().Add(time.Hour)),
+		HideResultsUntilVoted: true,
+	})
+
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 1))
+
+	hidden := getPoll(t, env, viewer.UserID, postId)
+	assert.True(t, hidden.ResultsHidden)
+	assert.Equal(t, 0, hidden.Options[1].VoteTotal)
+
+	for _, userId := range []int{author.UserID, voter.UserID} {
+		poll := getPoll(t, env, userId, postId)
+		assert.False(t, poll.ResultsHidden)
+		assert.Equal(t, 1, poll.Options[1].VoteTotal)
+	}
+
+	closePoll(t, env, postId)
+
+	closed := getPoll(t, env, viewer.UserID, postId)
+	assert.True(t, closed.IsClosed)
+	assert.False(t, closed.ResultsHidden)
+	assert.Equal(t, 1, closed.Options[1].VoteTotal)
+}
+
+func TestVoteOnPoll_RejectsVotesAfterPollCloses(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	voter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	postId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll", ClosesAt: new(time.Now().Add(time.Hour))})
+
+	require.NoError(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 0))
+	closePoll(t, env, postId)
+
+	assert.ErrorIs(t, env.svc.VoteOnPoll(t.Context(), voter, postId, 1), post.ErrPollClosed)
+	assert.ErrorIs(t, env.svc.SetPollVotes(t.Context(), voter, postId, nil), post.ErrPollClosed)
+	assert.Equal(t, []int{0}, getPoll(t, env, voter.UserID, postId).CurrentUserVotes)
+}
+
+func TestNewPost_RejectsInvalidPollSettings(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	poll := pgdb.Poll{Title: "poll", Options: []string{"a", "b"}, MaxSelections: 3}
+	_, err := env.svc.NewPost(t.Context(), author, "poll", nil, &poll, nil, nil)
+	assert.ErrorIs(t, err, post.ErrInvalidPollSelections)
+
+	poll = pgdb.Poll{Title: "poll", Options: []string{"a", "b"}, ClosesAt: new(time.Now().Add(-time.Minute))}
+	_, err = env.svc.NewPost(t.Context(), author, "poll", nil, &poll, nil, nil)
+	assert.ErrorIs(t, err, post.ErrInvalidPollDeadline)
+}
+
+func TestNotifyEndedPolls_NotifiesAuthorOnce(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	endedId := newTestPoll(t, env, author, pgdb.Poll{Title: "ended", ClosesAt: new(time.Now().Add(time.Hour))})
+	newTestPoll(t, env, author, pgdb.Poll{Title: "open", ClosesAt: new(time.Now().Add(time.Hour))})
+	closePoll(t, env, endedId)
+
+	require.NoError(t, env.svc.NotifyEndedPolls(t.Context()))
+	require.NoError(t, env.svc.NotifyEndedPolls(t.Context()))
+
+	notifications, err := env.notifications.GetNotificationsForUserId(t.Context(), author.UserID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "Your poll \"ended\" has ended", notifications[0].Message)
+	require.NotNil(t, notifications[0].PostID)
+	assert.Equal(t, endedId, *notifications[0].PostID)
+}
+
+func TestGetPosts_QueryCountDoesNotGrowWithPageSize(t *testing.T) {
+	env := setupPostTest(t)
+	counter := testutil.NewQueryCounter(env.pool)
+	q := queries.New(counter)
+	notificationService := notification.NewService(notification.NewNotificationStore(q), post.NewDBPostRepository(q), comment.NewStore(q), user.NewUserRepository(q, env.bucketRepository), env.bucketRepository, apns.Client{})
+	svc := post.NewService(post.NewDBPostRepository(q), user.NewUserRepository(q, env.bucketRepository), like.NewStore(q), reaction.NewStore(q), *notificationService, env.bucketRepository, nil, nil)
+
+	viewer := testutil.CreateTestUser(t, env.userRepository, "viewer")
+	addPosts := func(prefix string, n int) {
+		for i := range n {
+			author := testutil.CreateTestUser(t, env.userRepository, fmt.Sprintf("%s%d", prefix, i))
+			created, err := env.svc.NewPost(t.Context(), author, "post", nil, nil, nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, env.svc.AddLikeToPost(t.Context(), viewer, created.PostID))
+			_, err = env.commentSvc.AddCommentToPost(t.Context(), viewer, created.PostID, "comment", nil)
+			require.NoError(t, err)
+
+			pollId := newTestPoll(t, env, author, pgdb.Poll{Title: "poll"})
+			require.NoError(t, env.svc.VoteOnPoll(t.Context(), viewer, pollId, 1))
+		}
+	}
+
+	addPosts("first", 1)
+	counter.Reset()
+	posts, err := svc.GetPosts(t.Context(), viewer, post.FeedTypeAll, nil, 50, nil)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	singlePage := counter.Count()
+
+	addPosts("more", 10)
+	counter.Reset()
+	posts, err = svc.GetPosts(t.Context(), viewer, post.FeedTypeAll, nil, 50, nil)
+	require.NoError(t, err)
+	require.Len(t, posts, 22)
+	for _, p := range posts {
+		if p.Poll != nil {
+			assert.Equal(t, []int{1}, p.Poll.CurrentUserVotes)
+			assert.Equal(t, 1, p.Poll.VoterCount)
+			continue
+		}
+		assert.True(t, p.IsLiked)
+		assert.Equal(t, 1, p.LikeCount)
+		assert.Equal(t, 1, p.CommentCount)
+	}
+
+	assert.Equal(t, singlePage, counter.Count())
+}
+
+func TestGetPost_CountersFollowRemovals(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	other := testutil.CreateTestUser(t, env.userRepository, "user1")
+	created, err := env.svc.NewPost(t.Context(), author, "test post", nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddLikeToPost(t.Context(), other, created.PostID))
+	comment, err := env.commentSvc.AddCommentToPost(t.Context(), other, created.PostID, "comment", nil)
+	require.NoError(t, err)
+	require.NoError(t, env.commentSvc.AddLikeToCommentById(t.Context(), author, created.PostID, comment.CommentID))
+
+	detailed, err := env.svc.GetPostById(t.Context(), author.UserID, created.PostID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, detailed.LikeCount)
+	assert.Equal(t, 1, detailed.CommentCount)
+
+	comments, err := env.commentSvc.GetCommentsByPostId(t.Context(), author, created.PostID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, 1, comments[0].LikeCount)
+
+	require.NoError(t, env.svc.RemoveLikeFromPost(t.Context(), other, created.PostID))
+	require.NoError(t, env.commentSvc.DeleteComment(t.Context(), other, comment.CommentID))
+
+	detailed, err = env.svc.GetPostById(t.Context(), author.UserID, created.PostID)
+	require.NoError(t, err)
+	assert.Zero(t, detailed.LikeCount)
+	assert.Zero(t, detailed.CommentCount)
+}
+
+func TestGetPost_CommentCountLeavesOutHiddenComments(t *testing.T) {
+	env := setupPostTest(t)
+
+	author := testutil.CreateTestUser(t, env.userRepository, "user0")
+	commenter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	created, err := env.svc.NewPost(t.Context(), author, "test post", nil, nil, nil, nil)
+	require.NoError(t, err)
+	_, err = env.commentSvc.AddCommentToPost(t.Context(), commenter, created.PostID, "comment", nil)
+	require.NoError(t, err)
+
+	commentCount := func() int {
+		t.Helper()
+		detailed, err := env.svc.GetPostById(t.Context(), author.UserID, created.PostID)
+		require.NoError(t, err)
+		return detailed.CommentCount
+	}
+	require.Equal(t, 1, commentCount())
+
+	require.NoError(t, env.userSvc.BlockUser(t.Context(), author, commenter.UserID))
+	assert.Zero(t, commentCount(), "comments from blocked users are hidden")
+
+	require.NoError(t, env.userSvc.UnblockUser(t.Context(), author, commenter.UserID))
+	assert.Equal(t, 1, commentCount())
+
+	require.NoError(t, env.userRepository.DeactivateAccount(t.Context(), commenter.UserID))
+	assert.Zero(t, commentCount(), "comments from deactivated users are hidden")
+
+	require.NoError(t, env.userRepository.ReactivateAccount(t.Context(), commenter.UserID))
+	assert.Equal(t, 1, commentCount())
 }
@@ -9,7 +9,9 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/utilities"
 )
 
@@ -17,14 +19,30 @@ type Store struct {
 	querier queries.Querier
 }
 
+// ReservePostId allocates the ID of a post that hasn't been created yet, for InsertReservedPost
+func (r Store) ReservePostId(ctx context.Context) (int, error) {
+	return r.querier.ReservePostId(ctx)
+}
+
 // InsertPost creates a new post
-func (r Store) InsertPost(ctx context.Context, userId int, content string, facets db.Facets, attributes *db.Attributes, visibilityType *models.VisibilityTypeEnum) (*models.Post, error) {
+func (r Store) InsertPost(ctx context.Context, userId int, content string, facets db.Facets, attributes *db.Attributes, visibilityType *models.VisibilityTypeEnum, audienceListId *int) (*models.Post, error) {
+	return r.insertPost(ctx, nil, userId, content, facets, attributes, visibilityType, audienceListId)
+}
+
+// InsertReservedPost creates a new post with an ID from ReservePostId
+func (r Store) InsertReservedPost(ctx context.Context, postId int, userId int, content string, facets db.Facets, attributes *db.Attributes, visibilityType *models.VisibilityTypeEnum, audienceListId *int) (*models.Post, error) {
+	return r.insertPost(ctx, &postId, userId, content, facets, attributes, visibilityType, audienceListId)
+}
+
+func (r Store) insertPost(ctx context.Context, postId *int, userId int, content string, facets db.Facets, attributes *db.Attributes, visibilityType *models.VisibilityTypeEnum, audienceListId *int) (*models.Post, error) {
 	var post, err = r.querier.InsertPost(ctx, queries.InsertPostParams{
 		UserID:         userId,
 		Text:           pgtype.Text{String: content, Valid: true},
 		Facets:         facets,
 		Attributes:     attributes,
 		Visibilitytype: int(*visibilityType),
+		AudienceListID: audienceListId,
+		PostID:         postId,
 	})
 	if err != nil {
 		return nil, err
@@ -50,49 +68,96 @@ func (r Store) GetPostById(ctx context.Context, postId int, currentUserId int) (
 		}
 		return nil, err
 	}
-	return &models.Post{
-		PostID:     dbPost.PostID,
-		UserID:     dbPost.UserID,
-		Text:       dbPost.Text.String,
-		CreatedAt:  dbPost.CreatedAt.Time.UTC(),
-		Facets:     dbPost.Facets,
-		Attributes: dbPost.Attributes,
-		Visibility: (*models.VisibilityTypeEnum)(&dbPost.Visibilitytype),
-	}, nil
-}
-
-// IsPostLikedByUserId checks if a post is liked by a specific user
-func (r Store) IsPostLikedByUserId(ctx context.Context, userId int, postId int) (bool, error) {
-	return r.querier.GetIsPostLikedByUser(ctx, queries.GetIsPostLikedByUserParams{
-		PostID: postId,
-		UserID: userId,
+	return mapPost(dbPost), nil
+}
+
+// GetPostsByIds retrieves the posts out of postIds that the current user can see, keyed by ID
+func (r Store) GetPostsByIds(ctx context.Context, postIds []int, currentUserId int) (map[int]*models.Post, error) {
+	dbPosts, err := r.querier.GetPostsByIds(ctx, queries.GetPostsByIdsParams{
+		PostIds:      postIds,
+		TargetUserID: currentUserId,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(map[int]*models.Post, len(dbPosts))
+	for _, dbPost := range dbPosts {
+		posts[dbPost.PostID] = mapPost(dbPost)
+	}
+	return posts, nil
+}
+
+func mapPost(dbPost queries.Post) *models.Post {
+	return &models.Post{
+		PostID:         dbPost.PostID,
+		UserID:         dbPost.UserID,
+		Text:           dbPost.Text.String,
+		CreatedAt:      dbPost.CreatedAt.Time.UTC(),
+		Facets:         dbPost.Facets,
+		Attributes:     dbPost.Attributes,
+		Visibility:     (*models.VisibilityTypeEnum)(&dbPost.Visibilitytype),
+		AudienceListID: dbPost.AudienceListID,
+	}
+}
+
+// IsAudienceListOwnedByUser checks whether an audience list exists and belongs to a user
+func (r Store) IsAudienceListOwnedByUser(ctx context.Context, listId int, userId int) (bool, error) {
+	list, err := r.querier.GetAudienceListById(ctx, listId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return list.UserID == userId, nil
 }
 
 // GetImagesForPost retrieves all images for a specific post
-func (r Store) GetImagesForPost(ctx context.Context, postId int) ([]queries.Image, error) {
-	return r.querier.GetImagesByPostId(ctx, postId)
+func (r Store) GetImagesForPost(ctx context.Context, postId int) ([]queries.Media, error) {
+	return r.querier.GetMediaByPostId(ctx, postId)
+}
+
+// GetImagesForPosts retrieves the images attached to each of postIds in display order, keyed by post ID
+func (r Store) GetImagesForPosts(ctx context.Context, postIds []int) (map[int][]queries.Media, error) {
+	rows, err := r.querier.GetMediaByPostIds(ctx, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[int][]queries.Media)
+	for _, row := range rows {
+		images[row.PostID] = append(images[row.PostID], row.Media)
+	}
+	return images, nil
 }
 
 // GetAllImagesForUser retrieves all images for a specific user
-func (r Store) GetAllImagesForUser(ctx context.Context, userId int) ([]queries.Image, error) {
-	return r.querier.GetAllImagesByUserId(ctx, userId)
+func (r Store) GetAllImagesForUser(ctx context.Context, userId int) ([]queries.Media, error) {
+	return r.querier.GetAllMediaByUserId(ctx, userId)
 }
 
-// InsertImage adds a new image to a post
-func (r Store) InsertImage(ctx context.Context, postId int, height int, width int, url string, displayOrder int) (*queries.Image, error) {
-	image, err := r.querier.InsertImage(ctx, queries.InsertImageParams{
-		Height:       height,
-		Width:        width,
-		ImageBlobUrl: url,
+// UpdateImageAltText sets the description of one of a post's images, reporting whether the
+// image belongs to the post.
+func (r Store) UpdateImageAltText(ctx context.Context, postId int, imageId int, altText string) (bool, error) {
+	updated, err := r.querier.UpdatePostMediaAltText(ctx, queries.UpdatePostMediaAltTextParams{
+		PostID:  postId,
+		MediaID: imageId,
+		AltText: media.AltTextParam(altText),
 	})
+	return updated > 0, err
+}
+
+// InsertImage adds a new attachment to a post
+func (r Store) InsertImage(ctx context.Context, postId int, published media.PublishedMedia, displayOrder int) (*queries.Media, error) {
+	image, err := r.querier.InsertMedia(ctx, published.InsertParams())
 	if err != nil {
 		return nil, err
 	}
 
-	err = r.querier.InsertPostImage(ctx, queries.InsertPostImageParams{
+	err = r.querier.InsertPostMedia(ctx, queries.InsertPostMediaParams{
 		PostID:       postId,
-		ImageID:      image.ImageID,
+		MediaID:      image.MediaID,
 		DisplayOrder: displayOrder,
 	})
 	if err != nil {
@@ -102,10 +167,21 @@ func (r Store) InsertImage(ctx context.Context, postId int, height int, width in
 	return &image, nil
 }
 
-// GetCommentCountForPost returns the number of comments for a post
-func (r Store) GetCommentCountForPost(ctx context.Context, postId int) (int, error) {
-	count, err := r.querier.GetCommentCountByPostID(ctx, postId)
-	return int(count), err
+// GetPostCounters returns the like, comment and poll vote counters of each of postIds, keyed by
+// post ID. Posts that have never been engaged with may have no counters. Like GetCommentsByPostId,
+// the comment count leaves out comments from deactivated users and from users on either side of a
+// block with the post's author.
+func (r Store) GetPostCounters(ctx context.Context, postIds []int) (map[int]queries.PostCounter, error) {
+	rows, err := r.querier.GetPostCounters(ctx, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[int]queries.PostCounter, len(rows))
+	for _, row := range rows {
+		counters[row.PostID] = row
+	}
+	return counters, nil
 }
 
 // GetPollVotesGrouped retrieves poll votes grouped by option index
@@ -113,19 +189,68 @@ func (r Store) GetPollVotesGrouped(ctx context.Context, postId int) ([]queries.G
 	return r.querier.GetPollVotesGrouped(ctx, postId)
 }
 
-// GetUserVoteInPoll retrieves the user's vote for a specific poll
-func (r Store) GetUserVoteInPoll(ctx context.Context, postId int, userId int) (*int, error) {
-	vote, err := r.querier.GetUserVoteInPoll(ctx, queries.GetUserVoteInPollParams{
+// GetUserVotesInPoll retrieves the options a user voted for in a poll, in option order
+func (r Store) GetUserVotesInPoll(ctx context.Context, postId int, userId int) ([]int, error) {
+	return r.querier.GetUserVotesInPoll(ctx, queries.GetUserVotesInPollParams{
 		PostID: postId,
 		UserID: userId,
 	})
+}
+
+// GetPollVoterCount returns the number of users who voted in a poll
+func (r Store) GetPollVoterCount(ctx context.Context, postId int) (int, error) {
+	count, err := r.querier.GetPollVoterCount(ctx, postId)
+	return int(count), err
+}
+
+// GetPollVoteCountsForPosts retrieves the number of votes for each option of the polls in postIds,
+// keyed by post ID and then option index
+func (r Store) GetPollVoteCountsForPosts(ctx context.Context, postIds []int) (map[int]map[int]int64, error) {
+	rows, err := r.querier.GetPollVotesGroupedByPostIds(ctx, postIds)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+		return nil, err
+	}
+
+	counts := make(map[int]map[int]int64)
+	for _, row := range rows {
+		if counts[row.PostID] == nil {
+			counts[row.PostID] = make(map[int]int64)
 		}
+		counts[row.PostID][row.OptionIndex] = row.Count
+	}
+	return counts, nil
+}
+
+// GetUserVotesInPolls retrieves the options a user voted for in each of the polls in postIds, in
+// option order, keyed by post ID
+func (r Store) GetUserVotesInPolls(ctx context.Context, postIds []int, userId int) (map[int][]int, error) {
+	rows, err := r.querier.GetUserVotesInPolls(ctx, queries.GetUserVotesInPollsParams{
+		PostIds: postIds,
+		UserID:  userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make(map[int][]int)
+	for _, row := range rows {
+		votes[row.PostID] = append(votes[row.PostID], row.OptionIndex)
+	}
+	return votes, nil
+}
+
+// GetPollVoterCounts returns the number of users who voted in each of the polls in postIds, keyed by post ID
+func (r Store) GetPollVoterCounts(ctx context.Context, postIds []int) (map[int]int, error) {
+	rows, err := r.querier.GetPollVoterCounts(ctx, postIds)
+	if err != nil {
 		return nil, err
 	}
-	return new(vote), nil
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.PostID] = int(row.Count)
+	}
+	return counts, nil
 }
 
 // InsertVote adds a vote for a poll option
@@ -137,6 +262,37 @@ func (r Store) InsertVote(ctx context.Context, postId int, userId int, optionInd
 	})
 }
 
+// LockPollVotes waits for any other transaction changing a user's votes in a poll to finish, and
+// keeps others waiting until this transaction does
+func (r Store) LockPollVotes(ctx context.Context, postId int, userId int) error {
+	return r.querier.LockPollVotesForUser(ctx, queries.LockPollVotesForUserParams{
+		PostID: postId,
+		UserID: userId,
+	})
+}
+
+// DeleteVote removes a user's vote for a single poll option
+func (r Store) DeleteVote(ctx context.Context, postId int, userId int, optionIndex int) error {
+	return r.querier.DeleteUserVoteInPoll(ctx, queries.DeleteUserVoteInPollParams{
+		PostID:      postId,
+		UserID:      userId,
+		OptionIndex: optionIndex,
+	})
+}
+
+// InsertPollDeadline records when a poll closes so its author can be notified
+func (r Store) InsertPollDeadline(ctx context.Context, postId int, closesAt time.Time) error {
+	return r.querier.InsertPollDeadline(ctx, queries.InsertPollDeadlineParams{
+		PostID:   postId,
+		ClosesAt: closesAt,
+	})
+}
+
+// ClaimEndedPolls marks up to limit polls that have closed as notified, returning their posts
+func (r Store) ClaimEndedPolls(ctx context.Context, limit int) ([]queries.ClaimEndedPollsRow, error) {
+	return r.querier.ClaimEndedPolls(ctx, limit)
+}
+
 // GetAllPostIdsCursor retrieves IDs of all posts using cursor-based pagination
 func (r Store) GetAllPostIdsCursor(ctx context.Context, limit int, beforeTimestamp *time.Time, currentUserId int) ([]int, error) {
 	var timestamp pgtype.Timestamp
@@ -216,3 +372,13 @@ func (r Store) GetPinnedPostId(ctx context.Context, userId int) (*int, error) {
 func NewDBPostRepository(querier queries.Querier) Store {
 	return Store{querier: querier}
 }
+
+// GetPinnedPostIds returns which of postIds are pinned to their author's profile
+func (r Store) GetPinnedPostIds(ctx context.Context, postIds []int) ([]int, error) {
+	return r.querier.GetPinnedPostIds(ctx, postIds)
+}
+
+// WithTx returns a copy of the repository that runs its queries in the unit of work's transaction
+func (r Store) WithTx(uow *transaction.UnitOfWork) Store {
+	return Store{querier: uow.Querier()}
+}
@@ -0,0 +1,97 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/utilities"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWriter, *http.Request))) {
+	withAuth("GET /reactions", h.GetEmojis)
+
+	withAuth("PUT /post/{post_id}/reactions/{emoji}", h.AddPostReaction)
+	withAuth("DELETE /post/{post_id}/reactions/{emoji}", h.RemovePostReaction)
+
+	withAuth("PUT /post/{post_id}/comment/{comment_id}/reactions/{emoji}", h.AddCommentReaction)
+	withAuth("DELETE /post/{post_id}/comment/{comment_id}/reactions/{emoji}", h.RemoveCommentReaction)
+}
+
+// handleServiceError writes the response for an error returned by the service.
+func handleServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, post.ErrPostNotFound), errors.Is(err, ErrCommentNotFound):
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrUnsupportedReaction):
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+	default:
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
+
+// GetEmojis GET /reactions
+//
+// Returns the emoji users can react with.
+func (h *Handler) GetEmojis(w http.ResponseWriter, r *http.Request) {
+	utilities.HandleSuccess(w, h.svc.GetEmojis())
+}
+
+// AddPostReaction PUT /post/{post_id}/reactions/{emoji}
+func (h *Handler) AddPostReaction(w http.ResponseWriter, r *http.Request) {
+	h.changeReaction(w, r, false, h.svc.AddReaction)
+}
+
+// RemovePostReaction DELETE /post/{post_id}/reactions/{emoji}
+func (h *Handler) RemovePostReaction(w http.ResponseWriter, r *http.Request) {
+	h.changeReaction(w, r, false, h.svc.RemoveReaction)
+}
+
+// AddCommentReaction PUT /post/{post_id}/comment/{comment_id}/reactions/{emoji}
+func (h *Handler) AddCommentReaction(w http.ResponseWriter, r *http.Request) {
+	h.changeReaction(w, r, true, h.svc.AddReaction)
+}
+
+// RemoveCommentReaction DELETE /post/{post_id}/comment/{comment_id}/reactions/{emoji}
+func (h *Handler) RemoveCommentReaction(w http.ResponseWriter, r *http.Request) {
+	h.changeReaction(w, r, true, h.svc.RemoveReaction)
+}
+
+type reactionChange func(ctx context.Context, currentUser models.PublicUser, postId int, commentId *int, emoji string) error
+
+func (h *Handler) changeReaction(w http.ResponseWriter, r *http.Request, isComment bool, change reactionChange) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	postId, err := utilities.GetIntPathParam(r, "post_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var commentId *int
+	if isComment {
+		id, err := utilities.GetIntPathParam(r, "comment_id")
+		if err != nil {
+			utilities.HandleError(w, http.StatusBadRequest, "Invalid comment ID")
+			return
+		}
+		commentId = &id
+	}
+
+	if err := change(r.Context(), *currentUser, postId, commentId, r.PathValue("emoji")); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
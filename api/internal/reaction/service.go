@@ -0,0 +1,163 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"splajompy.com/api/v2/internal/comment"
+	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/notification"
+	"splajompy.com/api/v2/internal/post"
+)
+
+// Heart is the reaction that is stored as a like, so clients that only know about likes keep
+// seeing it and the /liked endpoints keep working.
+const Heart = "❤️"
+
+// DefaultEmojis are the reactions offered when REACTION_EMOJIS isn't set.
+var DefaultEmojis = []string{Heart, "😂", "😮", "😢", "🔥", "👍"}
+
+var (
+	ErrUnsupportedReaction = errors.New("this reaction is not supported")
+	ErrCommentNotFound     = errors.New("this comment does not exist")
+)
+
+type Service struct {
+	store               Store
+	likeRepository      like.Store
+	postRepository      post.Store
+	commentRepository   *comment.Store
+	notificationService notification.Service
+	emojis              []string
+}
+
+func NewService(store Store, likeRepository like.Store, postRepository post.Store, commentRepository *comment.Store, notificationService notification.Service, emojis []string) *Service {
+	return &Service{
+		store:               store,
+		likeRepository:      likeRepository,
+		postRepository:      postRepository,
+		commentRepository:   commentRepository,
+		notificationService: notificationService,
+		emojis:              emojis,
+	}
+}
+
+// EmojisFromEnv returns the comma-separated reactions in REACTION_EMOJIS, or DefaultEmojis if
+// it isn't set. The heart is always offered, and always first.
+func EmojisFromEnv() []string {
+	configured := os.Getenv("REACTION_EMOJIS")
+	if configured == "" {
+		return DefaultEmojis
+	}
+
+	emojis := []string{Heart}
+	for _, emoji := range strings.Split(configured, ",") {
+		emoji = normalizeEmoji(emoji)
+		if emoji != "" && !slices.Contains(emojis, emoji) {
+			emojis = append(emojis, emoji)
+		}
+	}
+	return emojis
+}
+
+// normalizeEmoji trims an emoji and treats a heart without its emoji presentation selector as a heart.
+func normalizeEmoji(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == strings.TrimSuffix(Heart, "\ufe0f") {
+		return Heart
+	}
+	return emoji
+}
+
+// GetEmojis returns the reactions users can choose from.
+func (s *Service) GetEmojis() []string {
+	return s.emojis
+}
+
+// AddReaction reacts to a post, or to one of its comments if commentId isn't nil. Reacting with
+// the same emoji twice does nothing.
+func (s *Service) AddReaction(ctx context.Context, currentUser models.PublicUser, postId int, commentId *int, emoji string) error {
+	emoji = normalizeEmoji(emoji)
+	if !slices.Contains(s.emojis, emoji) {
+		return ErrUnsupportedReaction
+	}
+
+	if err := s.ensureVisible(ctx, currentUser, postId, commentId); err != nil {
+		return err
+	}
+
+	if emoji == Heart {
+		isLiked, err := s.likeRepository.IsLiked(ctx, currentUser.UserID, postId, commentId)
+		if err != nil || isLiked {
+			return err
+		}
+		if err := s.likeRepository.AddLike(ctx, currentUser.UserID, postId, commentId); err != nil {
+			return err
+		}
+		return s.notificationService.AddLikeNotification(ctx, currentUser.UserID, postId, commentId)
+	}
+
+	added, err := s.store.AddReaction(ctx, currentUser.UserID, postId, commentId, emoji)
+	if err != nil || !added {
+		return err
+	}
+
+	return s.notificationService.AddReactionNotification(ctx, currentUser.UserID, postId, commentId)
+}
+
+// RemoveReaction removes one of the current user's reactions from a post or comment. Reactions
+// that are no longer offered can still be removed.
+func (s *Service) RemoveReaction(ctx context.Context, currentUser models.PublicUser, postId int, commentId *int, emoji string) error {
+	emoji = normalizeEmoji(emoji)
+
+	if err := s.ensureVisible(ctx, currentUser, postId, commentId); err != nil {
+		return err
+	}
+
+	if emoji == Heart {
+		if err := s.likeRepository.RemoveLike(ctx, currentUser.UserID, postId, commentId); err != nil {
+			return err
+		}
+		return s.notificationService.RemoveLikeNotification(ctx, currentUser.UserID, postId, commentId)
+	}
+
+	removed, err := s.store.RemoveReaction(ctx, currentUser.UserID, postId, commentId, emoji)
+	if err != nil || !removed {
+		return err
+	}
+
+	// the author keeps hearing about the user as long as they have another reaction left
+	hasReacted, err := s.store.HasReacted(ctx, currentUser.UserID, postId, commentId)
+	if err != nil || hasReacted {
+		return err
+	}
+
+	return s.notificationService.RemoveReactionNotification(ctx, currentUser.UserID, postId, commentId)
+}
+
+// ensureVisible checks that the current user can see the post, and that the comment belongs to it.
+func (s *Service) ensureVisible(ctx context.Context, currentUser models.PublicUser, postId int, commentId *int) error {
+	if _, err := s.postRepository.GetPostById(ctx, postId, currentUser.UserID); err != nil {
+		return err
+	}
+
+	if commentId == nil {
+		return nil
+	}
+	comment, err := s.commentRepository.GetCommentById(ctx, *commentId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCommentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if comment.PostID != postId {
+		return ErrCommentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package reaction_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/comment"
+	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/notification"
+	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/reaction"
+	"splajompy.com/api/v2/internal/testutil"
+	"splajompy.com/api/v2/internal/user"
+)
+
+type reactionTestEnv struct {
+	svc             *reaction.Service
+	postSvc         *post.Service
+	commentSvc      *comment.Service
+	notificationSvc *notification.Service
+	userRepository  user.Store
+	postRepository  post.Store
+	likeRepository  like.Store
+}
+
+func setupReactionTest(t *testing.T) reactionTestEnv {
+	t.Helper()
+	db := testutil.StartPostgres(t)
+
+	notificationService := notification.NewService(db.NotificationStore, db.PostRepository, &db.CommentRepository, db.UserRepository, db.BucketRepository, apns.Client{})
+	postService := post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, db.ReactionStore, *notificationService, db.BucketRepository, nil, db.TxManager)
+	commentService := comment.NewService(&db.CommentRepository, db.PostRepository, *notificationService, db.UserRepository, db.LikeRepository, db.ReactionStore, db.BucketRepository, db.TxManager)
+	svc := reaction.NewService(db.ReactionStore, db.LikeRepository, db.PostRepository, &db.CommentRepository, *notificationService, reaction.DefaultEmojis)
+
+	return reactionTestEnv{
+		svc:             svc,
+		postSvc:         postService,
+		commentSvc:      commentService,
+		notificationSvc: notificationService,
+		userRepository:  db.UserRepository,
+		postRepository:  db.PostRepository,
+		likeRepository:  db.LikeRepository,
+	}
+}
+
+func TestAddReaction_SummarizedOnPost(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	user2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddReaction(t.Context(), user1, post.PostID, nil, "😂"))
+	require.NoError(t, env.svc.AddReaction(t.Context(), user1, post.PostID, nil, "🔥"))
+	require.NoError(t, env.svc.AddReaction(t.Context(), user2, post.PostID, nil, "😂"))
+	// reacting twice with the same emoji only counts once
+	require.NoError(t, env.svc.AddReaction(t.Context(), user2, post.PostID, nil, "😂"))
+
+	detailed, err := env.postSvc.GetPostById(t.Context(), user1.UserID, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, detailed.Reactions, 2)
+
+	assert.Equal(t, "😂", detailed.Reactions[0].Emoji)
+	assert.Equal(t, 2, detailed.Reactions[0].Count)
+	assert.True(t, detailed.Reactions[0].IsReacted)
+	assert.Equal(t, []models.RelevantLike{{Username: "user2", UserID: user2.UserID}}, detailed.Reactions[0].ReactedBy)
+
+	assert.Equal(t, "🔥", detailed.Reactions[1].Emoji)
+	assert.Equal(t, 1, detailed.Reactions[1].Count)
+	assert.Empty(t, detailed.Reactions[1].ReactedBy, "the current user isn't listed")
+}
+
+func TestAddReaction_HeartIsALike(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	user2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddReaction(t.Context(), user1, post.PostID, nil, reaction.Heart))
+	// older clients like posts through the /liked endpoints
+	require.NoError(t, env.postSvc.AddLikeToPost(t.Context(), user2, post.PostID))
+
+	isLiked, err := env.likeRepository.IsLiked(t.Context(), user1.UserID, post.PostID, nil)
+	require.NoError(t, err)
+	assert.True(t, isLiked)
+
+	detailed, err := env.postSvc.GetPostById(t.Context(), poster.UserID, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, detailed.Reactions, 1)
+	assert.Equal(t, reaction.Heart, detailed.Reactions[0].Emoji)
+	assert.Equal(t, 2, detailed.Reactions[0].Count)
+
+	notifications, err := env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), poster, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationTypeLike, notifications[0].NotificationType)
+
+	require.NoError(t, env.svc.RemoveReaction(t.Context(), user1, post.PostID, nil, reaction.Heart))
+
+	isLiked, err = env.likeRepository.IsLiked(t.Context(), user1.UserID, post.PostID, nil)
+	require.NoError(t, err)
+	assert.False(t, isLiked)
+}
+
+func TestAddReaction_RejectsUnsupportedEmoji(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	err = env.svc.AddReaction(t.Context(), poster, post.PostID, nil, "🦆")
+	assert.ErrorIs(t, err, reaction.ErrUnsupportedReaction)
+}
+
+func TestReactionNotifications_Combine(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	user1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	user2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddReaction(t.Context(), user1, post.PostID, nil, "😂"))
+	require.NoError(t, env.svc.AddReaction(t.Context(), user1, post.PostID, nil, "🔥"))
+	require.NoError(t, env.svc.AddReaction(t.Context(), user2, post.PostID, nil, "😮"))
+	// reacting to your own post doesn't notify
+	require.NoError(t, env.svc.AddReaction(t.Context(), poster, post.PostID, nil, "😮"))
+
+	notifications, err := env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), poster, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user2 and @user1 reacted to your post", notifications[0].Message)
+	assert.Equal(t, models.NotificationTypeReaction, notifications[0].NotificationType)
+	assert.True(t, notifications[0].HasNotificationActors)
+
+	// user1 still has a reaction left
+	require.NoError(t, env.svc.RemoveReaction(t.Context(), user1, post.PostID, nil, "😂"))
+
+	notifications, err = env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), poster, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user2 and @user1 reacted to your post", notifications[0].Message)
+
+	require.NoError(t, env.svc.RemoveReaction(t.Context(), user1, post.PostID, nil, "🔥"))
+
+	notifications, err = env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), poster, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user2 reacted to your post", notifications[0].Message)
+
+	require.NoError(t, env.svc.RemoveReaction(t.Context(), user2, post.PostID, nil, "😮"))
+
+	notifications, err = env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), poster, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, notifications, 0)
+}
+
+func TestAddReaction_Comment(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	commenter := testutil.CreateTestUser(t, env.userRepository, "user1")
+	reactor := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	otherPost, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post1", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	comment, err := env.commentSvc.AddCommentToPost(t.Context(), commenter, post.PostID, "comment", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddReaction(t.Context(), reactor, post.PostID, &comment.CommentID, "👍"))
+
+	err = env.svc.AddReaction(t.Context(), reactor, otherPost.PostID, &comment.CommentID, "👍")
+	assert.ErrorIs(t, err, reaction.ErrCommentNotFound)
+
+	comments, err := env.commentSvc.GetCommentsByPostId(t.Context(), commenter, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	require.Len(t, comments[0].Reactions, 1)
+	assert.Equal(t, "👍", comments[0].Reactions[0].Emoji)
+	assert.Equal(t, 1, comments[0].Reactions[0].Count)
+	assert.False(t, comments[0].Reactions[0].IsReacted)
+
+	// comment reactions don't show up on the post
+	detailed, err := env.postSvc.GetPostById(t.Context(), poster.UserID, post.PostID)
+	require.NoError(t, err)
+	assert.Empty(t, detailed.Reactions)
+
+	notifications, err := env.notificationSvc.GetUnreadNotificationsByUserIdWithTimeOffset(t.Context(), commenter, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user2 reacted to your comment", notifications[0].Message)
+}
+
+func TestGetCommentsByPostId_ReactionsPerComment(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	reactor := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	post, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	first, err := env.commentSvc.AddCommentToPost(t.Context(), poster, post.PostID, "first", nil)
+	require.NoError(t, err)
+	second, err := env.commentSvc.AddCommentToPost(t.Context(), poster, post.PostID, "second", nil)
+	require.NoError(t, err)
+	_, err = env.commentSvc.AddCommentToPost(t.Context(), poster, post.PostID, "third", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.AddReaction(t.Context(), reactor, post.PostID, &first.CommentID, "👍"))
+	require.NoError(t, env.svc.AddReaction(t.Context(), reactor, post.PostID, &second.CommentID, reaction.Heart))
+	require.NoError(t, env.svc.AddReaction(t.Context(), reactor, post.PostID, &second.CommentID, "🔥"))
+	// reactions to the post itself aren't counted on its comments
+	require.NoError(t, env.svc.AddReaction(t.Context(), reactor, post.PostID, nil, "😂"))
+
+	comments, err := env.commentSvc.GetCommentsByPostId(t.Context(), reactor, post.PostID)
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+
+	reactions := make(map[string][]string)
+	for _, comment := range comments {
+		require.NotNil(t, comment.Reactions)
+		for _, summary := range comment.Reactions {
+			assert.True(t, summary.IsReacted)
+			reactions[comment.Text] = append(reactions[comment.Text], summary.Emoji)
+		}
+	}
+	assert.Equal(t, []string{"👍"}, reactions["first"])
+	assert.ElementsMatch(t, []string{reaction.Heart, "🔥"}, reactions["second"])
+	assert.Empty(t, reactions["third"])
+}
+
+func TestGetCommentsByPostId_HiddenPost(t *testing.T) {
+	env := setupReactionTest(t)
+
+	poster := testutil.CreateTestUser(t, env.userRepository, "user0")
+	viewer := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	created, err := env.postRepository.InsertPost(t.Context(), poster.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	require.NoError(t, env.userRepository.BlockUser(t.Context(), poster.UserID, viewer.UserID))
+
+	_, err = env.commentSvc.GetCommentsByPostId(t.Context(), viewer, created.PostID)
+	assert.ErrorIs(t, err, post.ErrPostNotFound)
+
+	_, err = env.commentSvc.AddCommentToPost(t.Context(), viewer, created.PostID, "comment", nil)
+	assert.ErrorIs(t, err, post.ErrPostNotFound)
+}
+
+func TestEmojisFromEnv(t *testing.T) {
+	t.Setenv("REACTION_EMOJIS", "")
+	assert.Equal(t, reaction.DefaultEmojis, reaction.EmojisFromEnv())
+
+	t.Setenv("REACTION_EMOJIS", " 🎉, 👀,🎉,❤")
+	assert.Equal(t, []string{reaction.Heart, "🎉", "👀"}, reaction.EmojisFromEnv())
+}
@@ -0,0 +1,189 @@
+package reaction
+
+import (
+	"context"
+
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/models"
+)
+
+// reactedByPerEmoji is how many of the users who reacted with each emoji are named
+const reactedByPerEmoji = 3
+
+type Store struct {
+	querier queries.Querier
+}
+
+func NewStore(querier queries.Querier) Store {
+	return Store{querier: querier}
+}
+
+// AddReaction adds a reaction to a post or comment, returning false if the user had already
+// reacted with the emoji
+func (s Store) AddReaction(ctx context.Context, userId int, postId int, commentId *int, emoji string) (bool, error) {
+	added, err := s.querier.AddReaction(ctx, queries.AddReactionParams{
+		PostID:    postId,
+		CommentID: commentId,
+		UserID:    userId,
+		Emoji:     emoji,
+	})
+	return added > 0, err
+}
+
+// RemoveReaction removes a reaction from a post or comment, returning false if there wasn't one
+func (s Store) RemoveReaction(ctx context.Context, userId int, postId int, commentId *int, emoji string) (bool, error) {
+	removed, err := s.querier.RemoveReaction(ctx, queries.RemoveReactionParams{
+		PostID:    postId,
+		CommentID: commentId,
+		UserID:    userId,
+		Emoji:     emoji,
+	})
+	return removed > 0, err
+}
+
+// HasReacted checks if a user has any reactions other than a heart on a post or comment
+func (s Store) HasReacted(ctx context.Context, userId int, postId int, commentId *int) (bool, error) {
+	return s.querier.HasUserReacted(ctx, queries.HasUserReactedParams{
+		PostID:    postId,
+		CommentID: commentId,
+		UserID:    userId,
+	})
+}
+
+// GetReactionSummaries counts the reactions to a post or comment by emoji, most popular first.
+// Likes are counted as hearts. The users named for each emoji leave out the current user and
+// anyone they've blocked or been blocked by.
+func (s Store) GetReactionSummaries(ctx context.Context, currentUserId int, postId int, commentId *int) ([]models.ReactionSummary, error) {
+	counts, err := s.querier.GetReactionCounts(ctx, queries.GetReactionCountsParams{
+		CurrentUserID: currentUserId,
+		Heart:         Heart,
+		PostID:        postId,
+		CommentID:     commentId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactedBy, err := s.querier.GetReactedBy(ctx, queries.GetReactedByParams{
+		Heart:         Heart,
+		PostID:        postId,
+		CommentID:     commentId,
+		CurrentUserID: currentUserId,
+		PerEmoji:      reactedByPerEmoji,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usersByEmoji := make(map[string][]models.RelevantLike)
+	for _, row := range reactedBy {
+		usersByEmoji[row.Emoji] = append(usersByEmoji[row.Emoji], models.RelevantLike{
+			Username: row.Username,
+			UserID:   row.UserID,
+		})
+	}
+
+	summaries := make([]models.ReactionSummary, len(counts))
+	for i, count := range counts {
+		summaries[i] = summarize(count.Emoji, count.Count, count.IsReacted, usersByEmoji[count.Emoji])
+	}
+	return summaries, nil
+}
+
+// GetPostReactionSummaries is GetReactionSummaries for each of postIds, keyed by post ID. Posts
+// nobody has reacted to are left out.
+func (s Store) GetPostReactionSummaries(ctx context.Context, currentUserId int, postIds []int) (map[int][]models.ReactionSummary, error) {
+	counts, err := s.querier.GetPostReactionCounts(ctx, queries.GetPostReactionCountsParams{
+		CurrentUserID: currentUserId,
+		Heart:         Heart,
+		PostIds:       postIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactedBy, err := s.querier.GetPostReactedBy(ctx, queries.GetPostReactedByParams{
+		Heart:         Heart,
+		PostIds:       postIds,
+		CurrentUserID: currentUserId,
+		PerEmoji:      reactedByPerEmoji,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type postEmoji struct {
+		postId int
+		emoji  string
+	}
+	usersByEmoji := make(map[postEmoji][]models.RelevantLike)
+	for _, row := range reactedBy {
+		key := postEmoji{row.PostID, row.Emoji}
+		usersByEmoji[key] = append(usersByEmoji[key], models.RelevantLike{
+			Username: row.Username,
+			UserID:   row.UserID,
+		})
+	}
+
+	summaries := make(map[int][]models.ReactionSummary)
+	for _, count := range counts {
+		users := usersByEmoji[postEmoji{count.PostID, count.Emoji}]
+		summaries[count.PostID] = append(summaries[count.PostID], summarize(count.Emoji, count.Count, count.IsReacted, users))
+	}
+	return summaries, nil
+}
+
+// GetCommentReactionSummaries is GetReactionSummaries for each of commentIds, keyed by comment ID.
+// Comments nobody has reacted to are left out.
+func (s Store) GetCommentReactionSummaries(ctx context.Context, currentUserId int, commentIds []int) (map[int][]models.ReactionSummary, error) {
+	counts, err := s.querier.GetCommentReactionCounts(ctx, queries.GetCommentReactionCountsParams{
+		CurrentUserID: currentUserId,
+		Heart:         Heart,
+		CommentIds:    commentIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactedBy, err := s.querier.GetCommentReactedBy(ctx, queries.GetCommentReactedByParams{
+		Heart:         Heart,
+		CommentIds:    commentIds,
+		CurrentUserID: currentUserId,
+		PerEmoji:      reactedByPerEmoji,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type commentEmoji struct {
+		commentId int
+		emoji     string
+	}
+	usersByEmoji := make(map[commentEmoji][]models.RelevantLike)
+	for _, row := range reactedBy {
+		key := commentEmoji{*row.CommentID, row.Emoji}
+		usersByEmoji[key] = append(usersByEmoji[key], models.RelevantLike{
+			Username: row.Username,
+			UserID:   row.UserID,
+		})
+	}
+
+	summaries := make(map[int][]models.ReactionSummary)
+	for _, count := range counts {
+		users := usersByEmoji[commentEmoji{*count.CommentID, count.Emoji}]
+		summaries[*count.CommentID] = append(summaries[*count.CommentID], summarize(count.Emoji, count.Count, count.IsReacted, users))
+	}
+	return summaries, nil
+}
+
+func summarize(emoji string, count int64, isReacted bool, users []models.RelevantLike) models.ReactionSummary {
+	if users == nil {
+		users = []models.RelevantLike{}
+	}
+	return models.ReactionSummary{
+		Emoji:     emoji,
+		Count:     int(count),
+		IsReacted: isReacted,
+		ReactedBy: users,
+	}
+}
@@ -0,0 +1,42 @@
+package templates
+
+import (
+	"bytes"
+	"text/template"
+)
+
+type DataExportEmailData struct {
+	Username    string
+	DownloadUrl string
+	ExpiryDays  int
+}
+
+func GenerateDataExportEmail(username string, downloadUrl string, expiryDays int) (string, error) {
+	tmpl := `Hi @{{.Username}},
+
+The copy of your Splajompy data that you requested is ready. You can download it here for the next {{.ExpiryDays}} days:
+
+{{.DownloadUrl}}
+
+The download is a ZIP file containing your profile, posts, comments, likes, poll votes, connections and images.
+
+If you didn't request this, someone else may have access to your account.`
+
+	t, err := template.New("export").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := DataExportEmailData{
+		Username:    username,
+		DownloadUrl: downloadUrl,
+		ExpiryDays:  expiryDays,
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -17,10 +17,10 @@ type PostReportEmailData struct {
 	PostText         string
 	PostCreatedAt    time.Time
 	ReportedAt       time.Time
-	Images           []queries.Image
+	Images           []queries.Media
 }
 
-func GeneratePostReportEmail(reporterUsername string, authorUsername string, authorUserID int, post models.Post, images []queries.Image) (string, error) {
+func GeneratePostReportEmail(reporterUsername string, authorUsername string, authorUserID int, post models.Post, images []queries.Media) (string, error) {
 	tmpl := `
 <!DOCTYPE html>
 <html>
@@ -42,7 +42,8 @@ func GeneratePostReportEmail(reporterUsername string, authorUsername string, aut
     <h4>Images:</h4>
     {{range .Images}}
     <div>
-        <img src="{{.ImageBlobUrl}}" alt="Post image">
+        <img src="{{.BlobKey}}" alt="{{if .AltText.Valid}}{{.AltText.String}}{{else}}Post image{{end}}">
+        {{if .AltText.Valid}}<p><strong>Description:</strong> {{.AltText.String}}</p>{{end}}
     </div>
     {{end}}
     {{end}}
@@ -1,9 +1,17 @@
 package testutil
 
 import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/user"
 )
@@ -20,3 +28,23 @@ func CreateTestUser(t *testing.T, repo user.Store, username string) models.Publi
 		DisplayProperties: u.DisplayProperties,
 	}
 }
+
+// StageTestImage uploads a JPEG of the given size to a user's staging area, the way a client
+// would before creating a post or comment.
+func StageTestImage(t *testing.T, repo bucket.Repository, userId int, width int, height int) models.ImageData {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+	key := fmt.Sprintf("%sposts/%s.jpg", bucket.StagingPrefix(os.Getenv("ENVIRONMENT"), userId), uuid.New())
+	require.NoError(t, repo.PutObject(t.Context(), key, "image/jpeg", bytes.NewReader(buf.Bytes())))
+
+	return models.ImageData{S3Key: key, Width: width, Height: height}
+}
@@ -0,0 +1,45 @@
+package testutil
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"splajompy.com/api/v2/internal/db/queries"
+)
+
+// QueryCounter wraps a database connection and counts the round trips made through it, for tests
+// that check how many queries an operation costs.
+type QueryCounter struct {
+	db    queries.DBTX
+	count atomic.Int64
+}
+
+func NewQueryCounter(db queries.DBTX) *QueryCounter {
+	return &QueryCounter{db: db}
+}
+
+func (c *QueryCounter) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	c.count.Add(1)
+	return c.db.Exec(ctx, sql, args...)
+}
+
+func (c *QueryCounter) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	c.count.Add(1)
+	return c.db.Query(ctx, sql, args...)
+}
+
+func (c *QueryCounter) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	c.count.Add(1)
+	return c.db.QueryRow(ctx, sql, args...)
+}
+
+// Count returns the number of queries made since the counter was created or last reset.
+func (c *QueryCounter) Count() int {
+	return int(c.count.Load())
+}
+
+func (c *QueryCounter) Reset() {
+	c.count.Store(0)
+}
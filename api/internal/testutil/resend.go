@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/resend/resend-go/v3"
+)
+
+// FakeResend is a local stand-in for the Resend API that records every email sent through Client.
+type FakeResend struct {
+	Client *resend.Client
+
+	mu     sync.Mutex
+	emails []resend.SendEmailRequest
+}
+
+// NewFakeResend starts a fake Resend server that is shut down when the test finishes.
+func NewFakeResend(t *testing.T) *FakeResend {
+	t.Helper()
+
+	f := &FakeResend{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var email resend.SendEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&email); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		f.emails = append(f.emails, email)
+		f.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(resend.SendEmailResponse{Id: "test"})
+	}))
+	t.Cleanup(server.Close)
+
+	f.Client = resend.NewCustomClient(server.Client(), "test")
+	f.Client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return f
+}
+
+// Emails returns the emails sent so far.
+func (f *FakeResend) Emails() []resend.SendEmailRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]resend.SendEmailRequest(nil), f.emails...)
+}
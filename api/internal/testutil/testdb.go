@@ -20,6 +20,8 @@ import (
 	"splajompy.com/api/v2/internal/like"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/reaction"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/user"
 )
 
@@ -31,8 +33,10 @@ type TestDB struct {
 	PostRepository    post.Store
 	CommentRepository comment.Store
 	LikeRepository    like.Store
+	ReactionStore     reaction.Store
 	NotificationStore notification.Store
 	BucketRepository  bucket.Repository
+	TxManager         *transaction.Manager
 }
 
 // StartPostgres starts a PostgreSQL container, applies schema.sql, and returns a connected TestDB.
@@ -83,15 +87,18 @@ func StartPostgres(t *testing.T) *TestDB {
 	}
 
 	q := queries.New(pool)
+	bucketRepository := &bucket.FakeBucketRepository{}
 	return &TestDB{
 		Pool:              pool,
 		Queries:           q,
-		UserRepository:    user.NewUserRepository(q),
+		UserRepository:    user.NewUserRepository(q, bucketRepository),
 		PostRepository:    post.NewDBPostRepository(q),
 		CommentRepository: *comment.NewStore(q),
 		LikeRepository:    like.NewStore(q),
+		ReactionStore:     reaction.NewStore(q),
 		NotificationStore: notification.NewNotificationStore(q),
-		BucketRepository:  &bucket.FakeBucketRepository{},
+		BucketRepository:  bucketRepository,
+		TxManager:         transaction.NewManager(pool),
 	}
 }
 
@@ -0,0 +1,100 @@
+package transaction
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"splajompy.com/api/v2/internal/db/queries"
+)
+
+// Beginner starts database transactions. *pgxpool.Pool implements it.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Manager runs units of work against the database.
+type Manager struct {
+	db Beginner
+}
+
+func NewManager(db Beginner) *Manager {
+	return &Manager{db: db}
+}
+
+// UnitOfWork is a database transaction shared by every store taking part in it, along with the
+// side effects outside the database that depend on whether it commits.
+type UnitOfWork struct {
+	querier       queries.Querier
+	compensations []func(ctx context.Context) error
+	afterCommit   []func()
+}
+
+// Querier returns the queries bound to the transaction, for stores to build a copy of themselves from.
+func (u *UnitOfWork) Querier() queries.Querier {
+	return u.querier
+}
+
+// OnRollback registers fn to undo a side effect outside the database, such as writing a blob,
+// if the unit of work doesn't commit. Compensations run in the reverse order they were registered.
+func (u *UnitOfWork) OnRollback(fn func(ctx context.Context) error) {
+	u.compensations = append(u.compensations, fn)
+}
+
+// AfterCommit registers fn to run once the unit of work has committed, for side effects that
+// must not happen unless it does, such as push notifications.
+func (u *UnitOfWork) AfterCommit(fn func()) {
+	u.afterCommit = append(u.afterCommit, fn)
+}
+
+// Run calls fn in a transaction and commits it if fn succeeds. Otherwise the transaction is
+// rolled back and the compensations fn registered are run, including when fn panics.
+func (m *Manager) Run(ctx context.Context, fn func(uow *UnitOfWork) error) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	uow := &UnitOfWork{querier: queries.New(tx)}
+
+	// a canceled request still has to be cleaned up after
+	cleanupCtx := context.WithoutCancel(ctx)
+	rollback := func() {
+		if rollbackErr := tx.Rollback(cleanupCtx); rollbackErr != nil {
+			slog.ErrorContext(ctx, "unable to roll back transaction", "error", rollbackErr)
+		}
+		uow.compensate(cleanupCtx)
+	}
+
+	err = func() error {
+		defer func() {
+			if p := recover(); p != nil {
+				rollback()
+				panic(p)
+			}
+		}()
+		return fn(uow)
+	}()
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		uow.compensate(cleanupCtx)
+		return err
+	}
+
+	for _, fn := range uow.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+func (u *UnitOfWork) compensate(ctx context.Context) {
+	for i := len(u.compensations) - 1; i >= 0; i-- {
+		if err := u.compensations[i](ctx); err != nil {
+			slog.ErrorContext(ctx, "unable to compensate for rolled back transaction", "error", err)
+		}
+	}
+}
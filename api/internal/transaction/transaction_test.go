@@ -0,0 +1,97 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/post"
+	"splajompy.com/api/v2/internal/testutil"
+	"splajompy.com/api/v2/internal/transaction"
+)
+
+func TestRun_CommitsAndRunsAfterCommit(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	user0 := testutil.CreateTestUser(t, db.UserRepository, "user0")
+
+	var created *models.Post
+	var committed, compensated bool
+	err := db.TxManager.Run(t.Context(), func(uow *transaction.UnitOfWork) error {
+		uow.OnRollback(func(context.Context) error {
+			compensated = true
+			return nil
+		})
+		uow.AfterCommit(func() { committed = true })
+
+		visibility := models.VisibilityPublic
+		var err error
+		created, err = db.PostRepository.WithTx(uow).InsertPost(t.Context(), user0.UserID, "post", nil, nil, &visibility, nil)
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.True(t, committed)
+	assert.False(t, compensated)
+	_, err = db.PostRepository.GetPostById(t.Context(), created.PostID, user0.UserID)
+	assert.NoError(t, err)
+}
+
+func TestRun_RollsBackAndCompensatesInReverseOrder(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	user0 := testutil.CreateTestUser(t, db.UserRepository, "user0")
+
+	failure := errors.New("failure")
+	var created *models.Post
+	var compensations []int
+	var committed bool
+	err := db.TxManager.Run(t.Context(), func(uow *transaction.UnitOfWork) error {
+		for i := range 2 {
+			uow.OnRollback(func(context.Context) error {
+				compensations = append(compensations, i)
+				return nil
+			})
+		}
+		uow.AfterCommit(func() { committed = true })
+
+		visibility := models.VisibilityPublic
+		var err error
+		created, err = db.PostRepository.WithTx(uow).InsertPost(t.Context(), user0.UserID, "post", nil, nil, &visibility, nil)
+		require.NoError(t, err)
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	assert.False(t, committed)
+	assert.Equal(t, []int{1, 0}, compensations)
+	_, err = db.PostRepository.GetPostById(t.Context(), created.PostID, user0.UserID)
+	assert.ErrorIs(t, err, post.ErrPostNotFound)
+}
+
+func TestRun_RollsBackAndCompensatesOnPanic(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	user0 := testutil.CreateTestUser(t, db.UserRepository, "user0")
+
+	var created *models.Post
+	var compensated bool
+	assert.PanicsWithValue(t, "failure", func() {
+		_ = db.TxManager.Run(t.Context(), func(uow *transaction.UnitOfWork) error {
+			uow.OnRollback(func(context.Context) error {
+				compensated = true
+				return nil
+			})
+
+			visibility := models.VisibilityPublic
+			var err error
+			created, err = db.PostRepository.WithTx(uow).InsertPost(t.Context(), user0.UserID, "post", nil, nil, &visibility, nil)
+			require.NoError(t, err)
+			panic("failure")
+		})
+	})
+
+	assert.True(t, compensated)
+	_, err := db.PostRepository.GetPostById(t.Context(), created.PostID, user0.UserID)
+	assert.ErrorIs(t, err, post.ErrPostNotFound)
+}
@@ -3,8 +3,11 @@ package user
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"time"
 
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/utilities"
 )
@@ -25,12 +28,20 @@ func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWrit
 	// muting
 	withAuth("POST /user/{user_id}/mute", h.MuteUser)
 	withAuth("DELETE /user/{user_id}/mute", h.UnmuteUser)
+	withAuth("GET /v2/user/muted", h.ListMutedUsers)
+	withAuth("GET /v2/user/blocked", h.ListBlockedUsers)
+	withAuth("GET /muted-words", h.ListMutedWords)
+	withAuth("POST /muted-words", h.MuteWord)
+	withAuth("DELETE /muted-words/{id}", h.UnmuteWord)
 
 	// users
 	withAuth("GET /user/{id}", h.GetUserById)
+	withAuth("GET /v3/user/{id}/followers", h.GetFollowersByUserId)
 	withAuth("GET /v3/user/{id}/following", h.GetFollowingByUserId)
 	withAuth("GET /v3/user/{id}/mutuals", h.GetMutualsByUserIdV3)
 	withAuth("GET /users/notification/{id}", h.ListNotificationActors)
+	withAuth("GET /post/{id}/likes", h.ListPostLikers)
+	withAuth("GET /post/{post_id}/comment/{comment_id}/likes", h.ListCommentLikers)
 	withAuth("GET /users/search", h.SearchUsers)
 
 	withAuth("POST /user/{id}/friend", h.AddUserToCloseFriendsList)
@@ -42,8 +53,17 @@ func (h *Handler) RegisterRoutes(_, withAuth func(string, func(http.ResponseWrit
 	// follow
 	withAuth("POST /follow/{user_id}", h.FollowUser)
 	withAuth("DELETE /follow/{user_id}", h.UnfollowUser)
+	withAuth("GET /follow-requests", h.GetFollowRequests)
+	withAuth("POST /follow-requests/{user_id}/approve", h.ApproveFollowRequest)
+	withAuth("POST /follow-requests/{user_id}/deny", h.DenyFollowRequest)
 
 	withAuth("POST /user/profile", h.UpdateProfile)
+	withAuth("POST /user/privacy", h.UpdatePrivacy)
+	withAuth("POST /user/alt-text", h.UpdateAltTextSetting)
+	withAuth("POST /user/avatar", h.UpdateAvatar)
+	withAuth("DELETE /user/avatar", h.RemoveAvatar)
+	withAuth("POST /user/banner", h.UpdateBanner)
+	withAuth("DELETE /user/banner", h.RemoveBanner)
 
 }
 
@@ -132,6 +152,165 @@ func (h *Handler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 	utilities.HandleEmptySuccess(w)
 }
 
+// GetFollowRequests returns the users waiting for the current user to approve their follow request.
+func (h *Handler) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	limit, before, err := utilities.ParseTimeBasedPagination(r)
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Unable to parse pagination parameters ('limit' and 'before'")
+		return
+	}
+
+	result, err := h.svc.GetFollowRequests(r.Context(), *currentUser, limit, before)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, result)
+}
+
+func (h *Handler) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	userId, err := utilities.GetIntPathParam(r, "user_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	err = h.svc.ApproveFollowRequest(r.Context(), *currentUser, userId)
+	if errors.Is(err, ErrFollowRequestNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+func (h *Handler) DenyFollowRequest(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	userId, err := utilities.GetIntPathParam(r, "user_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	err = h.svc.DenyFollowRequest(r.Context(), *currentUser, userId)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+type UpdatePrivacyRequest struct {
+	IsPrivate bool `json:"isPrivate"`
+}
+
+// UpdatePrivacy sets whether the current user's account is private.
+func (h *Handler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	var request UpdatePrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err := h.svc.UpdatePrivacy(r.Context(), *currentUser, request.IsPrivate)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+type ProfileImageRequest struct {
+	S3Key string `json:"s3Key"`
+}
+
+// UpdateAvatar sets the current user's avatar to an image uploaded through a presigned staging URL.
+func (h *Handler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
+	h.updateProfileImage(w, r, ProfileImageAvatar)
+}
+
+// UpdateBanner sets the current user's banner to an image uploaded through a presigned staging URL.
+func (h *Handler) UpdateBanner(w http.ResponseWriter, r *http.Request) {
+	h.updateProfileImage(w, r, ProfileImageBanner)
+}
+
+func (h *Handler) RemoveAvatar(w http.ResponseWriter, r *http.Request) {
+	h.removeProfileImage(w, r, ProfileImageAvatar)
+}
+
+func (h *Handler) RemoveBanner(w http.ResponseWriter, r *http.Request) {
+	h.removeProfileImage(w, r, ProfileImageBanner)
+}
+
+func (h *Handler) updateProfileImage(w http.ResponseWriter, r *http.Request, imageType ProfileImageType) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	var request ProfileImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err := h.svc.UpdateProfileImage(r.Context(), *currentUser, imageType, request.S3Key)
+	if errors.Is(err, ErrInvalidProfileImage) || media.IsRejected(err) {
+		utilities.HandleError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+func (h *Handler) removeProfileImage(w http.ResponseWriter, r *http.Request, imageType ProfileImageType) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	err := h.svc.RemoveProfileImage(r.Context(), *currentUser, imageType)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+type UpdateAltTextSettingRequest struct {
+	RequireAltText bool `json:"requireAltText"`
+}
+
+// UpdateAltTextSetting sets whether the current user has to describe their images before posting.
+func (h *Handler) UpdateAltTextSetting(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	var request UpdateAltTextSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err := h.svc.UpdateAltTextSetting(r.Context(), *currentUser, request.RequireAltText)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
 type UpdateProfileRequest struct {
 	Name              string                       `json:"name"`
 	Bio               string                       `json:"bio"`
@@ -192,6 +371,12 @@ func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
 	utilities.HandleEmptySuccess(w)
 }
 
+type MuteUserRequest struct {
+	Mode      models.MuteMode `json:"mode"`
+	ExpiresAt *time.Time      `json:"expiresAt"`
+}
+
+// MuteUser POST /user/{user_id}/mute
 func (h *Handler) MuteUser(w http.ResponseWriter, r *http.Request) {
 	currentUser := utilities.GetAuthenticatedUser(r)
 
@@ -201,8 +386,126 @@ func (h *Handler) MuteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.svc.MuteUser(r.Context(), *currentUser, userId)
+	// older clients mute without a body
+	var request MuteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.svc.MuteUser(r.Context(), *currentUser, userId, request.Mode, request.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMuteMode) || errors.Is(err, ErrInvalidMuteExpiry) {
+			utilities.HandleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleEmptySuccess(w)
+}
+
+// ListMutedUsers GET /v2/user/muted
+func (h *Handler) ListMutedUsers(w http.ResponseWriter, r *http.Request) {
+	limit, before, err := utilities.ParseTimeBasedPagination(r)
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Unable to parse pagination parameters ('limit' and 'before'")
+		return
+	}
+
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	result, err := h.svc.GetMutedUsers(r.Context(), *currentUser, limit, before)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, result)
+}
+
+// ListBlockedUsers GET /v2/user/blocked
+func (h *Handler) ListBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	limit, before, err := utilities.ParseTimeBasedPagination(r)
 	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Unable to parse pagination parameters ('limit' and 'before'")
+		return
+	}
+
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	result, err := h.svc.GetBlockedUsers(r.Context(), *currentUser, limit, before)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, result)
+}
+
+type MuteWordRequest struct {
+	Phrase    string                  `json:"phrase"`
+	Scopes    []models.MutedWordScope `json:"scopes"`
+	ExpiresAt *time.Time              `json:"expiresAt"`
+}
+
+// MuteWord POST /muted-words
+func (h *Handler) MuteWord(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	var request MuteWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	mutedWord, err := h.svc.MuteWord(r.Context(), *currentUser, request.Phrase, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMutedWord),
+			errors.Is(err, ErrInvalidMutedWordScope),
+			errors.Is(err, ErrInvalidMutedWordExpiry),
+			errors.Is(err, ErrTooManyMutedWords):
+			utilities.HandleError(w, http.StatusBadRequest, err.Error())
+		default:
+			utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		}
+		return
+	}
+
+	utilities.HandleSuccess(w, mutedWord)
+}
+
+// ListMutedWords GET /muted-words
+func (h *Handler) ListMutedWords(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	mutedWords, err := h.svc.GetMutedWords(r.Context(), *currentUser)
+	if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, mutedWords)
+}
+
+// UnmuteWord DELETE /muted-words/{id}
+func (h *Handler) UnmuteWord(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	id, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	err = h.svc.UnmuteWord(r.Context(), *currentUser, id)
+	if err != nil {
+		if errors.Is(err, ErrMutedWordNotFound) {
+			utilities.HandleError(w, http.StatusNotFound, "This muted word cannot be found.")
+			return
+		}
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
@@ -229,6 +532,8 @@ func (h *Handler) UnmuteUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetFollowingByUserId returns a paginated list of users the given user follows.
+// Returns 404 if the user doesn't exist or either user blocks the other, and 403 if their account is
+// private and the current user doesn't follow them.
 func (h *Handler) GetFollowingByUserId(w http.ResponseWriter, r *http.Request) {
 	currentUser := utilities.GetAuthenticatedUser(r)
 
@@ -245,7 +550,46 @@ func (h *Handler) GetFollowingByUserId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.svc.GetFollowingByUserId(r.Context(), *currentUser, userId, limit, before)
+	if errors.Is(err, ErrUserNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, ErrPrivateAccount) {
+		utilities.HandleError(w, http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, result)
+}
+
+// GetFollowersByUserId returns a paginated list of users who follow the given user.
+// Returns 404 if the user doesn't exist or either user blocks the other, and 403 if their account is
+// private and the current user doesn't follow them.
+func (h *Handler) GetFollowersByUserId(w http.ResponseWriter, r *http.Request) {
+	currentUser := utilities.GetAuthenticatedUser(r)
+
+	userId, err := utilities.GetIntPathParam(r, "id")
 	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing user ID parameter")
+		return
+	}
+
+	limit, before, err := utilities.ParseTimeBasedPagination(r)
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Unable to parse pagination parameters ('limit' and 'before'")
+		return
+	}
+
+	result, err := h.svc.GetFollowersByUserId(r.Context(), *currentUser, userId, limit, before)
+	if errors.Is(err, ErrUserNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, ErrPrivateAccount) {
+		utilities.HandleError(w, http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
@@ -269,7 +613,13 @@ func (h *Handler) GetMutualsByUserIdV3(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.svc.GetMutualsByUserId(r.Context(), *user, targetUserId, limit, before)
-	if err != nil {
+	if errors.Is(err, ErrUserNotFound) {
+		utilities.HandleError(w, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, ErrPrivateAccount) {
+		utilities.HandleError(w, http.StatusForbidden, err.Error())
+		return
+	} else if err != nil {
 		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
@@ -372,6 +722,60 @@ func (h *Handler) ListNotificationActors(w http.ResponseWriter, r *http.Request)
 	utilities.HandleSuccess(w, result)
 }
 
+// ListPostLikers GET /post/{id}/likes
+func (h *Handler) ListPostLikers(w http.ResponseWriter, r *http.Request) {
+	postId, err := utilities.GetIntPathParam(r, "id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing ID parameter")
+		return
+	}
+
+	h.listLikers(w, r, postId, nil)
+}
+
+// ListCommentLikers GET /post/{post_id}/comment/{comment_id}/likes
+func (h *Handler) ListCommentLikers(w http.ResponseWriter, r *http.Request) {
+	postId, err := utilities.GetIntPathParam(r, "post_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing post ID parameter")
+		return
+	}
+
+	commentId, err := utilities.GetIntPathParam(r, "comment_id")
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Missing comment ID parameter")
+		return
+	}
+
+	h.listLikers(w, r, postId, &commentId)
+}
+
+func (h *Handler) listLikers(w http.ResponseWriter, r *http.Request, postId int, commentId *int) {
+	limit, before, err := utilities.ParseTimeBasedPagination(r)
+	if err != nil {
+		utilities.HandleError(w, http.StatusBadRequest, "Unable to parse pagination parameters ('limit' and 'before'")
+		return
+	}
+
+	user := utilities.GetAuthenticatedUser(r)
+
+	result, err := h.svc.GetLikers(r.Context(), user.UserID, postId, commentId, limit, before)
+	if err != nil {
+		if errors.Is(err, ErrPostNotFound) {
+			utilities.HandleError(w, http.StatusNotFound, "This post cannot be found.")
+			return
+		}
+		if errors.Is(err, ErrCommentNotFound) {
+			utilities.HandleError(w, http.StatusNotFound, "This comment cannot be found.")
+			return
+		}
+		utilities.HandleError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utilities.HandleSuccess(w, result)
+}
+
 func (h Handler) ListUserCloseFriendsV2(w http.ResponseWriter, r *http.Request) {
 	limit, before, err := utilities.ParseTimeBasedPagination(r)
 	if err != nil {
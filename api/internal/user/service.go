@@ -4,14 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/resend/resend-go/v3"
 	"golang.org/x/sync/errgroup"
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/db"
+	"splajompy.com/api/v2/internal/db/queries"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/notification"
 	"splajompy.com/api/v2/internal/templates"
+	"splajompy.com/api/v2/internal/transaction"
 	"splajompy.com/api/v2/internal/utilities"
 
 	"splajompy.com/api/v2/internal/models"
@@ -20,18 +29,55 @@ import (
 type Service struct {
 	store               Store
 	notificationService notification.Service
+	bucketRepository    bucket.Repository
+	imageProcessor      *media.Processor
 	emailService        *resend.Client
+	txManager           *transaction.Manager
 }
 
 var (
 	ErrNotificationDoesNotExist = errors.New("this notification no longer exists")
+	ErrFollowRequestNotFound    = errors.New("this follow request no longer exists")
+	ErrUserNotFound             = errors.New("this user doesn't exist")
+	ErrPrivateAccount           = errors.New("this account is private")
+	ErrInvalidProfileImage      = errors.New("this image can't be used as a profile image")
+	ErrPostNotFound             = errors.New("this post no longer exists")
+	ErrCommentNotFound          = errors.New("this comment no longer exists")
+	ErrInvalidMutedWord         = fmt.Errorf("muted words must be between 1 and %d characters", maxMutedWordLength)
+	ErrInvalidMutedWordScope    = errors.New("unknown muted word scope")
+	ErrInvalidMutedWordExpiry   = errors.New("muted words must expire in the future")
+	ErrTooManyMutedWords        = fmt.Errorf("you can mute up to %d words", maxMutedWords)
+	ErrMutedWordNotFound        = errors.New("this muted word no longer exists")
+	ErrInvalidMuteMode          = errors.New("unknown mute mode")
+	ErrInvalidMuteExpiry        = errors.New("mutes must expire in the future")
 )
 
-func NewUserService(userRepository Store, notificationService notification.Service, emailClient *resend.Client) *Service {
+const (
+	maxMutedWordLength = 100
+	maxMutedWords      = 200
+)
+
+var allMutedWordScopes = []models.MutedWordScope{
+	models.MutedWordScopeFeeds,
+	models.MutedWordScopeNotifications,
+	models.MutedWordScopeComments,
+}
+
+type ProfileImageType string
+
+const (
+	ProfileImageAvatar ProfileImageType = "avatar"
+	ProfileImageBanner ProfileImageType = "banner"
+)
+
+func NewUserService(userRepository Store, notificationService notification.Service, bucketRepository bucket.Repository, emailClient *resend.Client, txManager *transaction.Manager) *Service {
 	return &Service{
 		store:               userRepository,
 		notificationService: notificationService,
+		bucketRepository:    bucketRepository,
+		imageProcessor:      media.NewProcessor(bucketRepository),
 		emailService:        emailClient,
+		txManager:           txManager,
 	}
 }
 
@@ -41,12 +87,24 @@ func (s *Service) GetUserById(ctx context.Context, currentUserId int, userID int
 		return nil, err
 	}
 
+	// deactivated profiles are hidden from everyone until the account is restored or purged
+	if currentUserId != userID {
+		deactivatedAt, err := s.store.GetDeactivatedAt(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if deactivatedAt != nil {
+			return nil, pgx.ErrNoRows
+		}
+	}
+
 	bio, _ := s.store.GetBioForUser(ctx, userID)
 	isFollowing, _ := s.store.IsUserFollowingUser(ctx, currentUserId, userID)
 	isFollower, _ := s.store.IsUserFollowingUser(ctx, userID, currentUserId)
 	isBlocking, _ := s.store.IsUserBlockingUser(ctx, currentUserId, userID)
 	isMuting, _ := s.store.IsUserMutingUser(ctx, currentUserId, userID)
 	isFriend, _ := s.store.IsUserFriend(ctx, currentUserId, userID)
+	isFollowRequested, _ := s.store.HasRequestedToFollow(ctx, currentUserId, userID)
 
 	mutuals, err := s.store.GetMutualConnectionsForUser(ctx, currentUserId, userID)
 	if err != nil {
@@ -56,6 +114,11 @@ func (s *Service) GetUserById(ctx context.Context, currentUserId int, userID int
 		mutuals = []string{}
 	}
 
+	counters, err := s.store.GetCounters(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.DetailedUser{
 		UserID:            dbUser.UserID,
 		Username:          dbUser.Username,
@@ -67,9 +130,16 @@ func (s *Service) GetUserById(ctx context.Context, currentUserId int, userID int
 		IsBlocking:        isBlocking,
 		IsMuting:          isMuting,
 		IsFriend:          isFriend,
+		IsFollowRequested: isFollowRequested,
 		Mutuals:           mutuals,
 		MutualCount:       len(mutuals),
 		IsVerified:        dbUser.IsVerified,
+		IsPrivate:         dbUser.IsPrivate,
+		FollowerCount:     counters.FollowerCount,
+		FollowingCount:    counters.FollowingCount,
+		PostCount:         counters.PostCount,
+		AvatarURL:         dbUser.AvatarURL,
+		BannerURL:         dbUser.BannerURL,
 		DisplayProperties: dbUser.DisplayProperties,
 	}, nil
 }
@@ -96,23 +166,131 @@ func (s *Service) FollowUser(ctx context.Context, currentUser models.PublicUser,
 		return errors.New("user is blocked")
 	}
 
+	if user.IsPrivate {
+		isFollowing, err := s.store.IsUserFollowingUser(ctx, currentUser.UserID, userId)
+		if err != nil {
+			return err
+		}
+		if !isFollowing {
+			return s.requestToFollow(ctx, currentUser, user)
+		}
+	}
+
 	if err := s.store.FollowUser(ctx, currentUser.UserID, userId); err != nil {
 		return err
 	}
 
 	text := fmt.Sprintf("@%s followed you", currentUser.Username)
 	body := "Tap to view their profile"
-	_, err = s.notificationService.AddNotification(ctx, user.UserID, nil, nil, &currentUser.UserID, text, models.NotificationTypeFollowers, &body)
+	_, err = s.notificationService.AddNotification(ctx, user.UserID, &currentUser.UserID, nil, nil, &currentUser.UserID, text, models.NotificationTypeFollowers, &body)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// UnfollowUser unfollows the target user, or withdraws a pending request to follow them.
 func (s *Service) UnfollowUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
+	if err := s.store.DeleteFollowRequest(ctx, currentUser.UserID, userId); err != nil {
+		return err
+	}
+
 	return s.store.UnfollowUser(ctx, currentUser.UserID, userId)
 }
 
+// requestToFollow asks a private user to approve the current user as a follower.
+func (s *Service) requestToFollow(ctx context.Context, currentUser models.PublicUser, user models.PublicUser) error {
+	created, err := s.store.RequestToFollow(ctx, currentUser.UserID, user.UserID)
+	if err != nil {
+		return err
+	}
+
+	// don't notify again for a request that's already pending
+	if !created {
+		return nil
+	}
+
+	text := fmt.Sprintf("@%s requested to follow you", currentUser.Username)
+	body := "Tap to review the request"
+	_, err = s.notificationService.AddNotification(ctx, user.UserID, &currentUser.UserID, nil, nil, &currentUser.UserID, text, models.NotificationTypeFollowRequest, &body)
+	return err
+}
+
+// GetFollowRequests returns the users waiting for the current user to approve their follow request.
+func (s *Service) GetFollowRequests(ctx context.Context, currentUser models.PublicUser, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	userIDs, cursor, err := s.store.GetFollowRequestUserIds(ctx, currentUser.UserID, limit, before)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.fetchDetailedUsersFromIDs(ctx, currentUser.UserID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
+}
+
+// ApproveFollowRequest lets the requesting user follow the current user. The notification about
+// the request is removed in the same transaction.
+func (s *Service) ApproveFollowRequest(ctx context.Context, currentUser models.PublicUser, userId int) error {
+	return s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		store := s.store.WithTx(uow)
+		notificationService := s.notificationService.WithTx(uow)
+
+		accepted, err := store.AcceptFollowRequest(ctx, userId, currentUser.UserID)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrFollowRequestNotFound
+		}
+
+		err = notificationService.RemoveFollowRequestNotification(ctx, currentUser.UserID, userId)
+		if err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("@%s accepted your follow request", currentUser.Username)
+		body := "Tap to view their profile"
+		_, err = notificationService.AddNotification(ctx, userId, &currentUser.UserID, nil, nil, &currentUser.UserID, text, models.NotificationTypeFollowers, &body)
+		return err
+	})
+}
+
+// DenyFollowRequest removes a user's pending request to follow the current user, along with the
+// notification about it.
+func (s *Service) DenyFollowRequest(ctx context.Context, currentUser models.PublicUser, userId int) error {
+	return s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		err := s.store.WithTx(uow).DeleteFollowRequest(ctx, userId, currentUser.UserID)
+		if err != nil {
+			return err
+		}
+
+		return s.notificationService.WithTx(uow).RemoveFollowRequestNotification(ctx, currentUser.UserID, userId)
+	})
+}
+
+// UpdatePrivacy sets whether the current user's account is private. Making an account public
+// approves every pending follow request, since they no longer need approval.
+func (s *Service) UpdatePrivacy(ctx context.Context, currentUser models.PublicUser, isPrivate bool) error {
+	if err := s.store.UpdateIsPrivate(ctx, currentUser.UserID, isPrivate); err != nil {
+		return err
+	}
+
+	if isPrivate {
+		return nil
+	}
+
+	return s.store.AcceptAllFollowRequests(ctx, currentUser.UserID)
+}
+
+// UpdateAltTextSetting sets whether the current user has to describe every attachment before
+// posting or commenting.
+func (s *Service) UpdateAltTextSetting(ctx context.Context, currentUser models.PublicUser, requireAltText bool) error {
+	return s.store.UpdateRequireAltText(ctx, currentUser.UserID, requireAltText)
+}
+
 func (s *Service) UpdateProfile(ctx context.Context, userId int, name *string, bio *string, displayProperties *models.UserDisplayProperties) error {
 	if name != nil {
 		if err := s.store.UpdateUserName(ctx, userId, *name); err != nil {
@@ -150,31 +328,241 @@ func (s *Service) IsBlockingUser(ctx context.Context, userId int, targetUserId i
 	return s.store.IsUserBlockingUser(ctx, userId, targetUserId)
 }
 
-// BlockUser blocks the target user
+// BlockUser blocks the target user. Follows and follow requests between them are removed in both
+// directions, along with their likes, reactions and poll votes on each other's content, their
+// places on each other's close friends and audience lists, and their notifications about each other.
+// It all happens in one transaction, so nothing is removed unless the block is recorded.
 func (s *Service) BlockUser(ctx context.Context, currentUser models.PublicUser, targetUserId int) error {
-	err := s.store.UnfollowUser(ctx, currentUser.UserID, targetUserId)
+	return s.txManager.Run(ctx, func(uow *transaction.UnitOfWork) error {
+		store := s.store.WithTx(uow)
+		notificationService := s.notificationService.WithTx(uow)
+
+		err := store.UnfollowUser(ctx, currentUser.UserID, targetUserId)
+		if err != nil {
+			return err
+		}
+
+		err = store.UnfollowUser(ctx, targetUserId, currentUser.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = store.DeleteFollowRequest(ctx, currentUser.UserID, targetUserId)
+		if err != nil {
+			return err
+		}
+
+		err = store.DeleteFollowRequest(ctx, targetUserId, currentUser.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = store.DeleteInteractionsBetweenUsers(ctx, currentUser.UserID, targetUserId)
+		if err != nil {
+			return err
+		}
+
+		err = notificationService.RemoveNotificationsBetween(ctx, currentUser.UserID, targetUserId)
+		if err != nil {
+			return err
+		}
+
+		return store.BlockUser(ctx, currentUser.UserID, targetUserId)
+	})
+}
+
+func (s *Service) UnblockUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
+	return s.store.UnblockUser(ctx, currentUser.UserID, userId)
+}
+
+// MuteUser mutes a user until expiresAt, or indefinitely when it's nil. Muting hides their posts
+// and comments unless mode is MuteModeNotifications, and stops notifications caused by them unless
+// mode is MuteModePosts. An empty mode hides posts, the way mutes always have.
+func (s *Service) MuteUser(ctx context.Context, currentUser models.PublicUser, userId int, mode models.MuteMode, expiresAt *time.Time) error {
+	switch mode {
+	case "":
+		mode = models.MuteModePosts
+	case models.MuteModePosts, models.MuteModeNotifications, models.MuteModeAll:
+	default:
+		return ErrInvalidMuteMode
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return ErrInvalidMuteExpiry
+	}
+
+	return s.store.MuteUser(ctx, currentUser.UserID, userId, mode, expiresAt)
+}
+
+func (s *Service) UnmuteUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
+	return s.store.UnmuteUser(ctx, currentUser.UserID, userId)
+}
+
+// GetMutedUsers returns a paginated list of users the current user has muted, using the time of
+// the mute as a cursor. Expired mutes aren't included.
+func (s *Service) GetMutedUsers(ctx context.Context, currentUser models.PublicUser, limit int, before *time.Time) (*models.PaginatedMutedUserList, error) {
+	rows, cursor, err := s.store.ListMutedUsers(ctx, currentUser.UserID, limit, before)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	userIds := make([]int, len(rows))
+	mutes := make(map[int]queries.ListMutedUsersRow, len(rows))
+	for i, row := range rows {
+		userIds[i] = row.UserID
+		mutes[row.UserID] = row
+	}
+
+	users, err := s.fetchDetailedUsersFromIDs(ctx, currentUser.UserID, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	mutedUsers := make([]models.MutedUser, len(users))
+	for i, user := range users {
+		mute := mutes[user.UserID]
+		mode := models.MuteModeAll
+		if !mute.MuteNotifications {
+			mode = models.MuteModePosts
+		} else if !mute.HidePosts {
+			mode = models.MuteModeNotifications
+		}
+		mutedUsers[i] = models.MutedUser{User: user, Mode: mode, ExpiresAt: mute.ExpiresAt}
 	}
 
-	err = s.store.UnfollowUser(ctx, targetUserId, currentUser.UserID)
+	return &models.PaginatedMutedUserList{Users: mutedUsers, NextCursor: cursor}, nil
+}
+
+// GetBlockedUsers returns a paginated list of users the current user has blocked, using the time
+// of the block as a cursor.
+func (s *Service) GetBlockedUsers(ctx context.Context, currentUser models.PublicUser, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	userIds, cursor, err := s.store.GetBlockedUserIds(ctx, currentUser.UserID, limit, before)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.fetchDetailedUsersFromIDs(ctx, currentUser.UserID, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
+}
+
+// DeleteExpiredMutes removes user mutes that have expired. They're already ignored everywhere mutes
+// apply, so this only keeps the table small.
+func (s *Service) DeleteExpiredMutes(ctx context.Context) error {
+	return s.store.DeleteExpiredMutes(ctx)
+}
+
+// MuteWord hides posts, comments and notifications containing a word, phrase or hashtag from the
+// current user in the given scopes, or all of them when none are given. Muting a phrase again
+// replaces its scopes and expiry.
+func (s *Service) MuteWord(ctx context.Context, currentUser models.PublicUser, phrase string, scopes []models.MutedWordScope, expiresAt *time.Time) (*models.MutedWord, error) {
+	phrase = normalizeMutedWord(phrase)
+	if phrase == "" || utf8.RuneCountInString(phrase) > maxMutedWordLength {
+		return nil, ErrInvalidMutedWord
+	}
+
+	if len(scopes) == 0 {
+		scopes = allMutedWordScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allMutedWordScopes, scope) {
+			return nil, ErrInvalidMutedWordScope
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, ErrInvalidMutedWordExpiry
+	}
+
+	// re-muting a phrase replaces it, so only new phrases count towards the limit
+	count, err := s.store.CountOtherMutedWords(ctx, currentUser.UserID, phrase)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxMutedWords {
+		return nil, ErrTooManyMutedWords
+	}
+
+	mutedWord, err := s.store.UpsertMutedWord(ctx, currentUser.UserID, phrase, mutedWordPattern(phrase), scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(mapMutedWord(mutedWord)), nil
+}
+
+// GetMutedWords returns the current user's muted words that haven't expired.
+func (s *Service) GetMutedWords(ctx context.Context, currentUser models.PublicUser) ([]models.MutedWord, error) {
+	dbMutedWords, err := s.store.ListMutedWords(ctx, currentUser.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	mutedWords := make([]models.MutedWord, len(dbMutedWords))
+	for i, mutedWord := range dbMutedWords {
+		mutedWords[i] = mapMutedWord(mutedWord)
+	}
+
+	return mutedWords, nil
+}
+
+// UnmuteWord removes one of the current user's muted words.
+func (s *Service) UnmuteWord(ctx context.Context, currentUser models.PublicUser, id int) error {
+	deleted, err := s.store.DeleteMutedWord(ctx, currentUser.UserID, id)
 	if err != nil {
 		return err
 	}
+	if !deleted {
+		return ErrMutedWordNotFound
+	}
+	return nil
+}
 
-	return s.store.BlockUser(ctx, currentUser.UserID, targetUserId)
+// DeleteExpiredMutedWords removes muted words that have expired. They're already ignored when
+// filtering, so this only keeps the table small.
+func (s *Service) DeleteExpiredMutedWords(ctx context.Context) error {
+	return s.store.DeleteExpiredMutedWords(ctx)
 }
 
-func (s *Service) UnblockUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
-	return s.store.UnblockUser(ctx, currentUser.UserID, userId)
+// normalizeMutedWord lowercases a muted word and collapses its whitespace, so the same phrase
+// can't be muted twice.
+func normalizeMutedWord(phrase string) string {
+	return strings.ToLower(strings.Join(strings.Fields(phrase), " "))
 }
 
-func (s *Service) MuteUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
-	return s.store.MuteUser(ctx, currentUser.UserID, userId)
+// mutedWordPattern builds the case-insensitive Postgres regex used to find a muted word in text.
+// The word must not touch other letters, digits or underscores on either side, and any whitespace
+// can separate the words of a phrase. Hashtags match as-is since '#' isn't a word character.
+func mutedWordPattern(phrase string) string {
+	words := strings.Fields(phrase)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return `(^|[^[:alnum:]_])` + strings.Join(words, `\s+`) + `($|[^[:alnum:]_])`
 }
 
-func (s *Service) UnmuteUser(ctx context.Context, currentUser models.PublicUser, userId int) error {
-	return s.store.UnmuteUser(ctx, currentUser.UserID, userId)
+func mapMutedWord(mutedWord queries.MutedWord) models.MutedWord {
+	scopes := []models.MutedWordScope{}
+	if mutedWord.MuteFeeds {
+		scopes = append(scopes, models.MutedWordScopeFeeds)
+	}
+	if mutedWord.MuteNotifications {
+		scopes = append(scopes, models.MutedWordScopeNotifications)
+	}
+	if mutedWord.MuteComments {
+		scopes = append(scopes, models.MutedWordScopeComments)
+	}
+
+	return models.MutedWord{
+		ID:        mutedWord.ID,
+		Phrase:    mutedWord.Phrase,
+		Scopes:    scopes,
+		ExpiresAt: mutedWord.ExpiresAt,
+		CreatedAt: mutedWord.CreatedAt,
+	}
 }
 
 func (s *Service) RequestFeature(ctx context.Context, user models.PublicUser, text string) error {
@@ -199,7 +587,12 @@ func (s *Service) RequestFeature(ctx context.Context, user models.PublicUser, te
 	return err
 }
 
+// GetFollowingByUserId returns a paginated list of users the given user follows.
 func (s *Service) GetFollowingByUserId(ctx context.Context, user models.PublicUser, targetUserId int, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	if err := s.checkCanViewConnections(ctx, user.UserID, targetUserId); err != nil {
+		return nil, err
+	}
+
 	userIDs, cursor, err := s.store.GetFollowingUserIds(ctx, targetUserId, limit, before)
 	if err != nil {
 		return nil, err
@@ -213,8 +606,31 @@ func (s *Service) GetFollowingByUserId(ctx context.Context, user models.PublicUs
 	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
 }
 
+// GetFollowersByUserId returns a paginated list of users who follow the given user.
+func (s *Service) GetFollowersByUserId(ctx context.Context, user models.PublicUser, targetUserId int, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	if err := s.checkCanViewConnections(ctx, user.UserID, targetUserId); err != nil {
+		return nil, err
+	}
+
+	userIDs, cursor, err := s.store.GetFollowerUserIds(ctx, targetUserId, limit, before)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.fetchDetailedUsersFromIDs(ctx, user.UserID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
+}
+
 // GetMutualsByUserId returns users who are 'mutuals' with the current user and target user. That is, who follow both the current user and target user.
 func (s *Service) GetMutualsByUserId(ctx context.Context, user models.PublicUser, targetUserId int, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	if err := s.checkCanViewConnections(ctx, user.UserID, targetUserId); err != nil {
+		return nil, err
+	}
+
 	userIDs, cursor, err := s.store.GetMutualUserIds(ctx, user.UserID, targetUserId, limit, before)
 	if err != nil {
 		return nil, err
@@ -228,6 +644,54 @@ func (s *Service) GetMutualsByUserId(ctx context.Context, user models.PublicUser
 	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
 }
 
+// checkCanViewConnections applies the same rules as the profile feed to a user's follower and
+// following lists: they're hidden from users blocked either way and, while deactivated, from everyone
+// but the user themselves. A private account's lists are only shown to the user and their followers.
+func (s *Service) checkCanViewConnections(ctx context.Context, currentUserId int, targetUserId int) error {
+	if currentUserId == targetUserId {
+		return nil
+	}
+
+	target, err := s.store.GetUserById(ctx, targetUserId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	deactivatedAt, err := s.store.GetDeactivatedAt(ctx, targetUserId)
+	if err != nil {
+		return err
+	}
+	if deactivatedAt != nil {
+		return ErrUserNotFound
+	}
+
+	for _, pair := range [][2]int{{targetUserId, currentUserId}, {currentUserId, targetUserId}} {
+		isBlocking, err := s.store.IsUserBlockingUser(ctx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		if isBlocking {
+			return ErrUserNotFound
+		}
+	}
+
+	if !target.IsPrivate {
+		return nil
+	}
+
+	isFollowing, err := s.store.IsUserFollowingUser(ctx, currentUserId, targetUserId)
+	if err != nil {
+		return err
+	}
+	if !isFollowing {
+		return ErrPrivateAccount
+	}
+
+	return nil
+}
+
 // AddUserToCloseFriendsList creates a relationship to mark the given userId as close friend of the current user.
 func (s Service) AddUserToCloseFriendsList(ctx context.Context, currentUser models.PublicUser, userId int) error {
 	return s.store.AddUserRelationship(ctx, currentUser.UserID, userId)
@@ -279,20 +743,58 @@ func (s *Service) GetNotificationActors(ctx context.Context, currentUserId int,
 	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
 }
 
+// GetLikers returns a paginated list of users who liked a post, or one of its comments when
+// commentId is set, using the time of the like as a cursor.
+func (s *Service) GetLikers(ctx context.Context, currentUserId int, postId int, commentId *int, limit int, before *time.Time) (*models.PaginatedUserList, error) {
+	visible, err := s.store.IsPostVisible(ctx, currentUserId, postId)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+
+	if commentId != nil {
+		onPost, err := s.store.IsCommentOnPost(ctx, *commentId, postId)
+		if err != nil {
+			return nil, err
+		}
+		if !onPost {
+			return nil, ErrCommentNotFound
+		}
+	}
+
+	userIds, cursor, err := s.store.GetLikerUserIds(ctx, currentUserId, postId, commentId, limit, before)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.fetchDetailedUsersFromIDs(ctx, currentUserId, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedUserList{Users: users, NextCursor: cursor}, nil
+}
+
 // fetchDetailedUsersFromIDs concurrently fetches detailed user information for the given user IDs.
 // It uses an errgroup to parallelize the individual GetUserById calls and returns all results
-// once complete, or the first error encountered.
+// once complete, or the first error encountered. Users that can't be seen, such as deactivated
+// accounts, are left out.
 func (s *Service) fetchDetailedUsersFromIDs(ctx context.Context, currentUserId int, userIDs []int) ([]models.DetailedUser, error) {
-	detailedUsers := make([]models.DetailedUser, len(userIDs))
+	results := make([]*models.DetailedUser, len(userIDs))
 	g, ctx := errgroup.WithContext(ctx)
 
 	for i, userID := range userIDs {
 		g.Go(func() error {
 			user, err := s.GetUserById(ctx, currentUserId, userID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
 			if err != nil {
 				return err
 			}
-			detailedUsers[i] = *user
+			results[i] = user
 			return nil
 		})
 	}
@@ -301,5 +803,78 @@ func (s *Service) fetchDetailedUsersFromIDs(ctx context.Context, currentUserId i
 		return nil, err
 	}
 
+	detailedUsers := make([]models.DetailedUser, 0, len(results))
+	for _, user := range results {
+		if user != nil {
+			detailedUsers = append(detailedUsers, *user)
+		}
+	}
+
 	return detailedUsers, nil
 }
+
+// UpdateProfileImage processes an image the current user has staged, stripping its metadata like
+// any other upload, and makes it their avatar or banner, removing the image it replaces.
+func (s *Service) UpdateProfileImage(ctx context.Context, currentUser models.PublicUser, imageType ProfileImageType, stagedKey string) error {
+	if !bucket.IsStagedKeyForUser(currentUser.UserID, stagedKey) {
+		return ErrInvalidProfileImage
+	}
+
+	uploads, err := s.imageProcessor.Prepare(ctx, currentUser.UserID, map[int]models.ImageData{
+		0: {S3Key: stagedKey},
+	})
+	if err != nil {
+		return err
+	}
+
+	key, err := s.imageProcessor.PublishOriginal(ctx, currentUser.UserID, string(imageType), currentUser.UserID, uploads[0])
+	if err != nil {
+		return err
+	}
+
+	if err := s.setProfileImageKey(ctx, currentUser.UserID, imageType, key); err != nil {
+		if deleteErr := s.bucketRepository.DeleteObject(context.WithoutCancel(ctx), key); deleteErr != nil {
+			slog.ErrorContext(ctx, "failed to delete unused profile image", "user_id", currentUser.UserID, "key", key, "error", deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveProfileImage clears the current user's avatar or banner.
+func (s *Service) RemoveProfileImage(ctx context.Context, currentUser models.PublicUser, imageType ProfileImageType) error {
+	return s.setProfileImageKey(ctx, currentUser.UserID, imageType, "")
+}
+
+// setProfileImageKey stores the new key for a profile image and deletes the blob it replaces. An
+// empty key clears the image.
+func (s *Service) setProfileImageKey(ctx context.Context, userId int, imageType ProfileImageType, key string) error {
+	avatarKey, bannerKey, err := s.store.GetProfileImageKeys(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	var oldKey string
+	switch imageType {
+	case ProfileImageAvatar:
+		oldKey = avatarKey
+		err = s.store.UpdateAvatarKey(ctx, userId, key)
+	case ProfileImageBanner:
+		oldKey = bannerKey
+		err = s.store.UpdateBannerKey(ctx, userId, key)
+	default:
+		return ErrInvalidProfileImage
+	}
+	if err != nil {
+		return err
+	}
+
+	// the old image is unreachable once replaced, so failing to delete it only leaks storage
+	if oldKey != "" && oldKey != key {
+		if err := s.bucketRepository.DeleteObject(ctx, oldKey); err != nil {
+			slog.WarnContext(ctx, "failed to delete replaced profile image", "user_id", userId, "key", oldKey, "error", err)
+		}
+	}
+
+	return nil
+}
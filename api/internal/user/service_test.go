@@ -1,14 +1,22 @@
 package user_test
 
 import (
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"splajompy.com/api/v2/internal/apns"
+	"splajompy.com/api/v2/internal/bucket"
+	"splajompy.com/api/v2/internal/comment"
+	"splajompy.com/api/v2/internal/like"
+	"splajompy.com/api/v2/internal/media"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/notification"
+	"splajompy.com/api/v2/internal/post"
 	"splajompy.com/api/v2/internal/testutil"
 	"splajompy.com/api/v2/internal/user"
 	"splajompy.com/api/v2/internal/utilities"
@@ -18,6 +26,11 @@ type userServiceTestEnv struct {
 	svc               *user.Service
 	userRepository    user.Store
 	notificationStore notification.Store
+	postRepository    post.Store
+	commentRepository *comment.Store
+	likeRepository    like.Store
+	bucketRepository  bucket.Repository
+	postSvc           *post.Service
 }
 
 func setupTest(t *testing.T) userServiceTestEnv {
@@ -25,12 +38,17 @@ func setupTest(t *testing.T) userServiceTestEnv {
 	db := testutil.StartPostgres(t)
 
 	notificationService := notification.NewService(db.NotificationStore, db.PostRepository, &db.CommentRepository, db.UserRepository, db.BucketRepository, apns.Client{})
-	svc := user.NewUserService(db.UserRepository, *notificationService, nil)
+	svc := user.NewUserService(db.UserRepository, *notificationService, db.BucketRepository, nil, db.TxManager)
 
 	return userServiceTestEnv{
 		svc:               svc,
 		userRepository:    db.UserRepository,
 		notificationStore: db.NotificationStore,
+		postRepository:    db.PostRepository,
+		commentRepository: &db.CommentRepository,
+		likeRepository:    db.LikeRepository,
+		bucketRepository:  db.BucketRepository,
+		postSvc:           post.NewService(db.PostRepository, db.UserRepository, db.LikeRepository, db.ReactionStore, *notificationService, db.BucketRepository, nil, db.TxManager),
 	}
 }
 
@@ -128,3 +146,501 @@ func TestFollowUser_Idempotent(t *testing.T) {
 	err = env.svc.FollowUser(t.Context(), u0, u1.UserID)
 	require.NoError(t, err)
 }
+
+func TestFollowUser_PrivateAccountCreatesRequest(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u1, true))
+
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+
+	isFollowing, err := env.userRepository.IsUserFollowingUser(t.Context(), u0.UserID, u1.UserID)
+	require.NoError(t, err)
+	assert.False(t, isFollowing)
+
+	profile, err := env.svc.GetUserById(t.Context(), u0.UserID, u1.UserID)
+	require.NoError(t, err)
+	assert.True(t, profile.IsPrivate)
+	assert.True(t, profile.IsFollowRequested)
+
+	// repeated requests only notify once
+	notifications, err := env.notificationStore.GetUnreadNotificationsForUserIdWithTimeOffset(t.Context(), u1.UserID, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user0 requested to follow you", notifications[0].Message)
+
+	requests, err := env.svc.GetFollowRequests(t.Context(), u1, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, requests.Users, 1)
+	assert.Equal(t, u0.UserID, requests.Users[0].UserID)
+}
+
+func TestApproveFollowRequest(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u1, true))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+
+	require.NoError(t, env.svc.ApproveFollowRequest(t.Context(), u1, u0.UserID))
+
+	isFollowing, err := env.userRepository.IsUserFollowingUser(t.Context(), u0.UserID, u1.UserID)
+	require.NoError(t, err)
+	assert.True(t, isFollowing)
+
+	notifications, err := env.notificationStore.GetUnreadNotificationsForUserIdWithTimeOffset(t.Context(), u0.UserID, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "@user1 accepted your follow request", notifications[0].Message)
+
+	// the request has been answered, so the notification about it is gone
+	notifications, err = env.notificationStore.GetUnreadNotificationsForUserIdWithTimeOffset(t.Context(), u1.UserID, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	err = env.svc.ApproveFollowRequest(t.Context(), u1, u0.UserID)
+	assert.ErrorIs(t, err, user.ErrFollowRequestNotFound)
+}
+
+func TestDenyFollowRequest(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u1, true))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+
+	require.NoError(t, env.svc.DenyFollowRequest(t.Context(), u1, u0.UserID))
+
+	isFollowing, err := env.userRepository.IsUserFollowingUser(t.Context(), u0.UserID, u1.UserID)
+	require.NoError(t, err)
+	assert.False(t, isFollowing)
+
+	requests, err := env.svc.GetFollowRequests(t.Context(), u1, 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, requests.Users)
+
+	notifications, err := env.notificationStore.GetUnreadNotificationsForUserIdWithTimeOffset(t.Context(), u1.UserID, time.Now().UTC(), 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
+
+func TestUpdatePrivacy_MakingPublicApprovesPendingRequests(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u1, true))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u1, false))
+
+	isFollowing, err := env.userRepository.IsUserFollowingUser(t.Context(), u0.UserID, u1.UserID)
+	require.NoError(t, err)
+	assert.True(t, isFollowing)
+}
+
+func TestGetFollowersByUserId(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.svc.FollowUser(t.Context(), u1, u0.UserID))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u2, u0.UserID))
+
+	page, err := env.svc.GetFollowersByUserId(t.Context(), u0, u0.UserID, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u2.UserID, page.Users[0].UserID)
+	require.NotNil(t, page.NextCursor)
+
+	page, err = env.svc.GetFollowersByUserId(t.Context(), u0, u0.UserID, 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u1.UserID, page.Users[0].UserID)
+}
+
+func TestGetFollowersByUserId_PrivateAccount(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.svc.FollowUser(t.Context(), u1, u0.UserID))
+	require.NoError(t, env.svc.UpdatePrivacy(t.Context(), u0, true))
+
+	_, err := env.svc.GetFollowersByUserId(t.Context(), u2, u0.UserID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrPrivateAccount)
+	_, err = env.svc.GetFollowingByUserId(t.Context(), u2, u0.UserID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrPrivateAccount)
+	_, err = env.svc.GetMutualsByUserId(t.Context(), u2, u0.UserID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrPrivateAccount)
+
+	page, err := env.svc.GetFollowersByUserId(t.Context(), u1, u0.UserID, 10, nil)
+	require.NoError(t, err, "followers can see a private account's lists")
+	require.Len(t, page.Users, 1)
+
+	page, err = env.svc.GetFollowersByUserId(t.Context(), u0, u0.UserID, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+}
+
+func TestGetFollowersByUserId_Blocked(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	require.NoError(t, env.svc.BlockUser(t.Context(), u0, u1.UserID))
+
+	_, err := env.svc.GetFollowersByUserId(t.Context(), u1, u0.UserID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrUserNotFound)
+	_, err = env.svc.GetFollowingByUserId(t.Context(), u0, u1.UserID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrUserNotFound)
+
+	_, err = env.svc.GetFollowersByUserId(t.Context(), u1, 999999, 10, nil)
+	assert.ErrorIs(t, err, user.ErrUserNotFound)
+}
+
+func TestGetLikers_PaginatesAndHidesBlockedUsers(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+	u3 := testutil.CreateTestUser(t, env.userRepository, "user3")
+
+	p, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	for _, liker := range []models.PublicUser{u1, u2, u3} {
+		require.NoError(t, env.likeRepository.AddLike(t.Context(), liker.UserID, p.PostID, nil))
+	}
+	require.NoError(t, env.svc.BlockUser(t.Context(), u2, u0.UserID))
+
+	page, err := env.svc.GetLikers(t.Context(), u0.UserID, p.PostID, nil, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u3.UserID, page.Users[0].UserID)
+	require.NotNil(t, page.NextCursor)
+
+	page, err = env.svc.GetLikers(t.Context(), u0.UserID, p.PostID, nil, 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u1.UserID, page.Users[0].UserID)
+
+	page, err = env.svc.GetLikers(t.Context(), u0.UserID, p.PostID, nil, 1, page.NextCursor)
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+}
+
+func TestGetLikers_Comment(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	p0, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	p1, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post1", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	c, err := env.commentRepository.AddCommentToPost(t.Context(), u0.UserID, p0.PostID, "comment", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.likeRepository.AddLike(t.Context(), u1.UserID, p0.PostID, &c.CommentID))
+
+	page, err := env.svc.GetLikers(t.Context(), u0.UserID, p0.PostID, &c.CommentID, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u1.UserID, page.Users[0].UserID)
+
+	// comment likes aren't post likes
+	page, err = env.svc.GetLikers(t.Context(), u0.UserID, p0.PostID, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	_, err = env.svc.GetLikers(t.Context(), u0.UserID, p1.PostID, &c.CommentID, 10, nil)
+	assert.ErrorIs(t, err, user.ErrCommentNotFound)
+}
+
+func TestGetLikers_HiddenWhenPostIsNotVisible(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	p, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	require.NoError(t, env.svc.BlockUser(t.Context(), u0, u1.UserID))
+
+	_, err = env.svc.GetLikers(t.Context(), u1.UserID, p.PostID, nil, 10, nil)
+	assert.ErrorIs(t, err, user.ErrPostNotFound)
+}
+
+func TestMuteWord(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	mutedWord, err := env.svc.MuteWord(t.Context(), u0, "  Spoiler   Alert ", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "spoiler alert", mutedWord.Phrase)
+	assert.Equal(t, []models.MutedWordScope{models.MutedWordScopeFeeds, models.MutedWordScopeNotifications, models.MutedWordScopeComments}, mutedWord.Scopes)
+	assert.Nil(t, mutedWord.ExpiresAt)
+
+	// muting the same phrase again replaces its settings
+	expiresAt := time.Now().Add(time.Hour)
+	updated, err := env.svc.MuteWord(t.Context(), u0, "spoiler alert", []models.MutedWordScope{models.MutedWordScopeComments}, &expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, mutedWord.ID, updated.ID)
+	assert.Equal(t, []models.MutedWordScope{models.MutedWordScopeComments}, updated.Scopes)
+	require.NotNil(t, updated.ExpiresAt)
+
+	mutedWords, err := env.svc.GetMutedWords(t.Context(), u0)
+	require.NoError(t, err)
+	require.Len(t, mutedWords, 1)
+
+	assert.ErrorIs(t, env.svc.UnmuteWord(t.Context(), u1, mutedWord.ID), user.ErrMutedWordNotFound)
+	require.NoError(t, env.svc.UnmuteWord(t.Context(), u0, mutedWord.ID))
+
+	mutedWords, err = env.svc.GetMutedWords(t.Context(), u0)
+	require.NoError(t, err)
+	assert.Empty(t, mutedWords)
+}
+
+func TestMuteWord_Limit(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	for i := range 200 {
+		_, err := env.svc.MuteWord(t.Context(), u0, fmt.Sprintf("word%d", i), nil, nil)
+		require.NoError(t, err)
+	}
+
+	_, err := env.svc.MuteWord(t.Context(), u0, "one too many", nil, nil)
+	assert.ErrorIs(t, err, user.ErrTooManyMutedWords)
+
+	// phrases that are already muted can still be updated at the limit
+	updated, err := env.svc.MuteWord(t.Context(), u0, "word0", []models.MutedWordScope{models.MutedWordScopeFeeds}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.MutedWordScope{models.MutedWordScopeFeeds}, updated.Scopes)
+}
+
+func TestMuteWord_RejectsInvalidInput(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	_, err := env.svc.MuteWord(t.Context(), u0, "   ", nil, nil)
+	assert.ErrorIs(t, err, user.ErrInvalidMutedWord)
+
+	_, err = env.svc.MuteWord(t.Context(), u0, strings.Repeat("a", 101), nil, nil)
+	assert.ErrorIs(t, err, user.ErrInvalidMutedWord)
+
+	_, err = env.svc.MuteWord(t.Context(), u0, "spoiler", []models.MutedWordScope{"profiles"}, nil)
+	assert.ErrorIs(t, err, user.ErrInvalidMutedWordScope)
+
+	_, err = env.svc.MuteWord(t.Context(), u0, "spoiler", nil, new(time.Now().Add(-time.Minute)))
+	assert.ErrorIs(t, err, user.ErrInvalidMutedWordExpiry)
+}
+
+func TestGetMutedUsers(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+	u3 := testutil.CreateTestUser(t, env.userRepository, "user3")
+
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, env.svc.MuteUser(t.Context(), u0, u1.UserID, "", nil))
+	require.NoError(t, env.svc.MuteUser(t.Context(), u0, u2.UserID, models.MuteModeNotifications, &expiresAt))
+	require.NoError(t, env.userRepository.MuteUser(t.Context(), u0.UserID, u3.UserID, models.MuteModeAll, new(time.Now().Add(-time.Minute))))
+
+	page, err := env.svc.GetMutedUsers(t.Context(), u0, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u2.UserID, page.Users[0].User.UserID)
+	assert.Equal(t, models.MuteModeNotifications, page.Users[0].Mode)
+	require.NotNil(t, page.Users[0].ExpiresAt)
+	require.NotNil(t, page.NextCursor)
+
+	page, err = env.svc.GetMutedUsers(t.Context(), u0, 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u1.UserID, page.Users[0].User.UserID)
+	assert.Equal(t, models.MuteModePosts, page.Users[0].Mode)
+	assert.Nil(t, page.Users[0].ExpiresAt)
+
+	// expired mutes are left out
+	page, err = env.svc.GetMutedUsers(t.Context(), u0, 1, page.NextCursor)
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+}
+
+func TestMuteUser_RejectsInvalidOptions(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	assert.ErrorIs(t, env.svc.MuteUser(t.Context(), u0, u1.UserID, "everything", nil), user.ErrInvalidMuteMode)
+	assert.ErrorIs(t, env.svc.MuteUser(t.Context(), u0, u1.UserID, models.MuteModeAll, new(time.Now().Add(-time.Minute))), user.ErrInvalidMuteExpiry)
+}
+
+func TestGetBlockedUsers(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.svc.BlockUser(t.Context(), u0, u1.UserID))
+	require.NoError(t, env.svc.BlockUser(t.Context(), u0, u2.UserID))
+
+	page, err := env.svc.GetBlockedUsers(t.Context(), u0, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u2.UserID, page.Users[0].UserID)
+	assert.True(t, page.Users[0].IsBlocking)
+	require.NotNil(t, page.NextCursor)
+
+	page, err = env.svc.GetBlockedUsers(t.Context(), u0, 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, u1.UserID, page.Users[0].UserID)
+}
+
+func TestGetUserById_Counters(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	require.NoError(t, env.svc.FollowUser(t.Context(), u1, u0.UserID))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u2, u0.UserID))
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+	// following twice doesn't count twice
+	require.NoError(t, env.svc.FollowUser(t.Context(), u0, u1.UserID))
+
+	p, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	_, err = env.postRepository.InsertPost(t.Context(), u0.UserID, "post1", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+
+	profile, err := env.svc.GetUserById(t.Context(), u1.UserID, u0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, profile.FollowerCount)
+	assert.Equal(t, 1, profile.FollowingCount)
+	assert.Equal(t, 2, profile.PostCount)
+
+	require.NoError(t, env.svc.UnfollowUser(t.Context(), u2, u0.UserID))
+	require.NoError(t, env.postRepository.DeletePost(t.Context(), p.PostID))
+	require.NoError(t, env.userRepository.DeleteAccount(t.Context(), u1.UserID))
+
+	profile, err = env.svc.GetUserById(t.Context(), u0.UserID, u0.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, profile.FollowerCount)
+	assert.Equal(t, 0, profile.FollowingCount)
+	assert.Equal(t, 1, profile.PostCount)
+}
+
+func TestUpdateProfileImage(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	first := testutil.StageTestImage(t, env.bucketRepository, u0.UserID, 100, 100)
+	require.NoError(t, env.svc.UpdateProfileImage(t.Context(), u0, user.ProfileImageAvatar, first.S3Key))
+
+	profile, err := env.svc.GetUserById(t.Context(), u1.UserID, u0.UserID)
+	require.NoError(t, err)
+	require.NotNil(t, profile.AvatarURL)
+	assert.Nil(t, profile.BannerURL)
+
+	firstKey, _, err := env.userRepository.GetProfileImageKeys(t.Context(), u0.UserID)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.S3Key, firstKey, "the processed image is published, not the staged upload")
+	_, err = env.bucketRepository.GetObject(t.Context(), firstKey)
+	require.NoError(t, err)
+
+	// replacing the avatar removes the old image
+	second := testutil.StageTestImage(t, env.bucketRepository, u0.UserID, 100, 100)
+	require.NoError(t, env.svc.UpdateProfileImage(t.Context(), u0, user.ProfileImageAvatar, second.S3Key))
+	_, err = env.bucketRepository.GetObject(t.Context(), firstKey)
+	assert.ErrorIs(t, err, bucket.ErrFakeObjectNotFound)
+
+	secondKey, _, err := env.userRepository.GetProfileImageKeys(t.Context(), u0.UserID)
+	require.NoError(t, err)
+	require.NoError(t, env.svc.RemoveProfileImage(t.Context(), u0, user.ProfileImageAvatar))
+	_, err = env.bucketRepository.GetObject(t.Context(), secondKey)
+	assert.ErrorIs(t, err, bucket.ErrFakeObjectNotFound)
+
+	profile, err = env.svc.GetUserById(t.Context(), u1.UserID, u0.UserID)
+	require.NoError(t, err)
+	assert.Nil(t, profile.AvatarURL)
+}
+
+func TestUpdateProfileImage_RejectsInvalidImages(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+
+	key := bucket.StagingPrefix(os.Getenv("ENVIRONMENT"), u0.UserID) + "avatar/notes.jpg"
+	require.NoError(t, env.bucketRepository.PutObject(t.Context(), key, "image/jpeg", strings.NewReader("not an image")))
+
+	err := env.svc.UpdateProfileImage(t.Context(), u0, user.ProfileImageAvatar, key)
+	assert.ErrorIs(t, err, media.ErrInvalidImage)
+
+	avatarKey, _, err := env.userRepository.GetProfileImageKeys(t.Context(), u0.UserID)
+	require.NoError(t, err)
+	assert.Empty(t, avatarKey)
+}
+
+func TestUpdateProfileImage_RejectsOtherUsersUploads(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+
+	key := bucket.StagingPrefix(os.Getenv("ENVIRONMENT"), u1.UserID) + "avatar/a.jpg"
+	err := env.svc.UpdateProfileImage(t.Context(), u0, user.ProfileImageBanner, key)
+	assert.ErrorIs(t, err, user.ErrInvalidProfileImage)
+}
+
+func TestBlockUser_RemovesInteractions(t *testing.T) {
+	env := setupTest(t)
+	u0 := testutil.CreateTestUser(t, env.userRepository, "user0")
+	u1 := testutil.CreateTestUser(t, env.userRepository, "user1")
+	u2 := testutil.CreateTestUser(t, env.userRepository, "user2")
+
+	p, err := env.postRepository.InsertPost(t.Context(), u0.UserID, "post0", nil, nil, new(models.VisibilityPublic), nil)
+	require.NoError(t, err)
+	c, err := env.commentRepository.AddCommentToPost(t.Context(), u1.UserID, p.PostID, "comment", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, env.likeRepository.AddLike(t.Context(), u1.UserID, p.PostID, nil))
+	require.NoError(t, env.likeRepository.AddLike(t.Context(), u2.UserID, p.PostID, nil))
+	require.NoError(t, env.likeRepository.AddLike(t.Context(), u0.UserID, p.PostID, &c.CommentID))
+	require.NoError(t, env.userRepository.AddUserRelationship(t.Context(), u0.UserID, u1.UserID))
+
+	_, err = env.notificationStore.InsertNotification(t.Context(), u0.UserID, &p.PostID, &c.CommentID, nil, "@user1 commented on your post", models.NotificationTypeComment, nil)
+	require.NoError(t, err)
+	_, err = env.notificationStore.InsertNotification(t.Context(), u1.UserID, nil, nil, nil, "@user0 followed you", models.NotificationTypeFollowers, &u0.UserID)
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.BlockUser(t.Context(), u0, u1.UserID))
+
+	detailed, err := env.postSvc.GetPostById(t.Context(), u0.UserID, p.PostID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, detailed.LikeCount)
+	assert.Equal(t, 0, detailed.CommentCount)
+
+	liked, err := env.likeRepository.IsLiked(t.Context(), u0.UserID, p.PostID, &c.CommentID)
+	require.NoError(t, err)
+	assert.False(t, liked)
+
+	closeFriends, _, err := env.userRepository.GetRelationshipUserIds(t.Context(), u0.UserID, 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, closeFriends)
+
+	for _, u := range []models.PublicUser{u0, u1} {
+		notifications, err := env.notificationStore.GetUnreadNotificationsForUserIdWithTimeOffset(t.Context(), u.UserID, time.Now().UTC(), 10, nil)
+		require.NoError(t, err)
+		assert.Empty(t, notifications)
+	}
+}
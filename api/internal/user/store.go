@@ -2,18 +2,67 @@ package user
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
+	"splajompy.com/api/v2/internal/bucket"
 	"splajompy.com/api/v2/internal/db"
 	"splajompy.com/api/v2/internal/utilities"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/models"
+	"splajompy.com/api/v2/internal/transaction"
 )
 
 type Store struct {
-	querier queries.Querier
+	querier          queries.Querier
+	bucketRepository bucket.Repository
+}
+
+// MapPublicUser converts a database user to a PublicUser, signing URLs for their profile images.
+func (r Store) MapPublicUser(ctx context.Context, user queries.User) (models.PublicUser, error) {
+	publicUser := utilities.MapUserToPublicUser(user)
+
+	avatarURL, bannerURL, err := r.signProfileImages(ctx, user)
+	if err != nil {
+		return models.PublicUser{}, err
+	}
+	publicUser.AvatarURL = avatarURL
+	publicUser.BannerURL = bannerURL
+
+	return publicUser, nil
+}
+
+// mapFullUser converts a database user to a FullUser, signing URLs for their profile images.
+func (r Store) mapFullUser(ctx context.Context, user queries.User) (models.FullUser, error) {
+	fullUser := utilities.MapUserToCurrentUserDTO(user)
+
+	avatarURL, bannerURL, err := r.signProfileImages(ctx, user)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+	fullUser.AvatarURL = avatarURL
+	fullUser.BannerURL = bannerURL
+
+	return fullUser, nil
+}
+
+func (r Store) signProfileImages(ctx context.Context, user queries.User) (*string, *string, error) {
+	var urls [2]*string
+	for i, key := range []pgtype.Text{user.AvatarKey, user.BannerKey} {
+		if !key.Valid {
+			continue
+		}
+		url, err := r.bucketRepository.GetPresignedGetObject(ctx, key.String)
+		if err != nil {
+			return nil, nil, err
+		}
+		urls[i] = &url
+	}
+	return urls[0], urls[1], nil
 }
 
 // GetUserById retrieves a user by their ID
@@ -23,7 +72,35 @@ func (r Store) GetUserById(ctx context.Context, userId int) (models.PublicUser,
 		return models.PublicUser{}, err
 	}
 
-	return utilities.MapUserToPublicUser(user), nil
+	return r.MapPublicUser(ctx, user)
+}
+
+// GetUsersByIds retrieves the users out of userIds that exist, keyed by ID
+func (r Store) GetUsersByIds(ctx context.Context, userIds []int) (map[int]models.PublicUser, error) {
+	rows, err := r.querier.GetUsersByIds(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[int]models.PublicUser, len(rows))
+	for _, row := range rows {
+		user, err := r.MapPublicUser(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		users[user.UserID] = user
+	}
+	return users, nil
+}
+
+// GetFullUserById retrieves a user by their ID, including private fields such as email
+func (r Store) GetFullUserById(ctx context.Context, userId int) (models.FullUser, error) {
+	user, err := r.querier.GetUserById(ctx, userId)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	return r.mapFullUser(ctx, user)
 }
 
 // GetUserLatestAppVersion retrieves the stored latest app version for a user.
@@ -38,6 +115,15 @@ func (r Store) GetUserLatestAppVersion(ctx context.Context, userId int) (*string
 	return user.UserDisplayProperties.LatestAppVersion, nil
 }
 
+// GetUserRequiresAltText retrieves whether a user has to describe their images before posting.
+func (r Store) GetUserRequiresAltText(ctx context.Context, userId int) (bool, error) {
+	user, err := r.querier.GetUserById(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return user.RequireAltText, nil
+}
+
 // GetUserByUsername retrieves a user by their username
 func (r Store) GetUserByUsername(ctx context.Context, username string) (models.PublicUser, error) {
 	user, err := r.querier.GetUserByUsername(ctx, username)
@@ -45,7 +131,7 @@ func (r Store) GetUserByUsername(ctx context.Context, username string) (models.P
 		return models.PublicUser{}, err
 	}
 
-	return utilities.MapUserToPublicUser(user), nil
+	return r.MapPublicUser(ctx, user)
 }
 
 // GetUserByIdentifier retrieves a user by email or username
@@ -55,7 +141,34 @@ func (r Store) GetUserByIdentifier(ctx context.Context, identifier string) (mode
 		return models.FullUser{}, err
 	}
 
-	return utilities.MapUserToCurrentUserDTO(user), nil
+	return r.mapFullUser(ctx, user)
+}
+
+// GetUserByEmail retrieves a user by email, ignoring case
+func (r Store) GetUserByEmail(ctx context.Context, email string) (models.FullUser, error) {
+	user, err := r.querier.GetUserByEmail(ctx, email)
+	if err != nil {
+		return models.FullUser{}, err
+	}
+
+	return r.mapFullUser(ctx, user)
+}
+
+// GetAppleIdentity retrieves the Apple identity linked to an Apple subject identifier
+func (r Store) GetAppleIdentity(ctx context.Context, subject string) (queries.AppleIdentity, error) {
+	return r.querier.GetAppleIdentityBySubject(ctx, subject)
+}
+
+// LinkAppleIdentity links an Apple subject identifier to a user, returning false without linking
+// anything if the subject or the user is already linked
+func (r Store) LinkAppleIdentity(ctx context.Context, userId int, subject string, email string, isPrivateEmail bool) (bool, error) {
+	linked, err := r.querier.InsertAppleIdentity(ctx, queries.InsertAppleIdentityParams{
+		UserID:         userId,
+		Subject:        subject,
+		Email:          pgtype.Text{String: email, Valid: email != ""},
+		IsPrivateEmail: isPrivateEmail,
+	})
+	return linked > 0, err
 }
 
 // GetBioForUser retrieves a user's bio
@@ -95,6 +208,87 @@ func (r Store) UnfollowUser(ctx context.Context, followerId int, followingId int
 	})
 }
 
+// RequestToFollow records a pending follow request, reporting whether a new request was created
+func (r Store) RequestToFollow(ctx context.Context, followerId int, followingId int) (bool, error) {
+	n, err := r.querier.InsertFollowRequest(ctx, queries.InsertFollowRequestParams{
+		FollowerID:  followerId,
+		FollowingID: followingId,
+	})
+	return n > 0, err
+}
+
+// HasRequestedToFollow checks if a user has a pending request to follow another user
+func (r Store) HasRequestedToFollow(ctx context.Context, followerId int, followingId int) (bool, error) {
+	return r.querier.GetHasRequestedToFollow(ctx, queries.GetHasRequestedToFollowParams{
+		FollowerID:  followerId,
+		FollowingID: followingId,
+	})
+}
+
+// AcceptFollowRequest turns a pending follow request into a follow, reporting whether there was a
+// request to accept
+func (r Store) AcceptFollowRequest(ctx context.Context, followerId int, followingId int) (bool, error) {
+	n, err := r.querier.AcceptFollowRequest(ctx, queries.AcceptFollowRequestParams{
+		FollowerID:  followerId,
+		FollowingID: followingId,
+	})
+	return n > 0, err
+}
+
+// AcceptAllFollowRequests turns every pending request to follow a user into a follow
+func (r Store) AcceptAllFollowRequests(ctx context.Context, followingId int) error {
+	return r.querier.AcceptAllFollowRequests(ctx, followingId)
+}
+
+// DeleteFollowRequest removes a pending follow request
+func (r Store) DeleteFollowRequest(ctx context.Context, followerId int, followingId int) error {
+	return r.querier.DeleteFollowRequest(ctx, queries.DeleteFollowRequestParams{
+		FollowerID:  followerId,
+		FollowingID: followingId,
+	})
+}
+
+// GetFollowRequestUserIds retrieves the users with pending requests to follow a user, newest first
+func (r Store) GetFollowRequestUserIds(ctx context.Context, userId int, limit int, before *time.Time) ([]int, *time.Time, error) {
+	rows, err := r.querier.GetFollowRequestUserIds(ctx, queries.GetFollowRequestUserIdsParams{
+		UserID: userId,
+		Before: before,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIds := make([]int, len(rows))
+	for i, row := range rows {
+		userIds[i] = row.FollowerID
+	}
+
+	var cursor *time.Time
+	if len(rows) > 0 {
+		t := rows[len(rows)-1].CreatedAt
+		cursor = &t
+	}
+
+	return userIds, cursor, nil
+}
+
+// UpdateRequireAltText sets whether a user has to describe their images before posting
+func (r Store) UpdateRequireAltText(ctx context.Context, userId int, requireAltText bool) error {
+	return r.querier.UpdateUserRequireAltText(ctx, queries.UpdateUserRequireAltTextParams{
+		UserID:         userId,
+		RequireAltText: requireAltText,
+	})
+}
+
+// UpdateIsPrivate sets whether a user's account is private
+func (r Store) UpdateIsPrivate(ctx context.Context, userId int, isPrivate bool) error {
+	return r.querier.UpdateUserIsPrivate(ctx, queries.UpdateUserIsPrivateParams{
+		UserID:    userId,
+		IsPrivate: isPrivate,
+	})
+}
+
 // SearchUsername retrieves users with usernames matching a pattern
 func (r Store) SearchUsername(ctx context.Context, prefix string, limit int, currentUserId int) ([]models.PublicUser, error) {
 	users, err := r.querier.UserSearchWithHeuristics(ctx, queries.UserSearchWithHeuristicsParams{
@@ -112,7 +306,10 @@ func (r Store) SearchUsername(ctx context.Context, prefix string, limit int, cur
 		if err != nil {
 			return nil, err
 		}
-		publicUser := utilities.MapUserToPublicUser(user)
+		publicUser, err := r.MapPublicUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
 
 		isFriend, err := r.querier.GetIsUserFriend(ctx, queries.GetIsUserFriendParams{
 			UserID:       currentUserId,
@@ -176,7 +373,7 @@ func (r Store) CreateUser(ctx context.Context, username string, email string, pa
 		return models.FullUser{}, err
 	}
 
-	return utilities.MapUserToCurrentUserDTO(user), nil
+	return r.mapFullUser(ctx, user)
 }
 
 // GetVerificationCode retrieves a verification code for a user
@@ -229,6 +426,48 @@ func (r Store) UnblockUser(ctx context.Context, currentUserId int, targetUserId
 	})
 }
 
+// DeleteInteractionsBetweenUsers removes the likes, reactions and poll votes each user has left on
+// the other's posts and comments, and takes each of them off the other's close friends and
+// audience lists.
+func (r Store) DeleteInteractionsBetweenUsers(ctx context.Context, userId int, targetUserId int) error {
+	err := r.querier.DeleteLikesBetweenUsers(ctx, queries.DeleteLikesBetweenUsersParams{
+		UserID:       userId,
+		TargetUserID: targetUserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.querier.DeleteReactionsBetweenUsers(ctx, queries.DeleteReactionsBetweenUsersParams{
+		UserID:       userId,
+		TargetUserID: targetUserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.querier.DeletePollVotesBetweenUsers(ctx, queries.DeletePollVotesBetweenUsersParams{
+		UserID:       userId,
+		TargetUserID: targetUserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.querier.DeleteRelationshipsBetweenUsers(ctx, queries.DeleteRelationshipsBetweenUsersParams{
+		UserID:       userId,
+		TargetUserID: targetUserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.querier.DeleteAudienceListMembershipsBetweenUsers(ctx, queries.DeleteAudienceListMembershipsBetweenUsersParams{
+		UserID:       userId,
+		TargetUserID: targetUserId,
+	})
+}
+
 func (r Store) IsUserBlockingUser(ctx context.Context, blockerId int, blockedId int) (bool, error) {
 	return r.querier.GetIsUserBlockingUser(ctx, queries.GetIsUserBlockingUserParams{
 		UserID:       blockerId,
@@ -236,10 +475,15 @@ func (r Store) IsUserBlockingUser(ctx context.Context, blockerId int, blockedId
 	})
 }
 
-func (r Store) MuteUser(ctx context.Context, currentUserId int, targetUserId int) error {
+// MuteUser mutes a user until expiresAt, or indefinitely when it's nil, replacing any existing
+// mute of that user.
+func (r Store) MuteUser(ctx context.Context, currentUserId int, targetUserId int, mode models.MuteMode, expiresAt *time.Time) error {
 	return r.querier.MuteUser(ctx, queries.MuteUserParams{
-		UserID:       currentUserId,
-		TargetUserID: targetUserId,
+		UserID:            currentUserId,
+		TargetUserID:      targetUserId,
+		HidePosts:         mode != models.MuteModeNotifications,
+		MuteNotifications: mode != models.MuteModePosts,
+		ExpiresAt:         expiresAt,
 	})
 }
 
@@ -250,6 +494,94 @@ func (r Store) UnmuteUser(ctx context.Context, currentUserId int, targetUserId i
 	})
 }
 
+// ListMutedUsers retrieves the current user's active mutes, most recent first.
+func (r Store) ListMutedUsers(ctx context.Context, userId int, limit int, before *time.Time) ([]queries.ListMutedUsersRow, *time.Time, error) {
+	rows, err := r.querier.ListMutedUsers(ctx, queries.ListMutedUsersParams{
+		UserID: userId,
+		Before: before,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cursor *time.Time
+	if len(rows) > 0 {
+		t := rows[len(rows)-1].CreatedAt.Time
+		cursor = &t
+	}
+
+	return rows, cursor, nil
+}
+
+func (r Store) DeleteExpiredMutes(ctx context.Context) error {
+	return r.querier.DeleteExpiredMutes(ctx)
+}
+
+func (r Store) GetBlockedUserIds(ctx context.Context, userId int, limit int, before *time.Time) ([]int, *time.Time, error) {
+	rows, err := r.querier.ListBlockedUserIds(ctx, queries.ListBlockedUserIdsParams{
+		UserID: userId,
+		Before: before,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIds := make([]int, len(rows))
+	for i, row := range rows {
+		userIds[i] = row.UserID
+	}
+
+	var cursor *time.Time
+	if len(rows) > 0 {
+		t := rows[len(rows)-1].CreatedAt.Time
+		cursor = &t
+	}
+
+	return userIds, cursor, nil
+}
+
+// UpsertMutedWord mutes a phrase for a user, replacing the scopes and expiry if it's already muted.
+func (r Store) UpsertMutedWord(ctx context.Context, userId int, phrase string, pattern string, scopes []models.MutedWordScope, expiresAt *time.Time) (queries.MutedWord, error) {
+	return r.querier.UpsertMutedWord(ctx, queries.UpsertMutedWordParams{
+		UserID:            userId,
+		Phrase:            phrase,
+		Pattern:           pattern,
+		MuteFeeds:         slices.Contains(scopes, models.MutedWordScopeFeeds),
+		MuteNotifications: slices.Contains(scopes, models.MutedWordScopeNotifications),
+		MuteComments:      slices.Contains(scopes, models.MutedWordScopeComments),
+		ExpiresAt:         expiresAt,
+	})
+}
+
+// ListMutedWords retrieves a user's muted words that haven't expired, newest first.
+func (r Store) ListMutedWords(ctx context.Context, userId int) ([]queries.MutedWord, error) {
+	return r.querier.ListMutedWords(ctx, userId)
+}
+
+// CountOtherMutedWords counts a user's unexpired muted words other than phrase.
+func (r Store) CountOtherMutedWords(ctx context.Context, userId int, phrase string) (int, error) {
+	count, err := r.querier.CountOtherMutedWords(ctx, queries.CountOtherMutedWordsParams{
+		UserID: userId,
+		Phrase: phrase,
+	})
+	return int(count), err
+}
+
+// DeleteMutedWord unmutes one of a user's muted words, reporting whether it existed.
+func (r Store) DeleteMutedWord(ctx context.Context, userId int, id int) (bool, error) {
+	rows, err := r.querier.DeleteMutedWord(ctx, queries.DeleteMutedWordParams{
+		ID:     id,
+		UserID: userId,
+	})
+	return rows > 0, err
+}
+
+func (r Store) DeleteExpiredMutedWords(ctx context.Context) error {
+	return r.querier.DeleteExpiredMutedWords(ctx)
+}
+
 func (r Store) IsUserMutingUser(ctx context.Context, muterId int, mutedId int) (bool, error) {
 	return r.querier.GetIsUserMutingUser(ctx, queries.GetIsUserMutingUserParams{
 		UserID:       muterId,
@@ -261,6 +593,36 @@ func (r Store) DeleteAccount(ctx context.Context, userId int) error {
 	return r.querier.DeleteUserById(ctx, userId)
 }
 
+// DeactivateAccount marks a user as deactivated and signs them out everywhere. Run it in a
+// transaction so neither happens without the other.
+func (r Store) DeactivateAccount(ctx context.Context, userId int) error {
+	if err := r.querier.DeactivateUser(ctx, userId); err != nil {
+		return err
+	}
+
+	return r.querier.DeleteSessionsForUser(ctx, userId)
+}
+
+// ReactivateAccount clears a user's deactivation
+func (r Store) ReactivateAccount(ctx context.Context, userId int) error {
+	return r.querier.ReactivateUser(ctx, userId)
+}
+
+// GetDeactivatedAt retrieves when a user deactivated their account, or nil if it's active
+func (r Store) GetDeactivatedAt(ctx context.Context, userId int) (*time.Time, error) {
+	user, err := r.querier.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.DeactivatedAt, nil
+}
+
+// GetUserIdsDeactivatedBefore retrieves the users who deactivated their account before a given time
+func (r Store) GetUserIdsDeactivatedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	return r.querier.GetUserIdsDeactivatedBefore(ctx, before)
+}
+
 // GetMutualConnectionsForUser retrieves mutual connections between current user and target user
 func (r Store) GetMutualConnectionsForUser(ctx context.Context, currentUserId int, targetUserId int) ([]string, error) {
 	return r.querier.GetMutualConnectionsForUser(ctx, queries.GetMutualConnectionsForUserParams{
@@ -293,6 +655,40 @@ func (r Store) GetFollowingUserIds(ctx context.Context, userId int, limit int, b
 	return userIds, cursor, nil
 }
 
+func (r Store) GetFollowerUserIds(ctx context.Context, userId int, limit int, before *time.Time) ([]int, *time.Time, error) {
+	rows, err := r.querier.GetFollowerUserIds(ctx, queries.GetFollowerUserIdsParams{
+		UserID: userId,
+		Before: before,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIds := make([]int, len(rows))
+	for i, row := range rows {
+		userIds[i] = row.UserID
+	}
+
+	var cursor *time.Time
+	if len(rows) > 0 {
+		t := rows[len(rows)-1].CreatedAt.Time
+		cursor = &t
+	}
+
+	return userIds, cursor, nil
+}
+
+// GetCounters retrieves the follower, following and post counts for a user. Users without a
+// counter row yet have nothing to count.
+func (r Store) GetCounters(ctx context.Context, userId int) (queries.UserCounter, error) {
+	counter, err := r.querier.GetUserCounter(ctx, userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return queries.UserCounter{UserID: userId}, nil
+	}
+	return counter, err
+}
+
 func (r Store) GetMutualUserIds(ctx context.Context, userId int, targetUserId int, limit int, before *time.Time) ([]int, *time.Time, error) {
 	rows, err := r.querier.GetMutualsByUserIdV2(ctx, queries.GetMutualsByUserIdV2Params{
 		UserID:       userId,
@@ -391,6 +787,88 @@ func (r *Store) GetNotificationActorUserIds(ctx context.Context, notificationId
 }
 
 // NewUserRepository creates a new user repository
-func NewUserRepository(querier queries.Querier) Store {
-	return Store{querier: querier}
+func NewUserRepository(querier queries.Querier, bucketRepository bucket.Repository) Store {
+	return Store{querier: querier, bucketRepository: bucketRepository}
+}
+
+// WithTx returns a copy of the repository that runs its queries in the unit of work's transaction
+func (r Store) WithTx(uow *transaction.UnitOfWork) Store {
+	return Store{querier: uow.Querier(), bucketRepository: r.bucketRepository}
+}
+
+// GetProfileImageKeys retrieves the blob keys of a user's avatar and banner, which are empty if unset
+func (r Store) GetProfileImageKeys(ctx context.Context, userId int) (string, string, error) {
+	user, err := r.querier.GetUserById(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+	return user.AvatarKey.String, user.BannerKey.String, nil
+}
+
+// UpdateAvatarKey sets the blob key of a user's avatar, clearing it if key is empty
+func (r Store) UpdateAvatarKey(ctx context.Context, userId int, key string) error {
+	return r.querier.UpdateUserAvatarKey(ctx, queries.UpdateUserAvatarKeyParams{
+		UserID:    userId,
+		AvatarKey: pgtype.Text{String: key, Valid: key != ""},
+	})
+}
+
+// UpdateBannerKey sets the blob key of a user's banner, clearing it if key is empty
+func (r Store) UpdateBannerKey(ctx context.Context, userId int, key string) error {
+	return r.querier.UpdateUserBannerKey(ctx, queries.UpdateUserBannerKeyParams{
+		UserID:    userId,
+		BannerKey: pgtype.Text{String: key, Valid: key != ""},
+	})
+}
+
+// IsPostVisible checks whether the current user can see a post.
+func (r Store) IsPostVisible(ctx context.Context, currentUserId int, postId int) (bool, error) {
+	_, err := r.querier.GetPostById(ctx, queries.GetPostByIdParams{
+		PostID:       postId,
+		TargetUserID: currentUserId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsCommentOnPost checks whether a comment exists and belongs to the given post.
+func (r Store) IsCommentOnPost(ctx context.Context, commentId int, postId int) (bool, error) {
+	comment, err := r.querier.GetCommentById(ctx, commentId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return comment.PostID == postId, nil
+}
+
+// GetLikerUserIds retrieves the users who liked a post, or one of its comments when commentId is
+// set, newest first. Users on either side of a block with the current user are left out.
+func (r Store) GetLikerUserIds(ctx context.Context, currentUserId int, postId int, commentId *int, limit int, before *time.Time) ([]int, *time.Time, error) {
+	rows, err := r.querier.GetLikerUserIds(ctx, queries.GetLikerUserIdsParams{
+		PostID:        postId,
+		CommentID:     commentId,
+		Before:        before,
+		CurrentUserID: currentUserId,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIds := make([]int, len(rows))
+	for i, row := range rows {
+		userIds[i] = row.UserID
+	}
+
+	var cursor *time.Time
+	if len(rows) > 0 {
+		t := rows[len(rows)-1].CreatedAt.Time
+		cursor = &t
+	}
+
+	return userIds, cursor, nil
 }
@@ -0,0 +1,51 @@
+package utilities
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Retry calls fn until it succeeds or has been attempted the given number of times, doubling the
+// wait between attempts starting from delay. It returns the last error if every attempt fails, or
+// the context's error if it is cancelled while waiting.
+func Retry(ctx context.Context, attempts int, delay time.Duration, fn func() error) error {
+	var err error
+	for attempt := range attempts {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt == attempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return err
+}
+
+// RunPeriodically calls fn every interval until ctx is cancelled, starting immediately. Errors are
+// logged rather than stopping the loop, so a failed run is simply retried on the next tick.
+func RunPeriodically(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil {
+			slog.ErrorContext(ctx, "scheduled job failed", "job", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package utilities
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// SessionTokenPrefix marks tokens whose sessions are stored hashed. Tokens issued before hashing
+// was introduced are plain standard base64, which never contains a '.', so the two can't be confused.
+const SessionTokenPrefix = "v2."
+
+// NewSessionToken generates a session token to hand to the client, along with the session ID
+// that should be stored for it. Only the ID ever touches the database.
+func NewSessionToken() (token string, sessionId string, err error) {
+	b := make([]byte, 64)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = SessionTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return token, HashSessionToken(token), nil
+}
+
+// HashSessionToken returns the session ID stored for a token.
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsLegacySessionToken reports whether a token predates hashed sessions, meaning its session
+// may still be stored under the raw token.
+func IsLegacySessionToken(token string) bool {
+	return !strings.HasPrefix(token, SessionTokenPrefix)
+}
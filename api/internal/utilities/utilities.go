@@ -22,6 +22,7 @@ func MapUserToPublicUser(user queries.User) models.PublicUser {
 		Name:       user.Name.String,
 		CreatedAt:  user.CreatedAt.Time,
 		IsVerified: false,
+		IsPrivate:  user.IsPrivate,
 	}
 
 	if user.UserDisplayProperties != nil {
@@ -35,11 +36,13 @@ func MapUserToPublicUser(user queries.User) models.PublicUser {
 
 func MapUserToCurrentUserDTO(user queries.User) models.FullUser {
 	dto := models.FullUser{
-		UserID:    user.UserID,
-		Email:     user.Email,
-		Username:  user.Username,
-		Name:      user.Name.String,
-		CreatedAt: user.CreatedAt.Time,
+		UserID:         user.UserID,
+		Email:          user.Email,
+		Username:       user.Username,
+		Name:           user.Name.String,
+		CreatedAt:      user.CreatedAt.Time,
+		IsPrivate:      user.IsPrivate,
+		RequireAltText: user.RequireAltText,
 	}
 
 	if user.UserDisplayProperties != nil {
@@ -54,12 +57,13 @@ func MapUserToCurrentUserDTO(user queries.User) models.FullUser {
 // MapPost is a utility function to convert from queries.Post to models.Post.
 func MapPost(post queries.Post) models.Post {
 	return models.Post{
-		PostID:     post.PostID,
-		UserID:     post.UserID,
-		Text:       post.Text.String,
-		CreatedAt:  post.CreatedAt.Time.UTC(),
-		Facets:     post.Facets,
-		Visibility: (*models.VisibilityTypeEnum)(&post.Visibilitytype),
+		PostID:         post.PostID,
+		UserID:         post.UserID,
+		Text:           post.Text.String,
+		CreatedAt:      post.CreatedAt.Time.UTC(),
+		Facets:         post.Facets,
+		Visibility:     (*models.VisibilityTypeEnum)(&post.Visibilitytype),
+		AudienceListID: post.AudienceListID,
 	}
 }
 
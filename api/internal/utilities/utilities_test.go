@@ -2,6 +2,8 @@ package utilities_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -95,3 +97,46 @@ func TestSeededRandom(t *testing.T) {
 
 	assert.Equal(t, rand0, rand1)
 }
+
+func TestNewSessionToken(t *testing.T) {
+	token, sessionId, err := utilities.NewSessionToken()
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(token, utilities.SessionTokenPrefix))
+	assert.False(t, utilities.IsLegacySessionToken(token))
+	assert.NotContains(t, token, "/")
+	assert.Equal(t, utilities.HashSessionToken(token), sessionId)
+	assert.NotEqual(t, token, sessionId)
+
+	other, _, err := utilities.NewSessionToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestIsLegacySessionToken(t *testing.T) {
+	assert.True(t, utilities.IsLegacySessionToken("dGVzdA/+dGVzdA=="))
+	assert.False(t, utilities.IsLegacySessionToken("v2.dGVzdA"))
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := utilities.Retry(t.Context(), 3, time.Millisecond, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	calls := 0
+	err := utilities.Retry(t.Context(), 3, time.Millisecond, func() error {
+		calls++
+		return errors.New("permanent")
+	})
+	assert.EqualError(t, err, "permanent")
+	assert.Equal(t, 3, calls)
+}
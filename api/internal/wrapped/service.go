@@ -12,18 +12,20 @@ import (
 	"splajompy.com/api/v2/internal/db/queries"
 	"splajompy.com/api/v2/internal/models"
 	"splajompy.com/api/v2/internal/post"
-	"splajompy.com/api/v2/internal/utilities"
+	"splajompy.com/api/v2/internal/user"
 )
 
 type Service struct {
-	querier     queries.Querier
-	postService *post.Service
+	querier        queries.Querier
+	postService    *post.Service
+	userRepository user.Store
 }
 
-func NewService(querier queries.Querier, postService *post.Service) *Service {
+func NewService(querier queries.Querier, postService *post.Service, userRepository user.Store) *Service {
 	return &Service{
-		querier:     querier,
-		postService: postService,
+		querier:        querier,
+		postService:    postService,
+		userRepository: userRepository,
 	}
 }
 
@@ -514,7 +516,7 @@ func (s *Service) getFavoriteUsers(ctx context.Context, userId int) (*[]models.F
 
 	users := make([]models.FavoriteUserData, 0, limit)
 	for _, uw := range sorted[:limit] {
-		user, err := s.querier.GetUserById(ctx, uw.UserID)
+		user, err := s.userRepository.GetUserById(ctx, uw.UserID)
 		if err != nil {
 			continue
 		}
@@ -525,7 +527,7 @@ func (s *Service) getFavoriteUsers(ctx context.Context, userId int) (*[]models.F
 		}
 
 		users = append(users, models.FavoriteUserData{
-			User:       utilities.MapUserToPublicUser(user),
+			User:       user,
 			Proportion: scaledWeight,
 		})
 	}
@@ -543,7 +545,7 @@ func (s *Service) getControversialPoll(ctx context.Context, userId int) (*models
 	var poll *models.DetailedPoll
 
 	for index := range len(polls) {
-		pollDetails, err := s.postService.GetPollDetails(ctx, userId, polls[index].PostID, polls[index].Attributes.Poll)
+		pollDetails, err := s.postService.GetPollDetails(ctx, userId, polls[index].PostID, polls[index].UserID, polls[index].Attributes.Poll)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1 @@
+DROP TABLE apple_identity;
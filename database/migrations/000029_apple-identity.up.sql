@@ -0,0 +1,8 @@
+CREATE TABLE apple_identity (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    subject TEXT NOT NULL UNIQUE,
+    email TEXT,
+    is_private_email BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP TABLE follow_request;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_private;
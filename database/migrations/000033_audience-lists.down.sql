@@ -0,0 +1,5 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS audience_list_id;
+
+DROP TABLE audience_list_member;
+
+DROP TABLE audience_list;
@@ -0,0 +1,18 @@
+CREATE TABLE audience_list (
+    list_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audience_list_user_id ON audience_list(user_id);
+
+CREATE TABLE audience_list_member (
+    list_id INT NOT NULL REFERENCES audience_list(list_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, user_id)
+);
+
+-- posts shared with a deleted list stay visible only to their author
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS audience_list_id INT REFERENCES audience_list(list_id) ON DELETE SET NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE user_counter (
+    user_id INT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    follower_count INT NOT NULL DEFAULT 0,
+    following_count INT NOT NULL DEFAULT 0,
+    post_count INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO user_counter (user_id, follower_count, following_count, post_count)
+SELECT
+    users.user_id,
+    (SELECT COUNT(*) FROM follows WHERE follows.following_id = users.user_id),
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.user_id),
+    (SELECT COUNT(*) FROM posts WHERE posts.user_id = users.user_id)
+FROM users;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS banner_key;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
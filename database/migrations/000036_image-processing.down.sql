@@ -0,0 +1,2 @@
+ALTER TABLE images DROP COLUMN IF EXISTS variants;
+ALTER TABLE images DROP COLUMN IF EXISTS blurhash;
@@ -0,0 +1,2 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS blurhash TEXT;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]';
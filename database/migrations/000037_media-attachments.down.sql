@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_images_unprocessed;
+
+ALTER TABLE images DROP COLUMN IF EXISTS poster_key;
+ALTER TABLE images DROP COLUMN IF EXISTS duration_ms;
+ALTER TABLE images DROP COLUMN IF EXISTS processing_started_at;
+ALTER TABLE images DROP COLUMN IF EXISTS processing_attempts;
+ALTER TABLE images DROP COLUMN IF EXISTS processing_status;
+ALTER TABLE images DROP COLUMN IF EXISTS media_type;
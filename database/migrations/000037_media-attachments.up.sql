@@ -0,0 +1,8 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS media_type TEXT NOT NULL DEFAULT 'image';
+ALTER TABLE images ADD COLUMN IF NOT EXISTS processing_status TEXT NOT NULL DEFAULT 'ready';
+ALTER TABLE images ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMPTZ;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS duration_ms INT;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS poster_key TEXT;
+
+CREATE INDEX idx_images_unprocessed ON images(image_id) WHERE processing_status IN ('pending', 'processing');
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS require_alt_text;
+ALTER TABLE images DROP COLUMN IF EXISTS alt_text;
@@ -0,0 +1,2 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS alt_text TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS require_alt_text BOOLEAN NOT NULL DEFAULT FALSE;
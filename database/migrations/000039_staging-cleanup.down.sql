@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_images_blob_url_trgm;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- staged uploads are matched to published images by file name
+CREATE INDEX IF NOT EXISTS idx_images_blob_url_trgm ON images USING gin (image_blob_url gin_trgm_ops);
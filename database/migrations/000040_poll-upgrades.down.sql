@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS poll_deadline;
+
+-- keep each user's earliest vote
+DELETE FROM poll_vote a
+USING poll_vote b
+WHERE a.post_id = b.post_id AND a.user_id = b.user_id AND a.id > b.id;
+
+ALTER TABLE poll_vote DROP CONSTRAINT IF EXISTS poll_vote_post_id_user_id_option_index_key;
+ALTER TABLE poll_vote ADD CONSTRAINT poll_vote_post_id_user_id_key UNIQUE (post_id, user_id);
@@ -0,0 +1,11 @@
+-- multiple-choice polls store one row per selected option
+ALTER TABLE poll_vote DROP CONSTRAINT IF EXISTS poll_vote_post_id_user_id_key;
+ALTER TABLE poll_vote ADD CONSTRAINT poll_vote_post_id_user_id_option_index_key UNIQUE (post_id, user_id, option_index);
+
+CREATE TABLE IF NOT EXISTS poll_deadline (
+    post_id INT PRIMARY KEY REFERENCES posts(post_id) ON DELETE CASCADE,
+    closes_at TIMESTAMPTZ NOT NULL,
+    ended_notified_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_deadline_pending ON poll_deadline(closes_at) WHERE ended_notified_at IS NULL;
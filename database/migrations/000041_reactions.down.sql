@@ -0,0 +1,3 @@
+DELETE FROM notifications WHERE notification_type = 'reaction';
+
+DROP TABLE IF EXISTS reaction;
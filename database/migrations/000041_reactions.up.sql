@@ -0,0 +1,10 @@
+-- hearts are stored as likes so older clients keep seeing them; every other reaction lives here
+CREATE TABLE IF NOT EXISTS reaction (
+    post_id INT NOT NULL REFERENCES posts(post_id) ON DELETE CASCADE,
+    comment_id INT REFERENCES comments(comment_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reaction_target_user_emoji ON reaction (post_id, COALESCE(comment_id, 0), user_id, emoji);
@@ -0,0 +1,14 @@
+-- pattern is a case-insensitive whole-word regex built from the phrase when it's saved, so feeds
+-- can match it directly against post and comment text
+CREATE TABLE IF NOT EXISTS muted_word (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    phrase TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    mute_feeds BOOLEAN NOT NULL DEFAULT TRUE,
+    mute_notifications BOOLEAN NOT NULL DEFAULT TRUE,
+    mute_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, phrase)
+);
@@ -0,0 +1,6 @@
+DELETE FROM mute WHERE NOT hide_posts;
+
+ALTER TABLE mute
+    DROP COLUMN IF EXISTS hide_posts,
+    DROP COLUMN IF EXISTS mute_notifications,
+    DROP COLUMN IF EXISTS expires_at;
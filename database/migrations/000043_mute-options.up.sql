@@ -0,0 +1,5 @@
+-- existing mutes keep hiding posts; notification-only mutes leave posts visible
+ALTER TABLE mute
+    ADD COLUMN IF NOT EXISTS hide_posts BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS mute_notifications BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
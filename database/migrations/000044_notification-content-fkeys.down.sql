@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_notifications_comment_id;
+DROP INDEX IF EXISTS idx_notifications_post_id;
+
+ALTER TABLE notifications
+DROP CONSTRAINT IF EXISTS notifications_comment_id_fkey,
+DROP CONSTRAINT IF EXISTS notifications_post_id_fkey;
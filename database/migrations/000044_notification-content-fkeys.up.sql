@@ -0,0 +1,17 @@
+-- notifications left behind by posts and comments deleted before these keys existed
+DELETE FROM notifications
+WHERE (post_id IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM posts WHERE posts.post_id = notifications.post_id
+    ))
+    OR (comment_id IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM comments WHERE comments.comment_id = notifications.comment_id
+    ));
+
+ALTER TABLE notifications
+DROP CONSTRAINT IF EXISTS notifications_post_id_fkey,
+DROP CONSTRAINT IF EXISTS notifications_comment_id_fkey,
+ADD CONSTRAINT notifications_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+ADD CONSTRAINT notifications_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES comments(comment_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_post_id ON notifications(post_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_comment_id ON notifications(comment_id);
@@ -0,0 +1,2 @@
+DROP TABLE comment_counter;
+DROP TABLE post_counter;
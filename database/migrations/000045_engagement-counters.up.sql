@@ -0,0 +1,36 @@
+CREATE TABLE post_counter (
+    post_id INT PRIMARY KEY REFERENCES posts(post_id) ON DELETE CASCADE,
+    like_count INT NOT NULL DEFAULT 0,
+    comment_count INT NOT NULL DEFAULT 0,
+    poll_vote_count INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE comment_counter (
+    comment_id INT PRIMARY KEY REFERENCES comments(comment_id) ON DELETE CASCADE,
+    like_count INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO post_counter (post_id, like_count, comment_count, poll_vote_count)
+SELECT
+    posts.post_id,
+    (SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.post_id AND likes.comment_id IS NULL),
+    (
+        -- comments hidden by deactivation or by a block with the post's author aren't counted
+        SELECT COUNT(*)
+        FROM comments
+        JOIN users ON users.user_id = comments.user_id
+        WHERE comments.post_id = posts.post_id
+        AND users.deactivated_at IS NULL
+        AND NOT EXISTS (
+            SELECT 1
+            FROM block
+            WHERE (block.user_id = posts.user_id AND block.target_user_id = comments.user_id)
+                OR (block.user_id = comments.user_id AND block.target_user_id = posts.user_id)
+        )
+    ),
+    (SELECT COUNT(*) FROM poll_vote WHERE poll_vote.post_id = posts.post_id)
+FROM posts;
+
+INSERT INTO comment_counter (comment_id, like_count)
+SELECT comments.comment_id, (SELECT COUNT(*) FROM likes WHERE likes.comment_id = comments.comment_id)
+FROM comments;
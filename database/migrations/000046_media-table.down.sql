@@ -0,0 +1,15 @@
+ALTER INDEX IF EXISTS comment_media_pkey RENAME TO comment_images_pkey;
+ALTER TABLE comment_media RENAME COLUMN media_id TO image_id;
+ALTER TABLE comment_media RENAME TO comment_images;
+
+ALTER INDEX IF EXISTS post_media_pkey RENAME TO post_images_pkey;
+ALTER TABLE post_media RENAME COLUMN media_id TO image_id;
+ALTER TABLE post_media RENAME TO post_images;
+
+ALTER INDEX IF EXISTS idx_media_blob_key_trgm RENAME TO idx_images_blob_url_trgm;
+ALTER INDEX IF EXISTS idx_media_unprocessed RENAME TO idx_images_unprocessed;
+ALTER INDEX IF EXISTS media_pkey RENAME TO images_pkey;
+ALTER SEQUENCE IF EXISTS media_media_id_seq RENAME TO images_image_id_seq;
+ALTER TABLE media RENAME COLUMN blob_key TO image_blob_url;
+ALTER TABLE media RENAME COLUMN media_id TO image_id;
+ALTER TABLE media RENAME TO images;
@@ -0,0 +1,16 @@
+-- the table holds videos and GIFs as well as images
+ALTER TABLE images RENAME TO media;
+ALTER TABLE media RENAME COLUMN image_id TO media_id;
+ALTER TABLE media RENAME COLUMN image_blob_url TO blob_key;
+ALTER SEQUENCE IF EXISTS images_image_id_seq RENAME TO media_media_id_seq;
+ALTER INDEX IF EXISTS images_pkey RENAME TO media_pkey;
+ALTER INDEX IF EXISTS idx_images_unprocessed RENAME TO idx_media_unprocessed;
+ALTER INDEX IF EXISTS idx_images_blob_url_trgm RENAME TO idx_media_blob_key_trgm;
+
+ALTER TABLE post_images RENAME TO post_media;
+ALTER TABLE post_media RENAME COLUMN image_id TO media_id;
+ALTER INDEX IF EXISTS post_images_pkey RENAME TO post_media_pkey;
+
+ALTER TABLE comment_images RENAME TO comment_media;
+ALTER TABLE comment_media RENAME COLUMN image_id TO media_id;
+ALTER INDEX IF EXISTS comment_images_pkey RENAME TO comment_media_pkey;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_data_export_in_progress;
+
+UPDATE data_export SET status = 'pending' WHERE status = 'processing';
+
+ALTER TABLE data_export
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS attempts;
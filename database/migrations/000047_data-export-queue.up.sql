@@ -0,0 +1,12 @@
+ALTER TABLE data_export
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN started_at TIMESTAMPTZ;
+
+-- exports used to be built in-process, so a restart could strand them as pending; keep only the
+-- latest one per user for the queue to pick up
+UPDATE data_export
+SET status = 'failed', completed_at = CURRENT_TIMESTAMP
+WHERE status = 'pending'
+AND id NOT IN (SELECT MAX(id) FROM data_export WHERE status = 'pending' GROUP BY user_id);
+
+CREATE UNIQUE INDEX idx_data_export_in_progress ON data_export(user_id) WHERE status IN ('pending', 'processing');